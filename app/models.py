@@ -1,5 +1,5 @@
 """Pydantic models for API"""
-from typing import Optional, List, Dict, Any
+from typing import Optional, List, Dict, Any, Literal
 from pydantic import BaseModel, Field
 
 class Node(BaseModel):
@@ -82,6 +82,16 @@ class QueryRequest(BaseModel):
     q: str = Field(..., max_length=10000)
     conversation_id: Optional[str] = None
 
+class ConversationTurn(BaseModel):
+    role: Literal["user", "assistant"]
+    content: str = Field(..., max_length=4000)
+
+class AskRequest(BaseModel):
+    """POST /api/ask: the query plus the earlier turns of its conversation"""
+    q: str = Field(..., max_length=10000)
+    conversation_id: Optional[str] = None
+    history: List[ConversationTurn] = Field(default_factory=list)
+
 class AutoSessionRequest(BaseModel):
     conversation_id: str
     max_queries: int = Field(default=20, ge=1, le=50)
@@ -25,7 +25,7 @@ import hashlib
 import threading
 import time
 from datetime import datetime
-from typing import AsyncGenerator, Dict, Any, List
+from typing import AsyncGenerator, Dict, Any, List, Optional
 from functools import lru_cache
 from collections import OrderedDict
 
@@ -682,6 +682,21 @@ def fast_extract_python(text: str) -> Dict[str, List]:
 
 NL = chr(10)
 
+# Conversation context given to the model: the latest turns, each cut short
+MAX_HISTORY_TURNS = 10
+MAX_HISTORY_TURN_CHARS = 500
+
+def format_history(history: Optional[List[Dict[str, str]]], max_turns: int = MAX_HISTORY_TURNS) -> str:
+    """Render the latest conversation turns as 'USER: ...' / 'ASSISTANT: ...' lines"""
+    if not history:
+        return ""
+    lines = []
+    for turn in history[-max_turns:]:
+        content = " ".join(str(turn.get("content", "")).split())[:MAX_HISTORY_TURN_CHARS]
+        if content:
+            lines.append(f"{str(turn.get('role', 'user')).upper()}: {content}")
+    return NL.join(lines)
+
 # =============================================================================
 # LANGUAGE DETECTION
 # =============================================================================
@@ -1201,8 +1216,10 @@ async def extract_entities_from_results(results: List[Dict], use_local_llm: bool
 # MAIN PIPELINE - MULTI-STEP INVESTIGATION
 # =============================================================================
 
-async def process_query(query: str, conversation_id: str = None, is_auto: bool = False) -> AsyncGenerator[Dict[str, Any], None]:
-    """Multi-step investigation pipeline - deep local search, single API call"""
+async def process_query(query: str, conversation_id: str = None, is_auto: bool = False,
+                        history: Optional[List[Dict[str, str]]] = None) -> AsyncGenerator[Dict[str, Any], None]:
+    """Multi-step investigation pipeline - deep local search, single API call.
+    history holds the conversation's earlier turns ({"role", "content"}), oldest first."""
 
     # Detect language
     user_lang = detect_language(query)
@@ -1595,8 +1612,11 @@ NEXT STEP: Try alternate spellings, related names, or broader search terms."""
     from app.config import SYSTEM_PROMPT_L
     mind_context = load_mind_context(query, max_chars=1500)
 
+    # Earlier turns, so follow-up questions are answered in context
+    history_context = format_history(history)
+
     # Claude prompt - rich context from local processing + mind files
-    opus_prompt = f"""User query: "{query}"
+    opus_prompt = f"""{f"CONVERSATION SO FAR:{NL}{history_context}{NL}{NL}" if history_context else ""}User query: "{query}"
 
 SEARCH RESULTS ({len(all_results)} documents found):
 
@@ -1628,7 +1648,7 @@ Forensic analyst. Epstein investigation. Be concise. Cite #IDs.
 <|end|>
 
 <|user|>
-Query: "{query}"
+{f"Earlier:{NL}{format_history(history, max_turns=2)}{NL}" if history_context else ""}Query: "{query}"
 Found: {len(all_results)} docs
 
 {NL.join([f"#{r.get('id')}: {r.get('name', '')[:35]}" for r in all_results[:5]])}
@@ -10,7 +10,7 @@ from fastapi import APIRouter, Query, HTTPException
 from fastapi.responses import StreamingResponse
 
 from app.models import (
-    SearchResult, QueryRequest, AutoSessionRequest, LanguageRequest
+    SearchResult, QueryRequest, AskRequest, AutoSessionRequest, LanguageRequest
 )
 from app.search import search_all, search_emails, search_nodes
 from app.db import execute_query, execute_insert, execute_update
@@ -446,9 +446,18 @@ async def query_post(request: QueryRequest):
 @router.get("/api/ask")
 async def ask(q: str = Query(..., max_length=10000), conversation_id: Optional[str] = None):
     """Main investigation endpoint with SSE streaming"""
+    return _ask_stream(q, conversation_id)
 
+# Investigation with conversation context - the gateway posts earlier turns here
+@router.post("/api/ask")
+async def ask_post(request: AskRequest):
+    """Investigation endpoint with SSE streaming, answering in the context of history"""
+    history = [{"role": t.role, "content": t.content} for t in request.history]
+    return _ask_stream(request.q, request.conversation_id, history)
+
+def _ask_stream(q: str, conversation_id: Optional[str], history: Optional[List[dict]] = None):
     async def event_generator():
-        async for event in process_query(q, conversation_id):
+        async for event in process_query(q, conversation_id, history=history):
             yield f"data: {json.dumps(event)}\n\n"
 
     return StreamingResponse(
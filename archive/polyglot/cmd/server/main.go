@@ -1,15 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
-	"os"
-	"strconv"
 
 	"hybridcore/internal/api"
 	"hybridcore/internal/chat"
+	"hybridcore/internal/config"
 	"hybridcore/internal/db"
 	"hybridcore/internal/llm"
 	"hybridcore/internal/rag"
+	"hybridcore/internal/regex"
 )
 
 func main() {
@@ -17,26 +18,99 @@ func main() {
 	log.Println("Zero-cost OSINT platform with local LLM")
 
 	// Configuration from environment
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnvInt("DB_PORT", 5432)
-	dbUser := getEnv("DB_USER", "hybridcore")
-	dbPass := getEnv("DB_PASS", "hc_secure_2026!")
-	dbName := getEnv("DB_NAME", "hybridcore")
-
-	llmHost := getEnv("LLM_HOST", "127.0.0.1")
-	llmPort := getEnvInt("LLM_PORT", 8001)
-
-	serverPort := getEnv("PORT", "8080")
+	cfg := config.Load()
+	if len(cfg.Server.APIKeys) == 0 && !cfg.Server.SingleTenant {
+		log.Printf("[Server] Warning: no API_KEYS configured, running single-tenant: every caller sees every document")
+		cfg.Server.SingleTenant = true
+	}
 
 	// Connect to PostgreSQL
 	log.Println("[DB] Connecting to PostgreSQL...")
-	if err := db.Connect(dbHost, dbPort, dbUser, dbPass, dbName); err != nil {
+	if err := db.Connect(cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name); err != nil {
 		log.Fatalf("[DB] Failed to connect: %v", err)
 	}
+	if cfg.DB.Migrate {
+		if err := db.Migrate(context.Background()); err != nil {
+			log.Fatalf("[DB] %v", err)
+		}
+		n, err := db.BackfillFingerprints(context.Background())
+		if err != nil {
+			log.Fatalf("[DB] %v", err)
+		}
+		if n > 0 {
+			log.Printf("[DB] Fingerprinted %d documents", n)
+		}
+		if n, err = db.BackfillLanguages(context.Background()); err != nil {
+			log.Fatalf("[DB] %v", err)
+		}
+		if n > 0 {
+			log.Printf("[DB] Detected the language of %d documents", n)
+		}
+	}
+	db.FilterStopwords = cfg.Search.FilterStopwords
+	db.DefaultLimit, db.MaxLimit = cfg.Search.DefaultLimit, cfg.Search.MaxLimit
+	if policy, ok := db.ParseDedupPolicy(cfg.Dedup.Policy); ok {
+		db.Dedup = policy
+	} else {
+		log.Printf("[DB] Unknown dedup policy %q, using %s", cfg.Dedup.Policy, db.Dedup)
+	}
+	if d := cfg.Dedup.NearDistance; d < 0 || d > db.MaxNearDuplicateDistance {
+		log.Fatalf("[DB] DEDUP_NEAR_DISTANCE must be between 0 and %d, got %d", db.MaxNearDuplicateDistance, d)
+	}
+	db.NearDuplicateDistance = cfg.Dedup.NearDistance
+	db.MaxSynonyms, db.MaxExpandedTerms = cfg.Search.MaxSynonyms, cfg.Search.MaxQueryTerms
+	db.LanguageBoost = cfg.Search.LanguageBoost
+	if path := cfg.Search.SynonymsFile; path != "" {
+		synonyms, err := db.LoadSynonyms(path)
+		if err != nil {
+			log.Fatalf("[DB] Synonyms: %v", err)
+		}
+		db.Synonyms = synonyms
+		log.Printf("[DB] Loaded synonyms for %d languages from %s", len(synonyms), path)
+	}
+	if rule, ok := db.ParseMergeRule(cfg.Search.EntityMerge); ok {
+		db.EntityMerge = rule
+	} else {
+		log.Printf("[DB] Unknown entity merge rule %q, using %s", cfg.Search.EntityMerge, db.EntityMerge)
+	}
+
+	// Calibrated pattern confidences, applied before any matcher runs
+	if path := cfg.Regex.ConfidenceFile; path != "" {
+		overrides, err := regex.LoadConfidenceOverrides(path)
+		if err == nil {
+			err = regex.SetConfidenceOverrides(overrides)
+		}
+		if err != nil {
+			log.Fatalf("[Regex] Confidence overrides: %v", err)
+		}
+		log.Printf("[Regex] Loaded %d confidence overrides from %s", len(overrides), path)
+	}
+	// Pattern enable flags, applied before any matcher is built
+	if path := cfg.Regex.PatternsFile; path != "" {
+		flags, err := regex.LoadPatternFlags(path)
+		if err == nil {
+			err = regex.SetPatternFlags(flags)
+		}
+		if err != nil {
+			log.Fatalf("[Regex] Pattern flags: %v", err)
+		}
+		log.Printf("[Regex] Disabled patterns: %v", regex.DisabledPatterns())
+	}
+
+	// One matcher for the API, RAG and chat, built once the flags and
+	// overrides above are in place
+	matcher := regex.NewMatcher()
+	matcher.EnableMetrics(cfg.Regex.Metrics)
 
 	// Initialize LLM client
-	log.Printf("[LLM] Connecting to local LLM at %s:%d...", llmHost, llmPort)
-	llmClient := llm.NewClient(llmHost, llmPort)
+	var llmClient *llm.Client
+	if len(cfg.LLM.URLs) > 0 {
+		log.Printf("[LLM] Connecting to LLM backends %v (balance=%v)...", cfg.LLM.URLs, cfg.LLM.Balance)
+		llmClient = llm.NewMultiClient(cfg.LLM.URLs, cfg.LLM.Balance)
+	} else {
+		log.Printf("[LLM] Connecting to local LLM at %s:%d...", cfg.LLM.Host, cfg.LLM.Port)
+		llmClient = llm.NewClient(cfg.LLM.Host, cfg.LLM.Port)
+	}
 
 	// Check LLM health
 	health, err := llmClient.Health()
@@ -47,37 +121,64 @@ func main() {
 	}
 
 	// Initialize RAG engine
-	ragEngine := rag.NewEngine(llmClient)
+	ragEngine := rag.NewEngine(llmClient, matcher)
+	ragEngine.SetScoreWeights(rag.ScoreWeights{
+		Rank:     cfg.Search.RankWeight,
+		Recency:  cfg.Search.RecencyWeight,
+		Coverage: cfg.Search.CoverageWeight,
+		Feedback: cfg.Search.FeedbackWeight,
+		HalfLife: cfg.Search.RecencyHalfLife,
+	})
+
+	if post, err := rag.ParseTransformers(cfg.RAG.PostProcess); err != nil {
+		log.Fatalf("[RAG] %v", err)
+	} else {
+		ragEngine.SetPostProcessors(post...)
+	}
+	messages := rag.DefaultMessages
+	if path := cfg.RAG.MessagesFile; path != "" {
+		if messages, err = rag.LoadMessages(path); err != nil {
+			log.Fatalf("[RAG] Messages: %v", err)
+		}
+		log.Printf("[RAG] Loaded messages for %d languages from %s", len(messages), path)
+	}
+	if err := ragEngine.SetMessages(messages, cfg.RAG.Language); err != nil {
+		log.Fatalf("[RAG] RAG_LANGUAGE: %v", err)
+	}
+	ragEngine.SetMaxSuggestions(cfg.RAG.Suggestions)
+	wrapper, err := rag.ParseDocumentWrapper(cfg.RAG.Wrapper)
+	if err != nil {
+		log.Fatalf("[RAG] %v", err)
+	}
+	if policy, ok := rag.ParseInjectionPolicy(cfg.RAG.Injection); ok {
+		ragEngine.SetInjectionGuard(policy, wrapper)
+	} else {
+		log.Printf("[RAG] Unknown injection policy %q, using delimit", cfg.RAG.Injection)
+		ragEngine.SetInjectionGuard(rag.InjectionDelimit, wrapper)
+	}
 
 	// Initialize chat manager
-	chatManager := chat.NewManager(ragEngine, llmClient)
+	chatManager := chat.NewManager(ragEngine, llmClient, matcher)
+	if guard, ok := chat.ParseGuardPolicy(cfg.Chat.OutputGuard); ok {
+		chatManager.SetOutputGuard(guard)
+	} else {
+		log.Printf("[Chat] Unknown output guard %q, leaving it off", cfg.Chat.OutputGuard)
+	}
+	if cfg.Stream.ThinkFilter {
+		chatManager.SetThinkFilter(chat.ParseThinkMarkers(cfg.Stream.ThinkMarkers), cfg.Stream.ThinkPrefixes)
+	}
 
 	// Show stats
-	stats := ragEngine.GetStats()
+	stats := ragEngine.GetStats("")
 	log.Printf("[Stats] Documents: %v, Entities: %v, Edges: %v",
 		stats["documents"], stats["entities"], stats["edges"])
 
 	// Start server
-	server := api.NewServer(chatManager, ragEngine)
-	log.Printf("[Server] Starting on :%s", serverPort)
+	server := api.NewServer(cfg, chatManager, ragEngine, matcher)
+	log.Printf("[Server] Starting on :%s", cfg.Server.Port)
 
-	if err := server.Listen(":" + serverPort); err != nil {
+	if err := server.Listen(":" + cfg.Server.Port); err != nil {
 		log.Fatalf("[Server] Failed to start: %v", err)
 	}
 }
 
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultVal
-}
-
-func getEnvInt(key string, defaultVal int) int {
-	if val := os.Getenv(key); val != "" {
-		if i, err := strconv.Atoi(val); err == nil {
-			return i
-		}
-	}
-	return defaultVal
-}
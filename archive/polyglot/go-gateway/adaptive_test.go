@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// runWindow records calls upstream outcomes, errs of them failing, and
+// applies the resulting step to the limiter as StartAdaptiveLimit would
+func runWindow(a AdaptiveLimit, p *upstreamPressure, l *IPRateLimiter, calls, errs int) {
+	req := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < calls; i++ {
+		if i < errs {
+			p.Record(req, nil, errors.New("connection refused"), time.Millisecond)
+		} else {
+			p.Record(req, &http.Response{StatusCode: 200}, nil, time.Millisecond)
+		}
+	}
+	l.SetScale(a.nextScale(l.Scale(), p.Swap()))
+}
+
+func TestAdaptiveLimitTightensThenRecovers(t *testing.T) {
+	a := AdaptiveLimit{Enabled: true, MinSamples: 20, ErrorRate: 0.2, MinScale: 0.2}
+	l := NewIPRateLimiter(10, 20)
+	p := &upstreamPressure{}
+	client := l.GetLimiter("203.0.113.7")
+
+	// Half the upstream calls failing: the limit halves each window down
+	// to MinScale
+	for i := 0; i < 5; i++ {
+		runWindow(a, p, l, 40, 20)
+	}
+	if got := client.Limit(); got != rate.Limit(2) {
+		t.Fatalf("limit under errors = %v, want 2 (10 × MinScale)", got)
+	}
+
+	// Too few calls to judge: no further tightening, even if all fail
+	runWindow(a, p, l, 5, 5)
+	if got := l.Scale(); got <= a.MinScale {
+		t.Errorf("scale after a quiet failing window = %v, want it no lower than before", got)
+	}
+
+	// Throttled traffic stays below MinSamples; healthy quiet windows must
+	// still bring the limit back to full
+	for i := 0; i < 10; i++ {
+		runWindow(a, p, l, 10, 0)
+	}
+	if got := client.Limit(); got != rate.Limit(10) {
+		t.Errorf("limit after recovery = %v, want 10", got)
+	}
+	if got := l.Scale(); got != 1 {
+		t.Errorf("scale after recovery = %v, want 1", got)
+	}
+}
+
+func TestAdaptiveLimitTightensOnLatency(t *testing.T) {
+	a := AdaptiveLimit{MinSamples: 2, ErrorRate: 0.5, Latency: time.Second, MinScale: 0.1}
+	got := a.nextScale(1, pressureWindow{Calls: 10, Latency: 3 * time.Second})
+	if got != 0.5 {
+		t.Errorf("scale after a slow window = %v, want 0.5", got)
+	}
+}
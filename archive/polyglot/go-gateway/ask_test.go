@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAskSendsHistoryInBody(t *testing.T) {
+	var got []askRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req askRequest
+		if r.Method != "POST" || r.URL.RawQuery != "" {
+			t.Errorf("upstream got %s %s, want a POST with no query string", r.Method, r.URL)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		got = append(got, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"type\":\"chunk\",\"text\":\"answer "+req.Query+"\"}\n\n")
+	}))
+	defer llm.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.PythonLLMURL = llm.URL
+	g := NewGateway(cfg)
+
+	for _, q := range []string{"who", "and then"} {
+		rec := httptest.NewRecorder()
+		g.handleAsk(rec, httptest.NewRequest("GET", "/api/ask?conversation_id=c1&q="+url.QueryEscape(q), nil))
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("upstream saw %d requests, want 2", len(got))
+	}
+	if got[0].History == nil || len(got[0].History) != 0 {
+		t.Errorf("first history = %#v, want an empty list", got[0].History)
+	}
+	want := []Turn{{Role: "user", Content: "who"}, {Role: "assistant", Content: "answer who"}}
+	if len(got[1].History) != len(want) {
+		t.Fatalf("second history = %#v, want %#v", got[1].History, want)
+	}
+	for i := range want {
+		if got[1].History[i] != want[i] {
+			t.Errorf("history[%d] = %#v, want %#v", i, got[1].History[i], want[i])
+		}
+	}
+	if got[1].ConversationID != "c1" || got[1].Query != "and then" {
+		t.Errorf("second request = %#v", got[1])
+	}
+}
+
+func TestClassifyIntent(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"email of John Doe", routeLookup},
+		{"what is the phone number listed for the Geneva office", routeLookup},
+		{"qui est le directeur de la société écran", routeLookup},
+		{"IBAN", routeLookup},
+		{"summarize the fraud case", routeAnalysis},
+		{"pourquoi le compte a-t-il été fermé", routeAnalysis},
+		// Keywords match whole words only
+		{"show every transfer made by the holding in March", routeAnalysis},
+		{"accountability failures across the three subsidiaries last year", routeAnalysis},
+		{"emailing between the two directors over the summer", routeAnalysis},
+	}
+	for _, tt := range tests {
+		if got := classifyIntent(tt.query); got != tt.want {
+			t.Errorf("classifyIntent(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestAskRoutesByIntent(t *testing.T) {
+	var searched, asked []string
+	search := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searched = append(searched, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"results":[]}`)
+	}))
+	defer search.Close()
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked = append(asked, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"type\":\"chunk\",\"text\":\"ok\"}\n\n")
+	}))
+	defer llm.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL, cfg.PythonLLMURL = search.URL, llm.URL
+	g := NewGateway(cfg)
+
+	tests := []struct {
+		query, extra, route string
+	}{
+		{"email of John Doe", "", routeLookup},
+		{"summarize the fraud case", "", routeAnalysis},
+		{"email of John Doe", "&route=analysis", routeAnalysis},
+		{"summarize the fraud case", "&route=lookup", routeLookup},
+	}
+	for _, tt := range tests {
+		searched, asked = nil, nil
+		rec := httptest.NewRecorder()
+		g.handleAsk(rec, httptest.NewRequest("GET", "/api/ask?q="+url.QueryEscape(tt.query)+tt.extra, nil))
+
+		if got := rec.Header().Get("X-Route"); got != tt.route {
+			t.Errorf("%q%s: X-Route = %q, want %q", tt.query, tt.extra, got, tt.route)
+		}
+		wantSearched, wantAsked := 1, 0
+		if tt.route == routeAnalysis {
+			wantSearched, wantAsked = 0, 1
+		}
+		if len(searched) != wantSearched || len(asked) != wantAsked {
+			t.Errorf("%q%s: search saw %q, LLM saw %q", tt.query, tt.extra, searched, asked)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	g.handleAsk(rec, httptest.NewRequest("GET", "/api/ask?q=x&route=fast", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("route=fast: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeadLettersOffByDefault(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+	if err := g.StartDeadLetters(); err != nil {
+		t.Fatal(err)
+	}
+	if g.deadLetters != nil {
+		t.Error("dead letters recorded without GATEWAY_DEADLETTER_SIZE")
+	}
+}
+
+func TestDeadLettersSkipProbesAndClose(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	path := filepath.Join(t.TempDir(), "deadletters.jsonl")
+	letters, err := newDeadLetterLog(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d *deadLetterLog) { upstreamLog.deadLetters = d }(upstreamLog.deadLetters)
+	upstreamLog.deadLetters = letters
+
+	probeService(context.Background(), down.URL+"/health")
+	(&Gateway{}).fetchJSON(context.Background(), down.URL+"/search")
+	if _, total := letters.Entries(); total != 1 {
+		t.Fatalf("recorded %d letters, want only the proxied failure", total)
+	}
+
+	if err := letters.Close(); err != nil {
+		t.Fatal(err)
+	}
+	letters.Add(DeadLetter{Target: "after-close"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), "/search") {
+		t.Errorf("dead letter file holds %d lines:\n%s", lines, data)
+	}
+}
+
+// Past capacity the ring keeps the newest size letters, newest first,
+// while total counts every one recorded
+func TestDeadLetterRingKeepsNewest(t *testing.T) {
+	letters, err := newDeadLetterLog(3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		letters.Add(DeadLetter{Target: fmt.Sprintf("/call/%d", i)})
+	}
+
+	entries, total := letters.Entries()
+	if total != 7 {
+		t.Errorf("total = %d, want 7", total)
+	}
+	want := []string{"/call/7", "/call/6", "/call/5"}
+	if len(entries) != len(want) {
+		t.Fatalf("kept %d letters, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, target := range want {
+		if entries[i].Target != target {
+			t.Errorf("entry %d = %s, want %s", i, entries[i].Target, target)
+		}
+	}
+}
+
+func TestDeadLettersEndpoint(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.APIKey = "admin-key"
+	cfg.DeadLetters = DeadLetterSettings{Size: 2}
+	g := NewGateway(cfg)
+	defer func(d *deadLetterLog) { upstreamLog.deadLetters = d }(upstreamLog.deadLetters)
+	if err := g.StartDeadLetters(); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"/a", "/b", "/c"} {
+		g.deadLetters.Add(DeadLetter{Method: "GET", Target: target, Status: 502})
+	}
+
+	r, err := g.newRouter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/deadletters", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without the admin key: %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/admin/deadletters", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Total    int64        `json:"total"`
+		Capacity int          `json:"capacity"`
+		Entries  []DeadLetter `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Total != 3 || body.Capacity != 2 || len(body.Entries) != 2 ||
+		body.Entries[0].Target != "/c" || body.Entries[1].Target != "/b" {
+		t.Errorf("body = %+v, want total 3, capacity 2, entries /c then /b", body)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/gorilla/websocket v1.5.1
 	github.com/rs/cors v1.10.1
+	golang.org/x/sync v0.5.0
 	golang.org/x/time v0.5.0
 )
 
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// investigation is the decoded body of a handleInvestigate response
+type investigation struct {
+	Search        json.RawMessage   `json:"search"`
+	Entities      json.RawMessage   `json:"entities"`
+	SearchError   string            `json:"search_error"`
+	EntitiesError string            `json:"entities_error"`
+	Status        map[string]string `json:"status"`
+	Complete      bool              `json:"complete"`
+	Degraded      bool              `json:"degraded"`
+	Missing       []string          `json:"missing"`
+	Timeline      []PhaseTiming     `json:"timeline"`
+}
+
+// investigate runs one handleInvestigate call against stub search and
+// extract backends; tune adjusts the config first
+func investigate(t *testing.T, search, extract http.HandlerFunc, tune ...func(*Config)) investigation {
+	t.Helper()
+	searchSrv := httptest.NewServer(search)
+	defer searchSrv.Close()
+	extractSrv := httptest.NewServer(extract)
+	defer extractSrv.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL, cfg.RustExtractURL = searchSrv.URL, extractSrv.URL
+	cfg.InvestigationTTL = 0
+	for _, f := range tune {
+		f(cfg)
+	}
+
+	rec := httptest.NewRecorder()
+	NewGateway(cfg).handleInvestigate(rec, httptest.NewRequest("GET", "/api/investigate?q=alice", nil))
+	if rec.Code != 200 {
+		t.Fatalf("investigate: %d %s", rec.Code, rec.Body)
+	}
+	var out investigation
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return out
+}
+
+// reply answers every request with status and body
+func reply(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+func TestNormalizeResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      interface{}
+		want   interface{}
+		status string
+	}{
+		{"nil", nil, []interface{}{}, statusEmpty},
+		{"empty array", []interface{}{}, []interface{}{}, statusEmpty},
+		{"empty object", map[string]interface{}{}, []interface{}{}, statusEmpty},
+		{"results", []interface{}{"a"}, []interface{}{"a"}, statusOK},
+		{"scalar", 0.0, 0.0, statusOK},
+	}
+	for _, tt := range tests {
+		got, status := normalizeResult(tt.v, []interface{}{})
+		if status != tt.status || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: (%v, %s), want (%v, %s)", tt.name, got, status, tt.want, tt.status)
+		}
+	}
+}
+
+func TestDecodeUpstream(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		want    interface{}
+		wantErr string
+	}{
+		{200, "", nil, ""},
+		{200, "  \n", nil, ""},
+		{200, "[]", []interface{}{}, ""},
+		{200, "not json", nil, "decode upstream response"},
+		{502, `{"error":"down"}`, nil, "upstream returned 502"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+		got, err := decodeUpstream(resp)
+		if (err == nil) != (tt.wantErr == "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%d %q: err %v, want %q", tt.status, tt.body, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d %q: %#v, want %#v", tt.status, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestInvestigateEmptyResponses(t *testing.T) {
+	// An empty body and an empty array are "no results", not errors
+	out := investigate(t, reply(200, ""), reply(200, "[]"))
+
+	if string(out.Search) != "[]" || string(out.Entities) != "{}" {
+		t.Errorf("search %s, entities %s; want [] and {}", out.Search, out.Entities)
+	}
+	if out.Status["search"] != statusEmpty || out.Status["entities"] != statusEmpty {
+		t.Errorf("status = %v, want both empty", out.Status)
+	}
+	if out.SearchError != "" || out.EntitiesError != "" || !out.Complete {
+		t.Errorf("empty results reported as failures: %+v", out)
+	}
+}
+
+func TestFailedComponents(t *testing.T) {
+	got := failedComponents(map[string]string{"search": statusError, "entities": statusEmpty, "graph": statusError, "llm": statusOK})
+	if strings.Join(got, ",") != "graph,search" {
+		t.Errorf("failedComponents = %v, want [graph search]", got)
+	}
+	if got := failedComponents(nil); got == nil || len(got) != 0 {
+		t.Errorf("no statuses: %#v, want an empty list", got)
+	}
+}
+
+func TestInvestigateDegradation(t *testing.T) {
+	ok := reply(200, `[{"id":1}]`)
+	down := reply(503, "unavailable")
+
+	tests := []struct {
+		name            string
+		search, extract http.HandlerFunc
+		missing         string
+	}{
+		{"all succeed", ok, ok, ""},
+		{"search down", down, ok, "search"},
+		{"all down", down, down, "entities,search"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := investigate(t, tt.search, tt.extract)
+			if got := strings.Join(out.Missing, ","); got != tt.missing {
+				t.Errorf("missing = %q, want %q", got, tt.missing)
+			}
+			if out.Complete != (tt.missing == "") || out.Degraded != (tt.missing != "") {
+				t.Errorf("complete %v, degraded %v with missing %q", out.Complete, out.Degraded, tt.missing)
+			}
+			if out.Missing == nil {
+				t.Error("missing encoded as null, want []")
+			}
+			for _, name := range out.Missing {
+				if out.Status[name] != statusError {
+					t.Errorf("%s missing but status %q", name, out.Status[name])
+				}
+			}
+		})
+	}
+}
+
+// slow delays h by d
+func slow(d time.Duration, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(d)
+		h(w, r)
+	}
+}
+
+func TestInvestigateTimeline(t *testing.T) {
+	const searchDelay, extractDelay = 40 * time.Millisecond, 10 * time.Millisecond
+	out := investigate(t, slow(searchDelay, reply(200, `[{"id":1}]`)), slow(extractDelay, reply(503, "unavailable")))
+
+	want := map[string]struct {
+		status string
+		delay  time.Duration
+	}{
+		"search":  {"ok", searchDelay},
+		"extract": {"error", extractDelay},
+	}
+	if len(out.Timeline) != len(want) {
+		t.Fatalf("timeline = %+v, want one entry per phase", out.Timeline)
+	}
+	var last float64
+	for _, p := range out.Timeline {
+		w, ok := want[p.Phase]
+		if !ok {
+			t.Errorf("unexpected phase %+v", p)
+			continue
+		}
+		delete(want, p.Phase)
+		if p.Status != w.status {
+			t.Errorf("%s status %q, want %q", p.Phase, p.Status, w.status)
+		}
+		if p.DurationMs < float64(w.delay.Milliseconds()) || p.DurationMs > 2000 {
+			t.Errorf("%s took %vms, want at least the backend's %v", p.Phase, p.DurationMs, w.delay)
+		}
+		if p.StartMs < last {
+			t.Errorf("%s starts at %vms, before the previous phase at %vms", p.Phase, p.StartMs, last)
+		}
+		last = p.StartMs
+	}
+	if len(want) > 0 {
+		t.Errorf("phases missing from timeline: %v", want)
+	}
+}
+
+func TestFanoutGroupLimit(t *testing.T) {
+	for _, limit := range []int{1, 2, 0} {
+		group, _ := fanoutGroup(context.Background(), limit)
+		var inFlight, peak atomic.Int32
+		for i := 0; i < 6; i++ {
+			group.Go(func() error {
+				n := inFlight.Add(1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				time.Sleep(30 * time.Millisecond)
+				inFlight.Add(-1)
+				return nil
+			})
+		}
+		group.Wait()
+
+		want := int32(limit)
+		if limit == 0 {
+			want = 6 // unbounded
+		}
+		if got := peak.Load(); got != want {
+			t.Errorf("limit %d: %d calls at once, want %d", limit, got, want)
+		}
+	}
+}
+
+func TestFanoutFailFast(t *testing.T) {
+	for _, failFast := range []bool{true, false} {
+		group, ctx := fanoutGroup(context.Background(), 2)
+		group.Go(func() error { return fanoutErr(failFast, errors.New("search down")) })
+		var cancelled bool
+		group.Go(func() error {
+			select {
+			case <-ctx.Done():
+				cancelled = true
+			case <-time.After(100 * time.Millisecond):
+			}
+			return nil
+		})
+		group.Wait()
+		if cancelled != failFast {
+			t.Errorf("failFast %v: sibling cancelled = %v", failFast, cancelled)
+		}
+	}
+}
+
+func TestInvestigateFanout(t *testing.T) {
+	// With a limit of one the backends never overlap
+	var mu sync.Mutex
+	var busy, overlapped bool
+	serial := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		overlapped = overlapped || busy
+		busy = true
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		busy = false
+		mu.Unlock()
+		io.WriteString(w, `[{"id":1}]`)
+	}
+	out := investigate(t, serial, serial, func(cfg *Config) { cfg.FanoutLimit = 1 })
+	if overlapped || !out.Complete {
+		t.Errorf("limit 1: overlapped %v, complete %v", overlapped, out.Complete)
+	}
+
+	// Failing fast, a search error cancels the pending extract
+	cancelled := make(chan struct{})
+	hang := func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a closed client once the body is read
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	}
+	began := time.Now()
+	out = investigate(t, reply(503, "unavailable"), hang, func(cfg *Config) { cfg.FanoutFailFast = true })
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("extract call not cancelled after search failed")
+	}
+	if elapsed := time.Since(began); elapsed > 2*time.Second {
+		t.Errorf("investigation took %v despite failing fast", elapsed)
+	}
+	if got := strings.Join(out.Missing, ","); got != "entities,search" {
+		t.Errorf("missing = %q, want entities,search", got)
+	}
+}
+
+func TestInvestigationCacheSkipsFanout(t *testing.T) {
+	var calls atomic.Int32
+	var down atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `[{"id":1}]`)
+	}))
+	defer backend.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL, cfg.RustExtractURL = backend.URL, backend.URL
+	cfg.InvestigationTTL = 100 * time.Millisecond
+	g := NewGateway(cfg)
+
+	// ask returns the X-Cache status and how many backend calls it made
+	ask := func(target string) (string, int32) {
+		t.Helper()
+		before := calls.Load()
+		rec := httptest.NewRecorder()
+		g.handleInvestigate(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != 200 {
+			t.Fatalf("%s: %d %s", target, rec.Code, rec.Body)
+		}
+		return rec.Header().Get("X-Cache"), calls.Load() - before
+	}
+	check := func(step, target, wantCache string, wantCalls int32) {
+		t.Helper()
+		if cache, n := ask(target); cache != wantCache || n != wantCalls {
+			t.Errorf("%s: X-Cache %s with %d backend calls, want %s with %d", step, cache, n, wantCache, wantCalls)
+		}
+	}
+
+	check("first ask", "/api/investigate?q=Alice", "MISS", 2)
+	check("repeat within TTL", "/api/investigate?q=+alice+", "HIT", 0)
+	check("fresh", "/api/investigate?q=alice&fresh=1", "BYPASS", 2)
+
+	time.Sleep(cfg.InvestigationTTL)
+	check("after TTL", "/api/investigate?q=alice", "MISS", 2)
+
+	g.investigation.Purge("alice")
+	down.Store(true)
+	check("degraded", "/api/investigate?q=alice", "MISS", 2)
+	check("after degraded", "/api/investigate?q=alice", "MISS", 2)
+}
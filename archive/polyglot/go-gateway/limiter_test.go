@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Run with -race: thousands of distinct IPs hitting the limiter at once,
+// while the limits are changed and idle entries swept, must each get one
+// stable limiter
+func TestLimiterConcurrentDistinctIPs(t *testing.T) {
+	const ips, workers = 5000, 32
+	l := NewIPRateLimiter(10, 5)
+
+	var got [ips]atomic.Pointer[rate.Limiter]
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < ips; n++ {
+				ip := fmt.Sprintf("10.%d.%d.%d", n>>16, (n>>8)&0xff, n&0xff)
+				lim := l.GetLimiter(ip)
+				lim.Allow()
+				if !got[n].CompareAndSwap(nil, lim) && got[n].Load() != lim {
+					t.Errorf("%s got two different limiters", ip)
+					return
+				}
+			}
+		}(w)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for scale := 1.0; ; scale = 1.5 - scale/2 {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			l.SetScale(scale)
+			l.Evict(time.Hour) // nothing is idle that long
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-done
+
+	if n := l.Len(); n != ips {
+		t.Errorf("Len = %d, want %d", n, ips)
+	}
+	if n := l.Evict(-time.Second); n != ips {
+		t.Errorf("Evict removed %d, want all %d", n, ips)
+	}
+}
+
+// lockedLimiter is the single-mutex map IPRateLimiter replaced, with the
+// last-seen time eviction needs, kept as the benchmark baseline
+type lockedLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*lockedEntry
+}
+
+type lockedEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func (l *lockedLimiter) GetLimiter(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.limiters[ip]
+	if !ok {
+		entry = &lockedEntry{limiter: rate.NewLimiter(10, 5)}
+		l.limiters[ip] = entry
+	}
+	entry.lastSeen = time.Now()
+	return entry.limiter
+}
+
+// BenchmarkGetLimiter compares the sharded limiter with the single-lock
+// baseline on a scan (every call a new IP) and on steady traffic from a
+// known set of IPs
+func BenchmarkGetLimiter(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func() interface{ GetLimiter(string) *rate.Limiter }
+	}{
+		{"single-lock", func() interface{ GetLimiter(string) *rate.Limiter } {
+			return &lockedLimiter{limiters: make(map[string]*lockedEntry)}
+		}},
+		{"sharded", func() interface{ GetLimiter(string) *rate.Limiter } {
+			return NewIPRateLimiter(10, 5)
+		}},
+	}
+	for _, impl := range impls {
+		b.Run("unique/"+impl.name, func(b *testing.B) {
+			l := impl.new()
+			var next atomic.Uint64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					l.GetLimiter(fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff))
+				}
+			})
+		})
+		b.Run("known/"+impl.name, func(b *testing.B) {
+			l := impl.new()
+			ips := make([]string, 1024)
+			for n := range ips {
+				ips[n] = fmt.Sprintf("10.0.%d.%d", n>>8, n&0xff)
+				l.GetLimiter(ips[n])
+			}
+			var next atomic.Uint64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					l.GetLimiter(ips[next.Add(1)%uint64(len(ips))])
+				}
+			})
+		})
+	}
+}
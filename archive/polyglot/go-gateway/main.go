@@ -13,46 +13,363 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	mathrand "math/rand"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
+// =============================================================================
+// BUILD INFO
+// =============================================================================
+
+// Injected at build time:
+//
+//	go build -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=..."
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildTime = "dev"
+)
+
 // =============================================================================
 // CONFIGURATION
 // =============================================================================
 
+// Config is read from the environment, optionally overlaid by a JSON file
+// named in GATEWAY_CONFIG. POST /api/admin/reload re-reads both and swaps
+// in the result. Hot-reloadable: backend URLs, health paths and extra
+// health-checked services, rate limit and burst, request/stream timeouts,
+// CORS origins, the upstream log sample rate and the investigation cache
+// TTL. Port, MaxConnections, APIKey, the Server timeouts, the upstream
+// Transport, the proxied Routes, the Adaptive limiter settings and the
+// DeadLetters sink only change on restart.
 type Config struct {
-	Port            string
-	RustExtractURL  string
-	PythonLLMURL    string
-	GoSearchURL     string
-	RateLimit       rate.Limit
-	RateBurst       int
-	MaxConnections  int
+	Port              string
+	RustExtractURL    string
+	PythonLLMURL      string
+	GoSearchURL       string
+	BrainURL          string            // not proxied; probed by /api/health/all
+	HealthServices    map[string]string // more services for /api/health/all: name → base URL
+	RateLimit         rate.Limit
+	RateBurst         int
+	MaxConnections    int
+	HealthPaths       map[string]string // backend name → health check path
+	RequestTimeout    time.Duration     // proxied and fan-out calls
+	StreamTimeout     time.Duration     // SSE relays
+	WSPingInterval    time.Duration     // server pings on /api/ws; zero disables
+	WSIdleTimeout     time.Duration     // close sockets silent (no message or pong) this long; zero disables
+	UpstreamLogSample float64           // share of outbound calls logged, 0 (off) to 1
+	FanoutLimit       int               // simultaneous upstream calls per investigation
+	FanoutFailFast    bool              // first failed call cancels the rest
+	InvestigationTTL  time.Duration     // how long complete /api/investigate answers are reused; zero disables
+	CORSOrigins       []string          // browser origins allowed cross-origin on the HTTP API
+	WSOrigins         []string          // browser origins allowed on /api/ws besides the gateway's own; "*" allows any
+	APIKey            string            // guards /api/admin and /api/ws; empty disables both
+	Server            ServerTimeouts
+	Transport         TransportSettings
+	Adaptive          AdaptiveLimit
+	DeadLetters       DeadLetterSettings
+	Routes            []RouteConfig // extra proxied routes, from the config file
+}
+
+// RouteConfig declares a proxied route: requests under Prefix with one of
+// Methods go to Backend, with Prefix replaced by UpstreamPath (or dropped,
+// with StripPrefix) and Headers set on top of the client's
+type RouteConfig struct {
+	Prefix       string            `json:"prefix"`        // e.g. /api/graph; matches itself and paths below it
+	Backend      string            `json:"backend"`       // a HealthTargets name, e.g. go-search or brain
+	UpstreamPath string            `json:"upstream_path"` // defaults to Prefix, or to nothing with StripPrefix
+	StripPrefix  bool              `json:"strip_prefix"`  // forward only the path below Prefix
+	Methods      []string          `json:"methods"`       // defaults to GET
+	Headers      map[string]string `json:"headers"`       // e.g. an internal token; ${VAR} expands from the env
+	Stream       bool              `json:"stream"`        // relay as server-sent events; GET only
+}
+
+// upstreamPath rewrites a client path under Prefix for the backend
+func (rt RouteConfig) upstreamPath(path string) string {
+	base := rt.UpstreamPath
+	if base == "" && !rt.StripPrefix {
+		base = rt.Prefix
+	}
+	rewritten := strings.TrimSuffix(base, "/") + strings.TrimPrefix(path, rt.Prefix)
+	if rewritten == "" {
+		return "/"
+	}
+	return rewritten
+}
+
+// injectHeaders is Headers in canonical form with env references expanded
+func (rt RouteConfig) injectHeaders() http.Header {
+	if len(rt.Headers) == 0 {
+		return nil
+	}
+	h := make(http.Header, len(rt.Headers))
+	for name, value := range rt.Headers {
+		h.Set(name, os.ExpandEnv(value))
+	}
+	return h
+}
+
+// TransportSettings tune the pooled connections to the backends
+type TransportSettings struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int // zero means unlimited
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration // TCP keep-alive probe interval
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+}
+
+// ServerTimeouts bound each client connection. WriteTimeout must cover the
+// longest SSE relay (StreamTimeout); upgraded WebSockets are exempt.
+// Shutdown caps how long a SIGTERM waits for requests and WebSockets to end.
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	Shutdown   time.Duration
+}
+
+// AdaptiveLimit scales the per-IP rate down while the backends struggle:
+// each Interval with at least MinSamples upstream calls whose error share
+// reaches ErrorRate or whose mean latency reaches Latency halves the rate,
+// down to MinScale of RateLimit; every other Interval gives back a tenth.
+type AdaptiveLimit struct {
+	Enabled    bool
+	Interval   time.Duration
+	MinSamples int
+	ErrorRate  float64       // 5xx responses and transport errors over calls
+	Latency    time.Duration // mean time to response headers; zero ignores latency
+	MinScale   float64
+}
+
+// DeadLetterSettings size the record of failed upstream calls: the last
+// Size are kept in memory for /api/admin/deadletters, and with File every
+// one is also appended there as a JSON line. Size zero, the default, turns
+// both off.
+type DeadLetterSettings struct {
+	Size int
+	File string
+}
+
+// configFile is the JSON overlay; absent fields keep their env value
+type configFile struct {
+	RustExtractURL    *string           `json:"rust_extract_url"`
+	PythonLLMURL      *string           `json:"python_llm_url"`
+	GoSearchURL       *string           `json:"go_search_url"`
+	BrainURL          *string           `json:"brain_url"`
+	HealthServices    map[string]string `json:"health_services"`
+	RateLimit         *float64          `json:"rate_limit"`
+	RateBurst         *int              `json:"rate_burst"`
+	RequestTimeout    *string           `json:"request_timeout"`
+	StreamTimeout     *string           `json:"stream_timeout"`
+	InvestigationTTL  *string           `json:"investigate_cache_ttl"`
+	UpstreamLogSample *float64          `json:"upstream_log_sample"`
+	CORSOrigins       []string          `json:"cors_origins"`
+	WSOrigins         []string          `json:"ws_origins"`
+	HealthPaths       map[string]string `json:"health_paths"`
+	Routes            []RouteConfig     `json:"routes"`
+}
+
+// Backend is an upstream service the gateway proxies to
+type Backend struct {
+	Name       string
+	URL        string
+	HealthPath string
+}
+
+func loadConfig() (*Config, error) {
+	config := &Config{
+		Port:           getEnv("GATEWAY_PORT", "8080"),
+		RustExtractURL: getEnv("RUST_EXTRACT_URL", "http://127.0.0.1:9001"),
+		PythonLLMURL:   getEnv("PYTHON_LLM_URL", "http://127.0.0.1:8002"),
+		GoSearchURL:    getEnv("GO_SEARCH_URL", "http://127.0.0.1:9002"),
+		BrainURL:       getEnv("BRAIN_URL", "http://127.0.0.1:8085"),
+		HealthServices: getEnvMap("GATEWAY_HEALTH_SERVICES"),
+		RateLimit:      10, // requests per second
+		RateBurst:      50,
+		MaxConnections: 100,
+		HealthPaths: map[string]string{
+			"rust-extract": getEnv("RUST_EXTRACT_HEALTH_PATH", "/health"),
+			"python-llm":   getEnv("PYTHON_LLM_HEALTH_PATH", "/health"),
+			"go-search":    getEnv("GO_SEARCH_HEALTH_PATH", "/health"),
+			"brain":        getEnv("BRAIN_HEALTH_PATH", "/health"),
+		},
+		RequestTimeout:    getEnvDuration("GATEWAY_REQUEST_TIMEOUT", 30*time.Second),
+		StreamTimeout:     getEnvDuration("GATEWAY_STREAM_TIMEOUT", 5*time.Minute),
+		WSPingInterval:    getEnvDuration("GATEWAY_WS_PING_INTERVAL", 30*time.Second),
+		WSIdleTimeout:     getEnvDuration("GATEWAY_WS_IDLE_TIMEOUT", 75*time.Second),
+		UpstreamLogSample: getEnvFloat("GATEWAY_UPSTREAM_LOG_SAMPLE", 0),
+		FanoutLimit:       getEnvInt("GATEWAY_FANOUT_LIMIT", 4),
+		FanoutFailFast:    getEnvBool("GATEWAY_FANOUT_FAIL_FAST", false),
+		InvestigationTTL:  getEnvDuration("GATEWAY_INVESTIGATE_CACHE_TTL", time.Minute),
+		CORSOrigins:       getEnvList("GATEWAY_CORS_ORIGINS", []string{"*"}),
+		WSOrigins:         getEnvList("GATEWAY_WS_ORIGINS", nil),
+		APIKey:            os.Getenv("GATEWAY_API_KEY"),
+		Server: ServerTimeouts{
+			ReadHeader: getEnvDuration("GATEWAY_READ_HEADER_TIMEOUT", 5*time.Second),
+			Read:       getEnvDuration("GATEWAY_READ_TIMEOUT", 30*time.Second),
+			Write:      getEnvDuration("GATEWAY_WRITE_TIMEOUT", 6*time.Minute),
+			Idle:       getEnvDuration("GATEWAY_IDLE_TIMEOUT", 2*time.Minute),
+			Shutdown:   getEnvDuration("GATEWAY_SHUTDOWN_TIMEOUT", 15*time.Second),
+		},
+		Transport: TransportSettings{
+			MaxIdleConns:        getEnvInt("GATEWAY_UPSTREAM_MAX_IDLE_CONNS", 100),
+			MaxIdleConnsPerHost: getEnvInt("GATEWAY_UPSTREAM_MAX_IDLE_PER_HOST", 32),
+			MaxConnsPerHost:     getEnvInt("GATEWAY_UPSTREAM_MAX_CONNS_PER_HOST", 64),
+			DialTimeout:         getEnvDuration("GATEWAY_UPSTREAM_DIAL_TIMEOUT", 5*time.Second),
+			KeepAlive:           getEnvDuration("GATEWAY_UPSTREAM_KEEPALIVE", 30*time.Second),
+			TLSHandshakeTimeout: getEnvDuration("GATEWAY_UPSTREAM_TLS_TIMEOUT", 10*time.Second),
+			IdleConnTimeout:     getEnvDuration("GATEWAY_UPSTREAM_IDLE_TIMEOUT", 90*time.Second),
+		},
+		Adaptive: AdaptiveLimit{
+			Enabled:    getEnvBool("GATEWAY_ADAPTIVE_RATE", false),
+			Interval:   getEnvDuration("GATEWAY_ADAPTIVE_INTERVAL", 5*time.Second),
+			MinSamples: getEnvInt("GATEWAY_ADAPTIVE_MIN_SAMPLES", 20),
+			ErrorRate:  getEnvFloat("GATEWAY_ADAPTIVE_ERROR_RATE", 0.2),
+			Latency:    getEnvDuration("GATEWAY_ADAPTIVE_LATENCY", 2*time.Second),
+			MinScale:   getEnvFloat("GATEWAY_ADAPTIVE_MIN_SCALE", 0.2),
+		},
+		DeadLetters: DeadLetterSettings{
+			Size: getEnvInt("GATEWAY_DEADLETTER_SIZE", 0),
+			File: os.Getenv("GATEWAY_DEADLETTER_FILE"),
+		},
+	}
+
+	if path := os.Getenv("GATEWAY_CONFIG"); path != "" {
+		if err := config.applyFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// applyFile overlays the JSON config file at path
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	var f configFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	if f.RustExtractURL != nil {
+		c.RustExtractURL = *f.RustExtractURL
+	}
+	if f.PythonLLMURL != nil {
+		c.PythonLLMURL = *f.PythonLLMURL
+	}
+	if f.GoSearchURL != nil {
+		c.GoSearchURL = *f.GoSearchURL
+	}
+	if f.BrainURL != nil {
+		c.BrainURL = *f.BrainURL
+	}
+	for name, url := range f.HealthServices {
+		c.HealthServices[name] = url
+	}
+	if f.RateLimit != nil {
+		c.RateLimit = rate.Limit(*f.RateLimit)
+	}
+	if f.RateBurst != nil {
+		c.RateBurst = *f.RateBurst
+	}
+	if f.RequestTimeout != nil {
+		if c.RequestTimeout, err = time.ParseDuration(*f.RequestTimeout); err != nil {
+			return fmt.Errorf("request_timeout: %w", err)
+		}
+	}
+	if f.StreamTimeout != nil {
+		if c.StreamTimeout, err = time.ParseDuration(*f.StreamTimeout); err != nil {
+			return fmt.Errorf("stream_timeout: %w", err)
+		}
+	}
+	if f.InvestigationTTL != nil {
+		if c.InvestigationTTL, err = time.ParseDuration(*f.InvestigationTTL); err != nil {
+			return fmt.Errorf("investigate_cache_ttl: %w", err)
+		}
+	}
+	if f.UpstreamLogSample != nil {
+		c.UpstreamLogSample = *f.UpstreamLogSample
+	}
+	if f.CORSOrigins != nil {
+		c.CORSOrigins = f.CORSOrigins
+	}
+	if f.WSOrigins != nil {
+		c.WSOrigins = f.WSOrigins
+	}
+	for name, path := range f.HealthPaths {
+		c.HealthPaths[name] = path
+	}
+	if f.Routes != nil {
+		c.Routes = f.Routes
+	}
+	return nil
+}
+
+// Backends lists the upstream services with their health check paths
+func (c *Config) Backends() []Backend {
+	return []Backend{
+		{Name: "rust-extract", URL: c.RustExtractURL, HealthPath: c.HealthPaths["rust-extract"]},
+		{Name: "python-llm", URL: c.PythonLLMURL, HealthPath: c.HealthPaths["python-llm"]},
+		{Name: "go-search", URL: c.GoSearchURL, HealthPath: c.HealthPaths["go-search"]},
+	}
 }
 
-func loadConfig() *Config {
-	return &Config{
-		Port:            getEnv("GATEWAY_PORT", "8080"),
-		RustExtractURL:  getEnv("RUST_EXTRACT_URL", "http://127.0.0.1:9001"),
-		PythonLLMURL:    getEnv("PYTHON_LLM_URL", "http://127.0.0.1:8002"),
-		GoSearchURL:     getEnv("GO_SEARCH_URL", "http://127.0.0.1:9002"),
-		RateLimit:       10, // requests per second
-		RateBurst:       50,
-		MaxConnections:  100,
+// HealthTargets lists every service /api/health/all probes: the proxied
+// backends, the brain and the extra HealthServices, each on its
+// HealthPaths entry or /health
+func (c *Config) HealthTargets() []Backend {
+	targets := append(c.Backends(), Backend{Name: "brain", URL: c.BrainURL, HealthPath: c.HealthPaths["brain"]})
+
+	names := make([]string, 0, len(c.HealthServices))
+	for name := range c.HealthServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path, ok := c.HealthPaths[name]
+		if !ok {
+			path = "/health"
+		}
+		targets = append(targets, Backend{Name: name, URL: c.HealthServices[name], HealthPath: path})
 	}
+	return targets
 }
 
 func getEnv(key, fallback string) string {
@@ -62,61 +379,906 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// getEnvList reads a comma-separated list
+func getEnvList(key string, fallback []string) []string {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// backendURL resolves a backend name as used by RouteConfig
+func (c *Config) backendURL(name string) (string, bool) {
+	for _, t := range c.HealthTargets() {
+		if t.Name == name {
+			return t.URL, true
+		}
+	}
+	return "", false
+}
+
+// getEnvMap reads comma-separated name=value pairs
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key, nil) {
+		if name, value, ok := strings.Cut(item, "="); ok {
+			m[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return m
+}
+
 // =============================================================================
 // RATE LIMITER
 // =============================================================================
 
+// Number of independently locked shards; a power of two
+const limiterShards = 64
+
+// IPRateLimiter hands out one token bucket per client IP. The map is
+// sharded by IP hash so a burst of new IPs (e.g. a scan) only contends on
+// its shard, and lookups of known IPs take just a read lock.
 type IPRateLimiter struct {
-	limiters map[string]*rate.Limiter
+	shards [limiterShards]limiterShard
+	limits atomic.Pointer[limiterSettings]
+	setMu  sync.Mutex // serializes SetLimits and SetScale
+}
+
+// limiterSettings are the configured rate and burst and the scale applied
+// to both; rate and burst hold the effective values
+type limiterSettings struct {
+	rate      rate.Limit
+	burst     int
+	baseRate  rate.Limit
+	baseBurst int
+	scale     float64
+}
+
+func newLimiterSettings(r rate.Limit, b int, scale float64) *limiterSettings {
+	return &limiterSettings{
+		rate:      r * rate.Limit(scale),
+		burst:     max(1, int(math.Ceil(float64(b)*scale))),
+		baseRate:  r,
+		baseBurst: b,
+		scale:     scale,
+	}
+}
+
+type limiterShard struct {
 	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	limiters map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen atomic.Int64 // unix nanos
 }
 
 func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
-	return &IPRateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     r,
-		burst:    b,
+	i := &IPRateLimiter{}
+	i.limits.Store(newLimiterSettings(r, b, 1))
+	for n := range i.shards {
+		i.shards[n].limiters = make(map[string]*limiterEntry)
 	}
+	return i
+}
+
+// SetLimits changes the rate and burst for new and existing clients,
+// keeping the current scale
+func (i *IPRateLimiter) SetLimits(r rate.Limit, b int) {
+	i.setMu.Lock()
+	defer i.setMu.Unlock()
+	i.apply(newLimiterSettings(r, b, i.limits.Load().scale))
+}
+
+// SetScale multiplies the configured rate and burst by scale (burst stays
+// at least 1) for new and existing clients
+func (i *IPRateLimiter) SetScale(scale float64) {
+	i.setMu.Lock()
+	defer i.setMu.Unlock()
+	cur := i.limits.Load()
+	i.apply(newLimiterSettings(cur.baseRate, cur.baseBurst, scale))
+}
+
+// Scale returns the factor SetScale last applied
+func (i *IPRateLimiter) Scale() float64 {
+	return i.limits.Load().scale
+}
+
+func (i *IPRateLimiter) apply(limits *limiterSettings) {
+	i.limits.Store(limits)
+	for s := range i.shards {
+		sh := &i.shards[s]
+		sh.mu.RLock()
+		for _, entry := range sh.limiters {
+			entry.limiter.SetLimit(limits.rate)
+			entry.limiter.SetBurst(limits.burst)
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+// shard picks ip's shard by its FNV-1a hash, computed inline: this is on
+// every request's path
+func (i *IPRateLimiter) shard(ip string) *limiterShard {
+	h := uint32(2166136261)
+	for n := 0; n < len(ip); n++ {
+		h ^= uint32(ip[n])
+		h *= 16777619
+	}
+	return &i.shards[h&(limiterShards-1)]
 }
 
 func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
-	i.mu.Lock()
-	defer i.mu.Unlock()
+	now := time.Now().UnixNano()
+	sh := i.shard(ip)
+
+	sh.mu.RLock()
+	entry, exists := sh.limiters[ip]
+	sh.mu.RUnlock()
 
-	limiter, exists := i.limiters[ip]
 	if !exists {
-		limiter = rate.NewLimiter(i.rate, i.burst)
-		i.limiters[ip] = limiter
+		// Build outside the lock; a racing creator may win, which is fine.
+		// Stamp it first so a sweep can't take it for idle once inserted.
+		limits := i.limits.Load()
+		fresh := &limiterEntry{limiter: rate.NewLimiter(limits.rate, limits.burst)}
+		fresh.lastSeen.Store(now)
+
+		sh.mu.Lock()
+		if entry, exists = sh.limiters[ip]; !exists {
+			entry = fresh
+			sh.limiters[ip] = entry
+		}
+		sh.mu.Unlock()
+	}
+
+	// Eviction works in minutes; skip rewriting a hot IP's shared cache
+	// line on every request
+	if now-entry.lastSeen.Load() >= int64(time.Second) {
+		entry.lastSeen.Store(now)
+	}
+	return entry.limiter
+}
+
+// Len returns the number of tracked IPs
+func (i *IPRateLimiter) Len() int {
+	n := 0
+	for s := range i.shards {
+		sh := &i.shards[s]
+		sh.mu.RLock()
+		n += len(sh.limiters)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// Evict drops limiters idle for longer than maxIdle and returns how many
+// were removed. Shards are swept one at a time to keep lock holds short.
+func (i *IPRateLimiter) Evict(maxIdle time.Duration) int {
+	cutoff := time.Now().Add(-maxIdle).UnixNano()
+	removed := 0
+	for s := range i.shards {
+		sh := &i.shards[s]
+		sh.mu.Lock()
+		for ip, entry := range sh.limiters {
+			if entry.lastSeen.Load() < cutoff {
+				delete(sh.limiters, ip)
+				removed++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return removed
+}
+
+// StartCleanup evicts idle limiters every interval (±25% jitter, so
+// replicas don't sweep in lockstep) until ctx is done.
+func (i *IPRateLimiter) StartCleanup(ctx context.Context, interval, maxIdle time.Duration) {
+	go func() {
+		for {
+			jitter := time.Duration(mathrand.Int63n(int64(interval)/2)) - interval/4
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval + jitter):
+				if n := i.Evict(maxIdle); n > 0 {
+					log.Printf("Rate limiter: evicted %d idle IPs", n)
+				}
+			}
+		}
+	}()
+}
+
+// How often idle per-IP limiters are swept, and how long an IP may be idle
+const (
+	limiterCleanupInterval = time.Minute
+	limiterMaxIdle         = 10 * time.Minute
+)
+
+// upstreamPressure counts outbound call outcomes between adaptive limiter
+// ticks
+type upstreamPressure struct {
+	calls   atomic.Int64
+	errors  atomic.Int64
+	latency atomic.Int64 // nanos, summed
+}
+
+// Record counts one call. Calls the client abandoned say nothing about the
+// backend and are skipped.
+func (p *upstreamPressure) Record(req *http.Request, resp *http.Response, err error, took time.Duration) {
+	if req.Context().Err() != nil {
+		return
+	}
+	p.calls.Add(1)
+	p.latency.Add(int64(took))
+	if err != nil || resp.StatusCode >= 500 {
+		p.errors.Add(1)
+	}
+}
+
+// pressureWindow is one interval's worth of upstreamPressure
+type pressureWindow struct {
+	Calls     int64
+	ErrorRate float64
+	Latency   time.Duration // mean
+}
+
+// Swap returns the counts since the last Swap and starts a new window
+func (p *upstreamPressure) Swap() pressureWindow {
+	calls, errors, latency := p.calls.Swap(0), p.errors.Swap(0), p.latency.Swap(0)
+	if calls == 0 {
+		return pressureWindow{}
+	}
+	return pressureWindow{
+		Calls:     calls,
+		ErrorRate: float64(errors) / float64(calls),
+		Latency:   time.Duration(latency / calls),
+	}
+}
+
+// nextScale moves the limiter scale one step for window: halved when the
+// backends are struggling, otherwise raised by a tenth of the full rate.
+// Tightening needs MinSamples calls to judge by; a quiet window still
+// relaxes, since a tight limit itself keeps the call count down.
+func (a AdaptiveLimit) nextScale(scale float64, window pressureWindow) float64 {
+	struggling := window.ErrorRate >= a.ErrorRate || (a.Latency > 0 && window.Latency >= a.Latency)
+	if struggling && window.Calls >= int64(a.MinSamples) {
+		return math.Max(scale/2, a.MinScale)
 	}
+	return math.Min(scale+0.1, 1)
+}
 
-	return limiter
+// StartAdaptiveLimit retunes the limiter from upstream pressure every
+// Interval until ctx is done; a no-op unless enabled
+func (g *Gateway) StartAdaptiveLimit(ctx context.Context) {
+	a := g.cfg().Adaptive
+	if !a.Enabled || a.Interval <= 0 {
+		return
+	}
+	upstreamLog.pressure = &upstreamPressure{}
+	go func() {
+		ticker := time.NewTicker(a.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				window := upstreamLog.pressure.Swap()
+				scale := g.limiter.Scale()
+				if next := a.nextScale(scale, window); next != scale {
+					g.limiter.SetScale(next)
+					log.Printf("Adaptive rate: scale %.2f -> %.2f (calls=%d errors=%.0f%% latency=%v)",
+						scale, next, window.Calls, window.ErrorRate*100, window.Latency.Round(time.Millisecond))
+				}
+			}
+		}
+	}()
 }
 
 // =============================================================================
 // HTTP CLIENT POOL
 // =============================================================================
 
+// upstreamLog wraps the shared transport; NewGateway swaps in one built
+// from the config
+var upstreamLog = &upstreamLogger{next: http.DefaultTransport}
+
+// httpClient has no overall timeout, which would cut SSE relays short;
+// every call is bounded by its request's context instead
 var httpClient = &http.Client{
-	Timeout: 60 * time.Second,
-	Transport: &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 20,
-		IdleConnTimeout:     90 * time.Second,
-	},
+	Transport: upstreamLog,
+}
+
+// newTransport builds the pooled transport every upstream call shares.
+// HTTP/2 is negotiated with TLS backends; plain-HTTP ones reuse
+// keep-alive HTTP/1.1 connections up to the idle limits.
+func newTransport(t TransportSettings) *http.Transport {
+	dialer := &net.Dialer{Timeout: t.DialTimeout, KeepAlive: t.KeepAlive}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          t.MaxIdleConns,
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       t.MaxConnsPerHost,
+		IdleConnTimeout:       t.IdleConnTimeout,
+		TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// =============================================================================
+// UPSTREAM LOGGING
+// =============================================================================
+
+// Headers whose values never reach the log
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// logSampler picks an evenly spread share of calls: with rate r, exactly
+// floor(n*r) of the first n calls are sampled
+type logSampler struct {
+	rate atomic.Uint64 // math.Float64bits
+	n    atomic.Uint64
+}
+
+func (s *logSampler) SetRate(rate float64) {
+	s.rate.Store(math.Float64bits(math.Max(0, math.Min(1, rate))))
+}
+
+func (s *logSampler) Sample() bool {
+	rate := math.Float64frombits(s.rate.Load())
+	if rate <= 0 {
+		return false
+	}
+	n := s.n.Add(1)
+	return uint64(float64(n)*rate) != uint64(float64(n-1)*rate)
+}
+
+// upstreamLogger is the client transport; it logs method, URL, status,
+// latency and redacted request headers for a sample of outbound calls, and
+// feeds every outcome to the adaptive limiter when that is on
+type upstreamLogger struct {
+	logSampler
+	next        http.RoundTripper
+	pressure    *upstreamPressure // set before serving; nil when adaptive limiting is off
+	deadLetters *deadLetterLog    // set before serving; nil when dead letters are off
+}
+
+func (l *upstreamLogger) RoundTrip(req *http.Request) (*http.Response, error) {
+	sampled := l.Sample()
+	if !sampled && l.pressure == nil && l.deadLetters == nil {
+		return l.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := l.next.RoundTrip(req)
+	// Health probes aren't traffic: they'd skew the pressure window and
+	// fill the dead letters with every outage of a service nobody called
+	probe := isProbe(req.Context())
+	if l.pressure != nil && !probe {
+		l.pressure.Record(req, resp, err, time.Since(start))
+	}
+	if l.deadLetters != nil && !probe {
+		l.deadLetters.Record(req, resp, err, time.Since(start))
+	}
+	if !sampled {
+		return resp, err
+	}
+	status := "error: "
+	if err != nil {
+		status += err.Error()
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	log.Printf("[upstream] %s %s -> %s (%v) headers=%s",
+		req.Method, req.URL, status, time.Since(start).Round(time.Millisecond), redactHeaders(req.Header))
+	return resp, err
+}
+
+// redactHeaders renders h for logging, sorted, with sensitive values masked
+func redactHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := strings.Join(h[k], ",")
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, k+"="+value)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// =============================================================================
+// DEAD LETTERS
+// =============================================================================
+
+// DeadLetter is one failed upstream call: a transport error or a 5xx
+type DeadLetter struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Target     string    `json:"target"` // without the query string, which may carry user text
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// deadLetterLog keeps the most recent failed calls in a ring, appending
+// each to a file as well when one is configured
+type deadLetterLog struct {
+	mu      sync.Mutex
+	ring    []DeadLetter
+	next    int // slot the next letter goes in
+	full    bool
+	total   int64
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// newDeadLetterLog keeps the last size letters, appending every one to
+// path when it isn't empty
+func newDeadLetterLog(size int, path string) (*deadLetterLog, error) {
+	l := &deadLetterLog{ring: make([]DeadLetter, size)}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("dead letter file: %w", err)
+		}
+		l.file, l.encoder = f, json.NewEncoder(f)
+	}
+	return l, nil
+}
+
+// Record keeps the call if it failed. Calls the client abandoned, or a
+// failing fan-out sibling cancelled, are not the backend's failures.
+func (l *deadLetterLog) Record(req *http.Request, resp *http.Response, err error, took time.Duration) {
+	if err == nil && resp.StatusCode < 500 {
+		return
+	}
+	if errors.Is(req.Context().Err(), context.Canceled) {
+		return
+	}
+
+	target := *req.URL
+	target.RawQuery, target.User = "", nil
+	letter := DeadLetter{
+		Time:       time.Now().UTC(),
+		Method:     req.Method,
+		Target:     target.String(),
+		RequestID:  req.Header.Get("X-Request-ID"),
+		DurationMs: took.Milliseconds(),
+	}
+	if err != nil {
+		letter.Error = err.Error()
+	} else {
+		letter.Status = resp.StatusCode
+	}
+	l.Add(letter)
+}
+
+// Add stores letter, overwriting the oldest once the ring is full
+func (l *deadLetterLog) Add(letter DeadLetter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total++
+	if len(l.ring) > 0 {
+		l.ring[l.next] = letter
+		l.next = (l.next + 1) % len(l.ring)
+		l.full = l.full || l.next == 0
+	}
+	if l.encoder != nil {
+		if err := l.encoder.Encode(letter); err != nil {
+			log.Printf("Dead letter file: %v", err)
+		}
+	}
+}
+
+// Close closes the file sink; letters added afterwards are only kept in
+// memory
+func (l *deadLetterLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file, l.encoder = nil, nil
+	return err
+}
+
+// Entries returns the kept letters, newest first, and how many were
+// recorded in all
+func (l *deadLetterLog) Entries() ([]DeadLetter, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.ring)
+	}
+	out := make([]DeadLetter, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, l.ring[(l.next-i+len(l.ring))%len(l.ring)])
+	}
+	return out, l.total
+}
+
+// StartDeadLetters starts recording failed upstream calls per the
+// DeadLetters settings; a no-op when their Size is zero
+func (g *Gateway) StartDeadLetters() error {
+	settings := g.cfg().DeadLetters
+	if settings.Size <= 0 {
+		return nil
+	}
+	l, err := newDeadLetterLog(settings.Size, settings.File)
+	if err != nil {
+		return err
+	}
+	g.deadLetters = l
+	upstreamLog.deadLetters = l
+	return nil
+}
+
+// Admin: the most recent failed upstream calls, newest first
+func (g *Gateway) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if g.deadLetters == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "dead letters disabled (GATEWAY_DEADLETTER_SIZE=0)"})
+		return
+	}
+	entries, total := g.deadLetters.Entries()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":    total,
+		"capacity": len(g.deadLetters.ring),
+		"entries":  entries,
+	})
+}
+
+type requestIDKey struct{}
+
+type probeKey struct{}
+
+// asProbe marks ctx as a health probe's, which upstreamLogger keeps out
+// of the pressure window and the dead letters
+func asProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, probeKey{}, true)
+}
+
+func isProbe(ctx context.Context) bool {
+	probe, _ := ctx.Value(probeKey{}).(bool)
+	return probe
+}
+
+// withRequestID tags ctx so fetchJSON and postJSON forward id upstream
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func setRequestID(ctx context.Context, req *http.Request) {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		req.Header.Set("X-Request-ID", id)
+	}
 }
 
 // =============================================================================
 // WEBSOCKET UPGRADER
 // =============================================================================
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in dev
-	},
+// Browsers can't set headers on a WebSocket handshake, so they send the API
+// key as an "apikey.<key>" subprotocol; other clients can use X-API-Key.
+// Never a query parameter, which would end up in access logs.
+const wsKeyProtocolPrefix = "apikey."
+
+func (g *Gateway) newUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     g.checkOrigin,
+	}
+}
+
+// checkOrigin allows the gateway's own origin and those on WSOrigins.
+// Browsers always send Origin on a handshake; a client that doesn't is
+// refused too, as it can't be told apart from a stripped one.
+func (g *Gateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range g.cfg().WSOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// wsAuthorize checks the handshake's API key, returning the subprotocol to
+// echo back when the key came that way. With no key configured nobody is
+// let in.
+func (g *Gateway) wsAuthorize(r *http.Request) (protocol string, ok bool) {
+	key := g.cfg().APIKey
+	if key == "" {
+		return "", false
+	}
+
+	if given := r.Header.Get("X-API-Key"); given != "" {
+		return "", subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1
+	}
+	for _, p := range websocket.Subprotocols(r) {
+		if given, found := strings.CutPrefix(p, wsKeyProtocolPrefix); found {
+			return p, subtle.ConstantTimeCompare([]byte(given), []byte(key)) == 1
+		}
+	}
+	return "", false
+}
+
+// =============================================================================
+// CONVERSATION HISTORY
+// =============================================================================
+
+// History bounds per conversation
+const (
+	maxConversationTurns = 10
+	maxTurnChars         = 2000
+	maxConversations     = 1000
+)
+
+// Turn is one message of a conversation
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ConversationStore keeps the last few turns of each conversation in
+// memory so /api/ask can give the LLM context
+type ConversationStore struct {
+	mu       sync.Mutex
+	turns    map[string][]Turn
+	order    []string // conversation IDs, oldest first
+	maxTurns int
+}
+
+func NewConversationStore(maxTurns int) *ConversationStore {
+	return &ConversationStore{
+		turns:    make(map[string][]Turn),
+		maxTurns: maxTurns,
+	}
+}
+
+// History returns a copy of the stored turns, oldest first
+func (s *ConversationStore) History(id string) []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.turns[id]...)
+}
+
+// Append records a turn, dropping the oldest turns and conversations
+// once their bounds are reached
+func (s *ConversationStore) Append(id string, turn Turn) {
+	if runes := []rune(turn.Content); len(runes) > maxTurnChars {
+		turn.Content = string(runes[:maxTurnChars])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	turns, exists := s.turns[id]
+	if !exists {
+		s.order = append(s.order, id)
+		if len(s.order) > maxConversations {
+			delete(s.turns, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+
+	turns = append(turns, turn)
+	if len(turns) > s.maxTurns {
+		turns = turns[len(turns)-s.maxTurns:]
+	}
+	s.turns[id] = turns
+}
+
+// sseAnswerCollector accumulates the text of "chunk" events from a
+// relayed SSE stream ("data: {...}" lines)
+type sseAnswerCollector struct {
+	pending []byte
+	answer  strings.Builder
+}
+
+func (c *sseAnswerCollector) Write(p []byte) (int, error) {
+	c.pending = append(c.pending, p...)
+	for {
+		nl := bytes.IndexByte(c.pending, '\n')
+		if nl < 0 {
+			break
+		}
+		line := bytes.TrimSpace(c.pending[:nl])
+		c.pending = c.pending[nl+1:]
+
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			continue
+		}
+		var event struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(bytes.TrimSpace(data), &event) == nil && event.Type == "chunk" {
+			c.answer.WriteString(event.Text)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *sseAnswerCollector) String() string {
+	return strings.TrimSpace(c.answer.String())
+}
+
+// =============================================================================
+// INVESTIGATION CACHE
+// =============================================================================
+
+// maxCachedInvestigations bounds the cache; past it the entry closest to
+// expiry goes first
+const maxCachedInvestigations = 500
+
+// InvestigationCache keeps encoded /api/investigate answers by normalized
+// query so a repeat within the TTL skips the fan-out. Documents are
+// ingested behind the gateway, so an answer can't notice new matches: the
+// TTL bounds how stale it gets, and Purge drops answers early.
+type InvestigationCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedInvestigation
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+type cachedInvestigation struct {
+	body    []byte
+	expires time.Time
+}
+
+func NewInvestigationCache() *InvestigationCache {
+	return &InvestigationCache{entries: make(map[string]cachedInvestigation)}
+}
+
+// investigationKey folds case and spacing so trivially different queries
+// share an entry
+func investigationKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// Get returns the cached answer for query, counting the hit or miss
+func (c *InvestigationCache) Get(query string) ([]byte, bool) {
+	key := investigationKey(query)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !time.Now().Before(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.hits.Add(1)
+		return entry.body, true
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Put caches body as the answer for query for ttl
+func (c *InvestigationCache) Put(query string, body []byte, ttl time.Duration) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedInvestigations {
+		var oldest string
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+				continue
+			}
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = key
+			}
+		}
+		if len(c.entries) >= maxCachedInvestigations {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[investigationKey(query)] = cachedInvestigation{body: body, expires: now.Add(ttl)}
+}
+
+// Purge drops the answer for query, or every answer when query is empty,
+// and reports how many went
+func (c *InvestigationCache) Purge(query string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if query == "" {
+		n := len(c.entries)
+		c.entries = make(map[string]cachedInvestigation)
+		return n
+	}
+	key := investigationKey(query)
+	if _, ok := c.entries[key]; !ok {
+		return 0
+	}
+	delete(c.entries, key)
+	return 1
+}
+
+// Stats reports the hit and miss counts and the live entries
+func (c *InvestigationCache) Stats() map[string]int64 {
+	c.mu.Lock()
+	n := len(c.entries)
+	c.mu.Unlock()
+	return map[string]int64{
+		"hits":    c.hits.Load(),
+		"misses":  c.misses.Load(),
+		"entries": int64(n),
+	}
 }
 
 // =============================================================================
@@ -124,72 +1286,580 @@ var upgrader = websocket.Upgrader{
 // =============================================================================
 
 type Gateway struct {
-	config  *Config
-	limiter *IPRateLimiter
+	config        atomic.Pointer[Config]
+	upgrader      *websocket.Upgrader
+	limiter       *IPRateLimiter
+	conversations *ConversationStore
+	cors          atomic.Pointer[corsState]
+	sockets       *wsHub
+	investigation *InvestigationCache
+	deadLetters   *deadLetterLog // nil when off
 }
 
 func NewGateway(config *Config) *Gateway {
-	return &Gateway{
-		config:  config,
-		limiter: NewIPRateLimiter(config.RateLimit, config.RateBurst),
+	g := &Gateway{
+		limiter:       NewIPRateLimiter(config.RateLimit, config.RateBurst),
+		conversations: NewConversationStore(maxConversationTurns),
+		sockets:       newWSHub(),
+		investigation: NewInvestigationCache(),
+	}
+	g.config.Store(config)
+	g.upgrader = g.newUpgrader()
+	upstreamLog.SetRate(config.UpstreamLogSample)
+	upstreamLog.next = newTransport(config.Transport)
+	return g
+}
+
+// cfg returns the live configuration; treat it as read-only
+func (g *Gateway) cfg() *Config {
+	return g.config.Load()
+}
+
+// Admin: re-read env + config file and swap in the hot-reloadable fields
+func (g *Gateway) handleReload(w http.ResponseWriter, r *http.Request) {
+	old := g.cfg()
+	next, err := loadConfig()
+	if err != nil {
+		log.Printf("Config reload failed: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Restart-only fields keep their running values
+	var pending []string
+	if next.Port != old.Port {
+		pending = append(pending, "port")
 	}
+	if next.MaxConnections != old.MaxConnections {
+		pending = append(pending, "max_connections")
+	}
+	if next.APIKey != old.APIKey {
+		pending = append(pending, "api_key")
+	}
+	if next.Server != old.Server {
+		pending = append(pending, "server_timeouts")
+	}
+	next.Port, next.MaxConnections, next.APIKey = old.Port, old.MaxConnections, old.APIKey
+	if next.Transport != old.Transport {
+		pending = append(pending, "transport")
+	}
+	if !routesEqual(next.Routes, old.Routes) {
+		pending = append(pending, "routes")
+	}
+	if next.Adaptive != old.Adaptive {
+		pending = append(pending, "adaptive")
+	}
+	if next.DeadLetters != old.DeadLetters {
+		pending = append(pending, "dead_letters")
+	}
+	next.Server, next.Transport, next.Routes, next.Adaptive = old.Server, old.Transport, old.Routes, old.Adaptive
+	next.DeadLetters = old.DeadLetters
+
+	g.config.Store(next)
+	g.limiter.SetLimits(next.RateLimit, next.RateBurst)
+	upstreamLog.SetRate(next.UpstreamLogSample)
+	log.Printf("Config reloaded (rate=%v burst=%d)", next.RateLimit, next.RateBurst)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "reloaded",
+		"rate_limit":       float64(next.RateLimit),
+		"rate_burst":       next.RateBurst,
+		"request_timeout":  next.RequestTimeout.String(),
+		"stream_timeout":   next.StreamTimeout.String(),
+		"cors_origins":     next.CORSOrigins,
+		"ws_origins":       next.WSOrigins,
+		"backends":         next.Backends(),
+		"requires_restart": pending,
+	})
 }
 
 // Health check
 func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "healthy",
-		"service": "l-gateway-go",
-		"time":    time.Now().UTC().Format(time.RFC3339),
+		"status":     "healthy",
+		"service":    "l-gateway-go",
+		"time":       time.Now().UTC().Format(time.RFC3339),
+		"version":    version,
+		"commit":     commit,
+		"build_time": buildTime,
+		"go_version": runtime.Version(),
+		"uptime":     time.Since(startTime).Round(time.Second).String(),
+	})
+}
+
+// Liveness: the process is up
+func (g *Gateway) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// Readiness: every backend passes its health check
+func (g *Gateway) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	backends := g.probeBackends(r.Context())
+
+	ready := true
+	for _, b := range backends {
+		if b.(map[string]interface{})["status"] != "healthy" {
+			ready = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	status := "ready"
+	if !ready {
+		status = "not_ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"backends": backends,
 	})
 }
 
 // Stats
 func (g *Gateway) handleStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "ready",
-		"uptime":    time.Since(startTime).String(),
-		"goroutines": "active",
+		"status":            "ready",
+		"uptime":            time.Since(startTime).String(),
+		"goroutines":        "active",
+		"websockets":        g.sockets.Len(),
+		"rate_scale":        g.limiter.Scale(),
+		"investigate_cache": g.investigation.Stats(),
+		"backends":          g.probeBackends(r.Context()),
 	})
 }
 
+// probeBackends checks every backend's health path concurrently
+func (g *Gateway) probeBackends(ctx context.Context) map[string]interface{} {
+	backends := g.cfg().Backends()
+	health := make(map[string]interface{}, len(backends))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, b := range backends {
+		wg.Add(1)
+		go func(b Backend) {
+			defer wg.Done()
+			status, latency := probeHealth(ctx, b.URL+b.HealthPath)
+
+			mu.Lock()
+			health[b.Name] = map[string]interface{}{
+				"status":     status,
+				"latency_ms": latency.Milliseconds(),
+			}
+			mu.Unlock()
+		}(b)
+	}
+
+	wg.Wait()
+	return health
+}
+
+// probeHealth GETs a health URL, reporting "healthy" only on a 2xx
+func probeHealth(ctx context.Context, healthURL string) (string, time.Duration) {
+	h := probeService(ctx, healthURL)
+	return h.Status, time.Duration(h.LatencyMs) * time.Millisecond
+}
+
+// ServiceHealth is one service's entry in /api/health/all
+type ServiceHealth struct {
+	Status    string `json:"status"` // healthy, unhealthy or offline
+	LatencyMs int64  `json:"latency_ms"`
+	Version   string `json:"version,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// maxHealthBody bounds how much of a health response is read for its version
+const maxHealthBody = 64 << 10
+
+// probeService GETs a health URL like probeHealth, also picking the
+// version out of a JSON body when the service reports one
+func probeService(ctx context.Context, healthURL string) ServiceHealth {
+	ctx, cancel := context.WithTimeout(asProbe(ctx), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
+	if err != nil {
+		return ServiceHealth{Status: "offline", Error: err.Error()}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ServiceHealth{Status: "offline", LatencyMs: time.Since(start).Milliseconds(), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Version string `json:"version"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, maxHealthBody)).Decode(&body)
+	h := ServiceHealth{LatencyMs: time.Since(start).Milliseconds(), Version: body.Version}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		h.Status = "unhealthy"
+		h.Error = resp.Status
+		return h
+	}
+	h.Status = "healthy"
+	return h
+}
+
+// handleHealthAll probes every service of the stack concurrently and
+// returns one map with each one's status, latency and version. The overall
+// status is "healthy" only when all of them are; the gateway reports
+// itself without a probe.
+func (g *Gateway) handleHealthAll(w http.ResponseWriter, r *http.Request) {
+	targets := g.cfg().HealthTargets()
+	services := make(map[string]ServiceHealth, len(targets)+1)
+	services["gateway"] = ServiceHealth{Status: "healthy", Version: version}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t Backend) {
+			defer wg.Done()
+			h := probeService(r.Context(), t.URL+t.HealthPath)
+			h.URL = t.URL
+
+			mu.Lock()
+			services[t.Name] = h
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	status := "healthy"
+	down := []string{}
+	for name, h := range services {
+		if h.Status != "healthy" {
+			status = "degraded"
+			down = append(down, name)
+		}
+	}
+	sort.Strings(down)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"time":     time.Now().UTC().Format(time.RFC3339),
+		"services": services,
+		"down":     down,
+	})
+}
+
+// =============================================================================
+// CONFIGURED ROUTES
+// =============================================================================
+
+// reservedPrefixes can't be claimed by configured routes even though no
+// built-in route sits exactly on them
+var reservedPrefixes = []string{"/api/admin"}
+
+// registerRoutes validates the configured routes against the routes
+// already on r and against each other, then adds a proxy for each. Call it
+// after the built-in routes are registered.
+func (g *Gateway) registerRoutes(r *mux.Router, routes []RouteConfig) error {
+	// Only routes with a handler: the /api and /api/admin subrouter
+	// prefixes would otherwise clash with every route under them
+	var builtin []string
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			builtin = append(builtin, tpl)
+		}
+		return nil
+	})
+	builtin = append(builtin, reservedPrefixes...)
+
+	cfg := g.cfg()
+	seen := make(map[string]bool)
+	for i, rt := range routes {
+		if !strings.HasPrefix(rt.Prefix, "/") || strings.HasSuffix(rt.Prefix, "/") {
+			return fmt.Errorf("route %d: prefix %q must start and not end with /", i, rt.Prefix)
+		}
+		if seen[rt.Prefix] {
+			return fmt.Errorf("route %d: duplicate prefix %s", i, rt.Prefix)
+		}
+		seen[rt.Prefix] = true
+		if _, ok := cfg.backendURL(rt.Backend); !ok {
+			return fmt.Errorf("route %s: unknown backend %q", rt.Prefix, rt.Backend)
+		}
+		if rt.Stream && (len(rt.Methods) > 1 || len(rt.Methods) == 1 && !strings.EqualFold(rt.Methods[0], "GET")) {
+			return fmt.Errorf("route %s: stream routes only take GET", rt.Prefix)
+		}
+		for name := range rt.Headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				return fmt.Errorf("route %s: invalid header name %q", rt.Prefix, name)
+			}
+		}
+		for _, path := range builtin {
+			if pathsOverlap(rt.Prefix, path) {
+				return fmt.Errorf("route %s: conflicts with built-in %s", rt.Prefix, path)
+			}
+		}
+	}
+
+	for _, rt := range routes {
+		methods := rt.Methods
+		if len(methods) == 0 {
+			methods = []string{"GET"}
+		}
+		// Injected values, internal tokens included, stay out of the logs
+		for name := range rt.Headers {
+			sensitiveHeaders[http.CanonicalHeaderKey(name)] = true
+		}
+		handler := g.routeProxy(rt)
+		r.Path(rt.Prefix).Methods(methods...).Handler(handler)
+		r.PathPrefix(rt.Prefix + "/").Methods(methods...).Handler(handler)
+		log.Printf("Route %s %v → %s%s", rt.Prefix, methods, rt.Backend, rt.upstreamPath(rt.Prefix))
+	}
+	return nil
+}
+
+// newRouter builds the gateway's route table: the built-in routes, then
+// the configured proxies
+func (g *Gateway) newRouter(routes []RouteConfig) (*mux.Router, error) {
+	r := mux.NewRouter()
+	r.HandleFunc("/healthz", g.handleHealthz).Methods("GET")
+	r.HandleFunc("/readyz", g.handleReadyz).Methods("GET")
+
+	// API routes
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/health", g.handleHealth).Methods("GET")
+	api.HandleFunc("/health/all", g.handleHealthAll).Methods("GET")
+	api.HandleFunc("/stats", g.handleStats).Methods("GET")
+	api.HandleFunc("/extract", g.handleExtract).Methods("POST")
+	api.HandleFunc("/extract/batch", g.handleBatchExtract).Methods("POST")
+	api.HandleFunc("/ask", g.handleAsk).Methods("GET")
+	api.HandleFunc("/search", g.handleSearch).Methods("GET")
+	api.HandleFunc("/investigate", g.handleInvestigate).Methods("GET")
+	api.HandleFunc("/ws", g.handleWebSocket)
+
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(g.adminMiddleware)
+	admin.HandleFunc("/reload", g.handleReload).Methods("POST")
+	admin.HandleFunc("/investigate/purge", g.handlePurgeInvestigations).Methods("POST")
+	admin.HandleFunc("/deadletters", g.handleDeadLetters).Methods("GET")
+
+	// Config-declared proxies, after the built-ins they must not shadow
+	if err := g.registerRoutes(r, routes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// pathsOverlap reports whether one path equals the other or lies below it
+func pathsOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// routeProxy forwards to the route's backend, resolved per request so a
+// reloaded backend URL takes effect
+func (g *Gateway) routeProxy(rt RouteConfig) http.HandlerFunc {
+	inject := rt.injectHeaders()
+	return func(w http.ResponseWriter, r *http.Request) {
+		base, ok := g.cfg().backendURL(rt.Backend)
+		if !ok {
+			http.Error(w, `{"error":"backend not configured"}`, http.StatusBadGateway)
+			return
+		}
+		target := base + rt.upstreamPath(r.URL.Path)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		if rt.Stream {
+			g.proxySSE(w, r, target, nil, inject, nil)
+			return
+		}
+		g.proxyRequest(w, r, target, inject)
+	}
+}
+
+func routesEqual(a, b []RouteConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Prefix != b[i].Prefix || a[i].Backend != b[i].Backend ||
+			a[i].UpstreamPath != b[i].UpstreamPath || strings.Join(a[i].Methods, ",") != strings.Join(b[i].Methods, ",") ||
+			a[i].StripPrefix != b[i].StripPrefix || a[i].Stream != b[i].Stream || !headersEqual(a[i].Headers, b[i].Headers) {
+			return false
+		}
+	}
+	return true
+}
+
+func headersEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
 // Proxy to Rust extraction service
 func (g *Gateway) handleExtract(w http.ResponseWriter, r *http.Request) {
-	g.proxyRequest(w, r, g.config.RustExtractURL+"/extract")
+	g.proxyRequest(w, r, g.cfg().RustExtractURL+"/extract", nil)
 }
 
 // Proxy to Rust batch extraction
 func (g *Gateway) handleBatchExtract(w http.ResponseWriter, r *http.Request) {
-	g.proxyRequest(w, r, g.config.RustExtractURL+"/batch")
+	g.proxyRequest(w, r, g.cfg().RustExtractURL+"/batch", nil)
+}
+
+// askRequest is the body of POST /api/ask on the LLM service
+type askRequest struct {
+	Query          string `json:"q"`
+	ConversationID string `json:"conversation_id"`
+	History        []Turn `json:"history"`
+}
+
+// Ask routes: a lookup (a specific fact or identifier) is answered by the
+// search service alone, an analysis by full LLM synthesis
+const (
+	routeLookup   = "lookup"
+	routeAnalysis = "analysis"
+)
+
+var analysisKeywords = []string{
+	"summarize", "summary", "explain", "analyze", "analyse", "why", "how",
+	"compare", "relationship", "connection", "timeline", "overview", "pattern",
+	"résumer", "résumé", "expliquer", "analyser", "pourquoi", "comment", "lien",
+}
+
+var lookupKeywords = []string{
+	"email", "e-mail", "phone", "address", "number", "account", "iban", "wallet",
+	"who is", "where is", "when did", "date of", "adresse", "téléphone", "qui est",
 }
 
-// Proxy to Python LLM for query processing
+// classifyIntent labels query a lookup or an analysis. Keywords match
+// whole words, so "how" doesn't fire on "show" nor "account" on
+// "accountability"; queries of four words or fewer are lookups.
+func classifyIntent(query string) string {
+	words := queryWords(query)
+	for _, kw := range analysisKeywords {
+		if hasPhrase(words, kw) {
+			return routeAnalysis
+		}
+	}
+	for _, kw := range lookupKeywords {
+		if hasPhrase(words, kw) {
+			return routeLookup
+		}
+	}
+	if len(words) <= 4 {
+		return routeLookup
+	}
+	return routeAnalysis
+}
+
+// queryWords splits s into lowercase words of letters and digits
+func queryWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// hasPhrase reports whether the words of phrase occur in words, in a row
+func hasPhrase(words []string, phrase string) bool {
+	want := queryWords(phrase)
+	if len(want) == 0 {
+		return false
+	}
+	for i := 0; i+len(want) <= len(words); i++ {
+		match := true
+		for j, w := range want {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Proxy a question by its intent, named in the X-Route response header:
+// lookups to the Go search service, analysis to the Python LLM as an
+// event stream. route=lookup|analysis overrides the classifier. A
+// conversation is always analysis, its follow-ups leaning on the history:
+// the recent turns are forwarded as a JSON "history" parameter, and the
+// streamed answer is recorded as the next assistant turn.
 func (g *Gateway) handleAsk(w http.ResponseWriter, r *http.Request) {
-	// SSE streaming - proxy directly
 	query := r.URL.Query().Get("q")
 	convID := r.URL.Query().Get("conversation_id")
 
-	targetURL := fmt.Sprintf("%s/api/ask?q=%s", g.config.PythonLLMURL, query)
-	if convID != "" {
-		targetURL += "&conversation_id=" + convID
+	route := r.URL.Query().Get("route")
+	switch {
+	case route != "" && route != routeLookup && route != routeAnalysis:
+		http.Error(w, "route must be lookup or analysis", http.StatusBadRequest)
+		return
+	case convID != "":
+		route = routeAnalysis
+	case route == "":
+		route = classifyIntent(query)
+	}
+	w.Header().Set("X-Route", route)
+
+	if route == routeLookup {
+		params := url.Values{"q": {query}}
+		g.proxyRequest(w, r, g.cfg().GoSearchURL+"/search?"+params.Encode(), nil)
+		return
 	}
 
-	g.proxySSE(w, r, targetURL)
+	if convID == "" {
+		params := url.Values{"q": {query}}
+		g.proxySSE(w, r, g.cfg().PythonLLMURL+"/api/ask?"+params.Encode(), nil, nil, nil)
+		return
+	}
+
+	// The history goes in a POST body: it can outgrow a URL
+	body, err := json.Marshal(askRequest{
+		Query:          query,
+		ConversationID: convID,
+		History:        append([]Turn{}, g.conversations.History(convID)...),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.conversations.Append(convID, Turn{Role: "user", Content: query})
+
+	answer := &sseAnswerCollector{}
+	g.proxySSE(w, r, g.cfg().PythonLLMURL+"/api/ask", body, nil, answer)
+	if text := answer.String(); text != "" {
+		g.conversations.Append(convID, Turn{Role: "assistant", Content: text})
+	}
 }
 
 // Proxy to Go search service
 func (g *Gateway) handleSearch(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	limit := r.URL.Query().Get("limit")
-	if limit == "" {
-		limit = "20"
+	// go-search applies the default and max limits; only forward what was asked
+	params := url.Values{"q": {r.URL.Query().Get("q")}}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		params.Set("limit", limit)
 	}
 
-	targetURL := fmt.Sprintf("%s/search?q=%s&limit=%s", g.config.GoSearchURL, query, limit)
-	g.proxyRequest(w, r, targetURL)
+	g.proxyRequest(w, r, g.cfg().GoSearchURL+"/search?"+params.Encode(), nil)
 }
 
-// Parallel extraction + search (fan-out)
+// Parallel extraction + search (fan-out). Complete answers are cached for
+// InvestigationTTL; fresh=1 skips the cache and refreshes it. The
+// X-Cache header tells which happened.
 func (g *Gateway) handleInvestigate(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
@@ -197,63 +1867,178 @@ func (g *Gateway) handleInvestigate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fan-out to multiple services in parallel
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	cfg := g.cfg()
+	cacheStatus := "BYPASS"
+	if cfg.InvestigationTTL > 0 && !isTruthy(r.URL.Query().Get("fresh")) {
+		if body, ok := g.investigation.Get(query); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(body)
+			return
+		}
+		cacheStatus = "MISS"
+	}
+
+	// Fan-out to multiple services in parallel, at most FanoutLimit at once
+	ctx, cancel := context.WithTimeout(withRequestID(r.Context(), requestIDFor(r)), cfg.RequestTimeout)
 	defer cancel()
+	group, ctx := fanoutGroup(ctx, cfg.FanoutLimit)
 
-	var wg sync.WaitGroup
 	results := make(map[string]interface{})
+	statuses := make(map[string]string)
 	var mu sync.Mutex
+	tl := newTimeline()
 
 	// 1. Search
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		resp, err := g.fetchJSON(ctx, fmt.Sprintf("%s/search?q=%s&limit=20", g.config.GoSearchURL, query))
+	group.Go(func() error {
+		began := time.Now()
+		resp, err := g.fetchJSON(ctx, fmt.Sprintf("%s/search?q=%s", cfg.GoSearchURL, url.QueryEscape(query)))
+		tl.record("search", began, err)
 		mu.Lock()
+		defer mu.Unlock()
 		if err == nil {
-			results["search"] = resp
-		} else {
-			results["search_error"] = err.Error()
+			results["search"], statuses["search"] = normalizeResult(resp, []interface{}{})
+			return nil
 		}
-		mu.Unlock()
-	}()
+		results["search"], statuses["search"] = []interface{}{}, statusError
+		results["search_error"] = err.Error()
+		return fanoutErr(cfg.FanoutFailFast, err)
+	})
 
 	// 2. Extract entities from query
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	group.Go(func() error {
 		body := map[string]string{"text": query}
-		resp, err := g.postJSON(ctx, g.config.RustExtractURL+"/extract", body)
+		began := time.Now()
+		resp, err := g.postJSON(ctx, cfg.RustExtractURL+"/extract", body)
+		tl.record("extract", began, err)
 		mu.Lock()
+		defer mu.Unlock()
 		if err == nil {
-			results["entities"] = resp
-		} else {
-			results["entities_error"] = err.Error()
+			results["entities"], statuses["entities"] = normalizeResult(resp, map[string]interface{}{})
+			return nil
 		}
-		mu.Unlock()
-	}()
+		results["entities"], statuses["entities"] = map[string]interface{}{}, statusError
+		results["entities_error"] = err.Error()
+		return fanoutErr(cfg.FanoutFailFast, err)
+	})
 
-	wg.Wait()
+	group.Wait()
+	missing := failedComponents(statuses)
+	results["status"] = statuses
+	results["timeline"] = tl.entries()
+	results["complete"] = len(missing) == 0
+	results["degraded"] = len(missing) > 0
+	results["missing"] = missing
 
+	body, err := json.Marshal(results)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	// Degraded answers aren't kept, so the next request retries the backends
+	if cfg.InvestigationTTL > 0 && len(missing) == 0 {
+		g.investigation.Put(query, body, cfg.InvestigationTTL)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cache", cacheStatus)
+	w.Write(body)
+}
+
+// isTruthy reads a boolean query flag: 1, true, yes or on
+func isTruthy(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// Admin: drop cached investigations, for ?q= only or else all of them;
+// call it after ingesting documents so answers pick them up
+func (g *Gateway) handlePurgeInvestigations(w http.ResponseWriter, r *http.Request) {
+	n := g.investigation.Purge(r.URL.Query().Get("q"))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+	json.NewEncoder(w).Encode(map[string]int{"purged": n})
+}
+
+// fanoutGroup bounds an investigation's concurrent upstream calls. Calls
+// cancel their siblings by returning an error, see fanoutErr.
+func fanoutGroup(ctx context.Context, limit int) (*errgroup.Group, context.Context) {
+	group, ctx := errgroup.WithContext(ctx)
+	if limit > 0 {
+		group.SetLimit(limit)
+	}
+	return group, ctx
+}
+
+// fanoutErr is what a failed call returns to its group: the error when
+// failing fast (cancelling the other calls), nil to let them finish
+func fanoutErr(failFast bool, err error) error {
+	if failFast {
+		return err
+	}
+	return nil
 }
 
 // WebSocket handler for real-time updates
 func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if g.cfg().APIKey == "" {
+		http.Error(w, "WebSocket disabled", http.StatusForbidden)
+		return
+	}
+	if !g.checkOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	protocol, ok := g.wsAuthorize(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var header http.Header
+	if protocol != "" {
+		header = http.Header{"Sec-Websocket-Protocol": {protocol}}
+	}
+	conn, err := g.upgrader.Upgrade(w, r, header)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 	defer conn.Close()
+	// Upgraded after a drain started: say goodbye rather than serve
+	if !g.sockets.add(conn) {
+		conn.WriteControl(websocket.CloseMessage, wsGoingAway, time.Now().Add(wsWriteWait))
+		return
+	}
+	defer g.sockets.remove(conn)
+
+	// Any message or pong keeps the socket alive; a half-open peer stops
+	// answering pings and the read below times out
+	cfg := g.cfg()
+	keepAlive := func() error {
+		if cfg.WSIdleTimeout <= 0 {
+			return nil
+		}
+		return conn.SetReadDeadline(time.Now().Add(cfg.WSIdleTimeout))
+	}
+	keepAlive()
+	conn.SetPongHandler(func(string) error { return keepAlive() })
+
+	done := make(chan struct{})
+	defer close(done)
+	if cfg.WSPingInterval > 0 {
+		go wsPinger(conn, cfg.WSPingInterval, done)
+	}
 
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		keepAlive()
 
 		// Echo for now - can be extended
 		var req map[string]interface{}
@@ -261,19 +2046,16 @@ func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			action := req["action"]
 			switch action {
 			case "search":
-				// Handle search request via WebSocket
-				query := req["query"].(string)
-				resp, _ := g.fetchJSON(r.Context(), fmt.Sprintf("%s/search?q=%s", g.config.GoSearchURL, query))
-				data, _ := json.Marshal(map[string]interface{}{
-					"type":   "search_result",
-					"result": resp,
-				})
-				conn.WriteMessage(messageType, data)
+				// Stream per-term batches as they come back
+				query, _ := req["query"].(string)
+				g.streamSearch(r.Context(), conn, messageType, query)
 			case "extract":
 				// Handle extraction request via WebSocket
 				text := req["text"].(string)
 				body := map[string]string{"text": text}
-				resp, _ := g.postJSON(r.Context(), g.config.RustExtractURL+"/extract", body)
+				ctx, cancel := context.WithTimeout(r.Context(), g.cfg().RequestTimeout)
+				resp, _ := g.postJSON(ctx, g.cfg().RustExtractURL+"/extract", body)
+				cancel()
 				data, _ := json.Marshal(map[string]interface{}{
 					"type":   "extract_result",
 					"result": resp,
@@ -286,12 +2068,241 @@ func (g *Gateway) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Deadline for writing a single ping frame
+const wsWriteWait = 10 * time.Second
+
+// wsPinger pings conn every interval until done is closed or a ping fails.
+// WriteControl is safe alongside the handler's own writes.
+func wsPinger(conn *websocket.Conn, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close frame sent to every socket when the gateway shuts down
+var wsGoingAway = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+// wsHub tracks open WebSockets so shutdown can close them with a close
+// frame instead of dropping them with the listener
+type wsHub struct {
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]struct{}
+	draining bool
+	idle     chan struct{} // closed once draining and the last socket is gone
+}
+
+func newWSHub() *wsHub {
+	return &wsHub{conns: make(map[*websocket.Conn]struct{})}
+}
+
+// add registers conn; false once a drain has started
+func (h *wsHub) add(conn *websocket.Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.draining {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	return true
+}
+
+func (h *wsHub) remove(conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[conn]; !ok {
+		return
+	}
+	delete(h.conns, conn)
+	if h.draining && len(h.conns) == 0 {
+		close(h.idle)
+	}
+}
+
+// Len returns the number of open sockets
+func (h *wsHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns)
+}
+
+// Drain refuses new sockets, sends every open one a going-away close frame
+// and waits for their handlers to return. Sockets still open when ctx ends
+// are closed outright; Drain returns how many that was.
+func (h *wsHub) Drain(ctx context.Context) int {
+	h.mu.Lock()
+	if h.draining {
+		h.mu.Unlock()
+		return 0
+	}
+	h.draining = true
+	h.idle = make(chan struct{})
+	conns := make([]*websocket.Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	if len(conns) == 0 {
+		close(h.idle)
+	}
+	idle := h.idle
+	h.mu.Unlock()
+
+	// The peer echoes the close frame, which ends the handler's read loop
+	deadline := time.Now().Add(wsWriteWait)
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, wsGoingAway, deadline)
+	}
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn := range h.conns {
+		conn.Close()
+	}
+	return len(h.conns)
+}
+
+// Max number of per-term searches fanned out for a streamed search
+const maxStreamTerms = 4
+
+// streamSearch fans a query out to go-search one term at a time and emits a
+// search_partial frame per batch as it arrives, then a search_complete frame
+// with the merged, deduplicated results.
+func (g *Gateway) streamSearch(ctx context.Context, conn *websocket.Conn, messageType int, query string) {
+	ctx, cancel := context.WithTimeout(ctx, g.cfg().RequestTimeout)
+	defer cancel()
+
+	terms := strings.Fields(query)
+	if len(terms) > maxStreamTerms {
+		terms = terms[:maxStreamTerms]
+	}
+
+	type batch struct {
+		term   string
+		result interface{}
+		err    error
+	}
+
+	batches := make(chan batch, len(terms))
+	for _, term := range terms {
+		go func(t string) {
+			resp, err := g.fetchJSON(ctx, fmt.Sprintf("%s/search?q=%s", g.cfg().GoSearchURL, url.QueryEscape(t)))
+			batches <- batch{term: t, result: resp, err: err}
+		}(term)
+	}
+
+	seen := make(map[string]bool)
+	merged := []interface{}{}
+	errors := make(map[string]string)
+
+	for i := range terms {
+		b := <-batches
+		frame := map[string]interface{}{
+			"type":   "search_partial",
+			"term":   b.term,
+			"batch":  i + 1,
+			"of":     len(terms),
+			"result": b.result,
+		}
+		if b.err != nil {
+			errors[b.term] = b.err.Error()
+			frame["error"] = b.err.Error()
+		}
+		data, _ := json.Marshal(frame)
+		conn.WriteMessage(messageType, data)
+
+		items, _ := b.result.([]interface{})
+		for _, item := range items {
+			key := fmt.Sprint(item)
+			if obj, ok := item.(map[string]interface{}); ok && obj["id"] != nil {
+				key = fmt.Sprint(obj["id"])
+			}
+			if !seen[key] {
+				seen[key] = true
+				merged = append(merged, item)
+			}
+		}
+	}
+
+	complete := map[string]interface{}{
+		"type":    "search_complete",
+		"query":   query,
+		"batches": len(terms),
+		"result":  merged,
+	}
+	if len(errors) > 0 {
+		complete["errors"] = errors
+	}
+	data, _ := json.Marshal(complete)
+	conn.WriteMessage(messageType, data)
+}
+
+// PhaseTiming is one step of a fan-out's timeline. StartMs is the offset
+// from the start of the request.
+type PhaseTiming struct {
+	Phase      string  `json:"phase"`
+	StartMs    float64 `json:"start_ms"`
+	DurationMs float64 `json:"duration_ms"`
+	Status     string  `json:"status"` // ok or error
+}
+
+// timeline collects phase timings from concurrent goroutines
+type timeline struct {
+	start  time.Time
+	mu     sync.Mutex
+	phases []PhaseTiming
+}
+
+func newTimeline() *timeline {
+	return &timeline{start: time.Now()}
+}
+
+func (t *timeline) record(phase string, began time.Time, err error) {
+	status := statusOK
+	if err != nil {
+		status = statusError
+	}
+	t.mu.Lock()
+	t.phases = append(t.phases, PhaseTiming{
+		Phase:      phase,
+		StartMs:    float64(began.Sub(t.start).Microseconds()) / 1000,
+		DurationMs: float64(time.Since(began).Microseconds()) / 1000,
+		Status:     status,
+	})
+	t.mu.Unlock()
+}
+
+// entries returns the phases ordered by start
+func (t *timeline) entries() []PhaseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := append([]PhaseTiming(nil), t.phases...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartMs < out[j].StartMs })
+	return out
+}
+
 // =============================================================================
 // PROXY HELPERS
 // =============================================================================
 
-func (g *Gateway) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL string) {
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+// proxyRequest forwards r to targetURL with the client's headers, then
+// inject's, which replace any the client sent under the same names
+func (g *Gateway) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL string, inject http.Header) {
+	ctx, cancel := context.WithTimeout(r.Context(), g.cfg().RequestTimeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, r.Method, targetURL, r.Body)
@@ -306,9 +2317,13 @@ func (g *Gateway) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL
 			req.Header.Add(key, value)
 		}
 	}
+	requestID := requestIDFor(r)
+	req.Header.Set("X-Request-ID", requestID)
+	injectHeaders(req.Header, inject)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
+		w.Header().Set("X-Request-ID", requestID)
 		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
@@ -320,20 +2335,38 @@ func (g *Gateway) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL
 			w.Header().Add(key, value)
 		}
 	}
+	w.Header().Set("X-Request-ID", requestID)
 
 	w.WriteHeader(resp.StatusCode)
 	io.Copy(w, resp.Body)
 }
 
-func (g *Gateway) proxySSE(w http.ResponseWriter, r *http.Request, targetURL string) {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+// proxySSE relays an upstream event stream event by event: heartbeats are
+// dropped, JSON payloads are tagged with the request ID, and upstream
+// failures (error status, error event, broken stream) reach the client as
+// an "error" event instead of a silent truncation. The upstream request is
+// a GET, or a POST of body as JSON when body is set; inject is set on it.
+// tap, if set, sees every re-emitted byte.
+func (g *Gateway) proxySSE(w http.ResponseWriter, r *http.Request, targetURL string, body []byte, inject http.Header, tap io.Writer) {
+	ctx, cancel := context.WithTimeout(r.Context(), g.cfg().StreamTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	requestID := requestIDFor(r)
+
+	method, reqBody := "GET", io.Reader(nil)
+	if body != nil {
+		method, reqBody = "POST", bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, reqBody)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Request-ID", requestID)
+	injectHeaders(req.Header, inject)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -347,6 +2380,7 @@ func (g *Gateway) proxySSE(w http.ResponseWriter, r *http.Request, targetURL str
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Request-ID", requestID)
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -354,16 +2388,44 @@ func (g *Gateway) proxySSE(w http.ResponseWriter, r *http.Request, targetURL str
 		return
 	}
 
-	buf := make([]byte, 1024)
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			w.Write(buf[:n])
-			flusher.Flush()
+	emit := func(ev *sseEvent) {
+		data := ev.encode()
+		w.Write(data)
+		flusher.Flush()
+		if tap != nil {
+			tap.Write(data)
 		}
-		if err != nil {
-			break
+	}
+
+	if resp.StatusCode >= 400 {
+		log.Printf("SSE upstream %s returned %d [%s]", targetURL, resp.StatusCode, requestID)
+		emit(sseErrorEvent(requestID, fmt.Sprintf("upstream returned %d", resp.StatusCode)))
+		return
+	}
+
+	err = readSSE(resp.Body, func(ev *sseEvent) error {
+		keep, failure := annotateEvent(ev, requestID)
+		if !keep {
+			return nil
 		}
+		emit(ev)
+		if failure != "" {
+			log.Printf("SSE upstream error [%s]: %s", requestID, failure)
+			return errStreamFailed
+		}
+		return nil
+	})
+	if err != nil && err != errStreamFailed {
+		log.Printf("SSE relay interrupted [%s]: %v", requestID, err)
+		emit(sseErrorEvent(requestID, "upstream stream interrupted"))
+	}
+}
+
+// injectHeaders sets every inject header on h, replacing whatever values
+// h already holds for it, so a client can't supply its own
+func injectHeaders(h, inject http.Header) {
+	for name, values := range inject {
+		h[name] = append([]string(nil), values...)
 	}
 }
 
@@ -372,6 +2434,7 @@ func (g *Gateway) fetchJSON(ctx context.Context, url string) (interface{}, error
 	if err != nil {
 		return nil, err
 	}
+	setRequestID(ctx, req)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -379,24 +2442,21 @@ func (g *Gateway) fetchJSON(ctx context.Context, url string) (interface{}, error
 	}
 	defer resp.Body.Close()
 
-	var result interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	return result, nil
+	return decodeUpstream(resp)
 }
 
 func (g *Gateway) postJSON(ctx context.Context, url string, body interface{}) (interface{}, error) {
-	data, _ := json.Marshal(body)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, io.NopCloser(io.Reader(nil)))
+	data, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// Actually set the body
-	import_body := fmt.Sprintf(`{"text":"%s"}`, body.(map[string]string)["text"])
-	req, _ = http.NewRequestWithContext(ctx, "POST", url, io.NopCloser(
-		io.Reader(nil),
-	))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setRequestID(ctx, req)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -404,13 +2464,220 @@ func (g *Gateway) postJSON(ctx context.Context, url string, body interface{}) (i
 	}
 	defer resp.Body.Close()
 
+	return decodeUpstream(resp)
+}
+
+// Per-component outcome of a fan-out call
+const (
+	statusOK    = "ok"
+	statusEmpty = "empty"
+	statusError = "error"
+)
+
+// failedComponents lists, in sorted order, the fan-out components that
+// errored. Partial results are still returned with 200; this is what
+// tells clients the response is incomplete.
+func failedComponents(statuses map[string]string) []string {
+	missing := []string{}
+	for name, status := range statuses {
+		if status == statusError {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// decodeUpstream parses a JSON response. Error statuses become errors; an
+// empty body is a successful call with no result (nil, nil).
+func decodeUpstream(resp *http.Response) (interface{}, error) {
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
 	var result interface{}
-	json.NewDecoder(resp.Body).Decode(&result)
-	_ = import_body
-	_ = data
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode upstream response: %w", err)
+	}
 	return result, nil
 }
 
+// normalizeResult replaces nil/empty upstream results with the given empty
+// value so clients never see a bare null, and reports whether it was empty.
+func normalizeResult(v interface{}, empty interface{}) (interface{}, string) {
+	switch t := v.(type) {
+	case nil:
+		return empty, statusEmpty
+	case []interface{}:
+		if len(t) == 0 {
+			return empty, statusEmpty
+		}
+	case map[string]interface{}:
+		if len(t) == 0 {
+			return empty, statusEmpty
+		}
+	}
+	return v, statusOK
+}
+
+// =============================================================================
+// SSE RELAY
+// =============================================================================
+
+// sseEvent is one dispatched Server-Sent Event
+type sseEvent struct {
+	ID    string
+	Event string
+	Retry string
+	Data  string // data lines joined with "\n"
+}
+
+// errStreamFailed stops a relay after a terminal upstream error event
+var errStreamFailed = errors.New("upstream reported an error")
+
+// Max size of one SSE line
+const maxSSELine = 1 << 20
+
+// readSSE parses an event stream, calling fn for each dispatched event.
+// Comment lines (": keep-alive") are skipped, so comment-only heartbeats
+// never reach fn. A clean EOF returns nil.
+func readSSE(r io.Reader, fn func(*sseEvent) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxSSELine)
+
+	ev := &sseEvent{}
+	var data []string
+	pending := false
+
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if line == "" {
+			if pending {
+				ev.Data = strings.Join(data, "\n")
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			ev, data, pending = &sseEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			ev.Event = value
+		case "id":
+			ev.ID = value
+		case "retry":
+			ev.Retry = value
+		default:
+			continue
+		}
+		pending = true
+	}
+	return sc.Err()
+}
+
+// encode renders the event in wire format, terminated by a blank line
+func (e *sseEvent) encode() []byte {
+	var b bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	if e.Retry != "" {
+		fmt.Fprintf(&b, "retry: %s\n", e.Retry)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func isHeartbeat(kind string) bool {
+	return kind == "heartbeat" || kind == "ping" || kind == "keepalive"
+}
+
+// annotateEvent drops heartbeats, stamps JSON object payloads with the
+// request ID and reports the message of a terminal error event
+func annotateEvent(ev *sseEvent, requestID string) (keep bool, failure string) {
+	if isHeartbeat(ev.Event) {
+		return false, ""
+	}
+	if ev.Event == "error" {
+		failure = ev.Data
+	}
+
+	var payload map[string]interface{}
+	if json.Unmarshal([]byte(ev.Data), &payload) != nil {
+		return true, failure
+	}
+
+	kind, _ := payload["type"].(string)
+	if isHeartbeat(kind) {
+		return false, ""
+	}
+	if kind == "error" {
+		failure = firstString(payload, "error", "msg", "message")
+		if failure == "" {
+			failure = "unknown error"
+		}
+	}
+
+	payload["request_id"] = requestID
+	if data, err := json.Marshal(payload); err == nil {
+		ev.Data = string(data)
+	}
+	return true, failure
+}
+
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := m[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sseErrorEvent(requestID, msg string) *sseEvent {
+	data, _ := json.Marshal(map[string]string{
+		"type":       "error",
+		"error":      msg,
+		"request_id": requestID,
+	})
+	return &sseEvent{Event: "error", Data: string(data)}
+}
+
+// requestIDFor reuses the caller's X-Request-ID or mints a random one
+func requestIDFor(r *http.Request) string {
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
 // =============================================================================
 // MIDDLEWARE
 // =============================================================================
@@ -429,6 +2696,50 @@ func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// adminMiddleware requires the configured API key in X-API-Key
+func (g *Gateway) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := g.cfg().APIKey
+		if key == "" {
+			http.Error(w, "Admin API disabled", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-API-Key")), []byte(key)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsState caches the CORS handler built for one config generation
+type corsState struct {
+	config  *Config
+	handler http.Handler
+}
+
+// corsMiddleware applies the live CORS origins, rebuilding the handler
+// after a reload
+func (g *Gateway) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := g.cfg()
+		state := g.cors.Load()
+		if state == nil || state.config != config {
+			state = &corsState{
+				config: config,
+				handler: cors.New(cors.Options{
+					AllowedOrigins:   config.CORSOrigins,
+					AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+					AllowedHeaders:   []string{"*"},
+					AllowCredentials: true,
+				}).Handler(next),
+			}
+			g.cors.Store(state)
+		}
+		state.handler.ServeHTTP(w, r)
+	})
+}
+
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -443,30 +2754,40 @@ func loggingMiddleware(next http.Handler) http.Handler {
 
 var startTime = time.Now()
 
+// newServer serves handler on config.Port with the configured timeouts
+func newServer(config *Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + config.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: config.Server.ReadHeader,
+		ReadTimeout:       config.Server.Read,
+		WriteTimeout:      config.Server.Write,
+		IdleTimeout:       config.Server.Idle,
+	}
+}
+
 func main() {
-	config := loadConfig()
+	config, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
 	gateway := NewGateway(config)
+	if config.APIKey == "" {
+		log.Printf("GATEWAY_API_KEY not set: admin routes and WebSocket disabled")
+	}
+	gateway.limiter.StartCleanup(context.Background(), limiterCleanupInterval, limiterMaxIdle)
+	gateway.StartAdaptiveLimit(context.Background())
+	if err := gateway.StartDeadLetters(); err != nil {
+		log.Fatal(err)
+	}
 
-	r := mux.NewRouter()
-
-	// API routes
-	api := r.PathPrefix("/api").Subrouter()
-	api.HandleFunc("/health", gateway.handleHealth).Methods("GET")
-	api.HandleFunc("/stats", gateway.handleStats).Methods("GET")
-	api.HandleFunc("/extract", gateway.handleExtract).Methods("POST")
-	api.HandleFunc("/extract/batch", gateway.handleBatchExtract).Methods("POST")
-	api.HandleFunc("/ask", gateway.handleAsk).Methods("GET")
-	api.HandleFunc("/search", gateway.handleSearch).Methods("GET")
-	api.HandleFunc("/investigate", gateway.handleInvestigate).Methods("GET")
-	api.HandleFunc("/ws", gateway.handleWebSocket)
+	r, err := gateway.newRouter(config.Routes)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Apply middleware
-	handler := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
-	}).Handler(r)
+	handler := gateway.corsMiddleware(r)
 
 	handler = loggingMiddleware(handler)
 	handler = gateway.rateLimitMiddleware(handler)
@@ -478,6 +2799,7 @@ func main() {
 ╠═══════════════════════════════════════════════════════════╣
 ║  Endpoints:                                               ║
 ║    GET  /api/health       - Health check                  ║
+║    GET  /api/health/all   - Health of every service       ║
 ║    GET  /api/stats        - Server statistics             ║
 ║    POST /api/extract      - Entity extraction (→ Rust)    ║
 ║    POST /api/extract/batch- Batch extraction (→ Rust)     ║
@@ -485,6 +2807,9 @@ func main() {
 ║    GET  /api/search       - Search (→ Go)                 ║
 ║    GET  /api/investigate  - Parallel fan-out              ║
 ║    WS   /api/ws           - WebSocket real-time           ║
+║    POST /api/admin/reload - Reload config (API key)       ║
+║    POST /api/admin/investigate/purge - Drop cache         ║
+║    GET  /api/admin/deadletters - Failed upstream calls    ║
 ╚═══════════════════════════════════════════════════════════╝
 `)
 	fmt.Printf("Starting gateway on :%s\n", config.Port)
@@ -492,5 +2817,37 @@ func main() {
 	fmt.Printf("Python LLM:   %s\n", config.PythonLLMURL)
 	fmt.Printf("Go Search:    %s\n", config.GoSearchURL)
 
-	log.Fatal(http.ListenAndServe(":"+config.Port, handler))
+	srv := newServer(config, handler)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+	stop()
+
+	// Hijacked WebSockets are invisible to Shutdown, so drain them alongside
+	log.Printf("Shutting down, waiting up to %s", config.Server.Shutdown)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.Server.Shutdown)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if forced := gateway.sockets.Drain(shutdownCtx); forced > 0 {
+			log.Printf("Closed %d WebSocket(s) that did not finish in time", forced)
+		}
+	}()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown: %v", err)
+	}
+	wg.Wait()
+	if gateway.deadLetters != nil {
+		if err := gateway.deadLetters.Close(); err != nil {
+			log.Printf("Dead letter file: %v", err)
+		}
+	}
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbesSkipPressure(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	pressure := &upstreamPressure{}
+	defer func(p *upstreamPressure) { upstreamLog.pressure = p }(upstreamLog.pressure)
+	upstreamLog.pressure = pressure
+
+	if h := probeService(context.Background(), down.URL+"/health"); h.Status != "unhealthy" {
+		t.Fatalf("probe status = %q, want unhealthy", h.Status)
+	}
+	if calls := pressure.calls.Load(); calls != 0 {
+		t.Errorf("probe counted as %d upstream calls", calls)
+	}
+
+	g := &Gateway{}
+	g.fetchJSON(context.Background(), down.URL+"/search")
+	if calls, errors := pressure.calls.Load(), pressure.errors.Load(); calls != 1 || errors != 1 {
+		t.Errorf("proxied call counted as %d calls, %d errors; want 1, 1", calls, errors)
+	}
+}
+
+func TestHealthTimeIsUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	defer func() { time.Local = local }()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	NewGateway(cfg).handleHealth(rec, httptest.NewRequest("GET", "/health", nil))
+
+	var body struct{ Time string }
+	json.NewDecoder(rec.Body).Decode(&body)
+	if _, err := time.Parse(time.RFC3339, body.Time); err != nil || !strings.HasSuffix(body.Time, "Z") {
+		t.Errorf("time = %q, want RFC3339 UTC", body.Time)
+	}
+}
+
+// healthOn answers 200 on path only, 404 elsewhere
+func healthOn(path string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status":"ok","version":"1.2.3"}`))
+	}))
+}
+
+func TestBackendsUseHealthPaths(t *testing.T) {
+	search := healthOn("/health")
+	defer search.Close()
+	llm := healthOn("/api/health")
+	defer llm.Close()
+	gone := healthOn("/health")
+	gone.Close()
+
+	t.Setenv("PYTHON_LLM_HEALTH_PATH", "/api/health")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL, cfg.PythonLLMURL, cfg.RustExtractURL = search.URL, llm.URL, gone.URL
+
+	health := NewGateway(cfg).probeBackends(context.Background())
+	for _, name := range []string{"go-search", "python-llm"} {
+		status := health[name].(map[string]interface{})["status"]
+		if status != "healthy" {
+			t.Errorf("%s = %v, want healthy on its own path", name, status)
+		}
+	}
+	if status := health["rust-extract"].(map[string]interface{})["status"]; status == "healthy" {
+		t.Errorf("rust-extract = %v with its server gone", status)
+	}
+}
+
+func TestHealthAllConsolidates(t *testing.T) {
+	search := healthOn("/health")
+	defer search.Close()
+	llm := healthOn("/api/health")
+	defer llm.Close()
+	brain := healthOn("/health")
+	defer brain.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "db down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	gone := healthOn("/health")
+	gone.Close()
+	lungs := healthOn("/status")
+	defer lungs.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL, cfg.PythonLLMURL, cfg.RustExtractURL, cfg.BrainURL = search.URL, gone.URL, failing.URL, brain.URL
+	cfg.HealthPaths["python-llm"] = "/api/health"
+	cfg.HealthServices = map[string]string{"lungs": lungs.URL, "ocr": llm.URL}
+	cfg.HealthPaths["lungs"], cfg.HealthPaths["ocr"] = "/status", "/api/health"
+
+	rec := httptest.NewRecorder()
+	NewGateway(cfg).handleHealthAll(rec, httptest.NewRequest("GET", "/api/health/all", nil))
+
+	var body struct {
+		Status   string                   `json:"status"`
+		Services map[string]ServiceHealth `json:"services"`
+		Down     []string                 `json:"down"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"gateway":      "healthy",
+		"go-search":    "healthy",
+		"brain":        "healthy",
+		"lungs":        "healthy",
+		"ocr":          "healthy",
+		"rust-extract": "unhealthy",
+		"python-llm":   "offline",
+	}
+	if len(body.Services) != len(want) {
+		t.Errorf("services = %v", body.Services)
+	}
+	for name, status := range want {
+		h := body.Services[name]
+		if h.Status != status {
+			t.Errorf("%s = %q (%s), want %q", name, h.Status, h.Error, status)
+		}
+		if status == "healthy" && name != "gateway" && h.Version != "1.2.3" {
+			t.Errorf("%s version = %q, want 1.2.3", name, h.Version)
+		}
+	}
+	if body.Status != "degraded" || strings.Join(body.Down, ",") != "python-llm,rust-extract" {
+		t.Errorf("status %q, down %v; want degraded with python-llm and rust-extract", body.Status, body.Down)
+	}
+}
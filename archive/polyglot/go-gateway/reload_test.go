@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadChangesRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	writeConfig := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A rate this low refills nothing during the test, so only the burst counts
+	writeConfig(`{"rate_limit": 0.001, "rate_burst": 2}`)
+	t.Setenv("GATEWAY_CONFIG", path)
+	t.Setenv("GATEWAY_API_KEY", "secret")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+	router, err := g.newRouter(cfg.Routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := g.rateLimitMiddleware(router)
+
+	// allowed counts the requests a fresh client gets through before a 429
+	allowed := func(ip string) int {
+		t.Helper()
+		for n := 0; n < 20; n++ {
+			req := httptest.NewRequest("GET", "/healthz", nil)
+			req.RemoteAddr = ip + ":1234"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code == http.StatusTooManyRequests {
+				return n
+			}
+		}
+		return 20
+	}
+	reload := func() map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/admin/reload", nil)
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != 200 {
+			t.Fatalf("reload: %d %s", rec.Code, rec.Body)
+		}
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return body
+	}
+
+	if n := allowed("10.0.0.1"); n != 2 {
+		t.Fatalf("before reload: %d requests allowed, want 2", n)
+	}
+
+	writeConfig(`{"rate_limit": 0.001, "rate_burst": 5}`)
+	if body := reload(); body["rate_burst"] != 5.0 {
+		t.Errorf("reload reported burst %v, want 5", body["rate_burst"])
+	}
+	if n := allowed("10.0.0.2"); n != 5 {
+		t.Errorf("after raising the burst: %d requests allowed, want 5", n)
+	}
+	if got := g.cfg().RateBurst; got != 5 {
+		t.Errorf("live config burst = %d, want 5", got)
+	}
+
+	writeConfig(`{"rate_limit": 0.001, "rate_burst": 1}`)
+	reload()
+	if n := allowed("10.0.0.3"); n != 1 {
+		t.Errorf("after lowering the burst: %d requests allowed, want 1", n)
+	}
+}
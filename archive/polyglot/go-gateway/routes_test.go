@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfiguredRouteUnderAPI(t *testing.T) {
+	brain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "brain:"+r.URL.Path)
+	}))
+	defer brain.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.BrainURL = brain.URL
+	g := NewGateway(cfg)
+
+	r, err := g.newRouter([]RouteConfig{{Prefix: "/api/graph", Backend: "brain", StripPrefix: true}})
+	if err != nil {
+		t.Fatalf("registering /api/graph: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/graph/nodes", nil))
+	if rec.Code != 200 || rec.Body.String() != "brain:/nodes" {
+		t.Errorf("GET /api/graph/nodes = %d %q, want 200 \"brain:/nodes\"", rec.Code, rec.Body.String())
+	}
+
+	// Nothing declared /api/timeline, so it must not reach any backend
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/timeline/events", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/timeline/events = %d %q, want 404", rec.Code, rec.Body.String())
+	}
+}
+
+func TestConfiguredRouteConflicts(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+
+	for _, prefix := range []string{"/api/search", "/api/extract", "/api/admin", "/api/admin/tools", "/healthz"} {
+		_, err := g.newRouter([]RouteConfig{{Prefix: prefix, Backend: "brain"}})
+		if err == nil || !strings.Contains(err.Error(), "conflicts with built-in") {
+			t.Errorf("%s: err = %v, want a conflict", prefix, err)
+		}
+	}
+}
+
+// The gateway leaves the limit to go-search: it forwards one only when the
+// client sent it, so go-search's configured default applies otherwise
+func TestSearchForwardsLimit(t *testing.T) {
+	var got []string
+	search := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		io.WriteString(w, "[]")
+	}))
+	defer search.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL = search.URL
+	g := NewGateway(cfg)
+
+	for _, path := range []string{"/api/search?q=alpha+beta", "/api/search?q=alpha&limit=500"} {
+		g.handleSearch(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	want := []string{"q=alpha+beta", "limit=500&q=alpha"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("forwarded %q, want %q", got, want)
+	}
+}
+
+func TestRouteUpstreamPath(t *testing.T) {
+	tests := []struct {
+		rt   RouteConfig
+		path string
+		want string
+	}{
+		{RouteConfig{Prefix: "/api/graph"}, "/api/graph/nodes", "/api/graph/nodes"},
+		{RouteConfig{Prefix: "/api/graph", StripPrefix: true}, "/api/graph/nodes", "/nodes"},
+		{RouteConfig{Prefix: "/api/graph", StripPrefix: true}, "/api/graph", "/"},
+		{RouteConfig{Prefix: "/api/graph", UpstreamPath: "/v2/graph"}, "/api/graph/nodes", "/v2/graph/nodes"},
+		{RouteConfig{Prefix: "/api/graph", UpstreamPath: "/v2/"}, "/api/graph/nodes", "/v2/nodes"},
+		{RouteConfig{Prefix: "/api/graph", UpstreamPath: "/v2"}, "/api/graph", "/v2"},
+	}
+	for _, tt := range tests {
+		if got := tt.rt.upstreamPath(tt.path); got != tt.want {
+			t.Errorf("%+v: %s -> %s, want %s", tt.rt, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRouteInjectsHeaders(t *testing.T) {
+	var got http.Header
+	var path string
+	brain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, path = r.Header.Clone(), r.URL.Path
+	}))
+	defer brain.Close()
+
+	t.Setenv("GATEWAY_TEST_TOKEN", "internal-secret")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.BrainURL = brain.URL
+	g := NewGateway(cfg)
+
+	r, err := g.newRouter([]RouteConfig{{
+		Prefix:       "/api/graph",
+		Backend:      "brain",
+		UpstreamPath: "/v2/graph",
+		Headers:      map[string]string{"x-internal-token": "${GATEWAY_TEST_TOKEN}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client tries to supply the token itself, in two spellings
+	req := httptest.NewRequest("GET", "/api/graph/nodes", nil)
+	req.Header.Add("X-Internal-Token", "spoofed")
+	req.Header["x-internal-token"] = []string{"spoofed-too"}
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("GET /api/graph/nodes = %d %s", rec.Code, rec.Body)
+	}
+
+	if path != "/v2/graph/nodes" {
+		t.Errorf("backend saw %s, want /v2/graph/nodes", path)
+	}
+	if tokens := got.Values("X-Internal-Token"); len(tokens) != 1 || tokens[0] != "internal-secret" {
+		t.Errorf("backend got token %q, want only the configured one", tokens)
+	}
+	if got.Get("Accept") != "application/json" {
+		t.Errorf("client headers not forwarded: %v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerDropsSlowHeaderClient(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.ReadHeader = 100 * time.Millisecond
+	srv := newServer(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// A well-behaved client is still served
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("prompt client got %d, want 200", resp.StatusCode)
+	}
+
+	// A slowloris client sends part of its headers and then stalls
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: gateway\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	began := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatal("slow-header connection still open after 5s")
+	}
+	if elapsed := time.Since(began); elapsed < cfg.Server.ReadHeader/2 {
+		t.Errorf("disconnected after %v, before the %v header timeout", elapsed, cfg.Server.ReadHeader)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxySSEOutlastsRequestTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: {\"n\":%d}\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.RequestTimeout = 50 * time.Millisecond
+	cfg.StreamTimeout = 5 * time.Second
+	g := NewGateway(cfg)
+
+	rec := httptest.NewRecorder()
+	g.proxySSE(rec, httptest.NewRequest("GET", "/api/ask", nil), upstream.URL, nil, nil, nil)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"n":2`) || strings.Contains(body, `"type":"error"`) {
+		t.Errorf("stream cut short:\n%s", body)
+	}
+}
+
+func TestReadSSEMixedStream(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"id: 7\r\nevent: token\r\nretry: 3000\r\ndata: {\"text\":\"Hel\"}\r\n\r\n" +
+		"data: line one\ndata: line two\n\n" +
+		": another comment\n" +
+		"event: done\ndata:\n\n" +
+		"unknown: field\n\n" +
+		"data: never terminated\n"
+
+	var got []sseEvent
+	err := readSSE(strings.NewReader(stream), func(ev *sseEvent) error {
+		got = append(got, *ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sseEvent{
+		{ID: "7", Event: "token", Retry: "3000", Data: `{"text":"Hel"}`},
+		{Data: "line one\nline two"},
+		{Event: "done"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %+v\nwant %+v", got, want)
+	}
+
+	// encode writes the same event back
+	if enc := string(want[1].encode()); enc != "data: line one\ndata: line two\n\n" {
+		t.Errorf("encode = %q", enc)
+	}
+}
+
+func TestAnnotateEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		ev       sseEvent
+		keep     bool
+		failure  string
+		wantData string
+	}{
+		{"heartbeat event", sseEvent{Event: "heartbeat", Data: "{}"}, false, "", ""},
+		{"ping payload", sseEvent{Data: `{"type":"ping"}`}, false, "", ""},
+		{"token stamped", sseEvent{Event: "token", Data: `{"text":"Hi"}`}, true, "", `{"request_id":"req-1","text":"Hi"}`},
+		{"plain text untouched", sseEvent{Data: "hello"}, true, "", "hello"},
+		{"error event", sseEvent{Event: "error", Data: "model crashed"}, true, "model crashed", "model crashed"},
+		{"error payload", sseEvent{Data: `{"type":"error","message":"boom"}`}, true, "boom", `{"message":"boom","request_id":"req-1","type":"error"}`},
+		{"bare error payload", sseEvent{Data: `{"type":"error"}`}, true, "unknown error", `{"request_id":"req-1","type":"error"}`},
+	}
+	for _, tt := range tests {
+		ev := tt.ev
+		keep, failure := annotateEvent(&ev, "req-1")
+		if keep != tt.keep || failure != tt.failure {
+			t.Errorf("%s: keep %v failure %q, want %v %q", tt.name, keep, failure, tt.keep, tt.failure)
+		}
+		if keep && ev.Data != tt.wantData {
+			t.Errorf("%s: data %s, want %s", tt.name, ev.Data, tt.wantData)
+		}
+	}
+}
+
+func TestProxySSEMixedStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n"+
+			"event: heartbeat\ndata: {}\n\n"+
+			"event: token\ndata: {\"text\":\"Hel\"}\n\n"+
+			"data: {\"type\":\"ping\"}\n\n"+
+			"event: token\ndata: {\"text\":\"lo\"}\n\n"+
+			"event: error\ndata: {\"type\":\"error\",\"error\":\"model crashed\"}\n\n"+
+			"event: token\ndata: {\"text\":\"after the error\"}\n\n")
+	}))
+	defer upstream.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+
+	req := httptest.NewRequest("GET", "/api/ask", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	g.proxySSE(rec, req, upstream.URL+"/stream", nil, nil, nil)
+
+	var events []string
+	err = readSSE(rec.Body, func(ev *sseEvent) error {
+		if !strings.Contains(ev.Data, `"request_id":"req-42"`) {
+			t.Errorf("event %q not stamped: %s", ev.Event, ev.Data)
+		}
+		events = append(events, ev.Event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(events, ",") != "token,token,error" {
+		t.Errorf("relayed %v, want token,token,error", events)
+	}
+	if rec.Header().Get("X-Request-ID") != "req-42" {
+		t.Errorf("X-Request-ID = %q", rec.Header().Get("X-Request-ID"))
+	}
+
+	rec = httptest.NewRecorder()
+	g.proxySSE(rec, req, upstream.URL+"/fail", nil, nil, nil)
+	if body := rec.Body.String(); !strings.HasPrefix(body, "event: error\n") || !strings.Contains(body, "upstream returned 500") {
+		t.Errorf("upstream 500 relayed as %q", body)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpstreamConnectionsReused(t *testing.T) {
+	var conns atomic.Int32
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"id":1}]`)
+	}))
+	backend.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+
+	reused := 0
+	for i := 0; i < 5; i++ {
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				reused++
+			}
+		}}
+		ctx := httptrace.WithClientTrace(context.Background(), trace)
+		if _, err := g.fetchJSON(ctx, backend.URL+"/search"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.postJSON(ctx, backend.URL+"/extract", map[string]string{"text": "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if reused != 9 {
+		t.Errorf("%d of 10 sequential calls reused a connection, want 9", reused)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("backend saw %d connections, want 1", n)
+	}
+}
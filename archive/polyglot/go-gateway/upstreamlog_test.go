@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog sends the standard logger to a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestLogSamplerRate(t *testing.T) {
+	for _, rate := range []float64{0, 0.1, 0.25, 1, 2} {
+		var s logSampler
+		s.SetRate(rate)
+		sampled := 0
+		for i := 0; i < 100; i++ {
+			if s.Sample() {
+				sampled++
+			}
+		}
+		want := int(100 * min(rate, 1))
+		if sampled != want {
+			t.Errorf("rate %v: sampled %d of 100, want %d", rate, sampled, want)
+		}
+	}
+}
+
+func TestUpstreamLogSampledAndRedacted(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	defer upstreamLog.SetRate(0)
+	upstreamLog.SetRate(0.25)
+	upstreamLog.n.Store(0)
+	logs := captureLog(t)
+
+	for i := 0; i < 8; i++ {
+		req, _ := http.NewRequest("GET", backend.URL+"/search", nil)
+		req.Header.Set("Authorization", "Bearer hunter2")
+		req.Header.Set("X-API-Key", "hunter3")
+		req.Header.Set("Accept", "application/json")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	out := logs.String()
+	if n := strings.Count(out, "[upstream] GET "+backend.URL+"/search -> 200"); n != 2 {
+		t.Errorf("logged %d of 8 calls at rate 0.25, want 2:\n%s", n, out)
+	}
+	if strings.Contains(out, "hunter") {
+		t.Errorf("credentials leaked into the log:\n%s", out)
+	}
+	if !strings.Contains(out, "Authorization=[REDACTED]") || !strings.Contains(out, "X-Api-Key=[REDACTED]") {
+		t.Errorf("sensitive headers not masked:\n%s", out)
+	}
+	if !strings.Contains(out, "Accept=application/json") {
+		t.Errorf("ordinary headers dropped:\n%s", out)
+	}
+}
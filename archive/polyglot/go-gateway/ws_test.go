@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS serves g's WebSocket handler and opens an authorized, same-origin
+// socket to it; cfg.APIKey is set to "secret"
+func dialWS(t *testing.T, g *Gateway) *websocket.Conn {
+	t.Helper()
+	g.cfg().APIKey = "secret"
+	srv := httptest.NewServer(http.HandlerFunc(g.handleWebSocket))
+	t.Cleanup(srv.Close)
+
+	header := http.Header{"Origin": {srv.URL}, "X-API-Key": {"secret"}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestCheckOrigin(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.WSOrigins = []string{"https://ui.example.com"}
+	g := NewGateway(cfg)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", false},
+		{"http://gateway.local:8080", true},
+		{"https://ui.example.com", true},
+		{"https://evil.example.com", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://gateway.local:8080/api/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := g.checkOrigin(r); got != tt.want {
+			t.Errorf("origin %q: allowed = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestWSAuthorize(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+
+	r := httptest.NewRequest("GET", "/api/ws", nil)
+	if _, ok := g.wsAuthorize(r); ok {
+		t.Error("socket opened with no key configured")
+	}
+
+	cfg.APIKey = "secret"
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		protocol string
+		want     bool
+		echo     string
+	}{
+		{"no key", "/api/ws", "", "", false, ""},
+		{"query key ignored", "/api/ws?api_key=secret", "", "", false, ""},
+		{"header key", "/api/ws", "secret", "", true, ""},
+		{"wrong header key", "/api/ws", "nope", "", false, ""},
+		{"subprotocol key", "/api/ws", "", "apikey.secret", true, "apikey.secret"},
+		{"wrong subprotocol key", "/api/ws", "", "apikey.nope", false, "apikey.nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("X-API-Key", tt.header)
+			}
+			if tt.protocol != "" {
+				r.Header.Set("Sec-Websocket-Protocol", tt.protocol)
+			}
+			protocol, ok := g.wsAuthorize(r)
+			if ok != tt.want || protocol != tt.echo {
+				t.Errorf("got (%q, %v), want (%q, %v)", protocol, ok, tt.echo, tt.want)
+			}
+		})
+	}
+}
+
+func TestWSSearchStreamsPartialsThenComplete(t *testing.T) {
+	// alpha answers late, so batches arrive in a different order than asked
+	search := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "alpha":
+			time.Sleep(50 * time.Millisecond)
+			io.WriteString(w, `[{"id":1},{"id":2}]`)
+		case "beta":
+			io.WriteString(w, `[{"id":2},{"id":3}]`)
+		default:
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer search.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GoSearchURL = search.URL
+	conn := dialWS(t, NewGateway(cfg))
+
+	if err := conn.WriteJSON(map[string]string{"action": "search", "query": "alpha beta gamma"}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var partials []string
+	for {
+		var frame struct {
+			Type   string            `json:"type"`
+			Term   string            `json:"term"`
+			Batch  int               `json:"batch"`
+			Of     int               `json:"of"`
+			Error  string            `json:"error"`
+			Result json.RawMessage   `json:"result"`
+			Errors map[string]string `json:"errors"`
+		}
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatal(err)
+		}
+		if frame.Type == "search_partial" {
+			if frame.Batch != len(partials)+1 || frame.Of != 3 {
+				t.Errorf("partial %s is batch %d of %d", frame.Term, frame.Batch, frame.Of)
+			}
+			partials = append(partials, frame.Term)
+			continue
+		}
+
+		if frame.Type != "search_complete" {
+			t.Fatalf("unexpected frame %q", frame.Type)
+		}
+		if len(partials) != 3 || partials[2] != "alpha" {
+			t.Errorf("partials before complete = %v, want 3 with alpha last", partials)
+		}
+		var merged []struct{ ID int }
+		json.Unmarshal(frame.Result, &merged)
+		if len(merged) != 3 {
+			t.Errorf("merged = %s, want ids 1, 2, 3 once each", frame.Result)
+		}
+		if frame.Errors["gamma"] == "" || len(frame.Errors) != 1 {
+			t.Errorf("errors = %v, want gamma only", frame.Errors)
+		}
+		return
+	}
+}
+
+func TestWSIdleTimeout(t *testing.T) {
+	const interval, idle = 20 * time.Millisecond, 150 * time.Millisecond
+	newGateway := func() *Gateway {
+		cfg, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.WSPingInterval, cfg.WSIdleTimeout = interval, idle
+		return NewGateway(cfg)
+	}
+
+	t.Run("unresponsive client is closed", func(t *testing.T) {
+		conn := dialWS(t, newGateway())
+		// Swallow pings without answering, like a half-open peer
+		var pings atomic.Int32
+		conn.SetPingHandler(func(string) error { pings.Add(1); return nil })
+
+		began := time.Now()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := conn.ReadMessage()
+		var netErr net.Error
+		if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatalf("read = %v, want the server to close the socket", err)
+		}
+		if elapsed := time.Since(began); elapsed < idle-interval {
+			t.Errorf("closed after %v, before the %v idle timeout", elapsed, idle)
+		}
+		if pings.Load() == 0 {
+			t.Error("no pings sent before closing")
+		}
+	})
+
+	t.Run("pong keeps the socket open", func(t *testing.T) {
+		conn := dialWS(t, newGateway())
+		// The default ping handler answers with a pong while we read
+		conn.SetReadDeadline(time.Now().Add(3 * idle))
+		_, _, err := conn.ReadMessage()
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("read = %v, want our own deadline to expire with the socket still open", err)
+		}
+	})
+}
+
+func TestWSDrainSendsCloseFrame(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway(cfg)
+	conn := dialWS(t, g)
+	// A second client never reads, so it never answers the close frame
+	dialWS(t, g)
+	for deadline := time.Now().Add(5 * time.Second); g.sockets.Len() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d sockets registered, want 2", g.sockets.Len())
+		}
+	}
+
+	forced := make(chan int)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		forced <- g.sockets.Drain(ctx)
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("read = %v, want a going-away close frame", err)
+	}
+	if n := <-forced; n != 1 {
+		t.Errorf("Drain forced %d sockets closed, want only the one that never answered", n)
+	}
+	// The forced socket's handler unregisters it once its read fails
+	for deadline := time.Now().Add(5 * time.Second); g.sockets.Len() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d sockets still registered after Drain", g.sockets.Len())
+		}
+	}
+
+	// Sockets upgraded after the drain started are turned away the same way
+	late := dialWS(t, g)
+	late.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("late socket read = %v, want a going-away close frame", err)
+	}
+}
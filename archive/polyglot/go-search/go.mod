@@ -2,4 +2,4 @@ module go-search
 
 go 1.25.5
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
@@ -1,20 +1,50 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	_ "github.com/lib/pq"
 )
 
 var db *sql.DB
 
+// Injected at build time:
+//
+//	go build -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=..."
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildTime = "dev"
+)
+
+var startTime = time.Now()
+
+// Result limits, shared env names with the rest of the stack
+var (
+	defaultLimit = getEnvInt("SEARCH_DEFAULT_LIMIT", 10)
+	maxLimit     = getEnvInt("SEARCH_MAX_LIMIT", 100)
+)
+
+// Fan-out bounds for /search/fast: how many query terms are searched, how
+// many of those queries run at once, and how long each may take
+var (
+	fastMaxTerms     = getEnvInt("SEARCH_FAST_MAX_TERMS", 4)
+	fastConcurrency  = getEnvInt("SEARCH_FAST_CONCURRENCY", 4)
+	fastQueryTimeout = getEnvDuration("SEARCH_FAST_QUERY_TIMEOUT", 2*time.Second)
+)
+
 type SearchResult struct {
 	ID       int     `json:"id"`
 	Name     string  `json:"name"`
@@ -27,6 +57,11 @@ type HealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp string `json:"timestamp"`
 	DB        string `json:"db"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Uptime    string `json:"uptime"`
 }
 
 func main() {
@@ -50,35 +85,234 @@ func main() {
 	log.Println("Connected to PostgreSQL")
 
 	http.HandleFunc("/health", healthHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", readyzHandler)
 	http.HandleFunc("/search", searchHandler)
 	http.HandleFunc("/search/fast", fastSearchHandler)
+	http.HandleFunc("/search/stream", streamSearchHandler)
 
 	port := os.Getenv("GO_PORT")
 	if port == "" {
 		port = "8003"
 	}
 	log.Printf("Go search service on :%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	// Bounded connections: a slow or stalled client can't hold one open
+	srv := &http.Server{
+		Addr:              ":" + port,
+		ReadHeaderTimeout: getEnvDuration("SEARCH_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("SEARCH_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      getEnvDuration("SEARCH_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getEnvDuration("SEARCH_IDLE_TIMEOUT", 2*time.Minute),
+	}
+	log.Fatal(srv.ListenAndServe())
 }
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(HealthResponse{
 		Status:    "ok",
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		DB:        "connected",
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Uptime:    time.Since(startTime).Round(time.Second).String(),
 	})
 }
 
+// Liveness: the process is up
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// Readiness: the database answers a ping
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := db.PingContext(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "not_ready", "db": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready", "db": "ok"})
+}
+
 func searchHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	if q == "" {
 		http.Error(w, `{"error":"q required"}`, 400)
 		return
 	}
+	limit := requestLimit(r)
 
 	start := time.Now()
-	rows, err := db.Query(`
+	results, err := rankedSearch(r.Context(), q, limit)
+	if err != nil {
+		writeQueryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Search-Time", fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
+	json.NewEncoder(w).Encode(results)
+}
+
+func fastSearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, `{"error":"q required"}`, 400)
+		return
+	}
+
+	limit := requestLimit(r)
+	start := time.Now()
+	results, err := fastSearch(r.Context(), q, limit, false)
+	if err != nil {
+		writeQueryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Search-Time", fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
+	json.NewEncoder(w).Encode(results)
+}
+
+// fastSearch fans the first fastMaxTerms query terms out in parallel, at
+// most fastConcurrency at a time, and merges their hits in arrival order,
+// unranked across terms. With prefix set the last term matches as a word
+// prefix, for a query still being typed. A term whose query fails is
+// skipped; the error is returned only when no term succeeded or ctx ended.
+// Every query stops with ctx, so nothing outlives an early return.
+func fastSearch(ctx context.Context, q string, limit int, prefix bool) ([]SearchResult, error) {
+	terms := strings.Fields(q)
+	if fastMaxTerms > 0 {
+		terms = terms[:min(fastMaxTerms, len(terms))]
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type termResult struct {
+		results []SearchResult
+		err     error
+	}
+	// Buffered for every term, so a sender never blocks once we've gone
+	resultChan := make(chan termResult, len(terms))
+	slots := make(chan struct{}, max(fastConcurrency, 1))
+	for i, term := range terms {
+		tsquery, arg := "plainto_tsquery('english', $1)", term
+		if prefix && i == len(terms)-1 {
+			if p := prefixTerm(term); p != "" {
+				tsquery, arg = "to_tsquery('english', $1)", p
+			}
+		}
+		go func(tsquery, arg string) {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				resultChan <- termResult{err: ctx.Err()}
+				return
+			}
+			res, err := termSearch(ctx, tsquery, arg, limit)
+			resultChan <- termResult{res, err}
+		}(tsquery, arg)
+	}
+
+	// Collect and dedupe
+	seen := make(map[int]bool)
+	var results []SearchResult
+	var firstErr error
+	succeeded := 0
+	for range terms {
+		var res termResult
+		select {
+		case res = <-resultChan:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			continue
+		}
+		succeeded++
+		for _, r := range res.results {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				results = append(results, r)
+			}
+		}
+	}
+	if succeeded == 0 {
+		return nil, firstErr
+	}
+	if firstErr != nil {
+		log.Printf("fast search %q: %d of %d terms failed: %v", q, len(terms)-succeeded, len(terms), firstErr)
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// termSearch runs one fastSearch term under fastQueryTimeout
+func termSearch(ctx context.Context, tsquery, arg string, limit int) ([]SearchResult, error) {
+	if fastQueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fastQueryTimeout)
+		defer cancel()
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT doc_id, subject, '' as snippet,
+			ts_rank(tsv, `+tsquery+`) as rank
+		FROM emails
+		WHERE tsv @@ `+tsquery+`
+		ORDER BY rank DESC LIMIT $2
+	`, arg, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		r.Type = "email"
+		if err := rows.Scan(&r.ID, &r.Name, &r.Snippet, &r.Rank); err != nil {
+			continue
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// prefixTerm turns a partial word into a to_tsquery prefix match, keeping
+// only letters and digits so user input can't inject tsquery operators
+func prefixTerm(term string) string {
+	clean := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, term)
+	if clean == "" {
+		return ""
+	}
+	return clean + ":*"
+}
+
+// rankedSearch is the full FTS query behind /search, with headlines
+func rankedSearch(ctx context.Context, q string, limit int) ([]SearchResult, error) {
+	rows, err := db.QueryContext(ctx, `
 		SELECT doc_id, subject, 
 			ts_headline('english', COALESCE(body_text,''), plainto_tsquery('english', $1), 
 				'StartSel=<b>, StopSel=</b>, MaxWords=30') as snippet,
@@ -86,11 +320,10 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 		FROM emails
 		WHERE tsv @@ plainto_tsquery('english', $1)
 		ORDER BY rank DESC
-		LIMIT 20
-	`, q)
+		LIMIT $2
+	`, q, limit)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err), 500)
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -103,65 +336,129 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		results = append(results, r)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Search-Time", fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
-	json.NewEncoder(w).Encode(results)
+	return results, rows.Err()
 }
 
-func fastSearchHandler(w http.ResponseWriter, r *http.Request) {
+// streamSearchHandler serves search-as-you-type over SSE: a "fast" event
+// with the prefix-aware fan-out, then a "ranked" event with the full FTS
+// ranking that replaces it, then "done". The ranked query starts right
+// away but is never sent before the fast one.
+func streamSearchHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	if q == "" {
 		http.Error(w, `{"error":"q required"}`, 400)
 		return
 	}
-
-	terms := strings.Fields(q)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, `{"error":"streaming unsupported"}`, 500)
+		return
+	}
+	limit := requestLimit(r)
 	start := time.Now()
 
-	// Parallel search on multiple terms
-	resultChan := make(chan []SearchResult, len(terms))
-	for _, term := range terms[:min(4, len(terms))] {
-		go func(t string) {
-			rows, err := db.Query(`
-				SELECT doc_id, subject, '' as snippet,
-					ts_rank(tsv, plainto_tsquery('english', $1)) as rank
-				FROM emails
-				WHERE tsv @@ plainto_tsquery('english', $1)
-				ORDER BY rank DESC LIMIT 15
-			`, t)
-			if err != nil {
-				resultChan <- nil
-				return
-			}
-			defer rows.Close()
-			var res []SearchResult
-			for rows.Next() {
-				var r SearchResult
-				r.Type = "email"
-				rows.Scan(&r.ID, &r.Name, &r.Snippet, &r.Rank)
-				res = append(res, r)
-			}
-			resultChan <- res
-		}(term)
+	type ranked struct {
+		results []SearchResult
+		err     error
 	}
+	rankedChan := make(chan ranked, 1)
+	go func() {
+		results, err := rankedSearch(r.Context(), q, limit)
+		rankedChan <- ranked{results, err}
+	}()
 
-	// Collect and dedupe
-	seen := make(map[int]bool)
-	var results []SearchResult
-	for i := 0; i < min(4, len(terms)); i++ {
-		res := <-resultChan
-		for _, r := range res {
-			if !seen[r.ID] {
-				seen[r.ID] = true
-				results = append(results, r)
-			}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	fast, err := fastSearch(r.Context(), q, limit, true)
+	if err != nil && r.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Printf("search stream %q: fast: %v", q, err)
+	}
+	send("fast", map[string]interface{}{
+		"results":    nonNil(fast),
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	})
+
+	res := <-rankedChan
+	if res.err != nil {
+		if !errors.Is(res.err, context.Canceled) {
+			log.Printf("search stream %q: %v", q, res.err)
+			send("error", map[string]string{"error": "search failed"})
 		}
+		return
 	}
+	send("ranked", map[string]interface{}{
+		"results":    nonNil(res.results),
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	})
+	send("done", map[string]interface{}{"elapsed_ms": time.Since(start).Milliseconds()})
+}
+
+// nonNil keeps empty result sets encoding as [] rather than null
+func nonNil(results []SearchResult) []SearchResult {
+	if results == nil {
+		return []SearchResult{}
+	}
+	return results
+}
+
+// requestLimit reads the limit query parameter, falling back to
+// defaultLimit when unset or invalid and capping at maxLimit
+func requestLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit
+}
+
+// writeQueryError answers a failed query without leaking SQL or driver
+// text: 499 when the client went away, 503 on a deadline, 500 otherwise
+func writeQueryError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := http.StatusInternalServerError, "search failed"
+	switch {
+	case errors.Is(err, context.Canceled):
+		status, msg = 499, "request canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		status, msg = http.StatusServiceUnavailable, "search timed out"
+	}
+	log.Printf("search %q: %v", r.URL.Query().Get("q"), err)
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Search-Time", fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
-	json.NewEncoder(w).Encode(results)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	if val := os.Getenv(key); val != "" {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return defaultVal
 }
 
 func min(a, b int) int {
@@ -170,3 +467,10 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hangingDriver is a database whose queries block until their context
+// ends, counting the ones in flight
+type hangingDriver struct {
+	inFlight atomic.Int32
+	started  chan struct{}
+}
+
+func (d *hangingDriver) Open(string) (driver.Conn, error) { return hangingConn{d}, nil }
+
+type hangingConn struct{ d *hangingDriver }
+
+func (c hangingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c hangingConn) Close() error                        { return nil }
+func (c hangingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c hangingConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.inFlight.Add(1)
+	defer c.d.inFlight.Add(-1)
+	c.d.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var (
+	hanging      = &hangingDriver{}
+	registerOnce sync.Once
+)
+
+// Run with -race: a client hanging up mid-search stops every term query
+// and the handler returns without leaving one behind
+func TestFastSearchStopsWhenRequestCanceled(t *testing.T) {
+	d := hanging
+	d.started = make(chan struct{}, 16)
+	registerOnce.Do(func() { sql.Register("hanging", d) })
+	conn, err := sql.Open("hanging", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	db, fastQueryTimeout, fastConcurrency = conn, time.Minute, 2
+	defer func() { db = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/search/fast?q=alpha+beta+gamma", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		fastSearchHandler(rec, req)
+		close(done)
+	}()
+
+	// Two terms run at once; the third waits for a slot
+	for i := 0; i < 2; i++ {
+		select {
+		case <-d.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("term queries never started")
+		}
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still running after the request was canceled")
+	}
+	if rec.Code != 499 {
+		t.Errorf("status = %d, want 499", rec.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for d.inFlight.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d queries still running", d.inFlight.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-d.started:
+		t.Error("a term query started after the request was canceled")
+	default:
+	}
+}
+
+func TestHealthTimestampIsUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	defer func() { time.Local = local }()
+
+	rec := httptest.NewRecorder()
+	healthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+
+	var body HealthResponse
+	json.NewDecoder(rec.Body).Decode(&body)
+	if _, err := time.Parse(time.RFC3339, body.Timestamp); err != nil || !strings.HasSuffix(body.Timestamp, "Z") {
+		t.Errorf("timestamp = %q, want RFC3339 UTC", body.Timestamp)
+	}
+}
+
+func TestRequestLimit(t *testing.T) {
+	oldDef, oldMax := defaultLimit, maxLimit
+	defer func() { defaultLimit, maxLimit = oldDef, oldMax }()
+	defaultLimit, maxLimit = 10, 100
+
+	for query, want := range map[string]int{"": 10, "limit=abc": 10, "limit=0": 10, "limit=25": 25, "limit=500": 100} {
+		if got := requestLimit(httptest.NewRequest("GET", "/search?q=x&"+query, nil)); got != want {
+			t.Errorf("%q: limit %d, want %d", query, got, want)
+		}
+	}
+}
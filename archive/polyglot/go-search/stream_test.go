@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamDriver answers the ranked query at once and each fast term query
+// after fastDelay, so the ranked results are ready first
+type streamDriver struct{}
+
+const fastDelay = 50 * time.Millisecond
+
+func (streamDriver) Open(string) (driver.Conn, error) { return streamConn{}, nil }
+
+type streamConn struct{}
+
+func (streamConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (streamConn) Close() error                        { return nil }
+func (streamConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (streamConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "ts_headline") {
+		return &streamRows{row: []driver.Value{int64(1), "ranked", "<b>invoice</b>", 0.9}}, nil
+	}
+	select {
+	case <-time.After(fastDelay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &streamRows{row: []driver.Value{int64(2), "fast", "", 0.1}}, nil
+}
+
+type streamRows struct{ row []driver.Value }
+
+func (r *streamRows) Columns() []string { return []string{"doc_id", "subject", "snippet", "rank"} }
+func (r *streamRows) Close() error      { return nil }
+func (r *streamRows) Next(dest []driver.Value) error {
+	if r.row == nil {
+		return io.EOF
+	}
+	copy(dest, r.row)
+	r.row = nil
+	return nil
+}
+
+func init() {
+	sql.Register("search-stream-stub", streamDriver{})
+}
+
+func TestStreamSearchFastBeforeRanked(t *testing.T) {
+	conn, err := sql.Open("search-stream-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	db = conn
+	defer func() { db = nil }()
+
+	srv := httptest.NewServer(http.HandlerFunc(streamSearchHandler))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/search/stream?q=invo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	var events []string
+	names := map[string]string{}
+	scanner := bufio.NewScanner(resp.Body)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+			events = append(events, event)
+		case strings.HasPrefix(line, "data: ") && event != "done":
+			var data struct{ Results []SearchResult }
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("%s data: %v", event, err)
+			}
+			if len(data.Results) == 1 {
+				names[event] = data.Results[0].Name
+			}
+		}
+	}
+
+	if strings.Join(events, ",") != "fast,ranked,done" {
+		t.Fatalf("events = %v, want fast,ranked,done", events)
+	}
+	if names["fast"] != "fast" || names["ranked"] != "ranked" {
+		t.Errorf("event results = %v", names)
+	}
+}
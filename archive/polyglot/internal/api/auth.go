@@ -0,0 +1,61 @@
+package api
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tenantLocal is the fiber.Ctx local holding the caller's tenant
+const tenantLocal = "tenant"
+
+// authenticate resolves the caller's tenant from their API key, sent as
+// X-API-Key or an Authorization bearer token, and refuses the request when
+// no configured key matches. In single-tenant mode every caller is let
+// through with no tenant.
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	if s.config.Server.SingleTenant {
+		return c.Next()
+	}
+	tenant, ok := lookupKey(s.config.Server.APIKeys, apiKey(c))
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	c.Locals(tenantLocal, tenant)
+	return c.Next()
+}
+
+// apiKey reads the key the caller presented, "" when none
+func apiKey(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Get("X-API-Key")); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// lookupKey finds key's tenant, comparing every configured key in
+// constant time
+func lookupKey(keys map[string]string, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	tenant, found := "", false
+	for k, t := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			tenant, found = t, true
+		}
+	}
+	return tenant, found
+}
+
+// tenant is the caller's tenant, set by authenticate. Empty only in
+// single-tenant mode, where every document is visible.
+func tenant(c *fiber.Ctx) string {
+	t, _ := c.Locals(tenantLocal).(string)
+	return t
+}
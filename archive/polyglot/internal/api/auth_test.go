@@ -0,0 +1,86 @@
+package api
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/config"
+)
+
+func authApp(server config.ServerConfig) *fiber.App {
+	s := &Server{config: &config.Config{Server: server}}
+	app := fiber.New()
+	app.Use(s.authenticate)
+	app.Get("/whoami", func(c *fiber.Ctx) error {
+		return c.SendString(tenant(c))
+	})
+	return app
+}
+
+func TestAuthenticateResolvesTenantFromKey(t *testing.T) {
+	app := authApp(config.ServerConfig{APIKeys: map[string]string{"k1": "acme", "k2": "globex"}})
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+		tenant string
+	}{
+		{"no key", "", "", 401, ""},
+		{"unknown key", "X-API-Key", "nope", 401, ""},
+		{"forged tenant header", "X-Tenant", "acme", 401, ""},
+		{"api key header", "X-API-Key", "k1", 200, "acme"},
+		{"bearer token", "Authorization", "Bearer k2", 200, "globex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/whoami", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != 200 {
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.tenant {
+				t.Errorf("tenant = %q, want %q", body, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestAuthenticateDeniesWithoutKeys(t *testing.T) {
+	app := authApp(config.ServerConfig{})
+	req := httptest.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("X-API-Key", "anything")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 401 {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestAuthenticateSingleTenant(t *testing.T) {
+	app := authApp(config.ServerConfig{SingleTenant: true})
+	resp, err := app.Test(httptest.NewRequest("GET", "/whoami", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "" {
+		t.Errorf("got %d %q, want 200 with no tenant", resp.StatusCode, body)
+	}
+}
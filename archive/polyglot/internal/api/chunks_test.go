@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/regex"
+)
+
+// chunkTexts decodes the text of every "chunk" event in an SSE body
+func chunkTexts(t *testing.T, body string) []string {
+	t.Helper()
+	var texts []string
+	for _, event := range strings.Split(body, "\n\n") {
+		if !strings.HasPrefix(event, "event: chunk\n") {
+			continue
+		}
+		var data struct{ Text string }
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(event, "event: chunk\ndata: ")), &data); err != nil {
+			t.Fatalf("bad chunk %q: %v", event, err)
+		}
+		texts = append(texts, data.Text)
+	}
+	return texts
+}
+
+// One word per chunk puts "api_key:" and its value in separate chunks;
+// the guard must still see and mask them as one match
+func TestSendChunksRedactsAcrossChunks(t *testing.T) {
+	answer := "the config sets api_key: hunter2hunter2 for the staging cluster"
+	cfg := config.StreamConfig{ChunkWords: 1}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	sendChunks(w, answer, cfg, regex.NewMatcher().NewStreamRedactor(regex.RedactTypeToken, 16))
+
+	texts := chunkTexts(t, buf.String())
+	for _, text := range texts {
+		if strings.Contains(text, "hunter2") {
+			t.Fatalf("chunk %q leaked the secret", text)
+		}
+	}
+	got := strings.Join(texts, "")
+	if !strings.HasPrefix(got, "the config sets ") || !strings.HasSuffix(got, " for the staging cluster ") {
+		t.Errorf("streamed %q, want the text around the secret intact", got)
+	}
+}
+
+func TestSendChunksWithoutGuard(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	sendChunks(w, "one two three", config.StreamConfig{ChunkWords: 2}, nil)
+
+	texts := chunkTexts(t, buf.String())
+	if len(texts) != 2 || texts[0] != "one two " || texts[1] != "three " {
+		t.Errorf("chunks = %q, want [\"one two \" \"three \"]", texts)
+	}
+}
+
+func TestChunkMessageCounts(t *testing.T) {
+	twelve := "one two three four five six seven eight nine ten eleven twelve"
+	sentences := "The lease was signed. Payment followed in May! Was it late? "
+
+	tests := []struct {
+		name string
+		text string
+		cfg  config.StreamConfig
+		want int
+	}{
+		{"five words", twelve, config.StreamConfig{ChunkWords: 5}, 3},
+		{"one word", twelve, config.StreamConfig{ChunkWords: 1}, 12},
+		{"unset uses five", twelve, config.StreamConfig{}, 3},
+		{"larger than text", twelve, config.StreamConfig{ChunkWords: 20}, 1},
+		{"sentences", sentences, config.StreamConfig{Sentences: true, ChunkWords: 1}, 3},
+		{"empty", "", config.StreamConfig{ChunkWords: 5}, 0},
+	}
+	for _, tt := range tests {
+		if got := chunkMessage(tt.text, tt.cfg); len(got) != tt.want {
+			t.Errorf("%s: %d chunks %q, want %d", tt.name, len(got), got, tt.want)
+		}
+	}
+}
+
+func TestSendChunksConfiguredFromEnv(t *testing.T) {
+	t.Setenv("STREAM_CHUNK_WORDS", "4")
+	t.Setenv("STREAM_CHUNK_DELAY", "0s")
+	cfg := config.Load().Stream
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	start := time.Now()
+	sendChunks(w, "one two three four five six seven eight nine ten", cfg, nil)
+	w.Flush()
+
+	if texts := chunkTexts(t, buf.String()); len(texts) != 3 {
+		t.Errorf("%d chunk events %q, want 3", len(texts), texts)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond { // the default 50ms would take 150ms
+		t.Errorf("zero delay still paced: took %v", elapsed)
+	}
+}
+
+func TestSendChunksPaced(t *testing.T) {
+	var buf bytes.Buffer
+	start := time.Now()
+	sendChunks(bufio.NewWriter(&buf), "one two three", config.StreamConfig{ChunkWords: 1, ChunkDelay: 10 * time.Millisecond}, nil)
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("3 chunks at 10ms took %v", elapsed)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hybridcore/internal/chat"
+	"hybridcore/internal/config"
+	"hybridcore/internal/regex"
+)
+
+// assertUTC fails unless value is an RFC3339 timestamp in UTC
+func assertUTC(t *testing.T, field string, value any) {
+	t.Helper()
+	s, _ := value.(string)
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Errorf("%s = %v: %v", field, value, err)
+		return
+	}
+	if _, offset := ts.Zone(); offset != 0 || !strings.HasSuffix(s, "Z") {
+		t.Errorf("%s = %q, want UTC", field, s)
+	}
+}
+
+func getJSON(t *testing.T, s *Server, path string) map[string]any {
+	t.Helper()
+	resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return body
+}
+
+func TestTimestampsAreUTC(t *testing.T) {
+	// A non-UTC local zone, so any time.Now() that slips through shows up
+	local := time.Local
+	time.Local = time.FixedZone("EST", -5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	cfg := config.Load()
+	cfg.Server.SingleTenant = true
+	manager := chat.NewManager(nil, nil, nil)
+	s := NewServer(cfg, manager, nil, regex.NewMatcher())
+	t.Cleanup(s.jobs.Stop)
+
+	assertUTC(t, "health timestamp", getJSON(t, s, "/api/health")["timestamp"])
+
+	session := manager.GetOrCreateSession("", "")
+	body := getJSON(t, s, "/api/sessions/"+session.ID)
+	assertUTC(t, "session created_at", body["created_at"])
+	assertUTC(t, "session updated_at", body["updated_at"])
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/regex"
+)
+
+// corsServer is the API configured from the environment, as at startup
+func corsServer(t *testing.T) *Server {
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com,https://admin.example.com")
+	t.Setenv("CORS_ALLOW_METHODS", "GET,POST")
+	t.Setenv("SECURITY_FRAME_OPTIONS", "SAMEORIGIN")
+	t.Setenv("SECURITY_XSS_PROTECTION", "off")
+
+	cfg := config.Load()
+	cfg.Server.SingleTenant = true
+	s := NewServer(cfg, nil, nil, regex.NewMatcher())
+	t.Cleanup(s.jobs.Stop)
+	return s
+}
+
+func TestCORSConfiguredOrigins(t *testing.T) {
+	s := corsServer(t)
+
+	tests := []struct {
+		origin string
+		allow  string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://admin.example.com", "https://admin.example.com"},
+		{"https://evil.example.net", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set("Origin", tt.origin)
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.allow {
+			t.Errorf("GET from %s: Allow-Origin %q, want %q", tt.origin, got, tt.allow)
+		}
+
+		preflight := httptest.NewRequest("OPTIONS", "/api/chat", nil)
+		preflight.Header.Set("Origin", tt.origin)
+		preflight.Header.Set("Access-Control-Request-Method", "POST")
+		resp, err = s.app.Test(preflight, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.allow {
+			t.Errorf("preflight from %s: Allow-Origin %q, want %q", tt.origin, got, tt.allow)
+		}
+		if tt.allow != "" && resp.Header.Get("Access-Control-Allow-Methods") != "GET,POST" {
+			t.Errorf("preflight from %s: Allow-Methods %q", tt.origin, resp.Header.Get("Access-Control-Allow-Methods"))
+		}
+	}
+}
+
+func TestSecurityHeadersConfigured(t *testing.T) {
+	resp, err := corsServer(t).app.Test(httptest.NewRequest("GET", "/api/health", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "SAMEORIGIN",
+		"X-XSS-Protection":       "",
+		"Referrer-Policy":        "no-referrer",
+	}
+	for header, value := range want {
+		if got := resp.Header.Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hybridcore/internal/chat"
+	"hybridcore/internal/config"
+	"hybridcore/internal/db"
+	"hybridcore/internal/llm"
+	"hybridcore/internal/rag"
+	"hybridcore/internal/regex"
+)
+
+// testDB points db.DB at a migrated, empty schema of the
+// TEST_DATABASE_URL database for the rest of the test, skipping the test
+// without one
+func testDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	q.Set("timezone", "UTC")
+	u.RawQuery = q.Encode()
+	conn, err := sqlx.Connect("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := db.DB
+	db.DB = conn
+	t.Cleanup(func() {
+		db.DB = old
+		conn.Close()
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testServer is the whole API, single-tenant, over the test database,
+// with an LLM that is never up
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	testDB(t)
+
+	cfg := config.Load()
+	cfg.Server.SingleTenant = true
+	matcher := regex.NewMatcher()
+	client := llm.NewMultiClient([]string{"http://127.0.0.1:1"}, false)
+	engine := rag.NewEngine(client, matcher)
+	s := NewServer(cfg, chat.NewManager(engine, client, matcher), engine, matcher)
+	t.Cleanup(s.jobs.Stop)
+	return s
+}
+
+// insertDoc stores a document, failing the test on error
+func insertDoc(t *testing.T, filename, content string) *db.Document {
+	t.Helper()
+	doc, err := db.InsertDocument(filename, filename, content, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
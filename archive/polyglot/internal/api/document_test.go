@@ -0,0 +1,318 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/db"
+)
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		header     string
+		start, end int
+		ok         bool
+	}{
+		{"bytes=0-9", 0, 9, true},
+		{"bytes=90-", 90, 99, true},
+		{"bytes=-10", 90, 99, true},
+		{"bytes=-500", 0, 99, true},
+		{"bytes=95-200", 95, 99, true},
+		{"bytes=100-", 0, 0, false},
+		{"bytes=9-3", 0, 0, false},
+		{"bytes=0-1,5-6", 0, 0, false},
+		{"items=0-9", 0, 0, false},
+		{"bytes=-0", 0, 0, false},
+	}
+	for _, tc := range cases {
+		start, end, ok := parseByteRange(tc.header, 100)
+		if ok != tc.ok || (ok && (start != tc.start || end != tc.end)) {
+			t.Errorf("parseByteRange(%q) = %d, %d, %v, want %d, %d, %v",
+				tc.header, start, end, ok, tc.start, tc.end, tc.ok)
+		}
+	}
+}
+
+func TestDocumentContentRanges(t *testing.T) {
+	s := testServer(t)
+	content := strings.Repeat("0123456789", 30)
+	doc := insertDoc(t, "long.txt", content)
+	path := fmt.Sprintf("/api/documents/%d/content", doc.ID)
+
+	get := func(rangeHeader string) (int, string, map[string]string) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), map[string]string{
+			"Accept-Ranges":  resp.Header.Get("Accept-Ranges"),
+			"Content-Range":  resp.Header.Get("Content-Range"),
+			"Content-Length": resp.Header.Get("Content-Length"),
+		}
+	}
+
+	status, body, h := get("")
+	if status != 200 || body != content || h["Accept-Ranges"] != "bytes" || h["Content-Range"] != "" {
+		t.Errorf("full fetch: %d, %d bytes, headers %v", status, len(body), h)
+	}
+
+	status, body, h = get("bytes=100-149")
+	if status != 206 || body != content[100:150] {
+		t.Errorf("ranged fetch: %d %q", status, body)
+	}
+	if h["Content-Range"] != "bytes 100-149/300" || h["Content-Length"] != "50" || h["Accept-Ranges"] != "bytes" {
+		t.Errorf("ranged fetch headers: %v", h)
+	}
+
+	status, body, h = get("bytes=-20")
+	if status != 206 || body != content[280:] || h["Content-Range"] != "bytes 280-299/300" {
+		t.Errorf("suffix fetch: %d %q %v", status, body, h)
+	}
+
+	status, _, h = get("bytes=300-")
+	if status != 416 || h["Content-Range"] != "bytes */300" {
+		t.Errorf("unsatisfiable: %d %v", status, h)
+	}
+}
+
+func TestSendCachedETag(t *testing.T) {
+	doc := map[string]string{"title": "Lease"}
+	app := fiber.New()
+	app.Get("/doc", func(c *fiber.Ctx) error { return sendCached(c, doc) })
+
+	fetch := func(ifNoneMatch string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/doc", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := fetch("")
+	etag := first.Header.Get("ETag")
+	body, _ := io.ReadAll(first.Body)
+	if first.StatusCode != 200 || etag == "" || string(body) != `{"title":"Lease"}` {
+		t.Fatalf("first fetch: %d, ETag %q, body %s", first.StatusCode, etag, body)
+	}
+	if cc := first.Header.Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		resp := fetch(header)
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 304 || len(body) != 0 || resp.Header.Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: %d with %d bytes", header, resp.StatusCode, len(body))
+		}
+	}
+
+	if resp := fetch(`"stale"`); resp.StatusCode != 200 {
+		t.Errorf("stale tag: %d, want 200", resp.StatusCode)
+	}
+
+	doc["title"] = "Lease (amended)"
+	changed := fetch(etag)
+	if changed.StatusCode != 200 || changed.Header.Get("ETag") == etag {
+		t.Errorf("after a change: %d, ETag %q", changed.StatusCode, changed.Header.Get("ETag"))
+	}
+}
+
+func TestGetDocumentConditional(t *testing.T) {
+	s := testServer(t)
+	doc := insertDoc(t, "memo.txt", "The memo.")
+	path := fmt.Sprintf("/api/documents/%d", doc.ID)
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != 200 || etag == "" {
+		t.Fatalf("first fetch: %d, ETag %q", resp.StatusCode, etag)
+	}
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 304 {
+		t.Errorf("conditional re-fetch: %d, want 304", resp.StatusCode)
+	}
+
+	if _, err := db.DB.Exec("UPDATE documents SET title = 'Memo v2' WHERE id = $1", doc.ID); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest("GET", path, nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("ETag") == etag {
+		t.Errorf("after an update: %d, ETag %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	resp, err = s.app.Test(httptest.NewRequest("GET", "/api/documents", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("ETag") == "" {
+		t.Errorf("list: %d, ETag %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestSimilarDocuments(t *testing.T) {
+	s := testServer(t)
+	source := insertDoc(t, "source.txt", "Offshore shell company registered in Panama moved funds through a nominee director.")
+	wire := insertDoc(t, "wire.txt", "The nominee director signed for another offshore shell company.")
+	panama := insertDoc(t, "panama.txt", "Panama registry lists the company and its nominee shareholders.")
+	insertDoc(t, "recipe.txt", "Whisk the eggs with sugar and bake for twenty minutes.")
+
+	similar := func(limit int) []int {
+		t.Helper()
+		path := fmt.Sprintf("/api/documents/%d/similar?limit=%d", source.ID, limit)
+		resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Results []db.SearchResult `json:"results"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != 200 {
+			t.Fatalf("similar: %d", resp.StatusCode)
+		}
+		ids := make([]int, len(body.Results))
+		for i, r := range body.Results {
+			ids[i] = r.ID
+		}
+		return ids
+	}
+
+	ids := similar(10)
+	got := map[int]bool{}
+	for _, id := range ids {
+		got[id] = true
+	}
+	if len(ids) != 2 || !got[wire.ID] || !got[panama.ID] {
+		t.Errorf("similar = %v, want %d and %d", ids, wire.ID, panama.ID)
+	}
+	if got[source.ID] {
+		t.Error("source document returned as similar to itself")
+	}
+	if ids := similar(1); len(ids) != 1 {
+		t.Errorf("limit=1 returned %v", ids)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, id := range []int{1, 42, 1 << 40} {
+		if got, ok := decodeCursor(encodeCursor(id)); !ok || got != id {
+			t.Errorf("decodeCursor(encodeCursor(%d)) = %d, %v", id, got, ok)
+		}
+	}
+	for _, cursor := range []string{"", "!!", "42", encodeCursor(0), encodeCursor(-3),
+		base64.RawURLEncoding.EncodeToString([]byte("page:42"))} {
+		if id, ok := decodeCursor(cursor); ok {
+			t.Errorf("decodeCursor(%q) = %d, want invalid", cursor, id)
+		}
+	}
+}
+
+func TestListDocumentsCursorWalk(t *testing.T) {
+	s := testServer(t)
+	seeded := map[int]bool{}
+	for i := 0; i < 11; i++ {
+		seeded[insertDoc(t, fmt.Sprintf("doc%02d.txt", i), "Corpus entry.").ID] = true
+	}
+
+	type page struct {
+		Documents  []db.Document `json:"documents"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	list := func(query string) (int, page) {
+		t.Helper()
+		resp, err := s.app.Test(httptest.NewRequest("GET", "/api/documents?"+query, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p page
+		json.NewDecoder(resp.Body).Decode(&p)
+		return resp.StatusCode, p
+	}
+
+	seen := map[int]bool{}
+	last, pages := 0, 0
+	for cursor := ""; ; pages++ {
+		status, p := list("limit=3&cursor=" + cursor)
+		if status != 200 {
+			t.Fatalf("page %d: %d", pages, status)
+		}
+		for _, d := range p.Documents {
+			if seen[d.ID] {
+				t.Errorf("document %d listed twice", d.ID)
+			}
+			if d.ID <= last {
+				t.Errorf("document %d after %d: not in id order", d.ID, last)
+			}
+			seen[d.ID], last = true, d.ID
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		if len(p.Documents) != 3 {
+			t.Errorf("page %d has %d documents but a next cursor", pages, len(p.Documents))
+		}
+		cursor = p.NextCursor
+	}
+	for id := range seeded {
+		if !seen[id] {
+			t.Errorf("document %d never listed", id)
+		}
+	}
+	if pages < 3 {
+		t.Errorf("walked %d pages for %d documents", pages+1, len(seen))
+	}
+
+	// Offset paging lands on the same second page
+	_, first := list("limit=3")
+	_, byCursor := list("limit=3&cursor=" + first.NextCursor)
+	_, byOffset := list("limit=3&offset=3")
+	if fmt.Sprint(docIDs(byCursor.Documents)) != fmt.Sprint(docIDs(byOffset.Documents)) {
+		t.Errorf("cursor page %v, offset page %v", docIDs(byCursor.Documents), docIDs(byOffset.Documents))
+	}
+
+	if status, _ := list("cursor=bogus"); status != 400 {
+		t.Errorf("bogus cursor: %d, want 400", status)
+	}
+}
+
+func docIDs(docs []db.Document) []int {
+	ids := make([]int, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/db"
+)
+
+func TestResolveEntityEmail(t *testing.T) {
+	s := testServer(t)
+	ingest := func(filename, content string) *db.IngestResult {
+		t.Helper()
+		res, err := db.IngestDocument(filename, filename, content, "", s.graphEntities(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	memo := ingest("memo.txt", "Forward the invoice from Alice.Smith@Example.com to bob@example.org today.")
+	other := ingest("other.txt", "Unrelated note for carol@example.net.")
+
+	body, _ := json.Marshal(ResolveEntityRequest{Value: "  Alice.Smith@EXAMPLE.com ", Type: "email"})
+	req := httptest.NewRequest("POST", "/api/entities/resolve", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("resolve: %d", resp.StatusCode)
+	}
+
+	var profile struct {
+		Normalized string            `json:"normalized"`
+		Entities   []db.Entity       `json:"entities"`
+		Connected  []db.Entity       `json:"connected"`
+		Edges      []db.Edge         `json:"edges"`
+		Documents  []db.SearchResult `json:"documents"`
+	}
+	json.NewDecoder(resp.Body).Decode(&profile)
+
+	if profile.Normalized != "alice.smith@example.com" {
+		t.Errorf("normalized = %q", profile.Normalized)
+	}
+	if len(profile.Entities) != 1 || profile.Entities[0].Name != "alice.smith@example.com" {
+		t.Errorf("entities = %+v", profile.Entities)
+	}
+
+	connected := map[string]bool{}
+	for _, e := range profile.Connected {
+		connected[e.Name] = true
+	}
+	if !connected["bob@example.org"] || connected["carol@example.net"] {
+		t.Errorf("connected = %+v, want bob but not carol", profile.Connected)
+	}
+	if len(profile.Edges) == 0 || profile.Edges[0].Relationship != db.RelCooccurs {
+		t.Errorf("edges = %+v", profile.Edges)
+	}
+
+	if len(profile.Documents) != 1 || profile.Documents[0].ID != memo.Document.ID {
+		t.Errorf("documents = %+v, want only %d (not %d)", profile.Documents, memo.Document.ID, other.Document.ID)
+	}
+}
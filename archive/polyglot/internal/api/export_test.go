@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/db"
+)
+
+func exportRows() []db.SearchResult {
+	return []db.SearchResult{
+		{Document: db.Document{ID: 1, DocID: "a", Title: `Q3 "final", draft`}, Rank: 0.5, Excerpt: "line one\nline two"},
+		{Document: db.Document{ID: 2, DocID: "b", Title: "=HYPERLINK(\"http://x\")"}, MatchedTerms: []string{"x"}, Excerpt: "-2+3"},
+		{Document: db.Document{ID: 3, DocID: "c", Title: "@SUM(A1)", Filename: "+1.csv"}, Excerpt: "\tTab"},
+	}
+}
+
+func TestSearchExportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	out := newSearchExporter(&buf, exportCSV)
+	for _, r := range exportRows() {
+		if err := out.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := out.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Fatalf("records = %q, want the header and 3 rows", records)
+	}
+	if got := records[1][3]; got != `Q3 "final", draft` {
+		t.Errorf("quoted title = %q", got)
+	}
+	if got := records[1][8]; got != "line one\nline two" {
+		t.Errorf("multi-line excerpt = %q", got)
+	}
+
+	// No cell may start a spreadsheet formula
+	for _, row := range records[2:] {
+		for i, cell := range row {
+			if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+				t.Errorf("column %s = %q starts a formula", exportColumns[i], cell)
+			}
+		}
+	}
+	if got := records[2][3]; got != "'=HYPERLINK(\"http://x\")" {
+		t.Errorf("formula title = %q, want it quoted", got)
+	}
+}
+
+func TestSearchExportCSVHeaderWithoutResults(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newSearchExporter(&buf, exportCSV).close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != strings.Join(exportColumns, ",") {
+		t.Errorf("empty export = %q, want the header row", got)
+	}
+}
+
+func TestSearchExportJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	out := newSearchExporter(&buf, exportJSONL)
+	for _, r := range exportRows() {
+		if err := out.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d lines, want one per result:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var r db.SearchResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if r.ID != i+1 {
+			t.Errorf("line %d has id %d", i+1, r.ID)
+		}
+	}
+}
+
+// Each row reaches the connection as soon as it is written
+func TestFlushWriterPushesEveryWrite(t *testing.T) {
+	var conn bytes.Buffer
+	out := newSearchExporter(flushWriter{bufio.NewWriterSize(&conn, 4096)}, exportJSONL)
+	if err := out.write(exportRows()[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(conn.String(), `"doc_id":"a"`) {
+		t.Errorf("row still buffered: connection has %q", conn.String())
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/nlp"
+	"hybridcore/internal/regex"
+)
+
+func extractApp() *fiber.App {
+	s := &Server{
+		config:       &config.Config{Regex: config.RegexConfig{MaxUploadSize: 8 << 20}},
+		regexMatcher: regex.NewMatcher(),
+		binaryInput:  regex.BinaryReject,
+	}
+	app := fiber.New(fiber.Config{BodyLimit: 16 << 20})
+	app.Post(streamUploadPath, s.handleRegexExtractStream)
+	app.Post("/api/extract", s.handleExtract)
+	return app
+}
+
+// An upload several reader chunks long, with emails written across every
+// chunk and window boundary: each is reported once, at its true offset
+func TestExtractStreamAcrossChunks(t *testing.T) {
+	const size = 4*regex.ReaderChunkSize + 1000
+	content := bytes.Repeat([]byte(" "), size)
+	want := make(map[string]int) // email → offset
+
+	place := func(offset int) {
+		email := fmt.Sprintf("user%d@example.com", len(want))
+		copy(content[offset:], email)
+		want[email] = offset
+	}
+	for k := 1; k <= 3; k++ {
+		// Straddling the read boundary, and the end of the window that
+		// carries the previous one's tail
+		place(k*regex.ReaderChunkSize - 9)
+		place(k*regex.ReaderChunkSize + 2*regex.ReaderOverlap - 9)
+		place(k*regex.ReaderChunkSize - regex.ReaderOverlap - 9)
+	}
+	place(size - 40)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "dump.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", streamUploadPath, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := extractApp().Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got struct {
+		BytesProcessed int64             `json:"bytes_processed"`
+		Matches        []AggregatedMatch `json:"matches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.BytesProcessed != size {
+		t.Errorf("processed %d bytes, want %d", got.BytesProcessed, size)
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range got.Matches {
+		if m.Pattern != "email" {
+			continue
+		}
+		offset, ok := want[m.Value]
+		if !ok {
+			t.Errorf("unexpected email %q (a fragment?) at %d", m.Value, m.FirstOffset)
+			continue
+		}
+		if m.Count != 1 || m.FirstOffset != offset {
+			t.Errorf("%s: count %d at %d, want once at %d", m.Value, m.Count, m.FirstOffset, offset)
+		}
+		seen[m.Value] = true
+	}
+	for email := range want {
+		if !seen[email] {
+			t.Errorf("%s at %d not found", email, want[email])
+		}
+	}
+}
+
+// /api/extract answers in the canonical taxonomy: every entity has a
+// canonical type and a normalized value, and spellings are merged
+func TestExtractCanonicalEntities(t *testing.T) {
+	text := "Mail Bob@Example.COM or bob@example.com, host 10.0.0.7, tag #OSINT"
+	body, _ := json.Marshal(map[string]string{"text": text})
+	req := httptest.NewRequest("POST", "/api/extract", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := extractApp().Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Total    int                   `json:"total"`
+		Types    map[string]int        `json:"types"`
+		Entities []nlp.CanonicalEntity `json:"entities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Total != len(out.Entities) {
+		t.Errorf("total = %d for %d entities", out.Total, len(out.Entities))
+	}
+
+	byID := make(map[string]nlp.CanonicalEntity)
+	for _, e := range out.Entities {
+		byID[e.ID] = e
+		if e.Normalized == "" || e.ID != e.Type+":"+e.Normalized {
+			t.Errorf("entity %+v: missing or inconsistent normalized value", e)
+		}
+		for _, src := range e.Sources {
+			if typ, ok := nlp.CanonicalType(src); !ok || typ != e.Type {
+				t.Errorf("entity %s from %s, which maps to %q", e.ID, src, typ)
+			}
+		}
+	}
+
+	for id, count := range map[string]int{
+		"email:bob@example.com": 2,
+		"ip_address:10.0.0.7":   1,
+		"hashtag:osint":         1,
+	} {
+		if e, ok := byID[id]; !ok || e.Count != count {
+			t.Errorf("%s: got %+v, want count %d", id, e, count)
+		}
+	}
+	if _, ok := byID["domain:example.com"]; ok {
+		t.Error("the domain inside an email was reported as an entity")
+	}
+	if out.Types[nlp.TypeEmail] != 1 {
+		t.Errorf("types = %v", out.Types)
+	}
+}
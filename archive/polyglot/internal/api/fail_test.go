@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/errs"
+)
+
+// Handlers wrap store errors as Internal; fail keeps the cause out of the
+// response and still reports a timed-out query as one
+func TestFailWrappedInternal(t *testing.T) {
+	tests := []struct {
+		name   string
+		cause  error
+		status int
+		body   string
+	}{
+		{"store error", errors.New(`pq: relation "documents" does not exist`), 500, `{"error":"Failed to list documents"}`},
+		{"timeout", fmt.Errorf("query: %w", context.DeadlineExceeded), 503, `{"error":"Timed out"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return fail(c, errs.Wrap(errs.Internal, "Failed to list documents", tt.cause))
+			})
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || string(body) != tt.body {
+				t.Errorf("got %d %s, want %d %s", resp.StatusCode, body, tt.status, tt.body)
+			}
+		})
+	}
+}
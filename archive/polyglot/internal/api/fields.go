@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Fields a client may ask for with ?fields=, by response. Projection
+// happens on the encoded response, so the names are the JSON keys and
+// never reach a query.
+var (
+	documentFields = []string{
+		"id", "doc_id", "filename", "title", "content", "word_count", "owner", "language", "created_at",
+	}
+	searchFields = append(append([]string(nil), documentFields...), "rank", "excerpt", "matched_terms")
+)
+
+// parseFields reads the comma-separated ?fields= list against allowed.
+// Nil means no projection was asked for; every name not in allowed is
+// reported at once.
+func parseFields(c *fiber.Ctx, allowed []string) ([]string, *requestError) {
+	param := strings.TrimSpace(c.Query("fields"))
+	if param == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	var bad []FieldError
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !containsField(allowed, name) {
+			bad = append(bad, FieldError{Field: name, Error: "unknown field"})
+			continue
+		}
+		fields = append(fields, name)
+	}
+	if len(bad) > 0 {
+		return nil, &requestError{status: 400, msg: "Invalid fields", fields: bad}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+func containsField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// project keeps only fields of v, an object or an array of objects, by
+// way of its JSON encoding. Nil fields, or a nil v, leaves v as is.
+func project(v interface{}, fields []string) (interface{}, error) {
+	if fields == nil {
+		return v, nil
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(body, []byte("null")) {
+		return v, nil
+	}
+
+	if bytes.HasPrefix(body, []byte("[")) {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		out := make([]map[string]json.RawMessage, len(items))
+		for i, item := range items {
+			out[i] = pick(item, fields)
+		}
+		return out, nil
+	}
+
+	var item map[string]json.RawMessage
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+	return pick(item, fields), nil
+}
+
+func pick(item map[string]json.RawMessage, fields []string) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if v, ok := item[f]; ok {
+			out[f] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/db"
+	"hybridcore/internal/regex"
+)
+
+// keys lists the JSON object keys of v, sorted
+func keys(t *testing.T, v interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
+func TestProject(t *testing.T) {
+	results := []db.SearchResult{
+		{Document: db.Document{ID: 1, Title: "Lease", Content: "long body"}, Rank: 0.5},
+		{Document: db.Document{ID: 2, Title: "Memo", Content: "long body"}, Rank: 0.2},
+	}
+	out, err := project(results, []string{"title", "rank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := json.Marshal(out)
+	var decoded []map[string]json.RawMessage
+	json.Unmarshal(items, &decoded)
+	if len(decoded) != 2 {
+		t.Fatalf("projected %s", items)
+	}
+	for _, item := range decoded {
+		if got := keys(t, item); got != "rank,title" {
+			t.Errorf("item keys = %s, want rank,title", got)
+		}
+	}
+
+	doc, _ := project(db.Document{ID: 3, Title: "Lease"}, []string{"id"})
+	if got := keys(t, doc); got != "id" {
+		t.Errorf("document keys = %s, want id", got)
+	}
+	if same, _ := project(results, nil); len(same.([]db.SearchResult)) != 2 {
+		t.Errorf("nil fields changed the value: %v", same)
+	}
+}
+
+func TestFieldsUnknownRejected(t *testing.T) {
+	cfg := config.Load()
+	cfg.Server.SingleTenant = true
+	s := NewServer(cfg, nil, nil, regex.NewMatcher())
+	t.Cleanup(s.jobs.Stop)
+
+	for _, path := range []string{
+		"/api/search?q=lease&fields=title,password",
+		"/api/documents?fields=id,rank",
+	} {
+		resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Error  string       `json:"error"`
+			Fields []FieldError `json:"fields"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != 400 || body.Error != "Invalid fields" || len(body.Fields) != 1 {
+			t.Errorf("%s: %d %+v", path, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestFieldsProjection(t *testing.T) {
+	s := testServer(t)
+	insertDoc(t, "lease.txt", "The lease was signed in March.")
+	insertDoc(t, "lease2.txt", "A second lease, renewed in April.")
+
+	get := func(path string, v interface{}) {
+		t.Helper()
+		resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("%s: %d", path, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	var results []map[string]json.RawMessage
+	get("/api/search?q=lease&fields=id,TITLE,excerpt", &results)
+	if len(results) != 2 {
+		t.Fatalf("search: %d results", len(results))
+	}
+	for _, r := range results {
+		if got := keys(t, r); got != "excerpt,id,title" {
+			t.Errorf("search result keys = %s", got)
+		}
+	}
+
+	var page struct {
+		Documents []map[string]json.RawMessage `json:"documents"`
+	}
+	get("/api/documents?limit=10&fields=id,filename", &page)
+	if len(page.Documents) < 2 {
+		t.Fatalf("documents: %d", len(page.Documents))
+	}
+	for _, d := range page.Documents {
+		if got := keys(t, d); got != "filename,id" {
+			t.Errorf("document keys = %s", got)
+		}
+	}
+}
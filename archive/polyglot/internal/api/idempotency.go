@@ -0,0 +1,172 @@
+package api
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/clock"
+)
+
+// idempotencyStore remembers the response to each Idempotency-Key for a
+// TTL so a retried request gets the original answer instead of running
+// again. Keys live in memory: replays are only caught by the instance that
+// served the first attempt. At most maxKeys answered keys are kept, the
+// least recently used going first, and bodies over maxBody aren't kept at
+// all.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxKeys   int
+	maxBody   int
+	entries   map[string]*list.Element // of *idempotentEntry
+	lru       *list.List               // front is most recently used
+	lastSweep time.Time
+}
+
+type idempotentEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	done        bool // false while the first request is still running
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration, maxKeys, maxBody int) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		maxBody: maxBody,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// begin claims key for a request with the given body fingerprint. It
+// returns the stored entry when the key was seen before, or nil when the
+// caller now owns the key and must finish or release it.
+func (s *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte) *idempotentEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clock.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for _, el := range s.entries {
+			if e := el.Value.(*idempotentEntry); e.done && now.After(e.expires) {
+				s.remove(el)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if el, ok := s.entries[key]; ok {
+		if e := el.Value.(*idempotentEntry); !e.done || now.Before(e.expires) {
+			s.lru.MoveToFront(el)
+			copied := *e
+			return &copied
+		}
+		s.remove(el)
+	}
+	s.evict()
+	s.entries[key] = s.lru.PushFront(&idempotentEntry{key: key, fingerprint: fingerprint})
+	return nil
+}
+
+// evict drops least recently used answered keys until there's room for one
+// more. Keys still in progress stay: dropping one would let its replay run
+// twice.
+func (s *idempotencyStore) evict() {
+	if s.maxKeys <= 0 {
+		return
+	}
+	for el := s.lru.Back(); el != nil && len(s.entries) >= s.maxKeys; {
+		prev := el.Prev()
+		if el.Value.(*idempotentEntry).done {
+			s.remove(el)
+		}
+		el = prev
+	}
+}
+
+func (s *idempotencyStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*idempotentEntry).key)
+}
+
+// finish stores the answer to key. One too large to keep releases the key
+// instead, so a retry runs again.
+func (s *idempotencyStore) finish(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	if s.maxBody > 0 && len(body) > s.maxBody {
+		s.remove(el)
+		return
+	}
+	e := el.Value.(*idempotentEntry)
+	e.done = true
+	e.status = status
+	e.contentType = contentType
+	e.body = append([]byte(nil), body...)
+	e.expires = clock.Now().Add(s.ttl)
+}
+
+// release forgets key so the request can be retried for real
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+}
+
+// idempotent makes a write route replay-safe: a request carrying an
+// Idempotency-Key already answered for this tenant and route gets the
+// stored response back with Idempotent-Replayed set. Reusing a key with a
+// different body is a 422, and a replay while the first attempt is still
+// running a 409. Server errors and oversized responses aren't stored, so
+// those can be retried.
+func (s *Server) idempotent(c *fiber.Ctx) error {
+	key := c.Get("Idempotency-Key")
+	if key == "" || s.idempotency == nil {
+		return c.Next()
+	}
+	if len(key) > 255 {
+		return c.Status(400).JSON(fiber.Map{"error": "Idempotency-Key too long"})
+	}
+
+	scope := tenant(c) + "\x00" + c.Method() + " " + c.Route().Path + "\x00" + key
+	fingerprint := sha256.Sum256(c.Body())
+
+	if prev := s.idempotency.begin(scope, fingerprint); prev != nil {
+		switch {
+		case prev.fingerprint != fingerprint:
+			return c.Status(422).JSON(fiber.Map{"error": "Idempotency-Key reused with a different request"})
+		case !prev.done:
+			return c.Status(409).JSON(fiber.Map{"error": "Request with this Idempotency-Key still in progress"})
+		}
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, prev.contentType)
+		return c.Status(prev.status).Send(prev.body)
+	}
+
+	if err := c.Next(); err != nil {
+		s.idempotency.release(scope)
+		return err
+	}
+	resp := c.Response()
+	if status := resp.StatusCode(); status >= 500 {
+		s.idempotency.release(scope)
+	} else {
+		s.idempotency.finish(scope, status, string(resp.Header.ContentType()), resp.Body())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	s := newIdempotencyStore(time.Hour, 2, 0)
+	fp := sha256.Sum256([]byte("body"))
+
+	for _, key := range []string{"a", "b"} {
+		if s.begin(key, fp) != nil {
+			t.Fatalf("%s: new key reported as seen", key)
+		}
+		s.finish(key, 201, "application/json", []byte(key))
+	}
+	// Touch a so b is the least recently used
+	if prev := s.begin("a", fp); prev == nil || string(prev.body) != "a" {
+		t.Fatalf("a: got %+v, want the stored answer", prev)
+	}
+
+	if s.begin("c", fp) != nil {
+		t.Fatal("c: new key reported as seen")
+	}
+	s.finish("c", 201, "application/json", []byte("c"))
+
+	if len(s.entries) != 2 || s.lru.Len() != 2 {
+		t.Fatalf("store holds %d keys (%d in lru), want 2", len(s.entries), s.lru.Len())
+	}
+	if _, ok := s.entries["b"]; ok {
+		t.Error("b was kept, want it evicted as least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := s.entries[key]; !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestIdempotencyStoreKeepsKeysInProgress(t *testing.T) {
+	s := newIdempotencyStore(time.Hour, 1, 0)
+	fp := sha256.Sum256([]byte("body"))
+
+	s.begin("running", fp)
+	s.begin("next", fp)
+	if prev := s.begin("running", fp); prev == nil || prev.done {
+		t.Errorf("running: got %+v, want it still in progress", prev)
+	}
+}
+
+func TestIdempotencyStoreSkipsLargeBodies(t *testing.T) {
+	s := newIdempotencyStore(time.Hour, 10, 4)
+	fp := sha256.Sum256([]byte("body"))
+
+	s.begin("big", fp)
+	s.finish("big", 200, "application/json", []byte("too large"))
+	if prev := s.begin("big", fp); prev != nil {
+		t.Errorf("oversized answer was kept: %+v", prev)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/regex"
+)
+
+const ingestMemo = "yesterday Alice Martin met Bob Stone in the lobby."
+
+func TestGraphEntitiesTwoNames(t *testing.T) {
+	s := &Server{regexMatcher: regex.NewMatcher()}
+	var names []string
+	for _, e := range s.graphEntities(ingestMemo) {
+		names = append(names, e.Type+":"+e.Name)
+	}
+	if strings.Join(names, ",") != "person_name:Alice Martin,person_name:Bob Stone" {
+		t.Errorf("entities = %v", names)
+	}
+}
+
+func TestIngestTwoNamesMakesOneEdge(t *testing.T) {
+	s := testServer(t)
+
+	body, _ := json.Marshal(UploadDocumentRequest{Filename: "memo.txt", Content: ingestMemo})
+	req := httptest.NewRequest("POST", "/api/ingest", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result db.IngestResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != 201 || result.Document == nil || result.Document.Filename != "memo.txt" {
+		t.Fatalf("ingest: %d, %+v", resp.StatusCode, result)
+	}
+	if len(result.Entities) != 2 || result.EdgesCreated != 1 || result.EdgesUpdated != 0 {
+		t.Fatalf("entities %+v, edges created %d updated %d", result.Entities, result.EdgesCreated, result.EdgesUpdated)
+	}
+
+	alice, bob := result.Entities[0], result.Entities[1]
+	if alice.Name != "Alice Martin" || bob.Name != "Bob Stone" {
+		t.Errorf("entities = %+v", result.Entities)
+	}
+	edges, err := db.EntityEdges([]int{alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edges) != 1 || edges[0].Relationship != db.RelCooccurs {
+		t.Fatalf("edges = %+v", edges)
+	}
+	if ends := []int{edges[0].FromEntityID, edges[0].ToEntityID}; !(ends[0] == alice.ID && ends[1] == bob.ID || ends[0] == bob.ID && ends[1] == alice.ID) {
+		t.Errorf("edge links %v, want %d and %d", ends, alice.ID, bob.ID)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hybridcore/internal/jobs"
+)
+
+// jobResponse decodes a job as the API returns it
+type jobResponse struct {
+	ID     string      `json:"id"`
+	Status jobs.Status `json:"status"`
+	Error  string      `json:"error"`
+	Result struct {
+		DocumentID int64 `json:"document_id"`
+		Total      int   `json:"total"`
+		Matches    map[string][]struct {
+			Value string `json:"value"`
+		} `json:"matches"`
+	} `json:"result"`
+}
+
+func TestUploadDocumentJob(t *testing.T) {
+	s := testServer(t)
+
+	body := `{"filename":"memo.txt","content":"Wire the funds, then mail alice@example.com from 10.0.0.7."}`
+	req := httptest.NewRequest("POST", "/api/documents", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job jobResponse
+	json.NewDecoder(resp.Body).Decode(&job)
+	if resp.StatusCode != 202 || job.ID == "" || job.Status != jobs.StatusPending {
+		t.Fatalf("upload: %d, job %+v", resp.StatusCode, job)
+	}
+
+	// Statuses may be skipped between polls but must never go backwards
+	rank := map[jobs.Status]int{jobs.StatusPending: 0, jobs.StatusRunning: 1, jobs.StatusDone: 2, jobs.StatusFailed: 2}
+	seen := job.Status
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != jobs.StatusDone {
+		if job.Status == jobs.StatusFailed || time.Now().After(deadline) {
+			t.Fatalf("job ended %s: %s", job.Status, job.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+		resp, err := s.app.Test(httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job = jobResponse{}
+		json.NewDecoder(resp.Body).Decode(&job)
+		if rank[job.Status] < rank[seen] {
+			t.Fatalf("job went from %s back to %s", seen, job.Status)
+		}
+		seen = job.Status
+	}
+
+	if job.Result.DocumentID == 0 || job.Result.Total == 0 {
+		t.Errorf("result = %+v", job.Result)
+	}
+	found := false
+	for _, m := range job.Result.Matches["communication"] {
+		found = found || m.Value == "alice@example.com"
+	}
+	if !found {
+		t.Errorf("communication matches = %+v, want alice@example.com", job.Result.Matches["communication"])
+	}
+}
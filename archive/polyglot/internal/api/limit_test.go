@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/regex"
+)
+
+func limitApp(maxUpload int) *fiber.App {
+	s := &Server{config: &config.Config{Regex: config.RegexConfig{MaxUploadSize: maxUpload}}}
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    fiber.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(s.limitBody)
+	echoLen := func(c *fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(len(c.Body())))
+	}
+	app.Post("/api/regex/test", echoLen)
+	app.Post(streamUploadPath, echoLen)
+	return app
+}
+
+func TestLimitBody(t *testing.T) {
+	app := limitApp(8 << 20)
+	big := bytes.Repeat([]byte("a"), fiber.DefaultBodyLimit+1)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    []byte
+		chunked bool
+		status  int
+	}{
+		{"small body", "/api/regex/test", []byte(`{"text":"x"}`), false, 200},
+		{"small chunked body", "/api/regex/test", []byte(`{"text":"x"}`), true, 200},
+		{"over default limit", "/api/regex/test", big, false, 413},
+		{"chunked over default limit", "/api/regex/test", big, true, 413},
+		{"large upload on stream route", streamUploadPath, big, false, 200},
+		{"over upload limit on stream route", streamUploadPath, bytes.Repeat([]byte("a"), 8<<20+1), false, 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != 200 {
+				return
+			}
+			got, _ := io.ReadAll(resp.Body)
+			if string(got) != strconv.Itoa(len(tt.body)) {
+				t.Errorf("handler saw %s bytes, want %d", got, len(tt.body))
+			}
+		})
+	}
+}
+
+// MaxTextLength 0 means unlimited, as on the other regex routes
+func TestRegexCalibrateSampleLimit(t *testing.T) {
+	body := `{"samples":[{"text":"mail alice@example.com","expected":[{"pattern":"email","value":"alice@example.com"}]}]}`
+
+	tests := []struct {
+		name   string
+		max    int
+		status int
+	}{
+		{"unlimited", 0, 200},
+		{"under limit", 1024, 200},
+		{"over limit", 8, 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				config:       &config.Config{Regex: config.RegexConfig{MaxTextLength: tt.max}},
+				regexMatcher: regex.NewMatcher(),
+			}
+			app := fiber.New()
+			app.Post("/calibrate", s.handleRegexCalibrate)
+
+			req := httptest.NewRequest("POST", "/calibrate", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
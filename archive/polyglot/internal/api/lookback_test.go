@@ -0,0 +1,47 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLookback(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"30d", 30 * day},
+		{"2w", 14 * day},
+		{"6mo", 180 * day},
+		{"1y", 365 * day},
+		{"36h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{"6m0s", 6 * time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseLookback(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseLookback(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseLookbackRejects(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{"6m", "ambiguous"},
+		{"0d", "positive"},
+		{"-1w", "positive"},
+		{"xmo", "invalid"},
+		{"3q", "invalid"},
+	}
+	for _, tt := range tests {
+		_, err := parseLookback(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseLookback(%q) err = %v, want %q", tt.in, err, tt.err)
+		}
+	}
+}
@@ -2,73 +2,165 @@ package api
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"hybridcore/internal/buildinfo"
 	"hybridcore/internal/chat"
+	"hybridcore/internal/clock"
+	"hybridcore/internal/config"
 	"hybridcore/internal/db"
+	"hybridcore/internal/errs"
+	"hybridcore/internal/jobs"
+	"hybridcore/internal/nlp"
 	"hybridcore/internal/rag"
 	"hybridcore/internal/regex"
 )
 
 type Server struct {
 	app          *fiber.App
+	config       *config.Config
 	chatManager  *chat.Manager
 	ragEngine    *rag.Engine
 	regexMatcher *regex.Matcher
+	jobs         *jobs.Queue
+	idempotency  *idempotencyStore // nil when IDEMPOTENCY_TTL is 0
+	binaryInput  regex.BinaryPolicy
 }
 
-func NewServer(chatManager *chat.Manager, ragEngine *rag.Engine) *Server {
+// NewServer wires the API over the chat manager, the RAG engine and the
+// regex matcher they share
+func NewServer(cfg *config.Config, chatManager *chat.Manager, ragEngine *rag.Engine, matcher *regex.Matcher) *Server {
+	// Bodies past the limit are streamed rather than refused, so the
+	// streamed extraction route can take large uploads; limitBody holds
+	// every route to its own limit
 	app := fiber.New(fiber.Config{
-		AppName:      "HybridCore 2.0",
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 120 * time.Second,
+		AppName:                      "HybridCore 2.0",
+		ReadTimeout:                  30 * time.Second,
+		WriteTimeout:                 120 * time.Second,
+		BodyLimit:                    fiber.DefaultBodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
+	s := &Server{
+		app:          app,
+		config:       cfg,
+		chatManager:  chatManager,
+		ragEngine:    ragEngine,
+		regexMatcher: matcher,
+		jobs:         jobs.NewQueue(cfg.Jobs.Workers, cfg.Jobs.QueueSize),
+	}
+
 	// Middleware
 	app.Use(recover.New())
+	app.Use(s.limitBody)
 	app.Use(logger.New(logger.Config{
 		Format:     "${time} ${status} ${method} ${path} ${latency}\n",
 		TimeFormat: "15:04:05",
 	}))
+	corsCfg := cfg.Server.CORS
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:     strings.Join(corsCfg.AllowOrigins, ", "),
+		AllowMethods:     strings.Join(corsCfg.AllowMethods, ", "),
+		AllowHeaders:     strings.Join(corsCfg.AllowHeaders, ", "),
+		AllowCredentials: corsCfg.AllowCredentials,
 	}))
 
 	// Security headers
+	securityHeaders := cfg.Server.SecurityHeaders
 	app.Use(func(c *fiber.Ctx) error {
-		c.Set("X-Content-Type-Options", "nosniff")
-		c.Set("X-Frame-Options", "DENY")
-		c.Set("X-XSS-Protection", "1; mode=block")
+		for header, value := range securityHeaders {
+			c.Set(header, value)
+		}
 		return c.Next()
 	})
 
-	s := &Server{
-		app:          app,
-		chatManager:  chatManager,
-		ragEngine:    ragEngine,
-		regexMatcher: regex.NewMatcher(),
+	s.binaryInput = regex.BinaryReject
+	if policy, ok := regex.ParseBinaryPolicy(cfg.Regex.BinaryInput); ok {
+		s.binaryInput = policy
+	} else {
+		log.Printf("[API] Unknown binary input policy %q, using %s", cfg.Regex.BinaryInput, s.binaryInput)
+	}
+	if cfg.Server.IdempotencyTTL > 0 {
+		s.idempotency = newIdempotencyStore(cfg.Server.IdempotencyTTL, cfg.Server.IdempotencyKeys, cfg.Server.IdempotencyBody)
 	}
 
 	s.setupRoutes()
 	return s
 }
 
+// streamUploadPath is the one route allowed bodies past fiber's default
+// limit, up to REGEX_MAX_UPLOAD_SIZE
+const streamUploadPath = "/api/regex/extract/stream"
+
+// limitBody refuses bodies over the route's limit with 413. Chunked bodies
+// on other routes are read here, bounded, since the server streams them
+// whatever their size.
+func (s *Server) limitBody(c *fiber.Ctx) error {
+	limit := fiber.DefaultBodyLimit
+	if c.Path() == streamUploadPath {
+		if limit = s.config.Regex.MaxUploadSize; limit <= 0 {
+			return c.Next()
+		}
+	}
+
+	tooLarge := func() error {
+		// The rest of the body is never read, so the connection can't be reused
+		c.Context().SetConnectionClose()
+		return c.Status(413).JSON(fiber.Map{
+			"error": fmt.Sprintf("Request body too large (max %d bytes)", limit),
+		})
+	}
+	req := c.Request()
+	switch length := req.Header.ContentLength(); {
+	case length > limit:
+		return tooLarge()
+	case length == -1 && c.Path() != streamUploadPath && req.IsBodyStream():
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Could not read request body"})
+		}
+		if len(body) > limit {
+			return tooLarge()
+		}
+		req.SetBody(body)
+	}
+	return c.Next()
+}
+
 func (s *Server) setupRoutes() {
+	// Probes for orchestrators: liveness and readiness
+	s.app.Get("/healthz", s.handleHealthz)
+	s.app.Get("/readyz", s.handleReadyz)
+
 	// API routes
 	api := s.app.Group("/api")
 
-	// Health & stats
+	// Health, then everything else behind an API key
 	api.Get("/health", s.handleHealth)
+	api.Use(s.authenticate)
+
+	// Stats
 	api.Get("/stats", s.handleStats)
 
 	// Chat
@@ -77,18 +169,52 @@ func (s *Server) setupRoutes() {
 
 	// Documents
 	api.Get("/documents", s.handleListDocuments)
+	api.Post("/documents", s.idempotent, s.handleUploadDocument)
+	api.Post("/ingest", s.idempotent, s.handleIngest)
+	api.Get("/documents/duplicates", s.handleListDuplicates)
 	api.Get("/documents/:id", s.handleGetDocument)
+	api.Get("/documents/:id/content", s.handleDocumentContent)
+	api.Get("/documents/:id/similar", s.handleSimilarDocuments)
+	api.Post("/documents/:id/redact", s.handleRedactDocument)
 	api.Get("/search", s.handleSearch)
+	api.Get("/search/export", s.handleSearchExport)
+	api.Post("/search/bulk", s.handleBulkSearch)
+	api.Post("/search/feedback", s.handleSearchFeedback)
+
+	// Entities
+	api.Post("/entities/resolve", s.handleResolveEntity)
+	api.Get("/entities/:id/timeline", s.handleEntityTimeline)
+
+	// Background jobs
+	api.Get("/jobs/:id", s.handleGetJob)
 
 	// Sessions
 	api.Get("/sessions", s.handleListSessions)
 	api.Get("/sessions/:id", s.handleGetSession)
+	api.Get("/sessions/:id/export", s.handleExportSession)
+	api.Post("/sessions/:id/clear", s.handleClearSession)
+
+	// Canonical entity extraction
+	api.Post("/extract", s.handleExtract)
 
 	// Regex extraction
 	api.Post("/regex/extract", s.handleRegexExtract)
+	api.Post("/regex/extract/stream", s.handleRegexExtractStream)
 	api.Post("/regex/extract/:category", s.handleRegexExtractCategory)
 	api.Post("/regex/sensitive", s.handleRegexSensitive)
+	api.Post("/regex/has-sensitive", s.handleRegexHasSensitive)
 	api.Post("/regex/redact", s.handleRegexRedact)
+	api.Post("/regex/analyze", s.handleRegexAnalyze)
+	api.Post("/regex/test", s.handleRegexTest)
+	api.Get("/regex/metrics", s.handleRegexMetrics)
+	api.Get("/regex/patterns", s.handleRegexPatterns)
+	api.Post("/regex/calibrate", s.handleRegexCalibrate)
+
+	// Keywords
+	api.Post("/keywords", s.handleKeywords)
+
+	// Text utilities
+	api.Post("/text/normalize", s.handleTextNormalize)
 
 	// Static files
 	s.app.Static("/", "./static")
@@ -100,32 +226,85 @@ func (s *Server) setupRoutes() {
 }
 
 func (s *Server) handleHealth(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	resp := fiber.Map{
 		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   "2.0.0",
-	})
+		"timestamp": clock.Format(clock.Now()),
+	}
+	for k, v := range buildinfo.Info() {
+		resp[k] = v
+	}
+	return c.JSON(resp)
+}
+
+// handleHealthz is the liveness probe: the process is up and serving
+func (s *Server) handleHealthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// handleReadyz is the readiness probe: 503 until the database answers
+// with every migration applied and the LLM service answers
+func (s *Server) handleReadyz(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), 2*time.Second)
+	defer cancel()
+
+	checks := fiber.Map{}
+	ready := true
+
+	if err := db.Ping(ctx); err != nil {
+		checks["db"] = err.Error()
+		ready = false
+	} else {
+		checks["db"] = "ok"
+	}
+	if pending, err := db.PendingMigrations(ctx); err != nil {
+		checks["schema"] = err.Error()
+		ready = false
+	} else if len(pending) > 0 {
+		checks["schema"] = "pending migrations: " + strings.Join(pending, ", ")
+		ready = false
+	} else {
+		checks["schema"] = "ok"
+	}
+	if err := s.ragEngine.CheckLLM(ctx); err != nil {
+		checks["llm"] = err.Error()
+		ready = false
+	} else {
+		checks["llm"] = "ok"
+	}
+
+	if !ready {
+		return c.Status(503).JSON(fiber.Map{"status": "not_ready", "checks": checks})
+	}
+	return c.JSON(fiber.Map{"status": "ready", "checks": checks})
 }
 
 func (s *Server) handleStats(c *fiber.Ctx) error {
-	stats := s.ragEngine.GetStats()
+	stats := s.ragEngine.GetStats(tenant(c))
+
+	if detailed, err := db.GetDetailedStats(tenant(c)); err == nil {
+		stats["breakdown"] = detailed
+	} else {
+		log.Printf("[API] Detailed stats error: %v", err)
+	}
+
 	return c.JSON(stats)
 }
 
 func (s *Server) handleChat(c *fiber.Ctx) error {
 	var req chat.ChatRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
 	}
-
-	if strings.TrimSpace(req.Message) == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Message required"})
+	verbosity, ok := rag.ParseVerbosity(string(req.Verbosity))
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "verbosity must be brief, normal or detailed"})
 	}
+	req.Verbosity = verbosity
+	req.Owner = tenant(c)
 
 	resp, err := s.chatManager.Chat(req)
 	if err != nil {
-		log.Printf("[API] Chat error: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Chat failed"})
+		return fail(c, errs.Wrap(errs.Internal, "Chat failed", err))
 	}
 
 	return c.JSON(resp)
@@ -134,10 +313,17 @@ func (s *Server) handleChat(c *fiber.Ctx) error {
 func (s *Server) handleChatStream(c *fiber.Ctx) error {
 	query := c.Query("q")
 	sessionID := c.Query("session_id")
+	owner := tenant(c)
+	excerptLength := c.QueryInt("excerpt_length", 0)
+	plainExcerpts := c.QueryBool("plain_excerpts", false)
 
 	if query == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Query required"})
 	}
+	verbosity, ok := rag.ParseVerbosity(c.Query("verbosity"))
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "verbosity must be brief, normal or detailed"})
+	}
 
 	// Set SSE headers
 	c.Set("Content-Type", "text/event-stream")
@@ -154,8 +340,12 @@ func (s *Server) handleChatStream(c *fiber.Ctx) error {
 
 		// Process chat
 		req := chat.ChatRequest{
-			SessionID: sessionID,
-			Message:   query,
+			SessionID:     sessionID,
+			Message:       query,
+			Owner:         owner,
+			ExcerptLength: excerptLength,
+			PlainExcerpts: plainExcerpts,
+			Verbosity:     verbosity,
 		}
 
 		resp, err := s.chatManager.Chat(req)
@@ -167,25 +357,9 @@ func (s *Server) handleChatStream(c *fiber.Ctx) error {
 			return
 		}
 
-		// Send response in chunks for SSE effect
-		words := strings.Fields(resp.Message)
-		chunkSize := 5
-		var chunks []string
-
-		for i := 0; i < len(words); i += chunkSize {
-			end := i + chunkSize
-			if end > len(words) {
-				end = len(words)
-			}
-			chunks = append(chunks, strings.Join(words[i:end], " "))
-		}
-
-		for _, chunk := range chunks {
-			sendSSE(w, "chunk", map[string]interface{}{
-				"text": chunk + " ",
-			})
-			time.Sleep(50 * time.Millisecond)
-		}
+		// Send response in chunks for SSE effect; the chat manager has
+		// already stripped any reasoning trace
+		sendChunks(w, resp.Message, s.config.Stream, s.chatManager.StreamGuard(s.config.Chat.StreamRedactWindow))
 
 		// Send sources
 		if len(resp.Sources) > 0 {
@@ -210,6 +384,54 @@ func (s *Server) handleChatStream(c *fiber.Ctx) error {
 	return nil
 }
 
+// sendChunks streams text as "chunk" events. With a guard, every chunk
+// passes through it, so a sensitive value split across chunks is held
+// back until it can be masked whole; only what the guard lets out is sent.
+func sendChunks(w *bufio.Writer, text string, cfg config.StreamConfig, guard *regex.StreamRedactor) {
+	send := func(piece string) {
+		if piece != "" {
+			sendSSE(w, "chunk", map[string]interface{}{"text": piece})
+		}
+	}
+	for _, chunk := range chunkMessage(text, cfg) {
+		piece := chunk + " "
+		if guard != nil {
+			piece = guard.Push(piece)
+		}
+		send(piece)
+		if cfg.ChunkDelay > 0 {
+			time.Sleep(cfg.ChunkDelay)
+		}
+	}
+	if guard != nil {
+		send(guard.Flush())
+	}
+}
+
+// chunkMessage splits an answer into SSE chunks, either one per sentence
+// or in fixed-size word groups.
+func chunkMessage(text string, cfg config.StreamConfig) []string {
+	if cfg.Sentences {
+		return regex.SplitSentences(text)
+	}
+
+	chunkSize := cfg.ChunkWords
+	if chunkSize <= 0 {
+		chunkSize = 5
+	}
+
+	words := strings.Fields(text)
+	var chunks []string
+	for i := 0; i < len(words); i += chunkSize {
+		end := i + chunkSize
+		if end > len(words) {
+			end = len(words)
+		}
+		chunks = append(chunks, strings.Join(words[i:end], " "))
+	}
+	return chunks
+}
+
 func sendSSE(w *bufio.Writer, event string, data interface{}) {
 	jsonData, _ := json.Marshal(data)
 	fmt.Fprintf(w, "event: %s\n", event)
@@ -217,134 +439,1654 @@ func sendSSE(w *bufio.Writer, event string, data interface{}) {
 	w.Flush()
 }
 
+// Document listing pages: the default and largest page size, and how deep
+// offset paging may go before callers must switch to cursors
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+	maxListOffset   = 10000
+)
+
+// handleListDocuments lists every document, or one page of them when
+// limit, offset or cursor is given. Pages come with an opaque next_cursor,
+// empty on the last page, to pass back as cursor for the following one.
+// fields=title,created_at,... trims each document to those fields.
 func (s *Server) handleListDocuments(c *fiber.Ctx) error {
-	docs, err := db.ListDocuments()
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to list documents"})
+	fields, rerr := parseFields(c, documentFields)
+	if rerr != nil {
+		return rerr.send(c)
 	}
-	return c.JSON(docs)
-}
 
-func (s *Server) handleGetDocument(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	cursor := c.Query("cursor")
+	limit, offset := c.QueryInt("limit"), c.QueryInt("offset")
+	if cursor == "" && limit == 0 && offset == 0 {
+		docs, _, err := db.ListDocuments(tenant(c), db.Page{})
+		if err != nil {
+			return fail(c, errs.Wrap(errs.Internal, "Failed to list documents", err))
+		}
+		out, err := project(docs, fields)
+		if err != nil {
+			return err
+		}
+		return sendCached(c, out)
+	}
+
+	page := db.Page{Limit: limit, Offset: offset}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageSize
+	}
+	if page.Limit > maxPageSize {
+		page.Limit = maxPageSize
+	}
+	if offset < 0 || offset > maxListOffset {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("offset must be between 0 and %d; use cursor to go further", maxListOffset),
+		})
+	}
+	if cursor != "" {
+		after, ok := decodeCursor(cursor)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid cursor"})
+		}
+		page.After = after
 	}
 
-	doc, err := db.GetDocument(id)
+	docs, more, err := db.ListDocuments(tenant(c), page)
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Failed to list documents", err))
+	}
+	if docs == nil {
+		docs = []db.Document{}
+	}
+	next := ""
+	if more {
+		next = encodeCursor(docs[len(docs)-1].ID)
+	}
+	out, err := project(docs, fields)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Document not found"})
+		return err
 	}
+	return sendCached(c, fiber.Map{
+		"documents":   out,
+		"next_cursor": next,
+	})
+}
 
-	return c.JSON(doc)
+// Cursors wrap the last listed id so clients treat them as opaque
+const cursorPrefix = "doc:"
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(id)))
 }
 
-func (s *Server) handleSearch(c *fiber.Ctx) error {
-	query := c.Query("q")
-	if query == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Query required"})
+func decodeCursor(cursor string) (int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	return id, err == nil && id > 0
+}
+
+// fail answers with err's category status and client-safe message,
+// logging server-side failures with their cause
+func fail(c *fiber.Ctx, err error) error {
+	status := errs.Status(err)
+	if status >= 500 {
+		log.Printf("[API] %s %s: %v", c.Method(), c.Path(), err)
 	}
+	return c.Status(status).JSON(fiber.Map{"error": errs.Message(err)})
+}
 
-	limit := c.QueryInt("limit", 10)
-	results, err := db.Search(query, limit)
+// sendCached writes v as JSON with an ETag derived from the encoded body,
+// answering 304 when the client's If-None-Match already has it. Any change
+// to the document changes the body and so the tag.
+func sendCached(c *fiber.Ctx, v interface{}) error {
+	body, err := json.Marshal(v)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Search failed"})
+		return err
 	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// Responses depend on the tenant, so caches must revalidate per tenant
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Vary("X-API-Key", fiber.HeaderAuthorization)
 
-	return c.JSON(results)
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
 }
 
-func (s *Server) handleListSessions(c *fiber.Ctx) error {
-	sessions := s.chatManager.ListSessions()
-	return c.JSON(sessions)
+// etagMatches reports whether an If-None-Match header lists etag, using
+// the weak comparison RFC 9110 prescribes for it
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *Server) handleGetSession(c *fiber.Ctx) error {
-	id := c.Params("id")
-	session := s.chatManager.GetSession(id)
-	if session == nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+type UploadDocumentRequest struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title"`
+	Content  string `json:"content" validate:"required"`
+}
+
+// handleUploadDocument queues the document for insertion and extraction
+// and returns the job straight away; poll /api/jobs/:id for the outcome.
+func (s *Server) handleUploadDocument(c *fiber.Ctx) error {
+	var req UploadDocumentRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
 	}
-	return c.JSON(session)
+	if req.Title == "" {
+		req.Title = req.Filename
+	}
+
+	job, err := s.jobs.Enqueue("extract_document", s.extractDocumentTask(req, tenant(c)))
+	if err != nil {
+		return c.Status(503).JSON(fiber.Map{"error": "Job queue full, retry later"})
+	}
+
+	return c.Status(202).JSON(job)
 }
 
-// ═══════════════════════════════════════════════════════════════════
-// REGEX HANDLERS
-// ═══════════════════════════════════════════════════════════════════
+func (s *Server) extractDocumentTask(req UploadDocumentRequest, owner string) jobs.Task {
+	return func(progress jobs.ProgressFunc) (interface{}, error) {
+		progress(0.1, "inserting")
+		doc, dup, err := db.StoreDocument(req.Filename, req.Title, req.Content, owner)
+		if err != nil {
+			return nil, fmt.Errorf("insert document: %w", err)
+		}
+		if dup != nil && db.Dedup == db.DedupSkip {
+			return fiber.Map{
+				"document_id": doc.ID,
+				"doc_id":      doc.DocID,
+				"skipped":     true,
+				"duplicate":   dup,
+			}, nil
+		}
 
-type TextRequest struct {
-	Text string `json:"text"`
+		progress(0.5, "extracting")
+		matches := s.regexMatcher.FindAll(req.Content)
+		grouped := make(map[regex.Category][]regex.Match)
+		for _, m := range matches {
+			grouped[m.Category] = append(grouped[m.Category], m)
+		}
+
+		result := fiber.Map{
+			"document_id": doc.ID,
+			"doc_id":      doc.DocID,
+			"total":       len(matches),
+			"matches":     grouped,
+		}
+		if dup != nil {
+			result["duplicate"] = dup
+		}
+		return result, nil
+	}
 }
 
-func (s *Server) handleRegexExtract(c *fiber.Ctx) error {
-	var req TextRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+// Regex categories whose matches become graph entities; dates, hashes,
+// code and sensitive values stay out of the graph
+var graphCategories = map[regex.Category]bool{
+	regex.CategoryEntity:        true,
+	regex.CategoryCommunication: true,
+	regex.CategoryNetwork:       true,
+	regex.CategorySocial:        true,
+}
+
+// graphEntities runs the extractor over text and keeps the matches worth
+// a graph node, with normalized names so spellings merge
+func (s *Server) graphEntities(text string) []db.Entity {
+	var entities []db.Entity
+	for _, n := range nlp.FromMatches(s.regexMatcher.FindAll(text)) {
+		if n.Sensitive || !graphCategories[n.Category] {
+			continue
+		}
+		name := n.Value
+		if n.Category != regex.CategoryEntity {
+			name = n.Normalized
+		}
+		entities = append(entities, db.Entity{Name: name, Type: n.Type, Confidence: n.Confidence})
 	}
+	return entities
+}
 
-	if req.Text == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Text required"})
+// handleIngest stores a document and its entity graph in one transaction:
+// the document, its extracted entities and co-occurrence edges between them
+func (s *Server) handleIngest(c *fiber.Ctx) error {
+	var req UploadDocumentRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+	if req.Title == "" {
+		req.Title = req.Filename
 	}
 
-	matches := s.regexMatcher.FindAll(req.Text)
+	result, err := db.IngestDocument(req.Filename, req.Title, req.Content, tenant(c), s.graphEntities(req.Content))
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Ingest failed", err))
+	}
+	return c.Status(201).JSON(result)
+}
 
-	// Group by category
-	grouped := make(map[string][]regex.Match)
-	for _, m := range matches {
-		grouped[m.Category] = append(grouped[m.Category], m)
+// handleListDuplicates lists clusters of exact and near-duplicate
+// documents, a page (limit, then cursor) at a time
+func (s *Server) handleListDuplicates(c *fiber.Ctx) error {
+	page := db.Page{Limit: c.QueryInt("limit")}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageSize
+	}
+	if page.Limit > maxPageSize {
+		page.Limit = maxPageSize
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, ok := decodeCursor(cursor)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid cursor"})
+		}
+		page.After = after
 	}
 
+	clusters, more, err := db.ListDuplicates(tenant(c), page)
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Duplicate scan failed", err))
+	}
+	next := ""
+	if more {
+		next = encodeCursor(clusters[len(clusters)-1].Documents[0].ID)
+	}
 	return c.JSON(fiber.Map{
-		"total":   len(matches),
-		"matches": grouped,
+		"clusters":     clusters,
+		"max_distance": db.NearDuplicateDistance,
+		"next_cursor":  next,
 	})
 }
 
-func (s *Server) handleRegexExtractCategory(c *fiber.Ctx) error {
-	category := c.Params("category")
+func (s *Server) handleGetJob(c *fiber.Ctx) error {
+	job, ok := s.jobs.Get(c.Params("id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "Job not found"})
+	}
+	return c.JSON(job)
+}
 
-	var req TextRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+func (s *Server) handleGetDocument(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	fields, rerr := parseFields(c, documentFields)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	matches := s.regexMatcher.FindByCategory(req.Text, category)
+	doc, err := db.GetDocument(id, tenant(c))
+	if err != nil {
+		return fail(c, err)
+	}
 
-	return c.JSON(fiber.Map{
-		"category": category,
-		"total":    len(matches),
-		"matches":  matches,
-	})
+	out, err := project(doc, fields)
+	if err != nil {
+		return err
+	}
+	return sendCached(c, out)
 }
 
-func (s *Server) handleRegexSensitive(c *fiber.Ctx) error {
-	var req TextRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+// handleDocumentContent serves a document's raw text, honoring a single
+// HTTP byte range so viewers can page through long documents.
+func (s *Server) handleDocumentContent(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
 	}
 
-	matches := s.regexMatcher.FindSensitive(req.Text)
+	doc, err := db.GetDocument(id, tenant(c))
+	if err != nil {
+		return fail(c, err)
+	}
+
+	content := doc.Content
+	size := len(content)
+
+	c.Set("Accept-Ranges", "bytes")
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+
+	rangeHeader := c.Get("Range")
+	if rangeHeader == "" {
+		return c.SendString(content)
+	}
+
+	start, end, ok := parseByteRange(rangeHeader, size)
+	if !ok {
+		c.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return c.Status(416).JSON(fiber.Map{"error": "Range not satisfiable"})
+	}
+
+	c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	return c.Status(206).SendString(content[start : end+1])
+}
+
+// parseByteRange parses a single "bytes=start-end", "bytes=start-" or
+// "bytes=-suffix" range into inclusive offsets within size.
+func parseByteRange(header string, size int) (int, int, bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found || strings.Contains(spec, ",") || size == 0 {
+		return 0, 0, false
+	}
+
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	startStr, endStr = strings.TrimSpace(startStr), strings.TrimSpace(endStr)
+
+	if startStr == "" {
+		// Suffix range: last N bytes
+		n, err := strconv.Atoi(endStr)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.Atoi(endStr)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
+
+// Bounds for "more like this"
+const (
+	similarTerms    = 10
+	maxSimilarLimit = 20
+)
+
+func (s *Server) handleSimilarDocuments(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+
+	limit := c.QueryInt("limit", 5)
+	if limit <= 0 || limit > maxSimilarLimit {
+		limit = maxSimilarLimit
+	}
+
+	doc, err := db.GetDocument(id, tenant(c))
+	if err != nil {
+		return fail(c, err)
+	}
+
+	keywords, _ := nlp.ExtractKeywords(doc.Title+" "+doc.Content, similarTerms, db.DocumentFrequencies(tenant(c)))
+	terms := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		terms = append(terms, k.Word)
+	}
+
+	results, err := db.SimilarDocuments(doc.ID, terms, limit, tenant(c))
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Search failed", err))
+	}
 
 	return c.JSON(fiber.Map{
-		"warning":   "Sensitive data detected!",
-		"total":     len(matches),
-		"matches":   matches,
+		"document_id": doc.ID,
+		"terms":       terms,
+		"results":     results,
 	})
 }
 
-func (s *Server) handleRegexRedact(c *fiber.Ctx) error {
-	var req TextRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+type RedactDocumentRequest struct {
+	Mode    string `json:"mode"`
+	Persist bool   `json:"persist"`
+}
+
+func (s *Server) handleRedactDocument(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
 	}
 
-	redacted := regex.RedactSensitive(req.Text)
-	sensitiveMatches := s.regexMatcher.FindSensitive(req.Text)
+	var req RedactDocumentRequest
+	if len(c.Body()) > 0 {
+		if err := s.bind(c, &req); err != nil {
+			return err.send(c)
+		}
+	}
 
-	return c.JSON(fiber.Map{
-		"original":      req.Text,
-		"redacted":      redacted,
-		"items_redacted": len(sensitiveMatches),
+	mode, ok := regex.ParseRedactionMode(req.Mode)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid mode (full, last4, type)"})
+	}
+
+	doc, err := db.GetDocument(id, tenant(c))
+	if err != nil {
+		return fail(c, err)
+	}
+
+	matches := s.regexMatcher.FindSensitive(doc.Content)
+	redacted, manifest := regex.Redact(doc.Content, matches, mode)
+
+	resp := fiber.Map{
+		"id":       doc.ID,
+		"doc_id":   doc.DocID,
+		"title":    doc.Title,
+		"mode":     mode,
+		"redacted": redacted,
+		"manifest": manifest,
+	}
+
+	if req.Persist {
+		copyDoc, err := db.InsertDocument(doc.Filename+".redacted", doc.Title+" (redacted)", redacted, tenant(c))
+		if err != nil {
+			return fail(c, errs.Wrap(errs.Internal, "Failed to persist redacted copy", err))
+		}
+		resp["redacted_document_id"] = copyDoc.ID
+	}
+
+	return c.JSON(resp)
+}
+
+// handleSearch answers /api/search; fields=title,excerpt,... trims each
+// result to those fields
+func (s *Server) handleSearch(c *fiber.Ctx) error {
+	fields, rerr := parseFields(c, searchFields)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	results, err := runSearch(c)
+	if err != nil {
+		return fail(c, err)
+	}
+	out, err := project(results, fields)
+	if err != nil {
+		return err
+	}
+	return c.JSON(out)
+}
+
+// runSearch runs the search described by the /api/search query params
+// (q, limit, from, to, recent)
+func runSearch(c *fiber.Ctx) ([]db.SearchResult, error) {
+	query, filter, err := searchParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := db.Search(query, c.QueryInt("limit"), filter)
+	if err != nil {
+		return nil, errs.Wrap(errs.Internal, "Search failed", err)
+	}
+	return results, nil
+}
+
+// searchParams reads the query and filter of the /api/search query params
+// (q, from, to, recent)
+func searchParams(c *fiber.Ctx) (string, db.Filter, error) {
+	query := c.Query("q")
+	if query == "" {
+		return "", db.Filter{}, errs.New(errs.Validation, "Query required")
+	}
+
+	filter := db.Filter{Owner: tenant(c)}
+	if err := applyDateRange(&filter, c.Query("from"), c.Query("to"), c.Query("recent")); err != nil {
+		return "", db.Filter{}, errs.New(errs.Validation, err.Error())
+	}
+	return query, filter, nil
+}
+
+type SearchFeedbackRequest struct {
+	Query    string `json:"query" validate:"required"`
+	DocID    string `json:"doc_id" validate:"required"`
+	Relevant *bool  `json:"relevant" validate:"required"`
+}
+
+// handleSearchFeedback records a relevant/irrelevant vote on a result;
+// the RAG scorer boosts or demotes the document on later runs of the
+// same query (see db.FeedbackKey)
+func (s *Server) handleSearchFeedback(c *fiber.Ctx) error {
+	var req SearchFeedbackRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+	if db.FeedbackKey(req.Query) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Query required"})
+	}
+
+	if err := db.RecordFeedback(req.Query, req.DocID, *req.Relevant, tenant(c)); err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Recording feedback failed", err))
+	}
+	return c.Status(201).JSON(fiber.Map{
+		"query_key": db.FeedbackKey(req.Query),
+		"doc_id":    req.DocID,
+		"relevant":  *req.Relevant,
+	})
+}
+
+// Export formats for /api/search/export
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+var exportColumns = []string{
+	"id", "doc_id", "filename", "title", "owner", "created_at", "rank", "matched_terms", "excerpt",
+}
+
+// handleSearchExport runs an /api/search query and streams the results
+// row by row as CSV (with a header) or JSONL, picked by ?format= or else
+// Accept. Unlike /api/search, limit is not capped; without one every match
+// is exported.
+func (s *Server) handleSearchExport(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		switch c.Accepts("application/jsonl", "application/x-ndjson", "text/csv") {
+		case "text/csv":
+			format = exportCSV
+		default:
+			format = exportJSONL
+		}
+	}
+	if format != exportCSV && format != exportJSONL {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be csv or jsonl"})
+	}
+
+	query, filter, err := searchParams(c)
+	if err != nil {
+		return fail(c, err)
+	}
+	limit := c.QueryInt("limit")
+
+	if format == exportCSV {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		c.Set(fiber.HeaderContentType, "application/jsonl; charset=utf-8")
+	}
+	c.Attachment("search." + format)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		out := newSearchExporter(flushWriter{w}, format)
+		err := db.SearchEach(context.Background(), query, limit, filter, out.write)
+		if err == nil {
+			err = out.close()
+		}
+		if err != nil {
+			// The status is sent; all that's left is to cut the export short
+			log.Printf("[API] Search export stopped: %v", err)
+		}
+	})
+	return nil
+}
+
+// searchExporter encodes search results as CSV or JSONL rows
+type searchExporter struct {
+	csv *csv.Writer   // nil for JSONL
+	enc *json.Encoder // nil for CSV
+}
+
+// newSearchExporter starts an export to w, writing the CSV header row
+// straight away so an empty result still has one
+func newSearchExporter(w io.Writer, format string) *searchExporter {
+	if format != exportCSV {
+		return &searchExporter{enc: json.NewEncoder(w)}
+	}
+	e := &searchExporter{csv: csv.NewWriter(w)}
+	e.csv.Write(exportColumns)
+	return e
+}
+
+func (e *searchExporter) write(r db.SearchResult) error {
+	if e.enc != nil {
+		return e.enc.Encode(r)
+	}
+	e.csv.Write([]string{
+		strconv.Itoa(r.ID),
+		csvText(r.DocID),
+		csvText(r.Filename),
+		csvText(r.Title),
+		csvText(r.Owner),
+		r.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatFloat(r.Rank, 'f', -1, 64),
+		csvText(strings.Join(r.MatchedTerms, ";")),
+		csvText(r.Excerpt),
+	})
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// close flushes what's left of the export
+func (e *searchExporter) close() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// csvText defuses a text cell a spreadsheet would run as a formula, one
+// starting with = + - @ (or a tab or carriage return), by prefixing a quote
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// flushWriter pushes every write through to the client, so an export
+// reaches it as it is produced rather than when the buffer fills
+type flushWriter struct {
+	w *bufio.Writer
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		err = f.w.Flush()
+	}
+	return n, err
+}
+
+// applyDateRange sets the filter's created_at bounds from request values.
+// from/to accept YYYY-MM-DD (to is inclusive of that day) or RFC3339;
+// recent is a lookback like "30d", "2w", "6mo", "1y" or a Go duration, and
+// can't be combined with from.
+func applyDateRange(f *db.Filter, from, to, recent string) error {
+	if recent != "" {
+		if from != "" {
+			return fmt.Errorf("use either recent or from, not both")
+		}
+		since, err := parseLookback(recent)
+		if err != nil {
+			return err
+		}
+		f.From = clock.Now().Add(-since)
+	}
+
+	if from != "" {
+		t, _, err := parseDate(from)
+		if err != nil {
+			return fmt.Errorf("invalid from date %q", from)
+		}
+		f.From = t
+	}
+	if to != "" {
+		t, dateOnly, err := parseDate(to)
+		if err != nil {
+			return fmt.Errorf("invalid to date %q", to)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		f.To = t
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	return nil
+}
+
+// parseDate accepts YYYY-MM-DD or RFC3339, reporting which it was
+func parseDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t.UTC(), false, err
+}
+
+// parseLookback parses "30d", "2w", "6mo", "1y" or a Go duration like
+// "36h". A month is 30 days and a year 365. A bare "6m" is refused rather
+// than guessed: it reads as months here but minutes to time.ParseDuration.
+func parseLookback(s string) (time.Duration, error) {
+	day := 24 * time.Hour
+	units := map[string]time.Duration{"d": day, "w": 7 * day, "mo": 30 * day, "y": 365 * day}
+
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := s[len(num):]
+	if _, err := strconv.Atoi(num); err == nil && unit == "m" {
+		return 0, fmt.Errorf("ambiguous recent value %q: use %smo for months or %sm0s for minutes", s, num, num)
+	}
+
+	var d time.Duration
+	if mult, ok := units[unit]; ok {
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid recent value %q", s)
+		}
+		d = time.Duration(n) * mult
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid recent value %q", s)
+		}
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("recent must be positive")
+	}
+	return d, nil
+}
+
+// Bounds for bulk search
+const (
+	maxBulkQueries = 50
+	bulkWorkers    = 4
+)
+
+type BulkSearchRequest struct {
+	Queries []string `json:"queries" validate:"required"`
+	Limit   int      `json:"limit,omitempty"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Recent  string   `json:"recent,omitempty"`
+}
+
+type BulkSearchResult struct {
+	Query   string            `json:"query"`
+	Results []db.SearchResult `json:"results"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// handleBulkSearch runs several searches concurrently and returns one
+// entry per query, in request order
+func (s *Server) handleBulkSearch(c *fiber.Ctx) error {
+	var req BulkSearchRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+	if len(req.Queries) > maxBulkQueries {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many queries (max %d)", maxBulkQueries),
+		})
+	}
+	req.Limit = db.ClampLimit(req.Limit)
+
+	filter := db.Filter{Owner: tenant(c)}
+	if err := applyDateRange(&filter, req.From, req.To, req.Recent); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	out := make([]BulkSearchResult, len(req.Queries))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < bulkWorkers && w < len(req.Queries); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				q := strings.TrimSpace(req.Queries[i])
+				out[i] = BulkSearchResult{Query: q, Results: []db.SearchResult{}}
+				if q == "" {
+					out[i].Error = "Query required"
+					continue
+				}
+				results, err := db.Search(q, req.Limit, filter)
+				if err != nil {
+					log.Printf("[API] Bulk search %q failed: %v", q, err)
+					out[i].Error = "Search failed"
+					continue
+				}
+				if results != nil {
+					out[i].Results = results
+				}
+			}
+		}()
+	}
+	for i := range req.Queries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return c.JSON(fiber.Map{"results": out})
+}
+
+type ResolveEntityRequest struct {
+	Value string `json:"value" validate:"required"`
+	Type  string `json:"type"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// handleResolveEntity merges the graph entities matching a value, the
+// documents that mention it and the edges around it into one profile
+func (s *Server) handleResolveEntity(c *fiber.Ctx) error {
+	var req ResolveEntityRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+
+	value := regex.Normalize(req.Type, req.Value)
+	if value == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Value required"})
+	}
+	if req.Limit <= 0 || req.Limit > 50 {
+		req.Limit = 20
+	}
+
+	entities, err := db.FindEntities(value, req.Type)
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Entity lookup failed", err))
+	}
+
+	ids := make([]int, 0, len(entities))
+	matched := make(map[int]bool, len(entities))
+	for _, e := range entities {
+		ids = append(ids, e.ID)
+		matched[e.ID] = true
+	}
+
+	edges, err := db.EntityEdges(ids)
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Edge lookup failed", err))
+	}
+
+	var neighborIDs []int
+	for _, e := range edges {
+		for _, id := range []int{e.FromEntityID, e.ToEntityID} {
+			if !matched[id] {
+				matched[id] = true
+				neighborIDs = append(neighborIDs, id)
+			}
+		}
+	}
+	connected, err := db.GetEntities(neighborIDs)
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Entity lookup failed", err))
+	}
+
+	documents, err := db.Search(value, req.Limit, db.Filter{Owner: tenant(c)})
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Search failed", err))
+	}
+
+	if entities == nil {
+		entities = []db.Entity{}
+	}
+	if edges == nil {
+		edges = []db.Edge{}
+	}
+	if connected == nil {
+		connected = []db.Entity{}
+	}
+	if documents == nil {
+		documents = []db.SearchResult{}
+	}
+
+	return c.JSON(fiber.Map{
+		"value":      req.Value,
+		"type":       req.Type,
+		"normalized": value,
+		"entities":   entities,
+		"connected":  connected,
+		"edges":      edges,
+		"documents":  documents,
+	})
+}
+
+// How far (bytes) from a mention a date may sit and still date it, and
+// how much context a timeline snippet shows on either side
+const (
+	timelineDateWindow = 300
+	timelineSnippet    = 100
+)
+
+// TimelineEntry is one dated mention of an entity
+type TimelineEntry struct {
+	Date       string `json:"date"` // YYYY-MM-DD
+	DateText   string `json:"date_text"`
+	DocumentID int    `json:"document_id"`
+	Title      string `json:"title"`
+	Snippet    string `json:"snippet"`
+
+	at time.Time
+}
+
+// handleEntityTimeline lists, oldest first, the dates found near each
+// mention of a graph entity across the documents that mention it.
+// Documents with mentions but no usable date are listed as undated.
+func (s *Server) handleEntityTimeline(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid entity ID"})
+	}
+
+	entities, err := db.GetEntities([]int{id})
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Entity lookup failed", err))
+	}
+	if len(entities) == 0 {
+		return fail(c, errs.New(errs.NotFound, "Entity not found"))
+	}
+	entity := entities[0]
+
+	documents, err := db.Search(entity.Name, db.MaxLimit, db.Filter{Owner: tenant(c)})
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Search failed", err))
+	}
+
+	entries := []TimelineEntry{}
+	undated := []fiber.Map{}
+	for _, doc := range documents {
+		found, mentioned := s.timelineEntries(doc.Document, entity.Name)
+		if !mentioned {
+			continue
+		}
+		if len(found) == 0 {
+			undated = append(undated, fiber.Map{"document_id": doc.ID, "title": doc.Title})
+			continue
+		}
+		entries = append(entries, found...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].at.Equal(entries[j].at) {
+			return entries[i].at.Before(entries[j].at)
+		}
+		return entries[i].DocumentID < entries[j].DocumentID
+	})
+
+	return c.JSON(fiber.Map{
+		"entity":   entity,
+		"timeline": entries,
+		"undated":  undated,
+	})
+}
+
+// timelineEntries dates each case-insensitive mention of name in doc by
+// the nearest parseable date within timelineDateWindow. The bool reports
+// whether the document mentions name at all.
+func (s *Server) timelineEntries(doc db.Document, name string) ([]TimelineEntry, bool) {
+	if name == "" {
+		return nil, false
+	}
+	lower, needle := strings.ToLower(doc.Content), strings.ToLower(name)
+	if len(lower) != len(doc.Content) || len(needle) != len(name) {
+		// Lowercasing changed byte offsets; fall back to an exact search
+		lower, needle = doc.Content, name
+	}
+
+	var mentions []int
+	for from := 0; ; {
+		i := strings.Index(lower[from:], needle)
+		if i < 0 {
+			break
+		}
+		mentions = append(mentions, from+i)
+		from += i + len(needle)
+	}
+	if len(mentions) == 0 {
+		return nil, false
+	}
+
+	type dated struct {
+		at time.Time
+		m  regex.Match
+	}
+	var dates []dated
+	for _, m := range s.regexMatcher.FindByCategory(doc.Content, regex.CategoryTemporal) {
+		if at, ok := regex.ParseDate(m.Pattern, m.Value); ok {
+			dates = append(dates, dated{at, m})
+		}
+	}
+
+	var entries []TimelineEntry
+	seen := make(map[string]bool)
+	for _, pos := range mentions {
+		best, bestDist := -1, timelineDateWindow+1
+		for i, d := range dates {
+			dist := d.m.Start - (pos + len(needle))
+			if d.m.End <= pos {
+				dist = pos - d.m.End
+			} else if d.m.Start < pos+len(needle) {
+				dist = 0
+			}
+			if dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		d := dates[best]
+		day := d.at.Format("2006-01-02")
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		entries = append(entries, TimelineEntry{
+			Date:       day,
+			DateText:   d.m.Value,
+			DocumentID: doc.ID,
+			Title:      doc.Title,
+			Snippet:    snippetAround(doc.Content, pos, pos+len(needle), timelineSnippet),
+			at:         d.at,
+		})
+	}
+	return entries, true
+}
+
+// snippetAround returns text[start:end] with up to pad bytes of context on
+// each side, widened to rune boundaries and whitespace-folded
+func snippetAround(text string, start, end, pad int) string {
+	lo, hi := start-pad, end+pad
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > len(text) {
+		hi = len(text)
+	}
+	for lo > 0 && !utf8.RuneStart(text[lo]) {
+		lo--
+	}
+	for hi < len(text) && !utf8.RuneStart(text[hi]) {
+		hi++
+	}
+
+	snippet := strings.Join(strings.Fields(text[lo:hi]), " ")
+	if lo > 0 {
+		snippet = "…" + snippet
+	}
+	if hi < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func (s *Server) handleListSessions(c *fiber.Ctx) error {
+	sessions := s.chatManager.ListSessions(tenant(c))
+	return c.JSON(sessions)
+}
+
+func (s *Server) handleGetSession(c *fiber.Ctx) error {
+	id := c.Params("id")
+	session := s.chatManager.GetSession(id, tenant(c))
+	if session == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+	}
+	return c.JSON(session)
+}
+
+// handleExportSession downloads a session as Markdown (format=md, the
+// default) or JSON (format=json)
+func (s *Server) handleExportSession(c *fiber.Ctx) error {
+	id := c.Params("id")
+	session := s.chatManager.GetSession(id, tenant(c))
+	if session == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+	}
+
+	var write func(io.Writer) error
+	switch format := c.Query("format", "md"); format {
+	case "md", "markdown":
+		c.Attachment("session-" + id + ".md")
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		write = session.WriteMarkdown
+	case "json":
+		c.Attachment("session-" + id + ".json")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		write = session.WriteJSON
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "format must be md or json"})
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := write(flushWriter{w}); err != nil {
+			log.Printf("[API] Session %s export stopped: %v", id, err)
+		}
+	})
+	return nil
+}
+
+func (s *Server) handleClearSession(c *fiber.Ctx) error {
+	id := c.Params("id")
+	session := s.chatManager.ClearSession(id, tenant(c))
+	if session == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+	}
+	return c.JSON(session)
+}
+
+// ═══════════════════════════════════════════════════════════════════
+// ENTITY EXTRACTION
+// ═══════════════════════════════════════════════════════════════════
+
+// handleExtract is the entry point for entity extraction: it runs the
+// matcher (with calibrated confidences) and returns canonical entities,
+// typed in the nlp.Type* taxonomy, normalized and merged per value
+func (s *Server) handleExtract(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	entities := nlp.Canonicalize(nlp.FromMatches(s.regexMatcher.FindAll(req.Text)))
+	types := make(map[string]int)
+	for _, e := range entities {
+		types[e.Type]++
+	}
+
+	return c.JSON(fiber.Map{
+		"total":    len(entities),
+		"types":    types,
+		"entities": entities,
+	})
+}
+
+// ═══════════════════════════════════════════════════════════════════
+// REGEX HANDLERS
+// ═══════════════════════════════════════════════════════════════════
+
+type TextRequest struct {
+	Text          string `json:"text" validate:"required"`
+	MaxPerPattern int    `json:"max_per_pattern,omitempty"` // extract only; overrides REGEX_MAX_PER_PATTERN
+	Mode          string `json:"mode,omitempty"`            // analyze only; redaction mode, default full
+}
+
+// parseTextRequest decodes and validates a TextRequest; a non-nil error
+// is the rejection to send
+func (s *Server) parseTextRequest(c *fiber.Ctx) (TextRequest, *requestError) {
+	var req TextRequest
+	if err := s.bind(c, &req); err != nil {
+		return req, err
+	}
+
+	if max := s.config.Regex.MaxTextLength; max > 0 && len(req.Text) > max {
+		return req, &requestError{status: 413, msg: fmt.Sprintf("Text too large (max %d bytes)", max)}
+	}
+
+	if binary, contentType := regex.SniffBinary(req.Text); binary && s.binaryInput != regex.BinaryAllow {
+		if s.binaryInput == regex.BinaryReject {
+			return req, &requestError{status: 415, msg: fmt.Sprintf("Binary input not supported (detected %s)", contentType)}
+		}
+		req.Text = regex.PrintableText(req.Text)
+		if strings.TrimSpace(req.Text) == "" {
+			return req, &requestError{status: 415, msg: fmt.Sprintf("No text found in binary input (detected %s)", contentType)}
+		}
+		c.Set("X-Binary-Input", contentType)
+	}
+
+	return req, nil
+}
+
+func (s *Server) handleRegexExtract(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	maxPerPattern := req.MaxPerPattern
+	if maxPerPattern <= 0 {
+		maxPerPattern = s.config.Regex.MaxPerPattern
+	}
+	format := c.Query("format", "flat")
+	if format != "flat" && format != "graph" {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be flat or graph"})
+	}
+	matches, totals := s.regexMatcher.FindAllLimited(req.Text, maxPerPattern)
+
+	total := 0
+	for _, n := range totals {
+		total += n
+	}
+
+	if format == "graph" {
+		nodes, edges := matchGraph(matches)
+		return c.JSON(fiber.Map{
+			"total":     total,
+			"returned":  len(matches),
+			"truncated": len(matches) < total,
+			"nodes":     nodes,
+			"edges":     edges,
+		})
+	}
+
+	// Group by category
+	grouped := make(map[regex.Category][]regex.Match)
+	for _, m := range matches {
+		grouped[m.Category] = append(grouped[m.Category], m)
+	}
+
+	return c.JSON(fiber.Map{
+		"total":     total,
+		"returned":  len(matches),
+		"truncated": len(matches) < total,
+		"totals":    totals,
+		"matches":   grouped,
+	})
+}
+
+// GraphNode is one distinct extracted value in format=graph output
+type GraphNode struct {
+	ID         string         `json:"id"` // type:normalized, stable across calls so fragments merge
+	Type       string         `json:"type"`
+	Category   regex.Category `json:"category"`
+	Value      string         `json:"value"` // first spelling in the text
+	Normalized string         `json:"normalized"`
+	Confidence float64        `json:"confidence"` // highest among its matches
+	Count      int            `json:"count"`
+	Sensitive  bool           `json:"sensitive,omitempty"`
+}
+
+// GraphEdge links two GraphNodes by ID
+type GraphEdge struct {
+	Source       string  `json:"source"`
+	Target       string  `json:"target"`
+	Relationship string  `json:"relationship"`
+	Weight       float64 `json:"weight"`
+}
+
+// matchGraph shapes matches as graph fragments: one node per normalized
+// value and type, in text order, linked by the co_occurs edges ingest
+// would create between them
+func matchGraph(matches []regex.Match) ([]GraphNode, []GraphEdge) {
+	sorted := append([]regex.Match(nil), matches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	nodes := []GraphNode{}
+	index := make(map[string]int)
+	for _, n := range nlp.FromMatches(sorted) {
+		id := n.Type + ":" + n.Normalized
+		if i, ok := index[id]; ok {
+			nodes[i].Count++
+			if n.Confidence > nodes[i].Confidence {
+				nodes[i].Confidence = n.Confidence
+			}
+			continue
+		}
+		index[id] = len(nodes)
+		nodes = append(nodes, GraphNode{
+			ID:         id,
+			Type:       n.Type,
+			Category:   n.Category,
+			Value:      n.Value,
+			Normalized: n.Normalized,
+			Confidence: n.Confidence,
+			Count:      1,
+			Sensitive:  n.Sensitive,
+		})
+	}
+
+	edges := []GraphEdge{}
+	for _, p := range db.CooccurrencePairs(len(nodes)) {
+		edges = append(edges, GraphEdge{
+			Source:       nodes[p[0]].ID,
+			Target:       nodes[p[1]].ID,
+			Relationship: db.RelCooccurs,
+			Weight:       1,
+		})
+	}
+	return nodes, edges
+}
+
+// handleRegexExtractCategory runs one category's patterns; an unknown
+// category is a 400 naming the valid ones rather than an empty result
+func (s *Server) handleRegexExtractCategory(c *fiber.Ctx) error {
+	category, ok := regex.ParseCategory(c.Params("category"))
+	if !ok {
+		valid := make([]string, len(regex.Categories))
+		for i, cat := range regex.Categories {
+			valid[i] = string(cat)
+		}
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Unknown category %q; valid categories: %s", c.Params("category"), strings.Join(valid, ", ")),
+		})
+	}
+
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	matches := s.regexMatcher.FindByCategory(req.Text, category)
+
+	return c.JSON(fiber.Map{
+		"category": category,
+		"total":    len(matches),
+		"matches":  matches,
+	})
+}
+
+func (s *Server) handleRegexSensitive(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	matches := s.regexMatcher.FindSensitive(req.Text)
+
+	return c.JSON(fiber.Map{
+		"warning":   "Sensitive data detected!",
+		"total":     len(matches),
+		"matches":   matches,
+	})
+}
+
+// handleRegexHasSensitive is the yes/no check for blocking pastes: it
+// stops at the first sensitive match
+func (s *Server) handleRegexHasSensitive(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	found, pattern := s.regexMatcher.HasSensitive(req.Text)
+	return c.JSON(fiber.Map{
+		"sensitive": found,
+		"pattern":   pattern,
+	})
+}
+
+func (s *Server) handleRegexRedact(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	redacted := regex.RedactSensitive(req.Text)
+	sensitiveMatches := s.regexMatcher.FindSensitive(req.Text)
+
+	return c.JSON(fiber.Map{
+		"original":      req.Text,
+		"redacted":      redacted,
+		"items_redacted": len(sensitiveMatches),
+	})
+}
+
+// handleRegexAnalyze previews a document before sharing: the matches by
+// category, the sensitive ones and the text with them redacted, all from
+// a single scan
+func (s *Server) handleRegexAnalyze(c *fiber.Ctx) error {
+	req, rerr := s.parseTextRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	mode, ok := regex.ParseRedactionMode(req.Mode)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid mode (full, last4, type)"})
+	}
+
+	return c.JSON(s.regexMatcher.Analyze(req.Text, mode))
+}
+
+func (s *Server) handleRegexMetrics(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"enabled":  s.regexMatcher.MetricsEnabled(),
+		"patterns": s.regexMatcher.Metrics(),
+	})
+}
+
+// handleRegexPatterns lists the effective built-in pattern set, after
+// REGEX_PATTERNS_FILE, plus the names disabled there
+func (s *Server) handleRegexPatterns(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"patterns": s.regexMatcher.Patterns(),
+		"disabled": regex.DisabledPatterns(),
+	})
+}
+
+// Cap on labeled samples per calibration run
+const maxCalibrationSamples = 1000
+
+type CalibrateRequest struct {
+	Samples []regex.LabeledSample `json:"samples" validate:"required"`
+}
+
+// handleRegexCalibrate measures per-pattern precision on labeled samples
+// and returns adjusted confidences; persist "overrides" and point
+// REGEX_CONFIDENCE_FILE at it to apply them
+func (s *Server) handleRegexCalibrate(c *fiber.Ctx) error {
+	var req CalibrateRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+	if len(req.Samples) > maxCalibrationSamples {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Too many samples (max %d)", maxCalibrationSamples),
+		})
+	}
+	for _, sample := range req.Samples {
+		if max := s.config.Regex.MaxTextLength; max > 0 && len(sample.Text) > max {
+			return c.Status(413).JSON(fiber.Map{
+				"error": fmt.Sprintf("Sample too large (max %d bytes)", max),
+			})
+		}
+	}
+
+	return c.JSON(s.regexMatcher.Calibrate(req.Samples))
+}
+
+// AggregatedMatch is one distinct value found by a streamed extraction
+type AggregatedMatch struct {
+	Pattern     string         `json:"pattern"`
+	Category    regex.Category `json:"category"`
+	Value       string         `json:"value"`
+	Count       int            `json:"count"`
+	FirstOffset int            `json:"first_offset"`
+	Confidence  float64        `json:"confidence"`
+	Sensitive   bool           `json:"sensitive"`
+}
+
+// Cap on distinct values kept by a streamed extraction
+const maxStreamMatches = 100000
+
+// handleRegexExtractStream extracts from an uploaded file ("file" field)
+// chunk by chunk, returning matches aggregated by pattern and value
+func (s *Server) handleRegexExtractStream(c *fiber.Ctx) error {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "File required"})
+	}
+	if max := s.config.Regex.MaxUploadSize; max > 0 && header.Size > int64(max) {
+		return c.Status(413).JSON(fiber.Map{
+			"error": fmt.Sprintf("File too large (max %d bytes)", max),
+		})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Could not read upload", err))
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Could not read upload", err))
+	}
+	if binary, contentType := regex.SniffBinary(string(head[:n])); binary && s.binaryInput != regex.BinaryAllow {
+		if s.binaryInput == regex.BinaryReject {
+			return c.Status(415).JSON(fiber.Map{
+				"error": fmt.Sprintf("Binary input not supported (detected %s)", contentType),
+			})
+		}
+		input = regex.NewPrintableReader(file)
+		c.Set("X-Binary-Input", contentType)
+	}
+
+	start := time.Now()
+	index := make(map[string]int)
+	var matches []AggregatedMatch
+	truncated := false
+
+	processed, err := s.regexMatcher.FindAllReader(input, func(m regex.Match) {
+		key := m.Pattern + "\x00" + m.Value
+		if i, ok := index[key]; ok {
+			matches[i].Count++
+			return
+		}
+		if len(matches) >= maxStreamMatches {
+			truncated = true
+			return
+		}
+		index[key] = len(matches)
+		matches = append(matches, AggregatedMatch{
+			Pattern:     m.Pattern,
+			Category:    m.Category,
+			Value:       m.Value,
+			Count:       1,
+			FirstOffset: m.Start,
+			Confidence:  m.Confidence,
+			Sensitive:   m.Sensitive,
+		})
+	})
+	if err != nil {
+		return fail(c, errs.Wrap(errs.Internal, "Extraction failed", fmt.Errorf("after %d bytes: %w", processed, err)))
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].FirstOffset < matches[j].FirstOffset
+	})
+	if matches == nil {
+		matches = []AggregatedMatch{}
+	}
+
+	return c.JSON(fiber.Map{
+		"filename":        header.Filename,
+		"bytes_processed": processed,
+		"elapsed_ms":      time.Since(start).Milliseconds(),
+		"distinct":        len(matches),
+		"truncated":       truncated,
+		"matches":         matches,
+	})
+}
+
+type RegexTestRequest struct {
+	Pattern string `json:"pattern"`
+	Text    string `json:"text"`
+	Flags   string `json:"flags"`
+}
+
+func (s *Server) handleRegexTest(c *fiber.Ctx) error {
+	var req RegexTestRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+
+	if max := s.config.Regex.MaxTextLength; max > 0 && len(req.Text) > max {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("Text too large (max %d bytes)", max)})
+	}
+
+	re, err := regex.CompileUserPattern(req.Pattern, req.Flags)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Regex.UserTimeout)
+	defer cancel()
+
+	matches, truncated, err := regex.TestPatternContext(ctx, re, req.Text)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"pattern":   re.String(),
+		"total":     len(matches),
+		"truncated": truncated,
+		"matches":   matches,
+	})
+}
+
+// ═══════════════════════════════════════════════════════════════════
+// KEYWORD HANDLERS
+// ═══════════════════════════════════════════════════════════════════
+
+type KeywordsRequest struct {
+	Text  string `json:"text" validate:"required"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+func (s *Server) handleKeywords(c *fiber.Ctx) error {
+	var req KeywordsRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+
+	keywords, scoring := nlp.ExtractKeywords(req.Text, req.Limit, db.DocumentFrequencies(tenant(c)))
+
+	return c.JSON(fiber.Map{
+		"scoring":  scoring,
+		"total":    len(keywords),
+		"keywords": keywords,
+	})
+}
+
+// ═══════════════════════════════════════════════════════════════════
+// TEXT HANDLERS
+// ═══════════════════════════════════════════════════════════════════
+
+type NormalizeRequest struct {
+	Text       string   `json:"text"`
+	Transforms []string `json:"transforms" validate:"required"`
+}
+
+func (s *Server) handleTextNormalize(c *fiber.Ctx) error {
+	var req NormalizeRequest
+	if err := s.bind(c, &req); err != nil {
+		return err.send(c)
+	}
+
+	if max := s.config.Regex.MaxTextLength; max > 0 && len(req.Text) > max {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("Text too large (max %d bytes)", max)})
+	}
+
+	// Validate every name before applying anything
+	var unknown []string
+	for _, name := range req.Transforms {
+		if _, ok := regex.Transforms[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		available := make([]string, 0, len(regex.Transforms))
+		for name := range regex.Transforms {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		return c.Status(400).JSON(fiber.Map{
+			"error":     "Unknown transforms",
+			"unknown":   unknown,
+			"available": available,
+		})
+	}
+
+	text := req.Text
+	for _, name := range req.Transforms {
+		text = regex.Transforms[name](text)
+	}
+
+	return c.JSON(fiber.Map{
+		"text":       text,
+		"transforms": req.Transforms,
 	})
 }
 
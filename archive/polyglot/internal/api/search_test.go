@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/config"
+	"hybridcore/internal/db"
+	"hybridcore/internal/regex"
+)
+
+func TestBulkSearchTooManyQueries(t *testing.T) {
+	cfg := config.Load()
+	cfg.Server.SingleTenant = true
+	s := NewServer(cfg, nil, nil, regex.NewMatcher())
+	t.Cleanup(s.jobs.Stop)
+
+	queries := make([]string, maxBulkQueries+1)
+	for i := range queries {
+		queries[i] = fmt.Sprintf("term%d", i)
+	}
+	body, _ := json.Marshal(BulkSearchRequest{Queries: queries})
+	status, resp := postJSON(t, s.app, "/api/search/bulk", string(body))
+	if status != 400 || !strings.Contains(fmt.Sprint(resp["error"]), "max 50") {
+		t.Errorf("%d queries: %d %v", len(queries), status, resp)
+	}
+}
+
+func TestBulkSearch(t *testing.T) {
+	s := testServer(t)
+	words := []string{"zebra", "walrus", "alpaca", "pelican", "narwhal", "lemur"}
+	docs := map[string]int{}
+	for _, w := range words {
+		docs[w] = insertDoc(t, w+".txt", "Field notes on the "+w+" sighting.").ID
+	}
+
+	// More queries than workers, with a blank and a miss in the middle
+	queries := append([]string{}, words[:3]...)
+	queries = append(queries, "  ", "okapi")
+	queries = append(queries, words[3:]...)
+
+	body, _ := json.Marshal(BulkSearchRequest{Queries: queries})
+	req := httptest.NewRequest("POST", "/api/search/bulk", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Results []struct {
+			Query   string            `json:"query"`
+			Results []json.RawMessage `json:"results"`
+			Error   string            `json:"error"`
+		} `json:"results"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != 200 || len(out.Results) != len(queries) {
+		t.Fatalf("bulk: %d, %d results for %d queries", resp.StatusCode, len(out.Results), len(queries))
+	}
+
+	for i, r := range out.Results {
+		want := strings.TrimSpace(queries[i])
+		if r.Query != want {
+			t.Errorf("results[%d].query = %q, want %q", i, r.Query, want)
+		}
+		if r.Results == nil {
+			t.Errorf("results[%d].results is null, want an array", i)
+		}
+		switch id, seeded := docs[want]; {
+		case want == "":
+			if r.Error != "Query required" || len(r.Results) != 0 {
+				t.Errorf("blank query: %+v", r)
+			}
+		case !seeded:
+			if r.Error != "" || len(r.Results) != 0 {
+				t.Errorf("%q: %+v, want no results", want, r)
+			}
+		default:
+			var hit struct{ ID int }
+			if len(r.Results) != 1 || json.Unmarshal(r.Results[0], &hit) != nil || hit.ID != id {
+				t.Errorf("%q: %d results, want document %d", want, len(r.Results), id)
+			}
+		}
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	s := testServer(t)
+	oldDef, oldMax := db.DefaultLimit, db.MaxLimit
+	db.DefaultLimit, db.MaxLimit = 2, 3
+	t.Cleanup(func() { db.DefaultLimit, db.MaxLimit = oldDef, oldMax })
+	for i := 0; i < 5; i++ {
+		insertDoc(t, fmt.Sprintf("ledger%d.txt", i), "Entry in the ledger.")
+	}
+
+	search := func(query string) int {
+		t.Helper()
+		resp, err := s.app.Test(httptest.NewRequest("GET", "/api/search?q=ledger"+query, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []json.RawMessage
+		json.NewDecoder(resp.Body).Decode(&results)
+		return len(results)
+	}
+	bulk := func(limit int) int {
+		t.Helper()
+		body, _ := json.Marshal(BulkSearchRequest{Queries: []string{"ledger"}, Limit: limit})
+		req := httptest.NewRequest("POST", "/api/search/bulk", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct {
+			Results []BulkSearchResult `json:"results"`
+		}
+		json.NewDecoder(resp.Body).Decode(&out)
+		if len(out.Results) != 1 {
+			t.Fatalf("bulk: %d entries", len(out.Results))
+		}
+		return len(out.Results[0].Results)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		got, want int
+	}{
+		{"search default", search(""), 2},
+		{"search within max", search("&limit=3"), 3},
+		{"search over max", search("&limit=50"), 3},
+		{"bulk default", bulk(0), 2},
+		{"bulk over max", bulk(50), 3},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: %d results, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/config"
+)
+
+func normalizeApp() *fiber.App {
+	s := &Server{config: &config.Config{}}
+	app := fiber.New()
+	app.Post("/api/text/normalize", s.handleTextNormalize)
+	return app
+}
+
+// Transforms run in the order given: stripping tags before folding
+// whitespace leaves no trace of them, the other way round leaves the
+// padding they enclosed
+func TestTextNormalizeAppliesTransformsInOrder(t *testing.T) {
+	app := normalizeApp()
+	text := `"<p>\n  Quarterly   report </p>\n<b>Q3</b>"`
+
+	cases := []struct {
+		transforms string
+		want       string
+	}{
+		{`["strip_html","normalize_whitespace"]`, "Quarterly report Q3"},
+		{`["normalize_whitespace","strip_html"]`, " Quarterly report  Q3"},
+		{`["strip_html","remove_punctuation"]`, "\n  Quarterly   report \nQ3"},
+		{`["remove_punctuation","strip_html"]`, "p\n  Quarterly   report p\nbQ3b"},
+	}
+	for _, tc := range cases {
+		status, out := postJSON(t, app, "/api/text/normalize", `{"text":`+text+`,"transforms":`+tc.transforms+`}`)
+		if status != 200 {
+			t.Fatalf("%s: status %d: %v", tc.transforms, status, out)
+		}
+		if out["text"] != tc.want {
+			t.Errorf("%s: text = %q, want %q", tc.transforms, out["text"], tc.want)
+		}
+	}
+}
+
+func TestTextNormalizeRejectsUnknownTransforms(t *testing.T) {
+	status, out := postJSON(t, normalizeApp(), "/api/text/normalize",
+		`{"text":"<b>x</b>","transforms":["strip_html","lowercase","trim"]}`)
+	if status != 400 {
+		t.Fatalf("status %d, want 400", status)
+	}
+	if got := out["unknown"].([]any); len(got) != 2 || got[0] != "lowercase" || got[1] != "trim" {
+		t.Errorf("unknown = %v", got)
+	}
+	var available []string
+	for _, name := range out["available"].([]any) {
+		available = append(available, name.(string))
+	}
+	if !strings.Contains(strings.Join(available, ","), "strip_html") {
+		t.Errorf("available = %v", available)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/regex"
+)
+
+func TestTimelineEntries(t *testing.T) {
+	s := &Server{regexMatcher: regex.NewMatcher()}
+	doc := db.Document{ID: 7, Title: "Notes", Content: "On 12 May 2020 Orion Ltd opened an account. " +
+		"Nothing happened for a while. Then on 03/09/2021 orion ltd closed it, and ORION LTD again on 03/09/2021."}
+
+	entries, mentioned := s.timelineEntries(doc, "Orion Ltd")
+	if !mentioned {
+		t.Fatal("mentions not found")
+	}
+	// The third mention shares the second's date, so it is folded in
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].Date != "2020-05-12" || entries[0].DateText != "12 May 2020" || entries[1].Date != "2021-09-03" {
+		t.Errorf("dates = %s (%s), %s", entries[0].Date, entries[0].DateText, entries[1].Date)
+	}
+	if entries[0].DocumentID != 7 || entries[0].Title != "Notes" || entries[0].Snippet == "" {
+		t.Errorf("entry = %+v", entries[0])
+	}
+
+	if entries, mentioned := s.timelineEntries(db.Document{Content: "Orion Ltd, undated."}, "Orion Ltd"); !mentioned || len(entries) != 0 {
+		t.Errorf("undated document: %v, %+v", mentioned, entries)
+	}
+	if _, mentioned := s.timelineEntries(db.Document{Content: "On 12 May 2020 nothing."}, "Orion Ltd"); mentioned {
+		t.Error("document without the entity reported as mentioning it")
+	}
+}
+
+func TestEntityTimeline(t *testing.T) {
+	s := testServer(t)
+	entities, err := db.UpsertEntities([]db.Entity{{Name: "Orion Ltd", Type: "organization", Confidence: 0.9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inserted out of order, with one undated mention and one date-only doc
+	late := insertDoc(t, "late.txt", "Orion Ltd was dissolved on 2 February 2023.")
+	early := insertDoc(t, "early.txt", "Orion Ltd was incorporated on 14/03/2019.")
+	middle := insertDoc(t, "middle.txt", "On 1 July 2021 Orion Ltd moved offices.")
+	undated := insertDoc(t, "undated.txt", "Orion Ltd is mentioned here without a date.")
+	insertDoc(t, "other.txt", "Something else entirely happened on 1 January 2020.")
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", fmt.Sprintf("/api/entities/%d/timeline", entities[0].ID), nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Timeline []TimelineEntry `json:"timeline"`
+		Undated  []struct {
+			DocumentID int `json:"document_id"`
+		} `json:"undated"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("timeline: %d", resp.StatusCode)
+	}
+
+	var got []string
+	for _, e := range body.Timeline {
+		got = append(got, fmt.Sprintf("%s@%d", e.Date, e.DocumentID))
+	}
+	want := []string{
+		fmt.Sprintf("2019-03-14@%d", early.ID),
+		fmt.Sprintf("2021-07-01@%d", middle.ID),
+		fmt.Sprintf("2023-02-02@%d", late.ID),
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("timeline = %v, want %v", got, want)
+	}
+	if len(body.Undated) != 1 || body.Undated[0].DocumentID != undated.ID {
+		t.Errorf("undated = %+v, want document %d", body.Undated, undated.ID)
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FieldError is one rejected field of a request body
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// requestError is a rejected request: the status, the message, and for a
+// body that failed validation the fields at fault
+type requestError struct {
+	status int
+	msg    string
+	fields []FieldError
+}
+
+func (e *requestError) send(c *fiber.Ctx) error {
+	body := fiber.Map{"error": e.msg}
+	if len(e.fields) > 0 {
+		body["fields"] = e.fields
+	}
+	return c.Status(e.status).JSON(body)
+}
+
+func invalidRequest(fields []FieldError) *requestError {
+	return &requestError{status: 400, msg: "Invalid request", fields: fields}
+}
+
+// bind decodes the request body into v, a pointer to a request struct,
+// and reports every bad field at once: values of the wrong type, fields
+// tagged validate:"required" that are missing or blank, and with
+// STRICT_REQUEST_BODIES fields v doesn't have. Bodies that aren't JSON go
+// through BodyParser and only get the required checks.
+func (s *Server) bind(c *fiber.Ctx, v interface{}) *requestError {
+	if !strings.HasPrefix(strings.ToLower(c.Get(fiber.HeaderContentType)), fiber.MIMEApplicationJSON) {
+		if err := c.BodyParser(v); err != nil {
+			return invalidRequest(nil)
+		}
+		if fields := missingFields(v, nil); len(fields) > 0 {
+			return invalidRequest(fields)
+		}
+		return nil
+	}
+
+	body := c.Body()
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return &requestError{status: 400, msg: "Invalid JSON body"}
+	}
+
+	// Decode field by field first, so one bad value doesn't hide the rest.
+	// Keys match field names case-insensitively, as encoding/json does.
+	known := jsonFields(reflect.TypeOf(v).Elem())
+	var fields []FieldError
+	bad := make(map[string]bool)
+	for name, value := range raw {
+		field, ok := known[strings.ToLower(name)]
+		if !ok {
+			if s.config.Server.StrictBodies {
+				fields = append(fields, FieldError{Field: name, Error: "unknown field"})
+			}
+			continue
+		}
+		if err := json.Unmarshal(value, reflect.New(field.Type).Interface()); err != nil {
+			fields = append(fields, typeError(name, field.Type, err))
+			bad[field.Name] = true
+		}
+	}
+	// Unmarshal skips the mistyped values already reported and fills in
+	// the rest, which the required checks need
+	if err := json.Unmarshal(body, v); err != nil && len(fields) == 0 {
+		return invalidRequest(nil)
+	}
+
+	fields = append(fields, missingFields(v, bad)...)
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+	return invalidRequest(fields)
+}
+
+// jsonFields maps the lowercased JSON names of t's fields to the fields
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := jsonName(f); name != "" {
+			fields[strings.ToLower(name)] = f
+		}
+	}
+	return fields
+}
+
+// jsonName is the key f is encoded under, "" when it isn't
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// missingFields lists the required fields of *v left zero, or blank for
+// strings, skipping those named in skip
+func missingFields(v interface{}, skip map[string]bool) []FieldError {
+	rv := reflect.ValueOf(v).Elem()
+	var fields []FieldError
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.Tag.Get("validate") != "required" || skip[f.Name] {
+			continue
+		}
+		if blank(rv.Field(i)) {
+			fields = append(fields, FieldError{Field: jsonName(f), Error: "required"})
+		}
+	}
+	return fields
+}
+
+func blank(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// typeError describes a value that didn't decode into its field, naming
+// the nested field when the mismatch is inside it
+func typeError(name string, t reflect.Type, err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			name += "." + typeErr.Field
+		}
+		t = typeErr.Type
+	}
+	return FieldError{Field: name, Error: "must be " + jsonType(t)}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
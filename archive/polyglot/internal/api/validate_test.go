@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"hybridcore/internal/config"
+)
+
+type bindRequest struct {
+	Text  string `json:"text" validate:"required"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// bindApp answers 204 when the body binds, else the rejection
+func bindApp(strict bool) *fiber.App {
+	s := &Server{config: &config.Config{Server: config.ServerConfig{StrictBodies: strict}}}
+	app := fiber.New()
+	app.Post("/bind", func(c *fiber.Ctx) error {
+		var req bindRequest
+		if err := s.bind(c, &req); err != nil {
+			return err.send(c)
+		}
+		return c.SendStatus(204)
+	})
+	return app
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		body   string
+		status int
+		fields []FieldError
+	}{
+		{"valid", false, `{"text":"hi","limit":3}`, 204, nil},
+		{"missing required", false, `{"limit":3}`, 400, []FieldError{{Field: "text", Error: "required"}}},
+		{"blank required", false, `{"text":"  "}`, 400, []FieldError{{Field: "text", Error: "required"}}},
+		{"wrong type", false, `{"text":"hi","limit":"three"}`, 400, []FieldError{{Field: "limit", Error: "must be an integer"}}},
+		{"every bad field at once", false, `{"limit":"three"}`, 400, []FieldError{
+			{Field: "limit", Error: "must be an integer"},
+			{Field: "text", Error: "required"},
+		}},
+		{"unknown field, lenient", false, `{"text":"hi","colour":"red"}`, 204, nil},
+		{"unknown field, strict", true, `{"text":"hi","colour":"red"}`, 400, []FieldError{{Field: "colour", Error: "unknown field"}}},
+		{"known field, strict", true, `{"text":"hi","limit":3}`, 204, nil},
+		{"not JSON", false, `{"text":`, 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/bind", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := bindApp(tt.strict).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != 400 {
+				return
+			}
+			raw, _ := io.ReadAll(resp.Body)
+			var body struct {
+				Error  string       `json:"error"`
+				Fields []FieldError `json:"fields"`
+			}
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Fatalf("decoding %s: %v", raw, err)
+			}
+			if body.Error == "" || !reflect.DeepEqual(body.Fields, tt.fields) {
+				t.Errorf("body = %s, want fields %+v", raw, tt.fields)
+			}
+		})
+	}
+}
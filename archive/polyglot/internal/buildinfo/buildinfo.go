@@ -0,0 +1,36 @@
+// Package buildinfo exposes build metadata injected at link time:
+//
+//	go build -ldflags "-X hybridcore/internal/buildinfo.Version=2.1.0 \
+//		-X hybridcore/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//		-X hybridcore/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+package buildinfo
+
+import (
+	"runtime"
+	"time"
+)
+
+// Set via -ldflags; "dev" for local builds
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+var startTime = time.Now()
+
+// Uptime since process start
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// Info returns the build and runtime fields reported by health endpoints
+func Info() map[string]interface{} {
+	return map[string]interface{}{
+		"version":    Version,
+		"commit":     Commit,
+		"build_time": BuildTime,
+		"go_version": runtime.Version(),
+		"uptime":     Uptime().Round(time.Second).String(),
+	}
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"hybridcore/internal/rag"
+)
+
+// Source excerpts follow the request: highlights kept for Markdown
+// clients, stripped for plain ones, cut to the length asked
+func TestChatSourceExcerptOptions(t *testing.T) {
+	// Search re-marks the query terms in what ts_headline returns
+	excerpt := "The **lease** was **signed** by Alice Martin " + strings.Repeat("in the presence of witnesses ", 10)
+
+	tests := []struct {
+		name    string
+		length  int
+		plain   bool
+		max     int
+		markers bool
+	}{
+		{"defaults", 0, false, rag.DefaultExcerptLength, true},
+		{"short plain", 60, true, 60, false},
+		{"short markdown", 60, false, 60, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubSearch(t, excerpt, nil)
+			client := analyzeLLM(t, true)
+			m := NewManager(rag.NewEngine(client, nil), client, nil)
+
+			resp, err := m.Chat(ChatRequest{Message: "who signed the lease?", ExcerptLength: tt.length, PlainExcerpts: tt.plain})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Sources) != 1 {
+				t.Fatalf("%d sources", len(resp.Sources))
+			}
+			got := resp.Sources[0].Excerpt
+			if n := utf8.RuneCountInString(got); n > tt.max || !strings.HasSuffix(got, "...") {
+				t.Errorf("excerpt is %d runes, want at most %d and cut: %q", n, tt.max, got)
+			}
+			if strings.Contains(got, "**lease**") != tt.markers || strings.Contains(got, "**") != tt.markers {
+				t.Errorf("markers kept = %v, want %v: %q", !tt.markers, tt.markers, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// SESSION EXPORT
+// ═══════════════════════════════════════════════════════════════════
+
+// sessionExport is the JSON shape of a session: its fields without the lock
+type sessionExport struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner,omitempty"`
+	Messages  []Message `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// snapshot copies the session's fields under its lock
+func (s *Session) snapshot() sessionExport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Message, len(s.Messages))
+	copy(messages, s.Messages)
+	return sessionExport{
+		ID:        s.ID,
+		Owner:     s.Owner,
+		Messages:  messages,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+// MarshalJSON encodes a snapshot, so a session can be returned from the
+// API while Chat is still appending to it
+func (s *Session) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.snapshot())
+}
+
+// WriteJSON writes the session with its messages and their sources to w
+func (s *Session) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.snapshot())
+}
+
+// WriteMarkdown writes the session to w as a readable transcript, each
+// assistant turn followed by a numbered list of its sources. Messages are
+// written one at a time, never the whole transcript at once.
+func (s *Session) WriteMarkdown(w io.Writer) error {
+	snap := s.snapshot()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Chat session %s\n\n", snap.ID)
+	fmt.Fprintf(&b, "_Created %s, updated %s_\n", formatExportTime(snap.CreatedAt), formatExportTime(snap.UpdatedAt))
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+
+	for _, msg := range snap.Messages {
+		b.Reset()
+		role := msg.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&b, "\n## %s — %s\n\n", role, formatExportTime(msg.Timestamp))
+		b.WriteString(strings.TrimSpace(msg.Content))
+		b.WriteString("\n")
+
+		if len(msg.Sources) > 0 {
+			b.WriteString("\n**Sources**\n\n")
+			for i, src := range msg.Sources {
+				title := src.Title
+				if title == "" {
+					title = src.DocID
+				}
+				fmt.Fprintf(&b, "%d. **%s** (`%s`)", i+1, title, src.DocID)
+				if excerpt := strings.Join(strings.Fields(src.Excerpt), " "); excerpt != "" {
+					fmt.Fprintf(&b, " — %s", excerpt)
+				}
+				b.WriteString("\n")
+			}
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
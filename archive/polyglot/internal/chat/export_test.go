@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"hybridcore/internal/rag"
+)
+
+// countingWriter records how many writes reach it, failing after limit
+type countingWriter struct {
+	writes int
+	limit  int
+	b      strings.Builder
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.limit > 0 && w.writes >= w.limit {
+		return 0, errors.New("client gone")
+	}
+	w.writes++
+	return w.b.Write(p)
+}
+
+func TestWriteMarkdownStreamsEachMessage(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	session := m.GetOrCreateSession("", "")
+	for i := 0; i < 150; i++ {
+		session.addMessage(Message{Role: "user", Content: "hello"})
+	}
+
+	var w countingWriter
+	if err := session.WriteMarkdown(&w); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(w.b.String(), "## User"); got != 150 {
+		t.Errorf("exported %d messages, want all 150", got)
+	}
+	if w.writes != 151 {
+		t.Errorf("%d writes, want the header then one per message", w.writes)
+	}
+
+	// A client hanging up stops the export
+	stopped := countingWriter{limit: 3}
+	if err := session.WriteMarkdown(&stopped); err == nil {
+		t.Error("WriteMarkdown ignored a failed write")
+	}
+}
+
+// exportSession is a two-round session whose assistant turns cite sources
+func exportSession() *Session {
+	at := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	s := NewManager(nil, nil, nil).GetOrCreateSession("", "acme")
+	s.addMessage(Message{Role: "user", Content: "who signed the lease?", Timestamp: at})
+	s.addMessage(Message{Role: "assistant", Content: "Alice Martin signed it.", Timestamp: at.Add(time.Minute),
+		Sources: []rag.Source{
+			{DocID: "doc-1", Title: "Lease", Excerpt: "signed by\n  Alice   Martin"},
+			{DocID: "doc-2", Excerpt: "witness: Bob"},
+		}})
+	s.addMessage(Message{Role: "user", Content: "when?", Timestamp: at.Add(2 * time.Minute)})
+	s.addMessage(Message{Role: "assistant", Content: "On 1 March.", Timestamp: at.Add(3 * time.Minute),
+		Sources: []rag.Source{{DocID: "doc-3", Title: "Calendar"}}})
+	return s
+}
+
+func TestWriteJSONKeepsTurnsAndSources(t *testing.T) {
+	s := exportSession()
+	var b strings.Builder
+	if err := s.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var got sessionExport
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("export is not JSON: %v\n%s", err, b.String())
+	}
+	if got.ID != s.ID || got.Owner != "acme" || len(got.Messages) != 4 {
+		t.Fatalf("exported %+v", got)
+	}
+	roles := []string{"user", "assistant", "user", "assistant"}
+	for i, msg := range got.Messages {
+		if msg.Role != roles[i] {
+			t.Errorf("message %d role = %q, want %q", i, msg.Role, roles[i])
+		}
+	}
+	if src := got.Messages[1].Sources; len(src) != 2 || src[0].DocID != "doc-1" || src[1].DocID != "doc-2" {
+		t.Errorf("first answer sources = %+v", src)
+	}
+	if src := got.Messages[3].Sources; len(src) != 1 || src[0].Title != "Calendar" {
+		t.Errorf("second answer sources = %+v", src)
+	}
+	if got.Messages[0].Sources != nil {
+		t.Errorf("user turn exported sources %+v", got.Messages[0].Sources)
+	}
+}
+
+func TestWriteMarkdownListsSourcesUnderEachAnswer(t *testing.T) {
+	s := exportSession()
+	var b strings.Builder
+	if err := s.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	md := b.String()
+
+	for _, want := range []string{
+		"# Chat session " + s.ID + "\n",
+		"## User — 2026-03-04 10:30 UTC\n\nwho signed the lease?\n",
+		"## Assistant — 2026-03-04 10:31 UTC\n\nAlice Martin signed it.\n" +
+			"\n**Sources**\n\n" +
+			"1. **Lease** (`doc-1`) — signed by Alice Martin\n" +
+			"2. **doc-2** (`doc-2`) — witness: Bob\n",
+		"## Assistant — 2026-03-04 10:33 UTC\n\nOn 1 March.\n\n**Sources**\n\n1. **Calendar** (`doc-3`)\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md)
+		}
+	}
+	if got := strings.Count(md, "**Sources**"); got != 2 {
+		t.Errorf("%d source lists, want one per answer", got)
+	}
+	if first, second := strings.Index(md, "Alice Martin signed"), strings.Index(md, "## User — 2026-03-04 10:32"); second < first {
+		t.Errorf("second question missing or before the first answer:\n%s", md)
+	}
+}
@@ -0,0 +1,168 @@
+package chat
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/llm"
+	"hybridcore/internal/rag"
+)
+
+// searchStub answers the document search query with rows, or fails with
+// err, recording its args; every other query fails
+var searchStub struct {
+	rows [][]driver.Value
+	err  error
+	args []driver.Value
+}
+
+var searchColumns = []string{"id", "doc_id", "filename", "title", "content", "word_count",
+	"owner", "language", "created_at", "rank", "excerpt"}
+
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt(query), nil }
+func (stubConn) Close() error                              { return nil }
+func (stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type stubStmt string
+
+func (stubStmt) Close() error                               { return nil }
+func (stubStmt) NumInput() int                              { return -1 }
+func (stubStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("read only") }
+
+func (s stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.Contains(string(s), "FROM documents d") || !strings.Contains(string(s), " as rank") {
+		return nil, errors.New("unexpected query")
+	}
+	searchStub.args = args
+	if searchStub.err != nil {
+		return nil, searchStub.err
+	}
+	return &stubRows{rows: searchStub.rows}, nil
+}
+
+type stubRows struct{ rows [][]driver.Value }
+
+func (r *stubRows) Columns() []string { return searchColumns }
+func (r *stubRows) Close() error      { return nil }
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("chat-search-stub", stubDriver{})
+}
+
+// stubSearch makes db.Search return one document with excerpt, or fail
+// with err, until the test ends
+func stubSearch(t *testing.T, excerpt string, err error) {
+	t.Helper()
+	conn, openErr := sqlx.Open("chat-search-stub", "")
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	old := db.DB
+	db.DB = sqlx.NewDb(conn.DB, "postgres")
+	searchStub.rows = [][]driver.Value{{
+		int64(1), "doc-1", "lease.txt", "Lease", "The lease was signed by Alice Martin.", int64(7),
+		"", "en", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), 0.8, excerpt,
+	}}
+	searchStub.err = err
+	t.Cleanup(func() {
+		db.DB = old
+		conn.Close()
+	})
+}
+
+// analyzeLLM returns a client whose server answers /analyze and
+// /generate, or with up false one whose server is down
+func analyzeLLM(t *testing.T, up bool) *llm.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/analyze":
+			io.WriteString(w, `{"analysis":"Alice Martin signed the lease."}`)
+		case "/generate":
+			io.WriteString(w, `{"text":"Alice Martin, per the lease."}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	if !up {
+		srv.Close()
+	} else {
+		t.Cleanup(srv.Close)
+	}
+	return llm.NewMultiClient([]string{srv.URL}, false)
+}
+
+func TestChatFallbackChain(t *testing.T) {
+	const query = "who signed the lease?"
+	searchDown := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		useRAG    bool
+		llmUp     bool
+		excerpt   string
+		searchErr error
+		fallback  string
+		message   string // substring of the answer; a rag.Msg* key for canned ones
+		sources   int
+	}{
+		{"synthesis", true, true, "signed by **Alice Martin**", nil, "", "Alice Martin signed the lease.", 1},
+		{"LLM down: smart answer", true, false, "signed by **Alice Martin**", nil, rag.FallbackSmartAnswer, "signed by Alice Martin", 1},
+		{"LLM down, nothing to quote: sources only", true, false, "", nil, rag.FallbackSourcesOnly, rag.MsgSourcesOnly, 1},
+		{"search down: apology", true, true, "", searchDown, rag.FallbackApology, rag.MsgError, 0},
+		{"direct LLM down: smart answer", false, false, "signed by **Alice Martin**", nil, rag.FallbackSmartAnswer, "signed by Alice Martin", 1},
+		{"direct LLM and search down: apology", false, false, "", searchDown, rag.FallbackApology, rag.MsgLLMDown, 0},
+		{"direct LLM answers", false, true, "", searchDown, "", "Alice Martin, per the lease.", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubSearch(t, tt.excerpt, tt.searchErr)
+			client := analyzeLLM(t, tt.llmUp)
+			engine := rag.NewEngine(client, nil)
+			m := NewManager(engine, client, nil)
+
+			useRAG := tt.useRAG
+			resp, err := m.Chat(ChatRequest{Message: query, UseRAG: &useRAG})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Fallback != tt.fallback {
+				t.Errorf("fallback = %q, want %q", resp.Fallback, tt.fallback)
+			}
+			want := tt.message
+			if tt.fallback == rag.FallbackSourcesOnly || tt.fallback == rag.FallbackApology {
+				want = engine.Message(query, tt.message)
+			}
+			if !strings.Contains(resp.Message, want) {
+				t.Errorf("answer = %q, want it to contain %q", resp.Message, want)
+			}
+			if len(resp.Sources) != tt.sources {
+				t.Errorf("%d sources, want %d", len(resp.Sources), tt.sources)
+			}
+		})
+	}
+}
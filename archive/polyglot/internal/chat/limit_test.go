@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"testing"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/rag"
+)
+
+// useLimits sets the shared result limits until the test ends
+func useLimits(t *testing.T, def, max int) {
+	t.Helper()
+	oldDef, oldMax := db.DefaultLimit, db.MaxLimit
+	db.DefaultLimit, db.MaxLimit = def, max
+	t.Cleanup(func() { db.DefaultLimit, db.MaxLimit = oldDef, oldMax })
+}
+
+// searchLimit is the LIMIT the last document search ran with
+func searchLimit(t *testing.T) int64 {
+	t.Helper()
+	if len(searchStub.args) == 0 {
+		t.Fatal("no search ran")
+	}
+	limit, _ := searchStub.args[len(searchStub.args)-1].(int64)
+	return limit
+}
+
+func TestChatUsesDefaultLimit(t *testing.T) {
+	stubSearch(t, "The lease was signed by Alice Martin.", nil)
+	useLimits(t, 3, 7)
+
+	m := NewManager(rag.NewEngine(analyzeLLM(t, true), nil), analyzeLLM(t, true), nil)
+	if _, err := m.Chat(ChatRequest{Message: "who signed the lease?"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchLimit(t); got != 3 {
+		t.Errorf("chat searched with limit %d, want the default 3", got)
+	}
+}
+
+func TestRAGQueryLimits(t *testing.T) {
+	stubSearch(t, "The lease was signed by Alice Martin.", nil)
+	useLimits(t, 3, 7)
+	engine := rag.NewEngine(analyzeLLM(t, true), nil)
+
+	for _, tt := range []struct{ limit, want int }{{0, 3}, {-1, 3}, {5, 5}, {500, 7}} {
+		if _, err := engine.Query("lease", tt.limit, rag.QueryOptions{SkipSynthesis: true}); err != nil {
+			t.Fatal(err)
+		}
+		if got := searchLimit(t); got != int64(tt.want) {
+			t.Errorf("Query(limit %d) searched with %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -2,28 +2,71 @@ package chat
 
 import (
 	"log"
+	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"hybridcore/internal/clock"
+	"hybridcore/internal/db"
 	"hybridcore/internal/llm"
 	"hybridcore/internal/rag"
+	"hybridcore/internal/random"
+	"hybridcore/internal/regex"
 )
 
 type Manager struct {
-	sessions  map[string]*Session
-	mu        sync.RWMutex
-	ragEngine *rag.Engine
-	llmClient *llm.Client
+	sessions    map[string]*Session
+	mu          sync.RWMutex
+	ragEngine   *rag.Engine
+	llmClient   *llm.Client
+	matcher     *regex.Matcher
+	outputGuard GuardPolicy
+	rng         *random.Source
+
+	// Reasoning traces stripped from answers; none when both are empty
+	thinkMarkers  []ThinkMarker
+	thinkPrefixes []string
+}
+
+// GuardPolicy controls what happens when an answer contains sensitive data
+type GuardPolicy string
+
+const (
+	GuardOff    GuardPolicy = "off"
+	GuardWarn   GuardPolicy = "warn"   // flag the categories, leave text as-is
+	GuardRedact GuardPolicy = "redact" // mask sensitive spans before returning
+)
+
+func ParseGuardPolicy(s string) (GuardPolicy, bool) {
+	switch p := GuardPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "", GuardOff:
+		return GuardOff, true
+	case GuardWarn, GuardRedact:
+		return p, true
+	}
+	return "", false
 }
 
 type Session struct {
 	ID        string    `json:"id"`
+	Owner     string    `json:"owner,omitempty"`
 	Messages  []Message `json:"messages"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	mu sync.Mutex
+}
+
+func (s *Session) addMessage(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Messages = append(s.Messages, msg)
+	s.UpdatedAt = clock.Now()
 }
 
 type Message struct {
@@ -35,8 +78,14 @@ type Message struct {
 
 type ChatRequest struct {
 	SessionID string `json:"session_id"`
-	Message   string `json:"message"`
+	Message   string `json:"message" validate:"required"`
 	UseRAG    *bool  `json:"use_rag,omitempty"`
+	Owner     string `json:"-"` // tenant, set from the request by the API layer
+
+	// Source excerpt shaping, passed through to the RAG engine
+	ExcerptLength int           `json:"excerpt_length,omitempty"`
+	PlainExcerpts bool          `json:"plain_excerpts,omitempty"`
+	Verbosity     rag.Verbosity `json:"verbosity,omitempty"` // brief, normal or detailed
 }
 
 type ChatResponse struct {
@@ -44,22 +93,54 @@ type ChatResponse struct {
 	Message          string       `json:"message"`
 	Sources          []rag.Source `json:"sources,omitempty"`
 	SuggestedQueries []string     `json:"suggested_queries,omitempty"`
+	Filtered         []string     `json:"filtered,omitempty"`
+	Warning          string       `json:"warning,omitempty"`
+	Fallback         string       `json:"fallback,omitempty"` // see rag.Fallback*; empty when the LLM answered
 }
 
-func NewManager(ragEngine *rag.Engine, llmClient *llm.Client) *Manager {
+// NewManager builds a chat manager; matcher, the startup-configured one
+// the output guard scans with, may be nil for a default one
+func NewManager(ragEngine *rag.Engine, llmClient *llm.Client, matcher *regex.Matcher) *Manager {
+	if matcher == nil {
+		matcher = regex.NewMatcher()
+	}
 	return &Manager{
-		sessions:  make(map[string]*Session),
-		ragEngine: ragEngine,
-		llmClient: llmClient,
+		sessions:    make(map[string]*Session),
+		ragEngine:   ragEngine,
+		llmClient:   llmClient,
+		matcher:     matcher,
+		outputGuard: GuardOff,
+		rng:         random.NewTimeSeeded(),
 	}
 }
 
-func (m *Manager) GetOrCreateSession(sessionID string) *Session {
+// SetRand replaces the randomness behind greeting selection; seed it for
+// reproducible output
+func (m *Manager) SetRand(r *rand.Rand) {
+	m.rng = random.New(r)
+}
+
+// SetOutputGuard sets the sensitive-data policy applied to every answer
+func (m *Manager) SetOutputGuard(policy GuardPolicy) {
+	m.outputGuard = policy
+}
+
+// SetThinkFilter strips reasoning traces, between markers or on leading
+// lines starting with one of prefixes, from every answer before it is
+// returned or stored in the session
+func (m *Manager) SetThinkFilter(markers []ThinkMarker, prefixes []string) {
+	m.thinkMarkers = markers
+	m.thinkPrefixes = prefixes
+}
+
+// GetOrCreateSession returns the owner's session, or a fresh one when the
+// ID is unknown or belongs to another tenant.
+func (m *Manager) GetOrCreateSession(sessionID, owner string) *Session {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if sessionID != "" {
-		if s, ok := m.sessions[sessionID]; ok {
+		if s, ok := m.sessions[sessionID]; ok && s.Owner == owner {
 			return s
 		}
 	}
@@ -67,9 +148,10 @@ func (m *Manager) GetOrCreateSession(sessionID string) *Session {
 	newID := uuid.New().String()[:8]
 	session := &Session{
 		ID:        newID,
+		Owner:     owner,
 		Messages:  []Message{},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: clock.Now(),
+		UpdatedAt: clock.Now(),
 	}
 
 	m.sessions[newID] = session
@@ -77,15 +159,14 @@ func (m *Manager) GetOrCreateSession(sessionID string) *Session {
 }
 
 func (m *Manager) Chat(req ChatRequest) (*ChatResponse, error) {
-	session := m.GetOrCreateSession(req.SessionID)
+	session := m.GetOrCreateSession(req.SessionID, req.Owner)
 
 	// Add user message
-	session.Messages = append(session.Messages, Message{
+	session.addMessage(Message{
 		Role:      "user",
 		Content:   req.Message,
-		Timestamp: time.Now(),
+		Timestamp: clock.Now(),
 	})
-	session.UpdatedAt = time.Now()
 
 	// Determine if RAG should be used
 	useRAG := true
@@ -98,68 +179,141 @@ func (m *Manager) Chat(req ChatRequest) (*ChatResponse, error) {
 	// Check for greetings first
 	if isGreeting(req.Message) {
 		response = &ChatResponse{
-			SessionID: session.ID,
-			Message:   getGreetingResponse(),
+			Message: m.getGreetingResponse(),
 		}
 	} else if useRAG {
-		// Use RAG engine
-		result, err := m.ragEngine.Query(req.Message, 5)
-		if err != nil {
-			log.Printf("[Chat] RAG error: %v", err)
-			response = &ChatResponse{
-				SessionID: session.ID,
-				Message:   "Désolé, une erreur s'est produite. Réessayez.",
-			}
-		} else {
-			response = &ChatResponse{
-				SessionID:        session.ID,
-				Message:          result.Answer,
-				Sources:          result.Sources,
-				SuggestedQueries: result.SuggestedQueries,
-			}
-		}
+		response = m.ragAnswer(req, false, rag.MsgError)
 	} else {
-		// Direct LLM call without RAG
-		resp, err := m.llmClient.Generate(req.Message, 500, 0.3)
+		// Direct LLM call without RAG; when it fails, search results are
+		// still worth showing
+		resp, err := m.llmClient.Generate(req.Message, req.Verbosity.MaxTokens(), 0.3)
 		if err != nil {
 			log.Printf("[Chat] LLM error: %v", err)
-			response = &ChatResponse{
-				SessionID: session.ID,
-				Message:   "Désolé, le LLM n'est pas disponible.",
-			}
+			response = m.ragAnswer(req, true, rag.MsgLLMDown)
 		} else {
 			response = &ChatResponse{
-				SessionID: session.ID,
-				Message:   resp.Text,
+				Message: resp.Text,
 			}
 		}
 	}
+	response.SessionID = session.ID
+
+	if len(m.thinkMarkers) > 0 || len(m.thinkPrefixes) > 0 {
+		response.Message = StripThinking(response.Message, m.thinkMarkers, m.thinkPrefixes)
+	}
+	if m.outputGuard != GuardOff {
+		m.applyOutputGuard(response)
+	}
 
 	// Add assistant message
-	session.Messages = append(session.Messages, Message{
+	session.addMessage(Message{
 		Role:      "assistant",
 		Content:   response.Message,
 		Sources:   response.Sources,
-		Timestamp: time.Now(),
+		Timestamp: clock.Now(),
 	})
 
 	return response, nil
 }
 
-func (m *Manager) GetSession(sessionID string) *Session {
+// ragAnswer runs the RAG fallback chain: LLM synthesis over the search
+// results (unless skipSynthesis), then a smart answer built from them,
+// then the bare sources. Only when search itself fails does it give up
+// with the apology message (a rag.Msg* key), in the query's language.
+func (m *Manager) ragAnswer(req ChatRequest, skipSynthesis bool, apology string) *ChatResponse {
+	result, err := m.ragEngine.Query(req.Message, 0, rag.QueryOptions{
+		Filter:        db.Filter{Owner: req.Owner},
+		ExcerptLength: req.ExcerptLength,
+		PlainExcerpts: req.PlainExcerpts,
+		SkipSynthesis: skipSynthesis,
+		Verbosity:     req.Verbosity,
+	})
+	if err != nil {
+		log.Printf("[Chat] RAG error: %v", err)
+		return &ChatResponse{
+			Message:  m.ragEngine.Message(req.Message, apology),
+			Fallback: rag.FallbackApology,
+		}
+	}
+	return &ChatResponse{
+		Message:          result.Answer,
+		Sources:          result.Sources,
+		SuggestedQueries: result.SuggestedQueries,
+		Fallback:         result.Fallback,
+	}
+}
+
+// StreamGuard returns a redactor for answer text streamed to the client
+// under the redact policy, nil otherwise. window is the holdback in bytes
+// (see regex.StreamRedactor).
+func (m *Manager) StreamGuard(window int) *regex.StreamRedactor {
+	if m.outputGuard != GuardRedact {
+		return nil
+	}
+	return m.matcher.NewStreamRedactor(regex.RedactTypeToken, window)
+}
+
+// applyOutputGuard scans the answer for sensitive data and, per policy,
+// redacts it or flags it. The filtered categories are reported either way.
+func (m *Manager) applyOutputGuard(response *ChatResponse) {
+	matches := m.matcher.FindSensitive(response.Message)
+	if len(matches) == 0 {
+		return
+	}
+
+	seen := make(map[regex.Category]bool)
+	for _, match := range matches {
+		if !seen[match.Category] {
+			seen[match.Category] = true
+			response.Filtered = append(response.Filtered, string(match.Category))
+		}
+	}
+	sort.Strings(response.Filtered)
+
+	switch m.outputGuard {
+	case GuardRedact:
+		response.Message, _ = regex.Redact(response.Message, matches, regex.RedactTypeToken)
+	case GuardWarn:
+		response.Warning = "Answer contains sensitive data"
+	}
+}
+
+// GetSession returns the session if it exists and belongs to owner
+func (m *Manager) GetSession(sessionID, owner string) *Session {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.sessions[sessionID]
+	if s, ok := m.sessions[sessionID]; ok && s.Owner == owner {
+		return s
+	}
+	return nil
+}
+
+// ClearSession empties a session's history while keeping its ID and
+// creation metadata. Returns nil if the owner has no such session.
+func (m *Manager) ClearSession(sessionID, owner string) *Session {
+	session := m.GetSession(sessionID, owner)
+	if session == nil {
+		return nil
+	}
+
+	session.mu.Lock()
+	session.Messages = []Message{}
+	session.UpdatedAt = clock.Now()
+	session.mu.Unlock()
+
+	return session
 }
 
-func (m *Manager) ListSessions() []*Session {
+func (m *Manager) ListSessions(owner string) []*Session {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	sessions := make([]*Session, 0, len(m.sessions))
 	for _, s := range m.sessions {
-		sessions = append(sessions, s)
+		if s.Owner == owner {
+			sessions = append(sessions, s)
+		}
 	}
 	return sessions
 }
@@ -179,14 +333,12 @@ func isGreeting(msg string) bool {
 	return false
 }
 
-func getGreetingResponse() string {
+func (m *Manager) getGreetingResponse() string {
 	responses := []string{
 		"Bonjour! Je suis HybridCore, votre assistant OSINT. Comment puis-je vous aider?",
 		"Salut! Je peux rechercher dans les documents et analyser des informations. Que cherchez-vous?",
 		"Hello! Je suis prêt à vous aider avec vos recherches. Posez-moi une question!",
 	}
 
-	// Simple rotation based on time
-	idx := time.Now().Unix() % int64(len(responses))
-	return responses[idx]
+	return responses[m.rng.Intn(len(responses))]
 }
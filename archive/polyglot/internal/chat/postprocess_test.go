@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"hybridcore/internal/rag"
+)
+
+// The engine's transformers rewrite the synthesized answer, not the
+// fallbacks
+func TestChatAnswerPostProcessed(t *testing.T) {
+	ts, err := rag.ParseTransformers([]string{"citations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stubSearch(t, "signed by **Alice Martin**", nil)
+	client := analyzeLLM(t, true)
+	engine := rag.NewEngine(client, nil)
+	engine.SetPostProcessors(ts...)
+	m := NewManager(engine, client, nil)
+
+	resp, err := m.Chat(ChatRequest{Message: "who signed the lease?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Alice Martin signed the lease.\n\nSources:\n[1] Lease"; resp.Message != want {
+		t.Errorf("answer = %q, want %q", resp.Message, want)
+	}
+
+	down := analyzeLLM(t, false)
+	engine = rag.NewEngine(down, nil)
+	engine.SetPostProcessors(ts...)
+	resp, err = NewManager(engine, down, nil).Chat(ChatRequest{Message: "who signed the lease?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Fallback != rag.FallbackSmartAnswer || strings.Contains(resp.Message, "Sources:\n[1]") {
+		t.Errorf("fallback answer post-processed: %q", resp.Message)
+	}
+}
@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func greetings(t *testing.T, seed int64, n int) []string {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetRand(rand.New(rand.NewSource(seed)))
+	var out []string
+	for i := 0; i < n; i++ {
+		resp, err := m.Chat(ChatRequest{Message: "bonjour"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, resp.Message)
+	}
+	return out
+}
+
+// A seeded manager greets the same way every run, and still varies
+// across calls
+func TestGreetingSeededIsStable(t *testing.T) {
+	first, second := greetings(t, 42, 12), greetings(t, 42, 12)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed, different greetings:\n%q\n%q", first, second)
+	}
+
+	distinct := make(map[string]bool)
+	for _, g := range first {
+		distinct[g] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("12 greetings, only %d distinct", len(distinct))
+	}
+}
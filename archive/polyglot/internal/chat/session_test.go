@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// Run with -race: encoding a session must not race with Chat appending to it
+func TestSessionJSONWhileChatting(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	session := m.GetOrCreateSession("", "acme")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			session.addMessage(Message{Role: "user", Content: "hello"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := json.Marshal(m.ListSessions("acme")); err != nil {
+				t.Error(err)
+				return
+			}
+			m.ClearSession(session.ID, "acme")
+		}
+	}()
+	wg.Wait()
+
+	var got struct {
+		ID       string    `json:"id"`
+		Owner    string    `json:"owner"`
+		Messages []Message `json:"messages"`
+	}
+	body, err := json.Marshal(m.GetSession(session.ID, "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != session.ID || got.Owner != "acme" || got.Messages == nil {
+		t.Errorf("session encoded as %s", body)
+	}
+}
+
+func TestClearSessionKeepsIdentity(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	session := m.GetOrCreateSession("", "acme")
+	session.addMessage(Message{Role: "user", Content: "hello"})
+	session.addMessage(Message{Role: "assistant", Content: "hi"})
+	id, created := session.ID, session.CreatedAt
+
+	if m.ClearSession(session.ID, "globex") != nil {
+		t.Fatal("another tenant cleared the session")
+	}
+	cleared := m.ClearSession(session.ID, "acme")
+	if cleared == nil {
+		t.Fatal("ClearSession returned nil for an existing session")
+	}
+	if len(cleared.Messages) != 0 || cleared.Messages == nil {
+		t.Errorf("messages = %#v, want empty", cleared.Messages)
+	}
+	if cleared.ID != id || !cleared.CreatedAt.Equal(created) {
+		t.Errorf("id, created = %s, %v, want %s, %v", cleared.ID, cleared.CreatedAt, id, created)
+	}
+	if cleared.UpdatedAt.Before(created) {
+		t.Errorf("updated %v before created %v", cleared.UpdatedAt, created)
+	}
+	if got := m.GetSession(id, "acme"); got != cleared {
+		t.Error("cleared session no longer retrievable under its id")
+	}
+}
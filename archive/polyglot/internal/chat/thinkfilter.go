@@ -0,0 +1,168 @@
+package chat
+
+import "strings"
+
+// ThinkMarker delimits a reasoning trace to drop, e.g. <think>…</think>
+type ThinkMarker struct {
+	Open, Close string
+}
+
+// ParseThinkMarkers reads "open|close" pairs such as "<think>|</think>".
+// Entries without both halves are skipped.
+func ParseThinkMarkers(specs []string) []ThinkMarker {
+	var markers []ThinkMarker
+	for _, spec := range specs {
+		open, close, ok := strings.Cut(spec, "|")
+		if ok && open != "" && close != "" {
+			markers = append(markers, ThinkMarker{Open: open, Close: close})
+		}
+	}
+	return markers
+}
+
+// ThinkFilter strips reasoning traces from a streamed answer: anything
+// between a marker pair, and whole lines at the start of the answer that
+// begin with one of the prefixes (e.g. "Thinking:"). It holds back only
+// what could still turn out to be a marker or a prefixed line, so text
+// reaches the client as soon as it is known to be visible.
+type ThinkFilter struct {
+	markers  []ThinkMarker
+	prefixes []string
+
+	buf     string
+	inside  *ThinkMarker // the open marker being skipped, if any
+	leading bool         // still at the start, where prefixed lines drop
+}
+
+// NewThinkFilter returns a filter for one answer
+func NewThinkFilter(markers []ThinkMarker, prefixes []string) *ThinkFilter {
+	return &ThinkFilter{markers: markers, prefixes: prefixes, leading: len(prefixes) > 0}
+}
+
+// StripThinking drops reasoning traces from a complete answer, the way a
+// ThinkFilter would from the same text streamed
+func StripThinking(text string, markers []ThinkMarker, prefixes []string) string {
+	f := NewThinkFilter(markers, prefixes)
+	return f.Push(text) + f.Flush()
+}
+
+// Push feeds the next piece of the answer and returns the text now safe
+// to emit, possibly empty
+func (f *ThinkFilter) Push(piece string) string {
+	f.buf += piece
+	return f.drain(false)
+}
+
+// Flush returns whatever is still held at the end of the answer. An
+// unclosed marker drops the rest; a prefixed line never ended by a newline
+// is emitted, since there's no telling where the reasoning stopped.
+func (f *ThinkFilter) Flush() string {
+	out := f.drain(true)
+	if f.inside == nil {
+		out += f.buf
+	}
+	f.buf = ""
+	return out
+}
+
+func (f *ThinkFilter) drain(final bool) string {
+	var out strings.Builder
+	for {
+		if f.inside != nil {
+			i := strings.Index(f.buf, f.inside.Close)
+			if i < 0 {
+				// Keep just enough to recognize a close marker split across pieces
+				if keep := len(f.inside.Close) - 1; len(f.buf) > keep {
+					f.buf = f.buf[len(f.buf)-keep:]
+				}
+				return out.String()
+			}
+			f.buf = f.buf[i+len(f.inside.Close):]
+			f.inside = nil
+			continue
+		}
+
+		if f.leading {
+			rest := strings.TrimLeft(f.buf, " \t\r\n")
+			switch {
+			case rest == "":
+				if final {
+					f.buf = ""
+				}
+				return out.String()
+			case hasAnyPrefix(rest, f.prefixes):
+				nl := strings.IndexByte(rest, '\n')
+				if nl < 0 {
+					return out.String()
+				}
+				f.buf = rest[nl+1:]
+				continue
+			case !final && isPrefixOfAny(rest, f.prefixes):
+				return out.String()
+			}
+			f.leading = false
+			f.buf = rest
+		}
+
+		open, at := f.firstOpen()
+		if open == nil {
+			// Hold a trailing partial open marker; emit the rest
+			hold := 0
+			if !final {
+				hold = f.partialOpenSuffix()
+			}
+			out.WriteString(f.buf[:len(f.buf)-hold])
+			f.buf = f.buf[len(f.buf)-hold:]
+			return out.String()
+		}
+		out.WriteString(f.buf[:at])
+		f.buf = f.buf[at+len(open.Open):]
+		f.inside = open
+	}
+}
+
+// firstOpen finds the earliest open marker in the buffer
+func (f *ThinkFilter) firstOpen() (*ThinkMarker, int) {
+	var found *ThinkMarker
+	at := -1
+	for i := range f.markers {
+		if j := strings.Index(f.buf, f.markers[i].Open); j >= 0 && (at < 0 || j < at) {
+			found, at = &f.markers[i], j
+		}
+	}
+	return found, at
+}
+
+// partialOpenSuffix is the length of the longest buffer suffix that is a
+// proper prefix of an open marker
+func (f *ThinkFilter) partialOpenSuffix() int {
+	longest := 0
+	for _, m := range f.markers {
+		for n := len(m.Open) - 1; n > longest; n-- {
+			if strings.HasSuffix(f.buf, m.Open[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(strings.ToLower(s), strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrefixOfAny reports whether s could still grow into one of prefixes
+func isPrefixOfAny(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if len(s) < len(p) && strings.HasPrefix(strings.ToLower(p), strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
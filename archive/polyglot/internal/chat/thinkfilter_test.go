@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/llm"
+)
+
+var testMarkers = []ThinkMarker{{Open: "<think>", Close: "</think>"}}
+
+func TestThinkFilterStreamedInPieces(t *testing.T) {
+	f := NewThinkFilter(testMarkers, []string{"Thinking:"})
+	var out strings.Builder
+	for _, piece := range []string{"Thinking: let me see\n", "The answer <thi", "nk>hidden</th", "ink>is 42."} {
+		out.WriteString(f.Push(piece))
+	}
+	out.WriteString(f.Flush())
+	if got := out.String(); got != "The answer is 42." {
+		t.Errorf("filtered = %q", got)
+	}
+}
+
+func TestStripThinking(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<think>plan</think>Paris.", "Paris."},
+		{"Thinking: hmm\nParis.", "Paris."},
+		{"Paris, <think>unclosed", "Paris, "},
+		{"no trace", "no trace"},
+	}
+	for _, tt := range tests {
+		if got := StripThinking(tt.in, testMarkers, []string{"Thinking:"}); got != tt.want {
+			t.Errorf("StripThinking(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// A non-streamed answer, and the history it leaves behind, carry no trace
+func TestChatStripsThinkingFromAnswerAndHistory(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(llm.GenerateResponse{Text: "<think>the user wants a city</think>Paris."})
+	}))
+	defer backend.Close()
+
+	m := NewManager(nil, llm.NewMultiClient([]string{backend.URL}, false), nil)
+	m.SetThinkFilter(testMarkers, nil)
+	noRAG := false
+	resp, err := m.Chat(ChatRequest{Message: "capital of France, answer in one word please", UseRAG: &noRAG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message != "Paris." {
+		t.Errorf("answer = %q, want the trace stripped", resp.Message)
+	}
+
+	session := m.GetSession(resp.SessionID, "")
+	last := session.Messages[len(session.Messages)-1]
+	if last.Role != "assistant" || last.Content != "Paris." {
+		t.Errorf("stored %s turn = %q, want the stripped answer", last.Role, last.Content)
+	}
+}
@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hybridcore/internal/llm"
+	"hybridcore/internal/rag"
+)
+
+// stubResults makes db.Search return n documents with long excerpts
+func stubResults(t *testing.T, n int) {
+	t.Helper()
+	stubSearch(t, "", nil)
+	searchStub.rows = nil
+	for i := 1; i <= n; i++ {
+		excerpt := fmt.Sprintf("Document %d: the **lease** ", i) + strings.Repeat("was renewed under new terms ", 30)
+		searchStub.rows = append(searchStub.rows, []driver.Value{
+			int64(i), fmt.Sprintf("doc-%d", i), fmt.Sprintf("lease%d.txt", i), fmt.Sprintf("Lease %d", i),
+			"The lease was renewed.", int64(4), "", "en",
+			time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), 1 / float64(i), excerpt,
+		})
+	}
+}
+
+// Brief answers summarize fewer sources, in shorter excerpts, than
+// detailed ones, whether from the LLM's token budget or the fallback
+func TestVerbosityBriefShorterThanDetailed(t *testing.T) {
+	answers := make(map[rag.Verbosity]*ChatResponse)
+	for _, v := range []rag.Verbosity{rag.VerbosityBrief, rag.VerbosityDetailed} {
+		stubResults(t, 8)
+		down := analyzeLLM(t, false)
+		resp, err := NewManager(rag.NewEngine(down, nil), down, nil).Chat(ChatRequest{Message: "lease terms", Verbosity: v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Fallback != rag.FallbackSmartAnswer {
+			t.Fatalf("%s: fallback %q", v, resp.Fallback)
+		}
+		answers[v] = resp
+	}
+
+	brief, detailed := answers[rag.VerbosityBrief].Message, answers[rag.VerbosityDetailed].Message
+	if n := strings.Count(brief, "**["); n != 1 {
+		t.Errorf("brief quotes %d sources, want 1:\n%s", n, brief)
+	}
+	if n := strings.Count(detailed, "**["); n != 6 {
+		t.Errorf("detailed quotes %d sources, want 6:\n%s", n, detailed)
+	}
+	if len(brief) >= len(detailed)/4 {
+		t.Errorf("brief answer %d bytes vs detailed %d", len(brief), len(detailed))
+	}
+	if !strings.Contains(brief, "_...and 7 more sources available._") {
+		t.Errorf("brief answer doesn't count the rest:\n%s", brief)
+	}
+
+	// With the LLM up, verbosity sets its token budget
+	var mu sync.Mutex
+	var budgets []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.AnalyzeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		budgets = append(budgets, req.MaxTokens)
+		mu.Unlock()
+		io.WriteString(w, `{"analysis":"The lease was renewed."}`)
+	}))
+	defer srv.Close()
+	client := llm.NewMultiClient([]string{srv.URL}, false)
+	m := NewManager(rag.NewEngine(client, nil), client, nil)
+	for _, v := range []rag.Verbosity{rag.VerbosityBrief, rag.VerbosityDetailed} {
+		stubResults(t, 8)
+		if _, err := m.Chat(ChatRequest{Message: "lease terms", Verbosity: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(budgets) != 2 || budgets[0] != rag.VerbosityBrief.MaxTokens() || budgets[1] != rag.VerbosityDetailed.MaxTokens() || budgets[0] >= budgets[1] {
+		t.Errorf("max_tokens sent = %v", budgets)
+	}
+}
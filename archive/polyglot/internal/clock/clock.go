@@ -0,0 +1,15 @@
+// Package clock standardizes timestamps: everything the API stores or
+// returns is in UTC and rendered as RFC3339.
+package clock
+
+import "time"
+
+// Now returns the current time in UTC
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Format renders t as an RFC3339 UTC timestamp
+func Format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
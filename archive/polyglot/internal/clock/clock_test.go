@@ -0,0 +1,20 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNowIsUTC(t *testing.T) {
+	if loc := Now().Location(); loc != time.UTC {
+		t.Errorf("Now() location = %v, want UTC", loc)
+	}
+}
+
+func TestFormatConvertsToUTC(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	got := Format(time.Date(2024, 6, 1, 14, 30, 0, 0, paris))
+	if got != "2024-06-01T12:30:00Z" {
+		t.Errorf("Format = %q, want 2024-06-01T12:30:00Z", got)
+	}
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds all runtime settings, loaded from the environment
+type Config struct {
+	DB     DBConfig
+	LLM    LLMConfig
+	Server ServerConfig
+	Stream StreamConfig
+	Regex  RegexConfig
+	Search SearchConfig
+	RAG    RAGConfig
+	Chat   ChatConfig
+	Jobs   JobsConfig
+	Dedup  DedupConfig
+}
+
+type DBConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	Migrate  bool // apply pending schema migrations at startup
+}
+
+type LLMConfig struct {
+	Host    string
+	Port    int
+	URLs    []string // ordered fallback list; overrides Host/Port when set
+	Balance bool     // rotate requests across healthy URLs
+}
+
+type ServerConfig struct {
+	Port            string
+	CORS            CORSConfig
+	SecurityHeaders map[string]string // header → value, disabled headers omitted
+	IdempotencyTTL  time.Duration     // how long Idempotency-Key responses are replayed; 0 disables
+	IdempotencyKeys int               // most Idempotency-Key responses kept, least recently used evicted first
+	IdempotencyBody int               // bytes; larger responses aren't kept for replay
+	StrictBodies    bool              // reject JSON request bodies with fields the endpoint doesn't take
+	APIKeys         map[string]string // API key → tenant; /api requests without a listed key are refused
+	SingleTenant    bool              // no API keys: every caller sees every document
+}
+
+type CORSConfig struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	AllowCredentials bool
+}
+
+// Security headers sent on every response, each overridable through its
+// env var; setting one to "off" disables that header.
+var securityHeaderEnv = []struct {
+	header, env, value string
+}{
+	{"X-Content-Type-Options", "SECURITY_CONTENT_TYPE_OPTIONS", "nosniff"},
+	{"X-Frame-Options", "SECURITY_FRAME_OPTIONS", "DENY"},
+	{"X-XSS-Protection", "SECURITY_XSS_PROTECTION", "1; mode=block"},
+	{"Referrer-Policy", "SECURITY_REFERRER_POLICY", "no-referrer"},
+}
+
+func loadSecurityHeaders() map[string]string {
+	headers := make(map[string]string)
+	for _, h := range securityHeaderEnv {
+		if val := getEnv(h.env, h.value); !strings.EqualFold(val, "off") {
+			headers[h.header] = val
+		}
+	}
+	return headers
+}
+
+// StreamConfig controls how chat answers are chunked over SSE
+type StreamConfig struct {
+	ChunkWords int           // words per chunk when not splitting on sentences
+	ChunkDelay time.Duration // pause between chunks, zero disables pacing
+	Sentences  bool          // emit one chunk per sentence
+
+	// Reasoning traces dropped from chat answers, streamed or not, and
+	// from the session history; off by default
+	ThinkFilter   bool
+	ThinkMarkers  []string // "open|close" pairs
+	ThinkPrefixes []string // leading lines starting with these are dropped
+}
+
+// RegexConfig bounds the regex extraction endpoints
+type RegexConfig struct {
+	MaxTextLength  int           // bytes; larger payloads are rejected with 413
+	MaxUploadSize  int           // bytes; cap for streamed file extraction
+	MaxPerPattern  int           // matches returned per pattern by /api/regex/extract; 0 for all
+	UserTimeout    time.Duration // deadline for running user-supplied patterns
+	Metrics        bool          // record per-pattern timings in FindAll
+	ConfidenceFile string        // JSON pattern → confidence overrides, e.g. from /api/regex/calibrate
+	PatternsFile   string        // JSON pattern → enabled; disabled patterns never match
+	BinaryInput    string        // reject, extract (printable text only) or allow input that sniffs as binary
+}
+
+type SearchConfig struct {
+	FilterStopwords bool    // drop stopwords from the OR-expanded FTS query
+	EntityMerge     string  // confidence rule for entity upserts: max or latest
+	DefaultLimit    int     // results returned when a request sets no limit
+	MaxLimit        int     // cap on any requested limit
+	SynonymsFile    string  // JSON alias groups per language, expanded into OR groups
+	MaxSynonyms     int     // aliases added per query term
+	MaxQueryTerms   int     // cap on the synonym-expanded query
+	LanguageBoost   float64 // rank multiplier for documents in the query's language
+
+	// Composite scoring of RAG sources
+	RankWeight      float64
+	RecencyWeight   float64
+	CoverageWeight  float64 // query-term coverage; SEARCH_ENTITY_WEIGHT is its old name
+	FeedbackWeight  float64
+	RecencyHalfLife time.Duration
+}
+
+type RAGConfig struct {
+	PostProcess  []string // answer transformers, applied in order
+	MessagesFile string   // JSON canned answers by language, over the built-in en/fr ones
+	Language     string   // canned answer language when the query's can't be detected
+	Suggestions  int      // max suggested follow-up queries per answer; 0 for none
+	Injection    string   // off, delimit or strip: how retrieved documents are guarded in the LLM context
+	Wrapper      string   // "open|close" markers fencing each document; {n} and {title} are filled in
+}
+
+type ChatConfig struct {
+	OutputGuard        string // off, warn or redact sensitive data in answers
+	StreamRedactWindow int    // bytes held back when redacting streamed answers
+}
+
+// DedupConfig controls duplicate detection on document insert
+type DedupConfig struct {
+	Policy       string // off, warn or skip
+	NearDistance int    // simhash bits apart still counted as a near duplicate, at most 3; 0 for exact only
+}
+
+// JobsConfig sizes the background extraction queue
+type JobsConfig struct {
+	Workers   int
+	QueueSize int
+}
+
+func Load() *Config {
+	return &Config{
+		DB: DBConfig{
+			Host:     getEnv("DB_HOST", "localhost"),
+			Port:     getEnvInt("DB_PORT", 5432),
+			User:     getEnv("DB_USER", "hybridcore"),
+			Password: getEnv("DB_PASS", "hc_secure_2026!"),
+			Name:     getEnv("DB_NAME", "hybridcore"),
+			Migrate:  getEnvBool("DB_MIGRATE", true),
+		},
+		LLM: LLMConfig{
+			Host:    getEnv("LLM_HOST", "127.0.0.1"),
+			Port:    getEnvInt("LLM_PORT", 8001),
+			URLs:    getEnvList("LLM_URLS", nil),
+			Balance: getEnvBool("LLM_BALANCE", false),
+		},
+		Server: ServerConfig{
+			Port: getEnv("PORT", "8080"),
+			CORS: CORSConfig{
+				AllowOrigins:     getEnvList("CORS_ALLOW_ORIGINS", []string{"*"}),
+				AllowMethods:     getEnvList("CORS_ALLOW_METHODS", []string{"GET", "POST", "HEAD", "OPTIONS"}),
+				AllowHeaders:     getEnvList("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key"}),
+				AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+			},
+			SecurityHeaders: loadSecurityHeaders(),
+			IdempotencyTTL:  getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			IdempotencyKeys: getEnvInt("IDEMPOTENCY_MAX_KEYS", 10000),
+			IdempotencyBody: getEnvInt("IDEMPOTENCY_MAX_BODY", 64<<10),
+			StrictBodies:    getEnvBool("STRICT_REQUEST_BODIES", false),
+			APIKeys:         getEnvMap("API_KEYS"),
+			SingleTenant:    getEnvBool("SINGLE_TENANT", false),
+		},
+		Stream: StreamConfig{
+			ChunkWords: getEnvInt("STREAM_CHUNK_WORDS", 5),
+			ChunkDelay: getEnvDuration("STREAM_CHUNK_DELAY", 50*time.Millisecond),
+			Sentences:  getEnvBool("STREAM_SENTENCES", false),
+
+			ThinkFilter:   getEnvBool("STREAM_THINK_FILTER", false),
+			ThinkMarkers:  getEnvList("STREAM_THINK_MARKERS", []string{"<think>|</think>"}),
+			ThinkPrefixes: getEnvList("STREAM_THINK_PREFIXES", []string{"Thinking:"}),
+		},
+		Regex: RegexConfig{
+			MaxTextLength:  getEnvInt("REGEX_MAX_TEXT_LENGTH", 1<<20),
+			MaxUploadSize:  getEnvInt("REGEX_MAX_UPLOAD_SIZE", 256<<20),
+			MaxPerPattern:  getEnvInt("REGEX_MAX_PER_PATTERN", 0),
+			UserTimeout:    getEnvDuration("REGEX_USER_TIMEOUT", 2*time.Second),
+			Metrics:        getEnvBool("REGEX_METRICS", false),
+			ConfidenceFile: getEnv("REGEX_CONFIDENCE_FILE", ""),
+			PatternsFile:   getEnv("REGEX_PATTERNS_FILE", ""),
+			BinaryInput:    getEnv("REGEX_BINARY_INPUT", "reject"),
+		},
+		Search: SearchConfig{
+			FilterStopwords: getEnvBool("SEARCH_FILTER_STOPWORDS", true),
+			EntityMerge:     getEnv("ENTITY_MERGE", "max"),
+			DefaultLimit:    getEnvInt("SEARCH_DEFAULT_LIMIT", 10),
+			MaxLimit:        getEnvInt("SEARCH_MAX_LIMIT", 100),
+			SynonymsFile:    getEnv("SEARCH_SYNONYMS_FILE", ""),
+			MaxSynonyms:     getEnvInt("SEARCH_MAX_SYNONYMS", 3),
+			MaxQueryTerms:   getEnvInt("SEARCH_MAX_QUERY_TERMS", 24),
+			LanguageBoost:   getEnvFloat("SEARCH_LANGUAGE_BOOST", 1.5),
+			RankWeight:      getEnvFloat("SEARCH_RANK_WEIGHT", 0.4),
+			RecencyWeight:   getEnvFloat("SEARCH_RECENCY_WEIGHT", 0.3),
+			CoverageWeight:  getEnvFloat("SEARCH_COVERAGE_WEIGHT", getEnvFloat("SEARCH_ENTITY_WEIGHT", 0.3)),
+			FeedbackWeight:  getEnvFloat("SEARCH_FEEDBACK_WEIGHT", 0.3),
+			RecencyHalfLife: getEnvDuration("SEARCH_RECENCY_HALF_LIFE", 180*24*time.Hour),
+		},
+		RAG: RAGConfig{
+			PostProcess:  getEnvList("RAG_POSTPROCESS", nil),
+			MessagesFile: getEnv("RAG_MESSAGES_FILE", ""),
+			Language:     getEnv("RAG_LANGUAGE", "fr"),
+			Suggestions:  getEnvInt("RAG_MAX_SUGGESTIONS", 3),
+			Injection:    getEnv("RAG_INJECTION_POLICY", "delimit"),
+			Wrapper:      getEnv("RAG_CONTEXT_WRAPPER", ""),
+		},
+		Chat: ChatConfig{
+			OutputGuard:        getEnv("CHAT_OUTPUT_GUARD", "off"),
+			StreamRedactWindow: getEnvInt("CHAT_STREAM_REDACT_WINDOW", 256),
+		},
+		Jobs: JobsConfig{
+			Workers:   getEnvInt("JOB_WORKERS", 2),
+			QueueSize: getEnvInt("JOB_QUEUE_SIZE", 100),
+		},
+		Dedup: DedupConfig{
+			Policy:       getEnv("DEDUP_POLICY", "warn"),
+			NearDistance: getEnvInt("DEDUP_NEAR_DISTANCE", 3),
+		},
+	}
+}
+
+func getEnv(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	if val := os.Getenv(key); val != "" {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+	return defaultVal
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+// getEnvDuration accepts Go durations ("250ms", "1s") or a bare number of milliseconds
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+		if ms, err := strconv.Atoi(val); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return defaultVal
+}
+
+func getEnvList(key string, defaultVal []string) []string {
+	if val := os.Getenv(key); val != "" {
+		var items []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultVal
+}
+
+// getEnvMap reads comma-separated key=value pairs, skipping malformed ones
+func getEnvMap(key string) map[string]string {
+	items := make(map[string]string)
+	for _, item := range getEnvList(key, nil) {
+		k, v, ok := strings.Cut(item, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); ok && k != "" && v != "" {
+			items[k] = v
+		}
+	}
+	return items
+}
@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hybridcore/internal/nlp"
+)
+
+// DedupPolicy decides what inserting a duplicate of an existing document does
+type DedupPolicy string
+
+const (
+	DedupOff  DedupPolicy = "off"  // don't look for duplicates
+	DedupWarn DedupPolicy = "warn" // insert anyway, reporting the duplicate
+	DedupSkip DedupPolicy = "skip" // don't insert, return the existing document
+)
+
+// Dedup is the policy StoreDocument and IngestDocument apply, and
+// NearDuplicateDistance the simhash distance (in bits) up to which two
+// documents count as near duplicates; 0 only catches exact copies. Set
+// from config at startup.
+var (
+	Dedup                 = DedupWarn
+	NearDuplicateDistance = MaxNearDuplicateDistance
+)
+
+// MaxNearDuplicateDistance is the furthest near duplicates are looked for:
+// fingerprints this close share one of their four 16-bit bands, which is
+// what the band indexes look up
+const MaxNearDuplicateDistance = 3
+
+// ParseDedupPolicy validates a dedup policy name
+func ParseDedupPolicy(s string) (DedupPolicy, bool) {
+	switch p := DedupPolicy(s); p {
+	case DedupOff, DedupWarn, DedupSkip:
+		return p, true
+	}
+	return "", false
+}
+
+// Duplicate is an existing document matching new content
+type Duplicate struct {
+	Document *Document `json:"document"`
+	Exact    bool      `json:"exact"`
+	Distance int       `json:"distance"` // simhash bits apart; 0 when exact
+}
+
+// ContentHash fingerprints content for exact-duplicate detection; runs of
+// whitespace are collapsed so re-wrapped copies still match. It is the
+// only place content is normalized for hashing: stored hashes all come
+// from here, never from SQL.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(content), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// simhashBands splits a simhash into the four 16-bit bands stored in
+// simhash_b0..simhash_b3
+func simhashBands(h uint64) [4]int {
+	return [4]int{int(h >> 48 & 0xffff), int(h >> 32 & 0xffff), int(h >> 16 & 0xffff), int(h & 0xffff)}
+}
+
+// simhashDistance is the bit count of the XOR of a's and b's simhash,
+// portable to Postgres versions without bit_count
+func simhashDistance(a, b string) string {
+	return "length(replace(((" + a + ".simhash # " + b + ".simhash)::bit(64))::text, '0', ''))"
+}
+
+// duplicateOf is the condition for documents a and b being copies of each
+// other: the same content hash or, through a shared band, a simhash within
+// NearDuplicateDistance
+func duplicateOf(q *queryBuilder, a, b string) string {
+	cond := a + ".content_hash = " + b + ".content_hash"
+	if NearDuplicateDistance > 0 {
+		cond += " OR ((" + a + ".simhash_b0 = " + b + ".simhash_b0 OR " + a + ".simhash_b1 = " + b + ".simhash_b1 OR " +
+			a + ".simhash_b2 = " + b + ".simhash_b2 OR " + a + ".simhash_b3 = " + b + ".simhash_b3) AND " +
+			simhashDistance(a, b) + " <= " + q.Arg(NearDuplicateDistance) + ")"
+	}
+	return "(" + cond + ")"
+}
+
+// visibleTo is Filter{Owner: owner} for a documents alias other than d
+func visibleTo(q *queryBuilder, alias, owner string) string {
+	if owner == "" {
+		return "TRUE"
+	}
+	return "(" + alias + ".owner IS NULL OR " + alias + ".owner = " + q.Arg(owner) + ")"
+}
+
+const duplicateColumns = `d.id, d.doc_id, d.filename, d.title, d.word_count,
+			COALESCE(d.owner, '') as owner, d.language, d.created_at`
+
+// FindDuplicate looks for a document visible to owner with the same
+// content, or failing that the closest one within NearDuplicateDistance.
+// It returns nil when there is none.
+func FindDuplicate(content, owner string) (*Duplicate, error) {
+	return findDuplicate(DB, content, owner)
+}
+
+func findDuplicate(q sqlx.Queryer, content, owner string) (*Duplicate, error) {
+	exact := &queryBuilder{}
+	exact.Where("d.content_hash = ?", ContentHash(content))
+	Filter{Owner: owner}.apply(exact)
+
+	var doc Document
+	err := sqlx.Get(q, &doc, `SELECT `+duplicateColumns+`
+		FROM documents d `+exact.WhereSQL()+` ORDER BY d.id LIMIT 1`, exact.Args()...)
+	if err == nil {
+		return &Duplicate{Document: &doc, Exact: true}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("find duplicate: %w", err)
+	}
+	if NearDuplicateDistance <= 0 {
+		return nil, nil
+	}
+
+	// A band in common narrows the candidates through the band indexes
+	near := &queryBuilder{}
+	hash := nlp.SimHash(content)
+	dist := "length(replace(((d.simhash # " + near.Arg(int64(hash)) + ")::bit(64))::text, '0', ''))"
+	bands := simhashBands(hash)
+	near.Where("(d.simhash_b0 = ? OR d.simhash_b1 = ? OR d.simhash_b2 = ? OR d.simhash_b3 = ?)",
+		bands[0], bands[1], bands[2], bands[3])
+	near.Where(dist+" <= ?", NearDuplicateDistance)
+	Filter{Owner: owner}.apply(near)
+
+	var row struct {
+		Document
+		Distance int `db:"distance"`
+	}
+	err = sqlx.Get(q, &row, `SELECT `+duplicateColumns+`, `+dist+` as distance
+		FROM documents d `+near.WhereSQL()+` ORDER BY distance, d.id LIMIT 1`, near.Args()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find near duplicate: %w", err)
+	}
+	return &Duplicate{Document: &row.Document, Distance: row.Distance}, nil
+}
+
+// StoreDocument is InsertDocument under the Dedup policy: with DedupSkip a
+// duplicate is returned instead of inserted; with DedupWarn the document
+// is inserted and the duplicate returned alongside it.
+func StoreDocument(filename, title, content, owner string) (*Document, *Duplicate, error) {
+	var dup *Duplicate
+	if Dedup != DedupOff {
+		var err error
+		if dup, err = findDuplicate(DB, content, owner); err != nil {
+			return nil, nil, err
+		}
+		if dup != nil && Dedup == DedupSkip {
+			return dup.Document, dup, nil
+		}
+	}
+	doc, err := InsertDocument(filename, title, content, owner)
+	return doc, dup, err
+}
+
+// DuplicateCluster groups documents that are copies or near copies of
+// the oldest of them
+type DuplicateCluster struct {
+	Exact       bool       `json:"exact"`        // every member has the same content
+	MaxDistance int        `json:"max_distance"` // furthest member from the oldest, in simhash bits
+	Documents   []Document `json:"documents"`
+}
+
+// ListDuplicates lists, a page at a time, clusters of the documents
+// visible to owner: a document with no older copy, followed by its newer
+// copies (same content hash, or a simhash within NearDuplicateDistance).
+// Near matches don't chain, so a copy of a copy that is too far from the
+// cluster's first document isn't listed. Every lookup goes through the
+// content hash or band indexes, so a page costs the same however large
+// the corpus. page.After is the id of the last cluster's first document.
+func ListDuplicates(owner string, page Page) (clusters []DuplicateCluster, more bool, err error) {
+	q := &queryBuilder{}
+	Filter{Owner: owner}.apply(q)
+	if page.After > 0 {
+		q.Where("d.id > ?", page.After)
+	}
+	q.Where("EXISTS (SELECT 1 FROM documents o WHERE o.id > d.id AND " +
+		visibleTo(q, "o", owner) + " AND " + duplicateOf(q, "d", "o") + ")")
+	q.Where("NOT EXISTS (SELECT 1 FROM documents o WHERE o.id < d.id AND " +
+		visibleTo(q, "o", owner) + " AND " + duplicateOf(q, "d", "o") + ")")
+
+	tail := ""
+	if page.Limit > 0 {
+		tail = " LIMIT " + q.Arg(page.Limit+1)
+	}
+	var seeds []Document
+	err = DB.Select(&seeds, `SELECT `+duplicateColumns+`
+		FROM documents d `+q.WhereSQL()+` ORDER BY d.id`+tail, q.Args()...)
+	if err != nil {
+		return nil, false, fmt.Errorf("list duplicates: %w", err)
+	}
+	if page.Limit > 0 && len(seeds) > page.Limit {
+		seeds, more = seeds[:page.Limit], true
+	}
+	if len(seeds) == 0 {
+		return []DuplicateCluster{}, false, nil
+	}
+
+	ids := make([]int64, len(seeds))
+	for i, seed := range seeds {
+		ids[i] = int64(seed.ID)
+	}
+	m := &queryBuilder{}
+	m.Where("s.id = ANY(?)", pq.Array(ids))
+	m.Where("d.id > s.id")
+	Filter{Owner: owner}.apply(m)
+	m.Where(duplicateOf(m, "s", "d"))
+
+	var rows []struct {
+		Document
+		SeedID   int  `db:"seed_id"`
+		Exact    bool `db:"exact"`
+		Distance int  `db:"distance"`
+	}
+	err = DB.Select(&rows, `SELECT `+duplicateColumns+`, s.id as seed_id,
+			COALESCE(d.content_hash = s.content_hash, false) as exact,
+			COALESCE(`+simhashDistance("s", "d")+`, 0) as distance
+		FROM documents s, documents d `+m.WhereSQL()+` ORDER BY s.id, d.id`, m.Args()...)
+	if err != nil {
+		return nil, false, fmt.Errorf("list duplicate members: %w", err)
+	}
+
+	bySeed := make(map[int]*DuplicateCluster, len(seeds))
+	clusters = make([]DuplicateCluster, len(seeds))
+	for i, seed := range seeds {
+		clusters[i] = DuplicateCluster{Exact: true, Documents: []Document{seed}}
+		bySeed[seed.ID] = &clusters[i]
+	}
+	for _, r := range rows {
+		c := bySeed[r.SeedID]
+		if !r.Exact {
+			c.Exact = false
+			if r.Distance > c.MaxDistance {
+				c.MaxDistance = r.Distance
+			}
+		}
+		c.Documents = append(c.Documents, r.Document)
+	}
+	return clusters, more, nil
+}
+
+// BackfillFingerprints computes the content hash and simhash of documents
+// stored without one, a batch at a time, and returns how many it filled
+func BackfillFingerprints(ctx context.Context) (int, error) {
+	const batch = 500
+	filled := 0
+	for {
+		var docs []struct {
+			ID      int    `db:"id"`
+			Content string `db:"content"`
+		}
+		err := DB.SelectContext(ctx, &docs, `SELECT id, content FROM documents
+			WHERE content_hash IS NULL OR simhash IS NULL ORDER BY id LIMIT $1`, batch)
+		if err != nil {
+			return filled, fmt.Errorf("backfill fingerprints: %w", err)
+		}
+		for _, d := range docs {
+			_, err := DB.ExecContext(ctx, `UPDATE documents SET content_hash = $1, simhash = $2 WHERE id = $3`,
+				ContentHash(d.Content), int64(nlp.SimHash(d.Content)), d.ID)
+			if err != nil {
+				return filled, fmt.Errorf("backfill fingerprints: document %d: %w", d.ID, err)
+			}
+			filled++
+		}
+		if len(docs) < batch {
+			return filled, nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Fingerprints within MaxNearDuplicateDistance must share a band, or the
+// band indexes would miss them
+func TestSimhashBandsCoverMaxDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		a := rng.Uint64()
+		b := a
+		for _, bit := range rng.Perm(64)[:MaxNearDuplicateDistance] {
+			b ^= 1 << uint(bit)
+		}
+
+		ba, bb := simhashBands(a), simhashBands(b)
+		shared := false
+		for k := range ba {
+			shared = shared || ba[k] == bb[k]
+		}
+		if !shared {
+			t.Fatalf("%016x and %016x are %d bits apart but share no band", a, b, MaxNearDuplicateDistance)
+		}
+	}
+}
+
+func TestSimhashBandsMatchSQL(t *testing.T) {
+	// The generated columns in 009_simhash_bands.sql shift the signed
+	// bigint and mask 16 bits; a high bit set must not leak into b0
+	got := simhashBands(0xfedc_ba98_7654_3210)
+	want := [4]int{0xfedc, 0xba98, 0x7654, 0x3210}
+	if got != want {
+		t.Errorf("bands = %x, want %x", got, want)
+	}
+}
+
+func TestContentHashCollapsesWhitespace(t *testing.T) {
+	if ContentHash("a  b\n\tc d ") != ContentHash("a b c d") {
+		t.Error("re-wrapped copies hash differently")
+	}
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"hybridcore/internal/nlp"
+)
+
+// MergeRule decides an upserted entity's confidence when it already exists
+type MergeRule string
+
+const (
+	MergeMax    MergeRule = "max"    // keep the higher confidence
+	MergeLatest MergeRule = "latest" // take the incoming confidence
+)
+
+// EntityMerge is the rule UpsertEntities applies; set from config at startup
+var EntityMerge = MergeMax
+
+// ParseMergeRule validates a merge rule name
+func ParseMergeRule(s string) (MergeRule, bool) {
+	switch r := MergeRule(s); r {
+	case MergeMax, MergeLatest:
+		return r, true
+	}
+	return "", false
+}
+
+// FindEntities returns graph entities whose name matches value
+// case-insensitively, entities of the given type first
+func FindEntities(value, entityType string) ([]Entity, error) {
+	var entities []Entity
+	err := DB.Select(&entities, `
+		SELECT id, name, type, confidence
+		FROM entities
+		WHERE lower(name) = lower($1)
+		ORDER BY (type = $2) DESC, confidence DESC
+		LIMIT 20`, value, entityType)
+	return entities, err
+}
+
+// EntityEdges returns every edge touching one of the given entities
+func EntityEdges(ids []int) ([]Edge, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var edges []Edge
+	err := DB.Select(&edges, `
+		SELECT id, from_entity_id, to_entity_id, relationship, weight
+		FROM edges
+		WHERE from_entity_id = ANY($1) OR to_entity_id = ANY($1)
+		ORDER BY weight DESC
+		LIMIT 200`, pq.Array(ids))
+	return edges, err
+}
+
+// GetEntities loads entities by ID
+func GetEntities(ids []int) ([]Entity, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var entities []Entity
+	err := DB.Select(&entities, `
+		SELECT id, name, type, confidence
+		FROM entities
+		WHERE id = ANY($1)
+		ORDER BY confidence DESC`, pq.Array(ids))
+	return entities, err
+}
+
+// entityKey is the dedup key: case-insensitive name within a type. It
+// mirrors the unique index on entities (lower(name), type).
+func entityKey(name, entityType string) string {
+	return entityType + "\x00" + strings.ToLower(name)
+}
+
+// UpsertEntities inserts or merges entities in one statement, keyed on
+// (lower(name), type), and returns the stored entity for each input in
+// input order (duplicates resolve to the same row). Names are trimmed and
+// whitespace-folded; confidences merge per EntityMerge.
+func UpsertEntities(entities []Entity) ([]Entity, error) {
+	return upsertEntities(DB, entities)
+}
+
+func upsertEntities(q sqlx.Queryer, entities []Entity) ([]Entity, error) {
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	// Dedupe within the batch: Postgres rejects an upsert touching the
+	// same row twice
+	var names, types []string
+	var confidences []float64
+	index := make(map[string]int)
+	keys := make([]string, len(entities))
+
+	for i, e := range entities {
+		name := strings.Join(strings.Fields(e.Name), " ")
+		if name == "" || e.Type == "" {
+			return nil, fmt.Errorf("entity %d: name and type required", i)
+		}
+		key := entityKey(name, e.Type)
+		keys[i] = key
+
+		if j, seen := index[key]; seen {
+			if EntityMerge == MergeLatest || e.Confidence > confidences[j] {
+				confidences[j] = e.Confidence
+			}
+			continue
+		}
+		index[key] = len(names)
+		names = append(names, name)
+		types = append(types, e.Type)
+		confidences = append(confidences, e.Confidence)
+	}
+
+	merge := "GREATEST(entities.confidence, EXCLUDED.confidence)"
+	if EntityMerge == MergeLatest {
+		merge = "EXCLUDED.confidence"
+	}
+
+	var stored []Entity
+	err := sqlx.Select(q, &stored, `
+		INSERT INTO entities (name, type, confidence)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::float8[])
+		ON CONFLICT ((lower(name)), type) DO UPDATE SET confidence = `+merge+`
+		RETURNING id, name, type, confidence`,
+		pq.Array(names), pq.Array(types), pq.Array(confidences))
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]Entity, len(stored))
+	for _, e := range stored {
+		byKey[entityKey(e.Name, e.Type)] = e
+	}
+
+	out := make([]Entity, len(entities))
+	for i, key := range keys {
+		out[i] = byKey[key]
+	}
+	return out, nil
+}
+
+// EntitiesFromNamed maps extracted entities onto graph entities
+func EntitiesFromNamed(named []nlp.NamedEntity) []Entity {
+	out := make([]Entity, len(named))
+	for i, n := range named {
+		out[i] = Entity{Name: n.Value, Type: n.Type, Confidence: n.Confidence}
+	}
+	return out
+}
+
+// symmetricRelationships don't depend on direction; their edges are
+// stored with the lower entity ID first so A→B and B→A are one edge
+var symmetricRelationships = map[string]bool{RelCooccurs: true}
+
+// edgeKey is the dedup key of an edge, mirroring the unique index on
+// edges (from_entity_id, to_entity_id, relationship)
+type edgeKey struct {
+	from, to     int
+	relationship string
+}
+
+// normalizeEdge lowercases and trims the relationship and orders the
+// endpoints of symmetric ones
+func normalizeEdge(e Edge) edgeKey {
+	k := edgeKey{e.FromEntityID, e.ToEntityID, strings.ToLower(strings.TrimSpace(e.Relationship))}
+	if symmetricRelationships[k.relationship] && k.from > k.to {
+		k.from, k.to = k.to, k.from
+	}
+	return k
+}
+
+// UpsertEdges adds edges to the graph, merging each into the existing edge
+// with the same normalized key by adding its weight. Every edge is claimed
+// for source (e.g. the content hash of the document it came from) before
+// its weight counts, so replaying a write for the same source, after a
+// retry or a re-ingest, changes nothing. It reports how many edges were
+// created and how many existing ones gained weight.
+func UpsertEdges(edges []Edge, source string) (created, updated int, err error) {
+	return upsertEdges(DB, edges, source)
+}
+
+func upsertEdges(q sqlx.Queryer, edges []Edge, source string) (created, updated int, err error) {
+	if len(edges) == 0 {
+		return 0, 0, nil
+	}
+	if source == "" {
+		return 0, 0, fmt.Errorf("edges: source required")
+	}
+
+	// Dedupe within the batch, summing weights, since one statement can't
+	// touch the same row twice
+	var from, to []int
+	var relationships []string
+	var weights []float64
+	index := make(map[edgeKey]int)
+	for i, e := range edges {
+		k := normalizeEdge(e)
+		if k.from == 0 || k.to == 0 || k.relationship == "" {
+			return 0, 0, fmt.Errorf("edge %d: endpoints and relationship required", i)
+		}
+		if j, seen := index[k]; seen {
+			weights[j] += e.Weight
+			continue
+		}
+		index[k] = len(from)
+		from, to = append(from, k.from), append(to, k.to)
+		relationships = append(relationships, k.relationship)
+		weights = append(weights, e.Weight)
+	}
+
+	// Claims conflict on the source's earlier rows, which drops them from
+	// the upsert; a concurrent write of the same source waits on the claim
+	var inserted []bool
+	err = sqlx.Select(q, &inserted, `
+		WITH input AS (
+			SELECT * FROM unnest($1::int[], $2::int[], $3::text[], $4::float8[])
+				AS i(from_id, to_id, relationship, weight)
+		), claimed AS (
+			INSERT INTO edge_sources (from_entity_id, to_entity_id, relationship, source)
+			SELECT from_id, to_id, relationship, $5 FROM input
+			ON CONFLICT DO NOTHING
+			RETURNING from_entity_id, to_entity_id, relationship
+		)
+		INSERT INTO edges (from_entity_id, to_entity_id, relationship, weight)
+		SELECT i.from_id, i.to_id, i.relationship, i.weight
+		FROM input i
+		JOIN claimed c ON c.from_entity_id = i.from_id AND c.to_entity_id = i.to_id
+			AND c.relationship = i.relationship
+		ON CONFLICT (from_entity_id, to_entity_id, relationship)
+			DO UPDATE SET weight = edges.weight + EXCLUDED.weight
+		RETURNING (xmax = 0) AS inserted`,
+		pq.Array(from), pq.Array(to), pq.Array(relationships), pq.Array(weights), source)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, ins := range inserted {
+		if ins {
+			created++
+		} else {
+			updated++
+		}
+	}
+	return created, updated, nil
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var errCaptured = errors.New("captured")
+
+// captureQueryer records the arguments of the statement it is handed
+// instead of running it, to check what a batch upsert would send
+type captureQueryer struct{ args []interface{} }
+
+func (c *captureQueryer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	c.args = args
+	return nil, errCaptured
+}
+
+func (c *captureQueryer) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	c.args = args
+	return nil, errCaptured
+}
+
+func (c *captureQueryer) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	c.args = args
+	return nil
+}
+
+func TestNormalizeEdge(t *testing.T) {
+	tests := []struct {
+		edge Edge
+		want edgeKey
+	}{
+		{Edge{FromEntityID: 9, ToEntityID: 4, Relationship: RelCooccurs}, edgeKey{4, 9, RelCooccurs}},
+		{Edge{FromEntityID: 4, ToEntityID: 9, Relationship: " CO_OCCURS "}, edgeKey{4, 9, RelCooccurs}},
+		// Directed relationships keep their direction
+		{Edge{FromEntityID: 9, ToEntityID: 4, Relationship: "Sent_To"}, edgeKey{9, 4, "sent_to"}},
+	}
+	for _, tt := range tests {
+		if got := normalizeEdge(tt.edge); got != tt.want {
+			t.Errorf("normalizeEdge(%+v) = %+v, want %+v", tt.edge, got, tt.want)
+		}
+	}
+}
+
+// The same co-occurrence seen twice in one batch, in either direction, is
+// sent as one row carrying both weights
+func TestUpsertEdgesMergesBatchDuplicates(t *testing.T) {
+	var q captureQueryer
+	_, _, err := upsertEdges(&q, []Edge{
+		{FromEntityID: 1, ToEntityID: 2, Relationship: RelCooccurs, Weight: 1},
+		{FromEntityID: 2, ToEntityID: 1, Relationship: RelCooccurs, Weight: 1},
+		{FromEntityID: 2, ToEntityID: 1, Relationship: "sent_to", Weight: 0.5},
+	}, "doc-hash")
+	if !errors.Is(err, errCaptured) {
+		t.Fatalf("err = %v", err)
+	}
+
+	from := q.args[0].(pq.GenericArray).A.([]int)
+	to := q.args[1].(pq.GenericArray).A.([]int)
+	rels := *q.args[2].(*pq.StringArray)
+	weights := *q.args[3].(*pq.Float64Array)
+	if len(from) != 2 {
+		t.Fatalf("sent %d rows, want 2: %v %v %v %v", len(from), from, to, rels, weights)
+	}
+	if from[0] != 1 || to[0] != 2 || rels[0] != RelCooccurs || weights[0] != 2 {
+		t.Errorf("co-occurrence row = %d→%d %s %v, want 1→2 co_occurs 2", from[0], to[0], rels[0], weights[0])
+	}
+	if from[1] != 2 || to[1] != 1 || rels[1] != "sent_to" || weights[1] != 0.5 {
+		t.Errorf("directed row = %d→%d %s %v, want 2→1 sent_to 0.5", from[1], to[1], rels[1], weights[1])
+	}
+	if q.args[4] != "doc-hash" {
+		t.Errorf("source = %v", q.args[4])
+	}
+}
+
+func TestUpsertEdgesRejectsIncompleteEdges(t *testing.T) {
+	var q captureQueryer
+	if _, _, err := upsertEdges(&q, []Edge{{FromEntityID: 1, ToEntityID: 2, Relationship: RelCooccurs}}, ""); err == nil {
+		t.Error("accepted edges without a source")
+	}
+	if _, _, err := upsertEdges(&q, []Edge{{FromEntityID: 1, Relationship: RelCooccurs}}, "s"); err == nil {
+		t.Error("accepted an edge without a target")
+	}
+	if q.args != nil {
+		t.Error("rejected batch still reached the database")
+	}
+}
+
+func TestUpsertEdgesDedupesBySource(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := UpsertEntities([]Entity{{Name: "Alice", Type: "person"}, {Name: "Bob", Type: "person"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := stored[0].ID, stored[1].ID
+	cooccur := func(from, to int) []Edge {
+		return []Edge{{FromEntityID: from, ToEntityID: to, Relationship: RelCooccurs, Weight: 1}}
+	}
+
+	steps := []struct {
+		name             string
+		edges            []Edge
+		source           string
+		created, updated int
+		weight           float64
+	}{
+		{"first document", cooccur(a, b), "doc-1", 1, 0, 1},
+		{"replay of the first document", cooccur(a, b), "doc-1", 0, 0, 1},
+		{"second document, reversed", cooccur(b, a), "doc-2", 0, 1, 2},
+	}
+	for _, step := range steps {
+		created, updated, err := UpsertEdges(step.edges, step.source)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if created != step.created || updated != step.updated {
+			t.Errorf("%s: created, updated = %d, %d, want %d, %d", step.name, created, updated, step.created, step.updated)
+		}
+		edges, err := EntityEdges([]int{a})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edges) != 1 || edges[0].Weight != step.weight {
+			t.Errorf("%s: edges = %+v, want one of weight %v", step.name, edges, step.weight)
+		}
+	}
+}
+
+// Case and whitespace variants of one entity in a batch are sent once,
+// with the confidence EntityMerge picks
+func TestUpsertEntitiesMergesBatchDuplicates(t *testing.T) {
+	defer func(rule MergeRule) { EntityMerge = rule }(EntityMerge)
+	batch := []Entity{
+		{Name: "Alice  Martin", Type: "person", Confidence: 0.9},
+		{Name: "Acme", Type: "org", Confidence: 0.5},
+		{Name: "alice martin", Type: "person", Confidence: 0.6},
+		{Name: "Alice Martin", Type: "org", Confidence: 0.4},
+	}
+
+	for _, tt := range []struct {
+		rule  MergeRule
+		alice float64
+	}{
+		{MergeMax, 0.9},
+		{MergeLatest, 0.6},
+	} {
+		EntityMerge = tt.rule
+		var q captureQueryer
+		if _, err := upsertEntities(&q, batch); !errors.Is(err, errCaptured) {
+			t.Fatalf("%s: err = %v", tt.rule, err)
+		}
+
+		names := *q.args[0].(*pq.StringArray)
+		types := *q.args[1].(*pq.StringArray)
+		confidences := *q.args[2].(*pq.Float64Array)
+		if len(names) != 3 {
+			t.Fatalf("%s: sent %v %v, want 3 distinct entities", tt.rule, names, types)
+		}
+		if names[0] != "Alice Martin" || types[0] != "person" || confidences[0] != tt.alice {
+			t.Errorf("%s: first row = %s/%s %v, want Alice Martin/person %v", tt.rule, names[0], types[0], confidences[0], tt.alice)
+		}
+		if names[2] != "Alice Martin" || types[2] != "org" {
+			t.Errorf("%s: same name under another type merged: %v %v", tt.rule, names, types)
+		}
+	}
+}
+
+func TestUpsertEntitiesResolvesDuplicatesToOneRow(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func(rule MergeRule) { EntityMerge = rule }(EntityMerge)
+	EntityMerge = MergeMax
+
+	stored, err := UpsertEntities([]Entity{
+		{Name: "Alice Martin", Type: "person", Confidence: 0.9},
+		{Name: "ALICE MARTIN", Type: "person", Confidence: 0.6},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].ID != stored[1].ID || stored[0].Confidence != 0.9 {
+		t.Fatalf("stored = %+v, want both inputs on one row at 0.9", stored)
+	}
+
+	// Across batches the rule applies against the stored row
+	EntityMerge = MergeLatest
+	again, err := UpsertEntities([]Entity{{Name: "alice martin", Type: "person", Confidence: 0.3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].ID != stored[0].ID || again[0].Confidence != 0.3 {
+		t.Errorf("latest merge = %+v, want row %d at 0.3", again[0], stored[0].ID)
+	}
+}
+
+func TestCooccurrencePairs(t *testing.T) {
+	for n, want := range map[int]int{0: 0, 1: 0, 2: 1, 3: 3, maxCooccurrenceEntities: 1225, 80: 1225} {
+		if got := len(CooccurrencePairs(n)); got != want {
+			t.Errorf("CooccurrencePairs(%d): %d pairs, want %d", n, got, want)
+		}
+	}
+	if got := CooccurrencePairs(3); fmt.Sprint(got) != "[[0 1] [0 2] [1 2]]" {
+		t.Errorf("CooccurrencePairs(3) = %v", got)
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// feedbackPrior is how many neutral votes each document starts with, so a
+// single click moves its score a little rather than to ±1
+const feedbackPrior = 2
+
+// FeedbackKey normalizes a query so identical and similar queries share
+// feedback: the lowercased meaningful terms, deduplicated and sorted, so
+// "Robert emails" and "emails robert" count as one
+func FeedbackKey(query string) string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range QueryTerms(query) {
+		t = strings.ToLower(t)
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, " ")
+}
+
+// RecordFeedback stores one relevant/irrelevant vote for docID on query.
+// Feedback is kept per tenant (owner, empty in single-tenant mode).
+func RecordFeedback(query, docID string, relevant bool, owner string) error {
+	_, err := DB.Exec(`
+		INSERT INTO search_feedback (query, query_key, doc_id, relevant, owner)
+		VALUES ($1, $2, $3, $4, $5)`,
+		query, FeedbackKey(query), docID, relevant, owner)
+	return err
+}
+
+// FeedbackScores aggregates the tenant's votes on query for the given
+// documents into a score in (-1, 1): positive when mostly marked relevant,
+// negative when mostly irrelevant. Documents without votes are absent.
+func FeedbackScores(query, owner string, docIDs []string) (map[string]float64, error) {
+	key := FeedbackKey(query)
+	if key == "" || len(docIDs) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	var rows []struct {
+		DocID string `db:"doc_id"`
+		Up    int    `db:"up"`
+		Down  int    `db:"down"`
+	}
+	err := DB.Select(&rows, `
+		SELECT doc_id,
+			COUNT(*) FILTER (WHERE relevant) AS up,
+			COUNT(*) FILTER (WHERE NOT relevant) AS down
+		FROM search_feedback
+		WHERE query_key = $1 AND owner = $2 AND doc_id = ANY($3)
+		GROUP BY doc_id`, key, owner, pq.Array(docIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		scores[r.DocID] = feedbackScore(r.Up, r.Down)
+	}
+	return scores, nil
+}
+
+// feedbackScore weighs up and down votes against feedbackPrior neutral ones
+func feedbackScore(up, down int) float64 {
+	return float64(up-down) / float64(up+down+feedbackPrior)
+}
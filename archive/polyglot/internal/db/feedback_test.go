@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestFeedbackKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Robert emails", "emails robert", true},
+		{"robert robert emails", "EMAILS Robert", true},
+		{"the emails of Robert", "robert emails", true},
+		{"robert emails", "robert invoices", false},
+	}
+	for _, tt := range tests {
+		a, b := FeedbackKey(tt.a), FeedbackKey(tt.b)
+		if (a == b) != tt.same {
+			t.Errorf("FeedbackKey(%q) = %q, FeedbackKey(%q) = %q, want same = %v", tt.a, a, tt.b, b, tt.same)
+		}
+	}
+	if got := FeedbackKey("Robert emails"); got != "emails robert" {
+		t.Errorf("FeedbackKey = %q, want sorted lowercase terms", got)
+	}
+}
+
+func TestFeedbackScore(t *testing.T) {
+	tests := []struct {
+		up, down int
+		want     float64
+	}{
+		{0, 0, 0},
+		{1, 0, 1.0 / 3}, // one click moves the score a little
+		{0, 1, -1.0 / 3},
+		{3, 3, 0},
+		{8, 0, 0.8},
+		{1, 7, -0.6},
+	}
+	for _, tt := range tests {
+		if got := feedbackScore(tt.up, tt.down); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("feedbackScore(%d, %d) = %v, want %v", tt.up, tt.down, got, tt.want)
+		}
+	}
+	// However many votes, the score stays inside (-1, 1)
+	if got := feedbackScore(1000, 0); got >= 1 {
+		t.Errorf("feedbackScore(1000, 0) = %v, want below 1", got)
+	}
+}
+
+func TestFeedbackScoresPerTenant(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vote := range []struct {
+		query, doc string
+		relevant   bool
+		owner      string
+	}{
+		{"Robert emails", "d1", true, "acme"},
+		{"emails robert", "d1", true, "acme"},
+		{"robert emails", "d2", false, "acme"},
+		{"robert emails", "d2", true, "globex"},
+	} {
+		if err := RecordFeedback(vote.query, vote.doc, vote.relevant, vote.owner); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scores, err := FeedbackScores("EMAILS robert", "acme", []string{"d1", "d2", "d3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 || scores["d1"] != feedbackScore(2, 0) || scores["d2"] != feedbackScore(0, 1) {
+		t.Errorf("scores = %v, want d1 %v and d2 %v", scores, feedbackScore(2, 0), feedbackScore(0, 1))
+	}
+}
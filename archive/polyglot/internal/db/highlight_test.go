@@ -0,0 +1,27 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"hybridcore/internal/nlp"
+)
+
+func TestHighlightTermsKeepsQuotedPhrases(t *testing.T) {
+	got := HighlightTerms(`"New York" office "budget"`)
+	want := []string{"New York", "office", "budget"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HighlightTerms = %q, want %q", got, want)
+	}
+}
+
+// Search excerpts come back from ts_headline marked word by word; the
+// response marks a quoted phrase as one span instead
+func TestRehighlightMarksPhrases(t *testing.T) {
+	h := nlp.NewHighlighter(HighlightTerms(`"new york" office`))
+	got := rehighlight(h, "the **New** **York** **office** opened")
+	want := "the **New York** **office** opened"
+	if got != want {
+		t.Errorf("rehighlight = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import "fmt"
+
+// RelCooccurs links entities found in the same document
+const RelCooccurs = "co_occurs"
+
+// maxCooccurrenceEntities caps the entities paired per document; edges
+// grow quadratically (50 entities → 1225 pairs)
+const maxCooccurrenceEntities = 50
+
+// CooccurrencePairs lists the index pairs (i < j) of n entities found
+// together that get a co_occurs edge: every pair among the first
+// maxCooccurrenceEntities
+func CooccurrencePairs(n int) [][2]int {
+	if n > maxCooccurrenceEntities {
+		n = maxCooccurrenceEntities
+	}
+	var pairs [][2]int
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	return pairs
+}
+
+// IngestResult summarizes one IngestDocument call
+type IngestResult struct {
+	Document     *Document `json:"document"`
+	Entities     []Entity  `json:"entities"`
+	EdgesCreated int       `json:"edges_created"`
+	EdgesUpdated int       `json:"edges_updated"`
+
+	// Set when the content duplicates an existing document (see Dedup);
+	// Skipped means nothing was stored and Document is the existing one
+	Duplicate *Duplicate `json:"duplicate,omitempty"`
+	Skipped   bool       `json:"skipped,omitempty"`
+}
+
+// IngestDocument inserts a document, upserts its entities and links every
+// pair of them with a co_occurs edge (bumping the weight of existing ones),
+// all in one transaction. Entities are deduplicated as in UpsertEntities,
+// edges as in UpsertEdges, with the content as the source: ingesting the
+// same text again adds no edge weight.
+func IngestDocument(filename, title, content, owner string, entities []Entity) (*IngestResult, error) {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("ingest: %w", err)
+	}
+	defer tx.Rollback()
+
+	var dup *Duplicate
+	if Dedup != DedupOff {
+		if dup, err = findDuplicate(tx, content, owner); err != nil {
+			return nil, fmt.Errorf("ingest: %w", err)
+		}
+		if dup != nil && Dedup == DedupSkip {
+			return &IngestResult{Document: dup.Document, Entities: []Entity{}, Duplicate: dup, Skipped: true}, nil
+		}
+	}
+
+	doc, err := insertDocument(tx, filename, title, content, owner)
+	if err != nil {
+		return nil, fmt.Errorf("ingest: insert document: %w", err)
+	}
+	result := &IngestResult{Document: doc, Entities: []Entity{}, Duplicate: dup}
+
+	stored, err := upsertEntities(tx, entities)
+	if err != nil {
+		return nil, fmt.Errorf("ingest: upsert entities: %w", err)
+	}
+
+	// Distinct entities in first-seen order
+	seen := make(map[int]bool)
+	for _, e := range stored {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			result.Entities = append(result.Entities, e)
+		}
+	}
+
+	var edges []Edge
+	for _, p := range CooccurrencePairs(len(result.Entities)) {
+		edges = append(edges, Edge{
+			FromEntityID: result.Entities[p[0]].ID,
+			ToEntityID:   result.Entities[p[1]].ID,
+			Relationship: RelCooccurs,
+			Weight:       1,
+		})
+	}
+
+	// Keyed on the content, so a retried or repeated ingest of the same
+	// text doesn't count its co-occurrences again
+	result.EdgesCreated, result.EdgesUpdated, err = upsertEdges(tx, edges, "content:"+ContentHash(content))
+	if err != nil {
+		return nil, fmt.Errorf("ingest: edges: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ingest: commit: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"hybridcore/internal/nlp"
+)
+
+// languageConfigs maps the languages documents are tagged with to their
+// Postgres text search configuration. Any other language is indexed with
+// DefaultLanguage's; the lang_vector column (migration 007) must follow the
+// same mapping.
+var languageConfigs = map[string]string{
+	"en": "english",
+	"fr": "french",
+}
+
+// DefaultLanguage tags documents whose language can't be detected
+const DefaultLanguage = "en"
+
+// LanguageBoost scales the rank of documents written in the query's
+// language, when that can be detected; 1 ranks every language alike. Set
+// from config at startup.
+var LanguageBoost = 1.5
+
+const headlineOptions = `'MaxWords=60, MinWords=30, StartSel=**, StopSel=**'`
+
+// DocumentLanguage detects the language to tag content with
+func DocumentLanguage(content string) string {
+	if lang := nlp.DetectLanguage(content); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// BackfillLanguages detects the language of documents stored before
+// language detection (migration 007 flags them), a batch at a time, and
+// returns how many it tagged. Their lang_vector follows the new language.
+func BackfillLanguages(ctx context.Context) (int, error) {
+	const batch = 500
+	tagged := 0
+	for {
+		var docs []struct {
+			ID      int    `db:"id"`
+			Content string `db:"content"`
+		}
+		err := DB.SelectContext(ctx, &docs, `SELECT id, content FROM documents
+			WHERE NOT language_detected ORDER BY id LIMIT $1`, batch)
+		if err != nil {
+			return tagged, fmt.Errorf("backfill languages: %w", err)
+		}
+		for _, d := range docs {
+			_, err := DB.ExecContext(ctx, `UPDATE documents SET language = $1, language_detected = true WHERE id = $2`,
+				DocumentLanguage(d.Content), d.ID)
+			if err != nil {
+				return tagged, fmt.Errorf("backfill languages: document %d: %w", d.ID, err)
+			}
+			tagged++
+		}
+		if len(docs) < batch {
+			return tagged, nil
+		}
+	}
+}
+
+// otherLanguages lists the tagged languages besides DefaultLanguage, sorted
+func otherLanguages() []string {
+	langs := make([]string, 0, len(languageConfigs))
+	for lang := range languageConfigs {
+		if lang != DefaultLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// languageWhere is the condition for documents d matching the tsquery
+// tsq builds for a text search configuration, each document under its own
+// language's configuration
+func languageWhere(tsq func(config string) string) string {
+	var where, quoted []string
+	for _, lang := range otherLanguages() {
+		where = append(where, "(d.language = '"+lang+"' AND d.lang_vector @@ "+tsq(languageConfigs[lang])+")")
+		quoted = append(quoted, "'"+lang+"'")
+	}
+
+	// Everything else was indexed with the default configuration
+	fallback := "d.lang_vector @@ " + tsq(languageConfigs[DefaultLanguage])
+	if len(quoted) > 0 {
+		fallback = "d.language NOT IN (" + strings.Join(quoted, ", ") + ") AND " + fallback
+	}
+	where = append(where, "("+fallback+")")
+	return "(" + strings.Join(where, " OR ") + ")"
+}
+
+// languageMatch holds the SQL that searches documents d each with their
+// own language's configuration, merging every language into one ranking
+type languageMatch struct {
+	where    string // documents matching in their language
+	rank     string // ts_rank under that language, boosted for queryLang
+	headline string // ts_headline under that language
+}
+
+// matchLanguages binds the websearch query tsQuery and builds a
+// languageMatch for it. Documents in queryLang ("" when unknown) get
+// LanguageBoost.
+func matchLanguages(q *queryBuilder, tsQuery, queryLang string) languageMatch {
+	arg := q.Arg(tsQuery)
+	tsq := func(config string) string {
+		return "websearch_to_tsquery('" + config + "', " + arg + ")"
+	}
+
+	var rank, headline strings.Builder
+	rank.WriteString("CASE d.language")
+	headline.WriteString("CASE d.language")
+	for _, lang := range otherLanguages() {
+		config := languageConfigs[lang]
+		rank.WriteString(" WHEN '" + lang + "' THEN ts_rank(d.lang_vector, " + tsq(config) + ")")
+		headline.WriteString(" WHEN '" + lang + "' THEN ts_headline('" + config + "', d.content, " +
+			tsq(config) + ", " + headlineOptions + ")")
+	}
+	config := languageConfigs[DefaultLanguage]
+	rank.WriteString(" ELSE ts_rank(d.lang_vector, " + tsq(config) + ") END")
+	headline.WriteString(" ELSE ts_headline('" + config + "', d.content, " + tsq(config) + ", " +
+		headlineOptions + ") END")
+
+	m := languageMatch{
+		where:    languageWhere(tsq),
+		rank:     rank.String(),
+		headline: headline.String(),
+	}
+	if queryLang != "" && LanguageBoost != 1 {
+		m.rank = "(" + m.rank + ") * CASE WHEN d.language = " + q.Arg(queryLang) +
+			" THEN " + q.Arg(LanguageBoost) + "::float8 ELSE 1.0 END"
+	}
+	return m
+}
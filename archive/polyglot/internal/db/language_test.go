@@ -0,0 +1,41 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// Document frequencies must count matches the way Search finds them: French
+// documents through their french vector, never the english one
+func TestLanguageWhereMatchesEachLanguageWithItsConfig(t *testing.T) {
+	where := languageWhere(func(config string) string {
+		return "plainto_tsquery('" + config + "', t.term)"
+	})
+	for _, want := range []string{
+		"d.language = 'fr' AND d.lang_vector @@ plainto_tsquery('french', t.term)",
+		"d.language NOT IN ('fr') AND d.lang_vector @@ plainto_tsquery('english', t.term)",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("condition lacks %q:\n%s", want, where)
+		}
+	}
+	if strings.Contains(where, "search_vector") {
+		t.Errorf("condition still uses the english-only search_vector:\n%s", where)
+	}
+}
+
+func TestDocumentLanguage(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"Le rapport de la commission est publié dans le journal et les documents sont disponibles", "fr"},
+		{"The report of the commission is published in the journal and the documents are available", "en"},
+		{"", DefaultLanguage},
+	}
+	for _, tt := range tests {
+		if got := DocumentLanguage(tt.content); got != tt.want {
+			t.Errorf("DocumentLanguage(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLock is the pg_advisory_lock key serializing Migrate across
+// instances starting at the same time
+const migrationLock = 0x6879_6272_6964 // "hybrid"
+
+// Migrate applies the embedded migrations/*.sql files not yet recorded in
+// schema_migrations, in filename order, each in its own transaction. The
+// version is the filename without ".sql"; re-running is a no-op.
+func Migrate(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	// The advisory lock is per session, so keep everything on one connection
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	applied := make(map[string]bool)
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return fmt.Errorf("migrate: %w", err)
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	versions, err := migrationVersions()
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	for _, version := range versions {
+		if applied[version] {
+			continue
+		}
+
+		body, err := migrationFS.ReadFile("migrations/" + version + ".sql")
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", version, err)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate %s: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate %s: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate %s: %w", version, err)
+		}
+		log.Printf("[DB] Applied migration %s", version)
+	}
+	return nil
+}
+
+// PendingMigrations lists the embedded migrations not yet recorded in
+// schema_migrations, in the order Migrate would apply them
+func PendingMigrations(ctx context.Context) ([]string, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	var applied []string
+	if err := DB.SelectContext(ctx, &applied, "SELECT version FROM schema_migrations"); err != nil {
+		return nil, fmt.Errorf("schema version: %w", err)
+	}
+	versions, err := migrationVersions()
+	if err != nil {
+		return nil, err
+	}
+	return unapplied(versions, applied), nil
+}
+
+// migrationVersions lists the embedded migrations by version, sorted
+func migrationVersions() ([]string, error) {
+	files, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	versions := make([]string, len(files))
+	for i, file := range files {
+		versions[i] = strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+	}
+	return versions, nil
+}
+
+// unapplied keeps the versions missing from applied
+func unapplied(versions, applied []string) []string {
+	done := make(map[string]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	var pending []string
+	for _, v := range versions {
+		if !done[v] {
+			pending = append(pending, v)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// freshDB points DB at an empty schema of the TEST_DATABASE_URL database
+// for the rest of the test, skipping the test without one
+func freshDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL must be a postgres:// URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	q.Set("timezone", "UTC")
+	u.RawQuery = q.Encode()
+	conn, err := sqlx.Connect("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := DB
+	DB = conn
+	t.Cleanup(func() {
+		DB = old
+		conn.Close()
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+}
+
+func TestMigrationVersionsSorted(t *testing.T) {
+	versions, err := migrationVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) == 0 || versions[0] != "001_documents" {
+		t.Fatalf("versions = %v, want 001_documents first", versions)
+	}
+	for i := 1; i < len(versions); i++ {
+		if versions[i-1] >= versions[i] {
+			t.Errorf("versions out of order: %q before %q", versions[i-1], versions[i])
+		}
+	}
+}
+
+// A schema missing any migration, even one in the middle, is not ready
+func TestUnappliedMigrations(t *testing.T) {
+	versions := []string{"001_a", "002_b", "003_c"}
+	if got := unapplied(versions, versions); got != nil {
+		t.Errorf("all applied: pending = %v, want none", got)
+	}
+	got := unapplied(versions, []string{"001_a", "003_c", "999_unknown"})
+	if !reflect.DeepEqual(got, []string{"002_b"}) {
+		t.Errorf("pending = %v, want [002_b]", got)
+	}
+}
+
+// BackfillLanguages selects on language_detected, which must exist as
+// soon as the language column does
+func TestLanguageMigrationFlagsExistingRows(t *testing.T) {
+	sql, err := migrationFS.ReadFile("migrations/007_document_language.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ADD COLUMN IF NOT EXISTS language_detected BOOLEAN NOT NULL DEFAULT false",
+		"ALTER COLUMN language_detected SET DEFAULT true",
+	} {
+		if !strings.Contains(string(sql), want) {
+			t.Errorf("007 lacks %q", want)
+		}
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	freshDB(t)
+	ctx := context.Background()
+
+	if err := Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"documents", "entities", "edges", "schema_migrations"} {
+		var exists bool
+		if err := DB.Get(&exists, "SELECT to_regclass($1) IS NOT NULL", table); err != nil || !exists {
+			t.Errorf("table %s missing after Migrate (err %v)", table, err)
+		}
+	}
+	pending, err := PendingMigrations(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("pending = %v (err %v), want none", pending, err)
+	}
+
+	var before []string
+	if err := DB.Select(&before, "SELECT version || applied_at::text FROM schema_migrations ORDER BY version"); err != nil {
+		t.Fatal(err)
+	}
+	versions, _ := migrationVersions()
+	if len(before) != len(versions) {
+		t.Fatalf("recorded %d migrations, want %d", len(before), len(versions))
+	}
+
+	// A second run applies nothing and records nothing
+	if err := Migrate(ctx); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	var after []string
+	if err := DB.Select(&after, "SELECT version || applied_at::text FROM schema_migrations ORDER BY version"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("second Migrate changed schema_migrations:\n%v\n%v", before, after)
+	}
+}
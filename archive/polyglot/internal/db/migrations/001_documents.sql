@@ -0,0 +1,20 @@
+-- Documents and their full-text index. IF NOT EXISTS keeps this a no-op on
+-- databases created before migrations were tracked.
+CREATE TABLE IF NOT EXISTS documents (
+    id            SERIAL PRIMARY KEY,
+    doc_id        TEXT NOT NULL DEFAULT gen_random_uuid()::text,
+    filename      TEXT NOT NULL DEFAULT '',
+    title         TEXT NOT NULL DEFAULT '',
+    content       TEXT NOT NULL DEFAULT '',
+    word_count    INTEGER NOT NULL DEFAULT 0,
+    char_count    INTEGER NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(content, '')), 'B')
+    ) STORED
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS documents_doc_id_idx ON documents (doc_id);
+CREATE INDEX IF NOT EXISTS documents_search_vector_idx ON documents USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS documents_created_at_idx ON documents (created_at);
@@ -0,0 +1,22 @@
+-- Entity graph
+CREATE TABLE IF NOT EXISTS entities (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS edges (
+    id             SERIAL PRIMARY KEY,
+    from_entity_id INTEGER NOT NULL REFERENCES entities (id) ON DELETE CASCADE,
+    to_entity_id   INTEGER NOT NULL REFERENCES entities (id) ON DELETE CASCADE,
+    relationship   TEXT NOT NULL DEFAULT '',
+    weight         DOUBLE PRECISION NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS edges_from_entity_idx ON edges (from_entity_id);
+CREATE INDEX IF NOT EXISTS edges_to_entity_idx ON edges (to_entity_id);
+
+-- UpsertEntities conflicts on this index. On older databases holding
+-- case-variant duplicates, merge them before running this migration.
+CREATE UNIQUE INDEX IF NOT EXISTS entities_name_type_idx ON entities (lower(name), type);
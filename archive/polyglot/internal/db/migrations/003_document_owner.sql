@@ -0,0 +1,4 @@
+-- Tenant ownership; NULL means shared by every tenant
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS owner TEXT;
+
+CREATE INDEX IF NOT EXISTS documents_owner_idx ON documents (owner);
@@ -0,0 +1,11 @@
+-- Mail corpus served by go-search
+CREATE TABLE IF NOT EXISTS emails (
+    doc_id    SERIAL PRIMARY KEY,
+    subject   TEXT NOT NULL DEFAULT '',
+    body_text TEXT,
+    tsv       TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(subject, '') || ' ' || coalesce(body_text, ''))
+    ) STORED
+);
+
+CREATE INDEX IF NOT EXISTS emails_tsv_idx ON emails USING GIN (tsv);
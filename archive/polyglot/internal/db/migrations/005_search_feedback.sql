@@ -0,0 +1,12 @@
+-- Relevance feedback on search results, aggregated per normalized query
+CREATE TABLE IF NOT EXISTS search_feedback (
+    id         SERIAL PRIMARY KEY,
+    query      TEXT NOT NULL,
+    query_key  TEXT NOT NULL,
+    doc_id     TEXT NOT NULL,
+    relevant   BOOLEAN NOT NULL,
+    owner      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS search_feedback_key_idx ON search_feedback (query_key, owner, doc_id);
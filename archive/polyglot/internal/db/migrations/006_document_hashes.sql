@@ -0,0 +1,7 @@
+-- Fingerprints for duplicate detection: content_hash for exact copies
+-- (whitespace-normalized SHA-256), simhash for near ones. Both are
+-- computed in Go, on insert and by BackfillFingerprints for existing rows.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_hash TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash BIGINT;
+
+CREATE INDEX IF NOT EXISTS documents_content_hash_idx ON documents (content_hash);
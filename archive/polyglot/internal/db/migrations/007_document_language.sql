@@ -0,0 +1,23 @@
+-- Document language, detected on ingest, and a search vector built with
+-- that language's text search configuration. Rows from before this
+-- migration are tagged 'en' and flagged with language_detected false, so
+-- BackfillLanguages detects their language; rows inserted from here on
+-- are detected on insert. The CASE must follow languageConfigs in
+-- internal/db/language.go.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS language_detected BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE documents ALTER COLUMN language_detected SET DEFAULT true;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS lang_vector TSVECTOR GENERATED ALWAYS AS (
+    CASE language
+        WHEN 'fr' THEN
+            setweight(to_tsvector('french', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('french', coalesce(content, '')), 'B')
+        ELSE
+            setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('english', coalesce(content, '')), 'B')
+    END
+) STORED;
+
+CREATE INDEX IF NOT EXISTS documents_lang_vector_idx ON documents USING GIN (lang_vector);
+CREATE INDEX IF NOT EXISTS documents_language_idx ON documents (language);
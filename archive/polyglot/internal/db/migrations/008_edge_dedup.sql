@@ -0,0 +1,38 @@
+-- One edge per (from, to, relationship), so graph writes can upsert. Merge
+-- the duplicates concurrent or retried ingests left behind, summing their
+-- weights into the oldest.
+WITH dups AS (
+    SELECT id,
+           first_value(id) OVER w AS keep,
+           sum(weight) OVER w AS total
+    FROM edges
+    WINDOW w AS (
+        PARTITION BY from_entity_id, to_entity_id, relationship
+        ORDER BY id
+        ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING
+    )
+)
+UPDATE edges e SET weight = d.total
+FROM dups d
+WHERE e.id = d.id AND d.id = d.keep;
+
+DELETE FROM edges e
+USING edges k
+WHERE k.from_entity_id = e.from_entity_id
+  AND k.to_entity_id = e.to_entity_id
+  AND k.relationship = e.relationship
+  AND k.id < e.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS edges_key_idx ON edges (from_entity_id, to_entity_id, relationship);
+
+-- Which sources (e.g. a document's content hash) already contributed to
+-- each edge. A write claims its rows here first, so replaying it adds no
+-- weight twice.
+CREATE TABLE IF NOT EXISTS edge_sources (
+    from_entity_id INTEGER NOT NULL REFERENCES entities (id) ON DELETE CASCADE,
+    to_entity_id   INTEGER NOT NULL REFERENCES entities (id) ON DELETE CASCADE,
+    relationship   TEXT NOT NULL,
+    source         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (from_entity_id, to_entity_id, relationship, source)
+);
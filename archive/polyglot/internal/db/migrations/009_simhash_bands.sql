@@ -0,0 +1,12 @@
+-- Near-duplicate lookup by simhash band. Two fingerprints at most 3 bits
+-- apart agree on at least one of their four 16-bit bands, so an indexed
+-- band match finds every candidate and the distance is checked after.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash_b0 INTEGER GENERATED ALWAYS AS (((simhash >> 48) & 65535)::integer) STORED;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash_b1 INTEGER GENERATED ALWAYS AS (((simhash >> 32) & 65535)::integer) STORED;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash_b2 INTEGER GENERATED ALWAYS AS (((simhash >> 16) & 65535)::integer) STORED;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash_b3 INTEGER GENERATED ALWAYS AS ((simhash & 65535)::integer) STORED;
+
+CREATE INDEX IF NOT EXISTS documents_simhash_b0_idx ON documents (simhash_b0);
+CREATE INDEX IF NOT EXISTS documents_simhash_b1_idx ON documents (simhash_b1);
+CREATE INDEX IF NOT EXISTS documents_simhash_b2_idx ON documents (simhash_b2);
+CREATE INDEX IF NOT EXISTS documents_simhash_b3_idx ON documents (simhash_b3);
@@ -1,17 +1,48 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"hybridcore/internal/clock"
+	"hybridcore/internal/errs"
+	"hybridcore/internal/nlp"
 )
 
 var DB *sqlx.DB
 
+// FilterStopwords drops English/French stopwords from the OR-expanded
+// search query. Set from config at startup.
+var FilterStopwords = true
+
+// Result limits shared by every search path. Set from config at startup.
+var (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// ClampLimit applies DefaultLimit to an unset (zero or negative) limit and
+// caps the rest at MaxLimit
+func ClampLimit(limit int) int {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if MaxLimit > 0 && limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit
+}
+
 type Document struct {
 	ID        int       `db:"id" json:"id"`
 	DocID     string    `db:"doc_id" json:"doc_id"`
@@ -19,13 +50,26 @@ type Document struct {
 	Title     string    `db:"title" json:"title"`
 	Content   string    `db:"content" json:"content"`
 	WordCount int       `db:"word_count" json:"word_count"`
+	Owner     string    `db:"owner" json:"owner,omitempty"`
+	Language  string    `db:"language" json:"language,omitempty"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 }
 
+// Filter narrows document queries. The zero value matches everything.
+type Filter struct {
+	// Owner is the requesting tenant. Empty means single-tenant mode;
+	// otherwise only the tenant's documents and shared (ownerless) ones match.
+	Owner string
+	// From and To bound created_at as [From, To); zero means unbounded.
+	From time.Time
+	To   time.Time
+}
+
 type SearchResult struct {
 	Document
-	Rank    float64 `db:"rank" json:"rank"`
-	Excerpt string  `db:"excerpt" json:"excerpt"`
+	Rank         float64  `db:"rank" json:"rank"`
+	Excerpt      string   `db:"excerpt" json:"excerpt"`
+	MatchedTerms []string `db:"-" json:"matched_terms"`
 }
 
 type Entity struct {
@@ -44,7 +88,8 @@ type Edge struct {
 }
 
 func Connect(host string, port int, user, password, dbname string) error {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	// timezone=UTC makes Postgres return every timestamp in UTC
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable timezone=UTC",
 		host, port, user, password, dbname)
 
 	var err error
@@ -61,63 +106,296 @@ func Connect(host string, port int, user, password, dbname string) error {
 	return nil
 }
 
-func Search(query string, limit int) ([]SearchResult, error) {
+// Ping checks the database connection
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("not connected")
+	}
+	return DB.PingContext(ctx)
+}
+
+func Search(query string, limit int, filter Filter) ([]SearchResult, error) {
+	results := []SearchResult{}
+	err := SearchEach(context.Background(), query, ClampLimit(limit), filter, func(r SearchResult) error {
+		results = append(results, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SearchEach runs Search and hands fn the results one row at a time as
+// they are read, for exports too large to hold in memory. limit <= 0
+// returns every match. An error from fn stops the scan and is returned.
+func SearchEach(ctx context.Context, query string, limit int, filter Filter, fn func(SearchResult) error) error {
+	// Convert query to OR-based search: "explain Go goroutines" -> "explain OR Go OR goroutines"
+	orQuery := expandQuery(query)
+	if orQuery == "" {
+		// Nothing meaningful to search for (e.g. only stopwords)
+		return nil
+	}
+
+	// Each document is matched in its own language; those in the query's
+	// language rank higher
+	q := &queryBuilder{}
+	match := matchLanguages(q, orQuery, nlp.DetectLanguage(query))
+	q.Where(match.where)
+	filter.apply(q)
+	tail := ""
+	if limit > 0 {
+		tail = "LIMIT " + q.Arg(limit)
+	}
+
+	sql := `
+		SELECT d.id, d.doc_id, d.filename, d.title, d.content, d.word_count,
+			COALESCE(d.owner, '') as owner, d.language, d.created_at,
+			` + match.rank + ` as rank,
+			` + match.headline + ` as excerpt
+		FROM documents d
+		` + q.WhereSQL() + `
+		ORDER BY rank DESC
+		` + tail
+
+	rows, err := DB.QueryxContext(ctx, sql, q.Args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	terms := expandTerms(query, QueryTerms(query))
+	h := nlp.NewHighlighter(expandTerms(query, HighlightTerms(query)))
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.StructScan(&r); err != nil {
+			return err
+		}
+		r.MatchedTerms = MatchTerms(terms, r.Title+" "+r.Content)
+		r.Excerpt = rehighlight(h, r.Excerpt)
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SimilarDocuments finds documents matching any of terms (typically the
+// source document's top keywords), excluding the source itself.
+func SimilarDocuments(id int, terms []string, limit int, owner string) ([]SearchResult, error) {
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
 	if limit <= 0 {
 		limit = 5
 	}
 
-	// Convert query to OR-based search: "explain Go goroutines" -> "explain OR Go OR goroutines"
-	orQuery := strings.Join(strings.Fields(query), " OR ")
+	q := &queryBuilder{}
+	match := matchLanguages(q, strings.Join(terms, " OR "), "")
+	q.Where(match.where)
+	q.Where("d.id <> ?", id)
+	Filter{Owner: owner}.apply(q)
+	limitArg := q.Arg(limit)
 
 	sql := `
-		SELECT d.id, d.doc_id, d.filename, d.title, d.content, d.word_count, d.created_at,
-			ts_rank(d.search_vector, websearch_to_tsquery('english', $1)) as rank,
-			ts_headline('english', d.content, websearch_to_tsquery('english', $1),
-				'MaxWords=60, MinWords=30, StartSel=**, StopSel=**') as excerpt
+		SELECT d.id, d.doc_id, d.filename, d.title, d.content, d.word_count,
+			COALESCE(d.owner, '') as owner, d.language, d.created_at,
+			` + match.rank + ` as rank,
+			` + match.headline + ` as excerpt
 		FROM documents d
-		WHERE d.search_vector @@ websearch_to_tsquery('english', $1)
+		` + q.WhereSQL() + `
 		ORDER BY rank DESC
-		LIMIT $2`
+		LIMIT ` + limitArg
 
 	var results []SearchResult
-	err := DB.Select(&results, sql, orQuery, limit)
-	return results, err
+	if err := DB.Select(&results, sql, q.Args()...); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].MatchedTerms = MatchTerms(terms, results[i].Title+" "+results[i].Content)
+	}
+	return results, nil
+}
+
+// QueryTerms returns the meaningful, punctuation-trimmed words of a query
+func QueryTerms(query string) []string {
+	var terms []string
+	for _, t := range strings.Fields(query) {
+		word := strings.TrimFunc(t, unicode.IsPunct)
+		if word == "" || (FilterStopwords && nlp.IsStopword(word)) {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
 }
 
-func GetDocument(id int) (*Document, error) {
+// HighlightTerms is QueryTerms keeping each "quoted phrase" of the query
+// whole, for a highlighter to mark as one span
+func HighlightTerms(query string) []string {
+	var terms []string
+	var rest strings.Builder
+	for i, part := range strings.Split(query, `"`) {
+		phrase := strings.Join(strings.Fields(part), " ")
+		if i%2 == 1 && strings.Contains(phrase, " ") {
+			terms = append(terms, phrase)
+			continue
+		}
+		rest.WriteString(" " + part)
+	}
+	return append(terms, QueryTerms(rest.String())...)
+}
+
+// rehighlight swaps ts_headline's word-by-word markers in excerpt for h's,
+// which mark phrases whole
+func rehighlight(h *nlp.Highlighter, excerpt string) string {
+	excerpt = strings.ReplaceAll(excerpt, nlp.DefaultStartSel, "")
+	excerpt = strings.ReplaceAll(excerpt, nlp.DefaultStopSel, "")
+	return h.Highlight(excerpt)
+}
+
+// expandQuery ORs the meaningful terms of a query and their synonyms
+// together. A single term is passed through as-is; an all-stopword query
+// yields "".
+func expandQuery(query string) string {
+	terms := expandTerms(query, QueryTerms(query))
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = tsTerm(t)
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// MatchTerms reports which query terms occur in text, case-insensitively.
+// A term also matches words it prefixes ("invest" → "investments") to
+// roughly follow the FTS stemming that selected the document.
+func MatchTerms(terms []string, text string) []string {
+	tokens := make(map[string]bool)
+	for _, tok := range nlp.Tokenize(text) {
+		tokens[tok] = true
+	}
+
+	matched := []string{}
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		lower := strings.ToLower(term)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+
+		if tokens[lower] {
+			matched = append(matched, lower)
+			continue
+		}
+		for tok := range tokens {
+			if strings.HasPrefix(tok, lower) {
+				matched = append(matched, lower)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// GetDocument loads a document visible to owner (see Filter.Owner)
+func GetDocument(id int, owner string) (*Document, error) {
+	q := &queryBuilder{}
+	q.Where("d.id = ?", id)
+	Filter{Owner: owner}.apply(q)
+
 	var doc Document
-	err := DB.Get(&doc, "SELECT id, doc_id, filename, title, content, word_count, created_at FROM documents WHERE id = $1", id)
+	err := DB.Get(&doc, `SELECT d.id, d.doc_id, d.filename, d.title, d.content, d.word_count,
+			COALESCE(d.owner, '') as owner, d.language, d.created_at
+		FROM documents d `+q.WhereSQL(), q.Args()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.New(errs.NotFound, "Document not found")
+	}
 	if err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.Internal, "Document lookup failed", err)
 	}
 	return &doc, nil
 }
 
-func ListDocuments() ([]Document, error) {
-	var docs []Document
-	err := DB.Select(&docs, "SELECT id, doc_id, filename, title, word_count, created_at FROM documents ORDER BY id")
-	return docs, err
+// Page picks part of an id-ordered listing: up to Limit documents after
+// ID After (keyset, as cheap deep in the table as at its start) or, with
+// no After, after skipping Offset rows (fine for small sets). Limit 0
+// lists everything.
+type Page struct {
+	Limit  int
+	After  int
+	Offset int
 }
 
-func InsertDocument(filename, title, content string) (*Document, error) {
-	sql := `INSERT INTO documents (filename, title, content, word_count, char_count)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id, doc_id, filename, title, content, word_count, created_at`
+// ListDocuments lists the documents visible to owner in id order; more
+// reports whether any remain past the page
+func ListDocuments(owner string, page Page) (docs []Document, more bool, err error) {
+	q := &queryBuilder{}
+	Filter{Owner: owner}.apply(q)
+	if page.After > 0 {
+		q.Where("d.id > ?", page.After)
+	}
+
+	// One row past the page tells whether there is a next one
+	tail := ""
+	if page.Limit > 0 {
+		tail += " LIMIT " + q.Arg(page.Limit+1)
+	}
+	if page.After <= 0 && page.Offset > 0 {
+		tail += " OFFSET " + q.Arg(page.Offset)
+	}
+
+	err = DB.Select(&docs, `SELECT d.id, d.doc_id, d.filename, d.title, d.word_count,
+			COALESCE(d.owner, '') as owner, d.language, d.created_at
+		FROM documents d `+q.WhereSQL()+` ORDER BY d.id`+tail, q.Args()...)
+	if err != nil {
+		return nil, false, err
+	}
+	if page.Limit > 0 && len(docs) > page.Limit {
+		docs, more = docs[:page.Limit], true
+	}
+	return docs, more, nil
+}
+
+// InsertDocument stores a document; an empty owner makes it shared
+func InsertDocument(filename, title, content, owner string) (*Document, error) {
+	return insertDocument(DB, filename, title, content, owner)
+}
+
+func insertDocument(q sqlx.Queryer, filename, title, content, owner string) (*Document, error) {
+	sql := `INSERT INTO documents (filename, title, content, word_count, char_count, owner, content_hash, simhash, language)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+		RETURNING id, doc_id, filename, title, content, word_count, COALESCE(owner, '') as owner, language, created_at`
 
 	words := len(splitWords(content))
 	chars := len(content)
 
 	var doc Document
-	err := DB.Get(&doc, sql, filename, title, content, words, chars)
+	err := sqlx.Get(q, &doc, sql, filename, title, content, words, chars, owner,
+		ContentHash(content), int64(nlp.SimHash(content)), DocumentLanguage(content))
 	return &doc, err
 }
 
-func GetStats() map[string]interface{} {
+// GetStats counts the documents visible to owner ("" for all of them).
+// Entities and edges form one graph shared by every tenant and aren't
+// tied to documents, so they are only counted for the unscoped caller.
+func GetStats(owner string) map[string]interface{} {
 	stats := make(map[string]interface{})
 
+	q := &queryBuilder{}
+	Filter{Owner: owner}.apply(q)
 	var docCount int
-	DB.Get(&docCount, "SELECT COUNT(*) FROM documents")
+	DB.Get(&docCount, "SELECT COUNT(*) FROM documents d "+q.WhereSQL(), q.Args()...)
 	stats["documents"] = docCount
 
+	if owner != "" {
+		return stats
+	}
+
 	var entityCount int
 	DB.Get(&entityCount, "SELECT COUNT(*) FROM entities")
 	stats["entities"] = entityCount
@@ -129,6 +407,136 @@ func GetStats() map[string]interface{} {
 	return stats
 }
 
+// Bucket is one row of a grouped count
+type Bucket struct {
+	Key   string `db:"key" json:"key"`
+	Count int    `db:"count" json:"count"`
+}
+
+type DetailedStats struct {
+	ByType         []Bucket  `json:"by_type"`
+	ByMonth        []Bucket  `json:"by_month"`
+	AvgWordCount   float64   `json:"avg_word_count"`
+	TopEntityTypes []Bucket  `json:"top_entity_types"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// DetailedStatsTTL is how long GetDetailedStats reuses its last result
+var DetailedStatsTTL = time.Minute
+
+var detailedStatsCache struct {
+	sync.Mutex
+	byOwner map[string]*DetailedStats
+}
+
+// GetDetailedStats returns breakdowns of the documents visible to owner
+// ("" for all of them): document type by file extension, month of
+// creation, average length and, for the unscoped caller only, top entity
+// types. Results are cached per owner for DetailedStatsTTL since the
+// aggregates scan whole tables.
+func GetDetailedStats(owner string) (*DetailedStats, error) {
+	detailedStatsCache.Lock()
+	defer detailedStatsCache.Unlock()
+
+	if cached := detailedStatsCache.byOwner[owner]; cached != nil && time.Since(cached.GeneratedAt) < DetailedStatsTTL {
+		return cached, nil
+	}
+
+	stats := &DetailedStats{GeneratedAt: clock.Now(), TopEntityTypes: []Bucket{}}
+	q := &queryBuilder{}
+	Filter{Owner: owner}.apply(q)
+	where := q.WhereSQL()
+
+	err := DB.Select(&stats.ByType, `
+		SELECT COALESCE(lower(substring(d.filename from '\.([^.]+)$')), 'unknown') AS key, COUNT(*) AS count
+		FROM documents d
+		`+where+`
+		GROUP BY 1
+		ORDER BY count DESC`, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("stats by type: %w", err)
+	}
+
+	err = DB.Select(&stats.ByMonth, `
+		SELECT to_char(date_trunc('month', d.created_at), 'YYYY-MM') AS key, COUNT(*) AS count
+		FROM documents d
+		`+where+`
+		GROUP BY 1
+		ORDER BY 1`, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("stats by month: %w", err)
+	}
+
+	if err := DB.Get(&stats.AvgWordCount, "SELECT COALESCE(AVG(d.word_count), 0) FROM documents d "+where, q.Args()...); err != nil {
+		return nil, fmt.Errorf("stats avg word count: %w", err)
+	}
+
+	// The entity graph is shared across tenants (see GetStats)
+	if owner == "" {
+		err = DB.Select(&stats.TopEntityTypes, `
+			SELECT type AS key, COUNT(*) AS count
+			FROM entities
+			GROUP BY type
+			ORDER BY count DESC
+			LIMIT 10`)
+		if err != nil {
+			return nil, fmt.Errorf("stats entity types: %w", err)
+		}
+	}
+
+	if detailedStatsCache.byOwner == nil {
+		detailedStatsCache.byOwner = make(map[string]*DetailedStats)
+	}
+	detailedStatsCache.byOwner[owner] = stats
+	return stats, nil
+}
+
+// DocumentFrequencies returns a DocFreqFunc over the documents visible
+// to owner ("" for all of them): how many documents match each term,
+// along with their total count, for IDF weighting.
+func DocumentFrequencies(owner string) nlp.DocFreqFunc {
+	return func(terms []string) (map[string]int, int, error) {
+		return documentFrequencies(terms, owner)
+	}
+}
+
+func documentFrequencies(terms []string, owner string) (map[string]int, int, error) {
+	count := &queryBuilder{}
+	Filter{Owner: owner}.apply(count)
+	var total int
+	if err := DB.Get(&total, "SELECT COUNT(*) FROM documents d "+count.WhereSQL(), count.Args()...); err != nil {
+		return nil, 0, fmt.Errorf("count documents: %w", err)
+	}
+
+	// Each document matched in its own language, as Search does; the
+	// owner filter goes in the join so unmatched terms still get a row
+	q := &queryBuilder{}
+	termsArg := q.Arg(pq.Array(terms))
+	q.Where(languageWhere(func(config string) string {
+		return "plainto_tsquery('" + config + "', t.term)"
+	}))
+	Filter{Owner: owner}.apply(q)
+	sql := `
+		SELECT t.term, COUNT(d.id) AS df
+		FROM unnest(` + termsArg + `::text[]) AS t(term)
+		LEFT JOIN documents d ON ` + strings.Join(q.conds, " AND ") + `
+		GROUP BY t.term`
+
+	var rows []struct {
+		Term string `db:"term"`
+		DF   int    `db:"df"`
+	}
+	if err := DB.Select(&rows, sql, q.Args()...); err != nil {
+		return nil, 0, fmt.Errorf("document frequencies: %w", err)
+	}
+
+	df := make(map[string]int, len(rows))
+	for _, r := range rows {
+		df[r.Term] = r.DF
+	}
+	return df, total, nil
+}
+
 func splitWords(s string) []string {
 	var words []string
 	word := ""
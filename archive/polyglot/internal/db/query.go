@@ -0,0 +1,65 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// queryBuilder composes a parameterized WHERE clause. Values only ever
+// travel through args and are referenced by $n placeholders; the SQL text
+// itself comes from constants in this package.
+type queryBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+// Arg binds v and returns its placeholder
+func (q *queryBuilder) Arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// Where adds a condition. Each "?" in cond is replaced, in order, by the
+// placeholder of the matching arg, so cond must not contain literal "?".
+func (q *queryBuilder) Where(cond string, args ...interface{}) {
+	if len(args) > 0 {
+		parts := strings.Split(cond, "?")
+		if len(parts)-1 != len(args) {
+			panic("db: placeholder/arg count mismatch in " + cond)
+		}
+		var b strings.Builder
+		for i, part := range parts {
+			b.WriteString(part)
+			if i < len(args) {
+				b.WriteString(q.Arg(args[i]))
+			}
+		}
+		cond = b.String()
+	}
+	q.conds = append(q.conds, cond)
+}
+
+// WhereSQL renders "WHERE c1 AND c2 ...", or "" without conditions
+func (q *queryBuilder) WhereSQL() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conds, "\n\t\t\tAND ")
+}
+
+func (q *queryBuilder) Args() []interface{} {
+	return q.args
+}
+
+// apply adds the filter's conditions on the documents table aliased d
+func (f Filter) apply(q *queryBuilder) {
+	if f.Owner != "" {
+		q.Where("(d.owner IS NULL OR d.owner = ?)", f.Owner)
+	}
+	if !f.From.IsZero() {
+		q.Where("d.created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q.Where("d.created_at < ?", f.To)
+	}
+}
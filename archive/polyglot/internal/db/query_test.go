@@ -0,0 +1,48 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Filter values only ever reach SQL as bound arguments
+func TestFilterBindsValues(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	hostile := "acme' OR 1=1--"
+
+	q := &queryBuilder{}
+	q.Where("d.id <> ?", 7)
+	Filter{Owner: hostile, From: from, To: to}.apply(q)
+
+	where := q.WhereSQL()
+	want := "WHERE d.id <> $1\n\t\t\tAND (d.owner IS NULL OR d.owner = $2)\n\t\t\tAND d.created_at >= $3\n\t\t\tAND d.created_at < $4"
+	if where != want {
+		t.Errorf("WhereSQL =\n%s\nwant\n%s", where, want)
+	}
+	if strings.Contains(where, "OR 1=1") || strings.Contains(where, "'") {
+		t.Errorf("owner leaked into the SQL: %s", where)
+	}
+	if args := q.Args(); !reflect.DeepEqual(args, []interface{}{7, hostile, from, to}) {
+		t.Errorf("Args = %v", args)
+	}
+}
+
+func TestFilterEmptyAddsNothing(t *testing.T) {
+	q := &queryBuilder{}
+	Filter{}.apply(q)
+	if where := q.WhereSQL(); where != "" || len(q.Args()) != 0 {
+		t.Errorf("empty filter gave %q with %v", where, q.Args())
+	}
+}
+
+func TestWherePanicsOnPlaceholderMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Where accepted two placeholders for one arg")
+		}
+	}()
+	(&queryBuilder{}).Where("a = ? AND b = ?", 1)
+}
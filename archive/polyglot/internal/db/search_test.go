@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExpandQueryDropsStopwords(t *testing.T) {
+	cases := map[string]string{
+		"the budget of the ministry":     "budget OR ministry",
+		"le budget de la SNCF":           "budget OR SNCF",
+		"What is the budget?":            "budget",
+		"offshore, accounts; (Panama)":   "offshore OR accounts OR Panama",
+		"transfers":                      "transfers",
+		"  the   transfers  ":            "transfers",
+		"l'enquête sur les transactions": "l'enquête OR transactions",
+	}
+	for query, want := range cases {
+		if got := expandQuery(query); got != want {
+			t.Errorf("expandQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestExpandQueryWithoutFiltering(t *testing.T) {
+	defer func(old bool) { FilterStopwords = old }(FilterStopwords)
+	FilterStopwords = false
+
+	if got, want := expandQuery("the budget"), "the OR budget"; got != want {
+		t.Errorf("expandQuery = %q, want %q", got, want)
+	}
+}
+
+// A query of only stopwords matches nothing rather than everything, and
+// never reaches the database (DB is nil here)
+func TestSearchOnlyStopwords(t *testing.T) {
+	for _, query := range []string{"the of and", "le la les de", "?!", ""} {
+		if got := expandQuery(query); got != "" {
+			t.Errorf("expandQuery(%q) = %q, want empty", query, got)
+		}
+		results, err := Search(query, 10, Filter{})
+		if err != nil || len(results) != 0 {
+			t.Errorf("Search(%q) = %v, %v, want no results", query, results, err)
+		}
+	}
+}
+
+// Each result lists the query terms it contains, lowercased and once
+// each, in query order; a term prefixing a word counts
+func TestMatchTermsPerResult(t *testing.T) {
+	terms := QueryTerms("Offshore accounts in Panama for the invest fund")
+	if !reflect.DeepEqual(terms, []string{"Offshore", "accounts", "Panama", "invest", "fund"}) {
+		t.Fatalf("fixture: terms = %v", terms)
+	}
+
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"PANAMA offshore structure; offshore again", []string{"offshore", "panama"}},
+		{"Investments and the fund's accounts", []string{"accounts", "invest", "fund"}},
+		{"Unrelated memo about lunch", []string{}},
+		{"Panamanian shell", []string{"panama"}},
+	}
+	for _, tc := range cases {
+		if got := MatchTerms(terms, tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("MatchTerms(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+
+	// A repeated query term is reported once
+	if got := MatchTerms([]string{"Fund", "fund"}, "fund"); !reflect.DeepEqual(got, []string{"fund"}) {
+		t.Errorf("repeated term: %v", got)
+	}
+}
+
+func TestSearchReportsMatchedTerms(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []struct{ name, content string }{
+		{"both.txt", "Offshore accounts were opened in Panama."},
+		{"one.txt", "The accounts were audited last spring."},
+	} {
+		if _, err := InsertDocument(d.name, d.name, d.content, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Search("panama accounts", 10, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"both.txt": {"panama", "accounts"},
+		"one.txt":  {"accounts"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, r := range results {
+		if !reflect.DeepEqual(r.MatchedTerms, want[r.Filename]) {
+			t.Errorf("%s: matched terms %v, want %v", r.Filename, r.MatchedTerms, want[r.Filename])
+		}
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	oldDef, oldMax := DefaultLimit, MaxLimit
+	defer func() { DefaultLimit, MaxLimit = oldDef, oldMax }()
+	DefaultLimit, MaxLimit = 8, 30
+
+	for _, tt := range []struct{ limit, want int }{{0, 8}, {-5, 8}, {12, 12}, {30, 30}, {31, 30}, {1000, 30}} {
+		if got := ClampLimit(tt.limit); got != tt.want {
+			t.Errorf("ClampLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+
+	MaxLimit = 0
+	if got := ClampLimit(1000); got != 1000 {
+		t.Errorf("with no max, ClampLimit(1000) = %d", got)
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func resetDetailedStatsCache(t *testing.T) {
+	detailedStatsCache.Lock()
+	detailedStatsCache.byOwner = nil
+	detailedStatsCache.Unlock()
+	t.Cleanup(func() {
+		detailedStatsCache.Lock()
+		detailedStatsCache.byOwner = nil
+		detailedStatsCache.Unlock()
+	})
+}
+
+func TestGetDetailedStatsBuckets(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resetDetailedStatsCache(t)
+
+	docs := []struct {
+		filename, content, owner, created string
+	}{
+		{"a.pdf", "one two three four", "", "2026-01-15"},
+		{"b.PDF", "one two", "", "2026-01-31"},
+		{"c.txt", "one two three four five six", "", "2026-03-02"},
+		{"README", "one two three four five six seven eight", "", "2026-03-20"},
+		{"d.txt", "private notes here", "acme", "2026-02-10"},
+	}
+	for _, d := range docs {
+		doc, err := InsertDocument(d.filename, d.filename, d.content, d.owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DB.Exec("UPDATE documents SET created_at = $1 WHERE id = $2", d.created, doc.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := UpsertEntities([]Entity{
+		{Name: "Alice Martin", Type: "person", Confidence: 0.9},
+		{Name: "Bob Stone", Type: "person", Confidence: 0.9},
+		{Name: "Acme Corp", Type: "organization", Confidence: 0.9},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetDetailedStats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Bucket{{"pdf", 2}, {"txt", 2}, {"unknown", 1}}; !sameBuckets(stats.ByType, want) {
+		t.Errorf("by type = %v, want %v", stats.ByType, want)
+	}
+	if want := []Bucket{{"2026-01", 2}, {"2026-02", 1}, {"2026-03", 2}}; !reflect.DeepEqual(stats.ByMonth, want) {
+		t.Errorf("by month = %v, want %v", stats.ByMonth, want)
+	}
+	if stats.AvgWordCount != 4.6 { // (4+2+6+8+3) / 5
+		t.Errorf("avg word count = %v, want 4.6", stats.AvgWordCount)
+	}
+	if want := []Bucket{{"person", 2}, {"organization", 1}}; !reflect.DeepEqual(stats.TopEntityTypes, want) {
+		t.Errorf("top entity types = %v, want %v", stats.TopEntityTypes, want)
+	}
+
+	// Another tenant's documents stay out of a tenant's breakdown, and the
+	// shared graph isn't reported to it
+	scoped, err := GetDetailedStats("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Bucket{{"2026-01", 2}, {"2026-03", 2}}; !reflect.DeepEqual(scoped.ByMonth, want) {
+		t.Errorf("scoped by month = %v, want %v", scoped.ByMonth, want)
+	}
+	if len(scoped.TopEntityTypes) != 0 {
+		t.Errorf("scoped top entity types = %v", scoped.TopEntityTypes)
+	}
+
+	// Within the TTL the cached result is served
+	if _, err := InsertDocument("e.csv", "e", "late", ""); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := GetDetailedStats("")
+	if again != stats {
+		t.Error("recomputed within the TTL")
+	}
+
+	defer func(ttl time.Duration) { DetailedStatsTTL = ttl }(DetailedStatsTTL)
+	DetailedStatsTTL = 0
+	fresh, err := GetDetailedStats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh == stats || len(fresh.ByType) != 4 {
+		t.Errorf("after the TTL: by type = %v, want csv added", fresh.ByType)
+	}
+}
+
+// sameBuckets compares ignoring the order of equal counts
+func sameBuckets(got, want []Bucket) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	counts := make(map[string]int)
+	for _, b := range got {
+		counts[b.Key] = b.Count
+	}
+	for i, b := range want {
+		if counts[b.Key] != b.Count || (i > 0 && got[i].Count > got[i-1].Count) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"hybridcore/internal/nlp"
+)
+
+// SynonymSet indexes alias groups per language: language code → lowercased
+// term → the other members of its group. Any member of a group expands to
+// the rest, so "bob" finds "robert" and the reverse.
+type SynonymSet map[string]map[string][]string
+
+// Synonyms expands search terms into OR groups; nil disables expansion.
+// Set from config at startup.
+var Synonyms SynonymSet
+
+// MaxSynonyms caps the aliases added per query term and MaxExpandedTerms
+// the whole expanded query, so a broad group can't blow up the tsquery.
+// Set from config at startup.
+var (
+	MaxSynonyms      = 3
+	MaxExpandedTerms = 24
+)
+
+// LoadSynonyms reads alias groups keyed by language, e.g.
+// {"en": [["robert", "bob", "rob"], ["ibm", "international business machines"]],
+//
+//	"fr": [["sncf", "société nationale des chemins de fer"]]}
+func LoadSynonyms(path string) (SynonymSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var groups map[string][][]string
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	set := make(SynonymSet, len(groups))
+	for lang, list := range groups {
+		index := make(map[string][]string)
+		for _, group := range list {
+			var members []string
+			for _, m := range group {
+				// Quotes would break the websearch_to_tsquery phrase syntax
+				m = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(m, `"`, "")))
+				if m != "" {
+					members = append(members, m)
+				}
+			}
+			for _, term := range members {
+				for _, alias := range members {
+					if alias != term && !contains(index[term], alias) {
+						index[term] = append(index[term], alias)
+					}
+				}
+			}
+		}
+		set[strings.ToLower(lang)] = index
+	}
+	return set, nil
+}
+
+// aliases returns the synonyms of term for lang, in file order. An empty
+// lang (undetected) draws on every language.
+func (s SynonymSet) aliases(lang, term string) []string {
+	term = strings.ToLower(term)
+	if lang != "" {
+		return s[lang][term]
+	}
+	var all []string
+	for _, index := range s {
+		for _, alias := range index[term] {
+			if !contains(all, alias) {
+				all = append(all, alias)
+			}
+		}
+	}
+	return all
+}
+
+// expandTerms adds the synonyms of each query term in the query's
+// language, up to MaxSynonyms per term and MaxExpandedTerms overall. The
+// original terms always come first and are never dropped.
+func expandTerms(query string, terms []string) []string {
+	if len(Synonyms) == 0 {
+		return terms
+	}
+	lang := nlp.DetectLanguage(query)
+
+	expanded := append([]string(nil), terms...)
+	seen := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		seen[strings.ToLower(t)] = true
+	}
+	for _, t := range terms {
+		added := 0
+		for _, alias := range Synonyms.aliases(lang, t) {
+			if added >= MaxSynonyms || len(expanded) >= MaxExpandedTerms {
+				break
+			}
+			if seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			expanded = append(expanded, alias)
+			added++
+		}
+	}
+	return expanded
+}
+
+// tsTerm renders a term for websearch_to_tsquery, quoting multi-word
+// aliases so they match as phrases
+func tsTerm(term string) string {
+	if strings.ContainsAny(term, " \t") {
+		return `"` + term + `"`
+	}
+	return term
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func useSynonyms(t *testing.T, groups string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "synonyms.json")
+	if err := os.WriteFile(path, []byte(groups), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadSynonyms(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Synonyms
+	Synonyms = set
+	t.Cleanup(func() { Synonyms = old })
+}
+
+// Any member of a group expands to the others, in the query's language;
+// multi-word aliases are quoted as phrases
+func TestExpandQueryAddsAliases(t *testing.T) {
+	useSynonyms(t, `{
+		"en": [["Robert", "Bob"], ["IBM", "International Business Machines"]],
+		"fr": [["SNCF", "société nationale des chemins de fer"]]
+	}`)
+
+	cases := map[string]string{
+		"bob":                                "bob OR robert",
+		"Robert":                             "Robert OR bob",
+		"payments to IBM from the bank":      `payments OR IBM OR bank OR "international business machines"`,
+		"le rapport de la SNCF sur les prix": `rapport OR SNCF OR prix OR "société nationale des chemins de fer"`,
+		"the SNCF report and the budget":     "SNCF OR report OR budget",
+	}
+	for query, want := range cases {
+		if got := expandQuery(query); got != want {
+			t.Errorf("expandQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+
+	// A document naming only the canonical form counts as matching the alias
+	terms := expandTerms("bob", QueryTerms("bob"))
+	if got := MatchTerms(terms, "Memo from Robert Smith"); !reflect.DeepEqual(got, []string{"robert"}) {
+		t.Errorf("MatchTerms = %v, want [robert]", got)
+	}
+}
+
+func TestExpandQueryIsBounded(t *testing.T) {
+	useSynonyms(t, `{"en": [
+		["a1", "a2", "a3", "a4", "a5", "a6", "a7"],
+		["b1", "b2", "b3", "b4", "b5", "b6", "b7"]
+	]}`)
+
+	got := expandTerms("a1 b1", []string{"a1", "b1"})
+	want := []string{"a1", "b1", "a2", "a3", "a4", "b2", "b3", "b4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandTerms = %v, want %v (MaxSynonyms %d each)", got, want, MaxSynonyms)
+	}
+
+	defer func(old int) { MaxExpandedTerms = old }(MaxExpandedTerms)
+	MaxExpandedTerms = 4
+	got = expandTerms("a1 b1", []string{"a1", "b1"})
+	if want := []string{"a1", "b1", "a2", "a3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("capped at 4: %v, want %v", got, want)
+	}
+
+	// The query's own terms are kept even past the cap
+	MaxExpandedTerms = 1
+	if got := expandTerms("a1 b1", []string{"a1", "b1"}); !reflect.DeepEqual(got, []string{"a1", "b1"}) {
+		t.Errorf("capped at 1: %v, want the original terms", got)
+	}
+}
+
+func TestSearchFindsCanonicalTermByAlias(t *testing.T) {
+	freshDB(t)
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	useSynonyms(t, `{"en": [["robert", "bob"]]}`)
+
+	if _, err := InsertDocument("memo.txt", "Memo", "Robert approved the wire transfer on Friday.", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InsertDocument("other.txt", "Other", "Nothing about anyone in particular here.", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Search("bob", 10, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Filename != "memo.txt" {
+		t.Fatalf("results = %+v, want memo.txt", results)
+	}
+	if !reflect.DeepEqual(results[0].MatchedTerms, []string{"robert"}) {
+		t.Errorf("matched terms = %v", results[0].MatchedTerms)
+	}
+}
@@ -0,0 +1,150 @@
+// Package errs classifies errors into a few categories that decide the
+// HTTP status and the message a client sees, so handlers don't each pick
+// a status or leak internal error text.
+package errs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+)
+
+// Kind is an error category
+type Kind int
+
+const (
+	Internal   Kind = iota // bug or unexpected failure
+	NotFound               // the requested thing doesn't exist (or isn't visible)
+	Validation             // the request is malformed or out of range
+	Upstream               // a backend (LLM, database, service) failed
+	Timeout                // a deadline ran out on our side
+	Canceled               // the client went away
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not_found"
+	case Validation:
+		return "validation"
+	case Upstream:
+		return "upstream"
+	case Timeout:
+		return "timeout"
+	case Canceled:
+		return "canceled"
+	}
+	return "internal"
+}
+
+// StatusClientClosed is the nginx convention for a request the client
+// abandoned; nobody reads the response, it only shows up in logs
+const StatusClientClosed = 499
+
+// Status is the HTTP status for a kind
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return 404
+	case Validation:
+		return 400
+	case Upstream:
+		return 502
+	case Timeout:
+		return 503
+	case Canceled:
+		return StatusClientClosed
+	}
+	return 500
+}
+
+// Error is a categorized error. Msg is safe to show clients; Err, the
+// cause, is only for logs.
+type Error struct {
+	Kind Kind
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New builds a categorized error with a client-facing message
+func New(kind Kind, msg string) error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+// Wrap categorizes err, hiding its text behind msg
+func Wrap(kind Kind, msg string, err error) error {
+	return &Error{Kind: kind, Msg: msg, Err: err}
+}
+
+// KindOf categorizes err. Cancellation and deadlines anywhere in the chain
+// win over an explicit kind, since a backend call that timed out is a
+// timeout however it was wrapped; sql.ErrNoRows is NotFound; anything
+// unrecognized is Internal.
+func KindOf(err error) Kind {
+	if err == nil {
+		return Internal
+	}
+	if errors.Is(err, context.Canceled) {
+		return Canceled
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound
+	}
+	return Internal
+}
+
+// Status is the HTTP status for err
+func Status(err error) int {
+	return KindOf(err).Status()
+}
+
+// Message is the client-facing text for err: the Msg of a categorized
+// error, or a generic line for its kind. Raw error text never leaks.
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Msg != "" {
+		switch kind := KindOf(err); kind {
+		case Timeout, Canceled:
+			return genericMessage(kind)
+		}
+		return e.Msg
+	}
+	return genericMessage(KindOf(err))
+}
+
+func genericMessage(kind Kind) string {
+	switch kind {
+	case NotFound:
+		return "Not found"
+	case Validation:
+		return "Invalid request"
+	case Upstream:
+		return "Upstream service failed"
+	case Timeout:
+		return "Timed out"
+	case Canceled:
+		return "Request canceled"
+	}
+	return "Internal error"
+}
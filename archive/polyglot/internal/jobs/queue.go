@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"hybridcore/internal/clock"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var ErrQueueFull = errors.New("job queue full")
+
+// Finished jobs are kept this long for status polling
+const retention = time.Hour
+
+type Job struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Status    Status      `json:"status"`
+	Progress  float64     `json:"progress"`
+	Stage     string      `json:"stage,omitempty"`
+	Result    interface{} `json:"result,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ProgressFunc lets a task report how far along it is (0..1) and what it's doing
+type ProgressFunc func(progress float64, stage string)
+
+type Task func(progress ProgressFunc) (interface{}, error)
+
+type queued struct {
+	id   string
+	task Task
+}
+
+// Queue runs tasks on a fixed pool of workers fed by a buffered channel.
+// Job state is in memory only and does not survive a restart.
+type Queue struct {
+	jobs    map[string]*Job
+	mu      sync.RWMutex
+	pending chan queued
+	wg      sync.WaitGroup
+}
+
+func NewQueue(workers, size int) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	q := &Queue{
+		jobs:    make(map[string]*Job),
+		pending: make(chan queued, size),
+	}
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Enqueue registers a pending job and hands it to the workers without
+// blocking; ErrQueueFull is returned when the buffer is exhausted.
+func (q *Queue) Enqueue(jobType string, task Task) (Job, error) {
+	now := clock.Now()
+	job := &Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	q.mu.Lock()
+	q.prune(now)
+	q.jobs[job.ID] = job
+	snapshot := *job
+	q.mu.Unlock()
+
+	select {
+	case q.pending <- queued{id: job.ID, task: task}:
+		return snapshot, nil
+	default:
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+		return Job{}, ErrQueueFull
+	}
+}
+
+// Get returns a snapshot of the job's current state
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Stop stops accepting work and waits for queued jobs to finish
+func (q *Queue) Stop() {
+	close(q.pending)
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	for item := range q.pending {
+		q.update(item.id, func(j *Job) {
+			j.Status = StatusRunning
+		})
+
+		result, err := q.run(item)
+
+		q.update(item.id, func(j *Job) {
+			if err != nil {
+				j.Status = StatusFailed
+				j.Error = err.Error()
+				return
+			}
+			j.Status = StatusDone
+			j.Progress = 1
+			j.Result = result
+		})
+	}
+}
+
+// run executes a task, converting a panic into a job failure
+func (q *Queue) run(item queued) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Jobs] Job %s panicked: %v", item.id, r)
+			err = errors.New("job panicked")
+		}
+	}()
+
+	return item.task(func(progress float64, stage string) {
+		q.update(item.id, func(j *Job) {
+			j.Progress = progress
+			j.Stage = stage
+		})
+	})
+}
+
+func (q *Queue) update(id string, fn func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job, ok := q.jobs[id]; ok {
+		fn(job)
+		job.UpdatedAt = clock.Now()
+	}
+}
+
+// prune drops finished jobs past retention; caller holds q.mu
+func (q *Queue) prune(now time.Time) {
+	for id, job := range q.jobs {
+		finished := job.Status == StatusDone || job.Status == StatusFailed
+		if finished && now.Sub(job.UpdatedAt) > retention {
+			delete(q.jobs, id)
+		}
+	}
+}
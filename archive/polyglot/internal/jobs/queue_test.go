@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls the job until it reaches status
+func waitFor(t *testing.T, q *Queue, id string, status Status) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, ok := q.Get(id)
+		if !ok {
+			t.Fatalf("job %s gone", id)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	q := NewQueue(1, 4)
+	defer q.Stop()
+
+	release, reported := make(chan struct{}), make(chan struct{})
+	first, err := q.Enqueue("extract", func(progress ProgressFunc) (interface{}, error) {
+		progress(0.5, "extracting")
+		close(reported)
+		<-release
+		return []string{"alice@example.com"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != StatusPending || first.Type != "extract" || first.ID == "" {
+		t.Errorf("enqueued = %+v", first)
+	}
+
+	<-reported
+	running := waitFor(t, q, first.ID, StatusRunning)
+	if running.Progress != 0.5 || running.Stage != "extracting" {
+		t.Errorf("running = %+v", running)
+	}
+
+	// The one worker is busy, so the next job waits
+	second, _ := q.Enqueue("extract", func(ProgressFunc) (interface{}, error) { return nil, errors.New("bad input") })
+	if job, _ := q.Get(second.ID); job.Status != StatusPending {
+		t.Errorf("second job is %s while the worker is busy", job.Status)
+	}
+
+	close(release)
+	done := waitFor(t, q, first.ID, StatusDone)
+	if done.Progress != 1 || done.Result.([]string)[0] != "alice@example.com" || done.Error != "" {
+		t.Errorf("done = %+v", done)
+	}
+	if done.CreatedAt.Location() != time.UTC || done.UpdatedAt.Location() != time.UTC {
+		t.Errorf("timestamps not UTC: %v, %v", done.CreatedAt, done.UpdatedAt)
+	}
+	if !done.UpdatedAt.After(done.CreatedAt) && !done.UpdatedAt.Equal(done.CreatedAt) {
+		t.Errorf("updated %v before created %v", done.UpdatedAt, done.CreatedAt)
+	}
+
+	failed := waitFor(t, q, second.ID, StatusFailed)
+	if failed.Error != "bad input" || failed.Result != nil {
+		t.Errorf("failed = %+v", failed)
+	}
+}
+
+func TestJobPanicFails(t *testing.T) {
+	q := NewQueue(1, 1)
+	defer q.Stop()
+
+	job, _ := q.Enqueue("extract", func(ProgressFunc) (interface{}, error) { panic("boom") })
+	if failed := waitFor(t, q, job.ID, StatusFailed); failed.Error != "job panicked" {
+		t.Errorf("error = %q", failed.Error)
+	}
+}
+
+func TestEnqueueFullQueue(t *testing.T) {
+	q := NewQueue(1, 1)
+	release := make(chan struct{})
+	block := func(ProgressFunc) (interface{}, error) { <-release; return nil, nil }
+
+	first, _ := q.Enqueue("a", block)
+	waitFor(t, q, first.ID, StatusRunning)
+	if _, err := q.Enqueue("b", block); err != nil {
+		t.Fatalf("buffered job: %v", err)
+	}
+	if _, err := q.Enqueue("c", block); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("err = %v, want ErrQueueFull", err)
+	}
+	close(release)
+	q.Stop()
+}
@@ -2,16 +2,30 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"hybridcore/internal/errs"
 )
 
+// Client talks to one or more LLM servers. Requests go to the backends in
+// order (healthy ones first) and fail over to the next on error or 5xx.
 type Client struct {
-	baseURL    string
+	backends   []*backend
 	httpClient *http.Client
+	balance    bool
+	next       atomic.Uint32
+}
+
+type backend struct {
+	baseURL string
+	healthy atomic.Bool
 }
 
 type GenerateRequest struct {
@@ -41,8 +55,9 @@ type IntentResponse struct {
 }
 
 type AnalyzeRequest struct {
-	Query   string `json:"query"`
-	Context string `json:"context"`
+	Query     string `json:"query"`
+	Context   string `json:"context"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
 }
 
 type AnalyzeResponse struct {
@@ -53,22 +68,83 @@ type AnalyzeResponse struct {
 }
 
 type HealthResponse struct {
+	Status   string          `json:"status"`
+	Model    string          `json:"model"`
+	Ready    bool            `json:"ready"`
+	Backends []BackendHealth `json:"backends,omitempty"`
+}
+
+type BackendHealth struct {
+	URL    string `json:"url"`
 	Status string `json:"status"`
-	Model  string `json:"model"`
+	Model  string `json:"model,omitempty"`
 	Ready  bool   `json:"ready"`
+	Error  string `json:"error,omitempty"`
 }
 
 func NewClient(host string, port int) *Client {
-	return &Client{
-		baseURL: fmt.Sprintf("http://%s:%d", host, port),
+	return NewMultiClient([]string{fmt.Sprintf("http://%s:%d", host, port)}, false)
+}
+
+// NewMultiClient builds a client over several LLM base URLs, tried in the
+// given order. With balance set, requests rotate across healthy backends.
+func NewMultiClient(baseURLs []string, balance bool) *Client {
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		balance: balance,
+	}
+	for _, u := range baseURLs {
+		b := &backend{baseURL: strings.TrimRight(u, "/")}
+		b.healthy.Store(true)
+		c.backends = append(c.backends, b)
 	}
+	return c
 }
 
+// Health probes every backend. The top-level fields reflect the first ready
+// backend (or the first reachable one); an error means none answered.
 func (c *Client) Health() (*HealthResponse, error) {
-	resp, err := c.httpClient.Get(c.baseURL + "/health")
+	return c.HealthContext(context.Background())
+}
+
+// HealthContext is Health bounded by ctx as well as the client timeout
+func (c *Client) HealthContext(ctx context.Context) (*HealthResponse, error) {
+	var overall *HealthResponse
+	var lastErr error
+	backends := make([]BackendHealth, 0, len(c.backends))
+
+	for _, b := range c.backends {
+		health, err := c.backendHealth(ctx, b)
+		if err != nil {
+			b.healthy.Store(false)
+			lastErr = err
+			backends = append(backends, BackendHealth{URL: b.baseURL, Status: "offline", Error: err.Error()})
+			continue
+		}
+
+		b.healthy.Store(true)
+		backends = append(backends, BackendHealth{URL: b.baseURL, Status: health.Status, Model: health.Model, Ready: health.Ready})
+		if overall == nil || (!overall.Ready && health.Ready) {
+			overall = health
+		}
+	}
+
+	if overall == nil {
+		return nil, lastErr
+	}
+
+	overall.Backends = backends
+	return overall, nil
+}
+
+func (c *Client) backendHealth(ctx context.Context, b *backend) (*HealthResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/health", nil)
+	if err != nil {
+		return nil, fmt.Errorf("health check failed: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("health check failed: %w", err)
 	}
@@ -125,10 +201,13 @@ func (c *Client) ParseIntent(query string) (*IntentResponse, error) {
 	return &resp, nil
 }
 
-func (c *Client) Analyze(query, context string) (*AnalyzeResponse, error) {
+// Analyze answers query from context; maxTokens 0 leaves the budget to
+// the server
+func (c *Client) Analyze(query, context string, maxTokens int) (*AnalyzeResponse, error) {
 	req := AnalyzeRequest{
-		Query:   query,
-		Context: context,
+		Query:     query,
+		Context:   context,
+		MaxTokens: maxTokens,
 	}
 
 	body, err := c.postRaw("/analyze", req)
@@ -150,11 +229,53 @@ func (c *Client) postRaw(path string, reqBody interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.baseURL+path, "application/json", bytes.NewReader(data))
+	var lastErr error
+	for _, b := range c.order() {
+		body, err := c.postBackend(b, path, data)
+		if err != nil {
+			b.healthy.Store(false)
+			lastErr = err
+			continue
+		}
+		b.healthy.Store(true)
+		return body, nil
+	}
+
+	return nil, errs.Wrap(errs.Upstream, "LLM unavailable", lastErr)
+}
+
+func (c *Client) postBackend(b *backend, path string, data []byte) ([]byte, error) {
+	resp, err := c.httpClient.Post(b.baseURL+path, "application/json", bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("post %s: %w", path, err)
+		return nil, fmt.Errorf("post %s%s: %w", b.baseURL, path, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("post %s%s: status %d", b.baseURL, path, resp.StatusCode)
+	}
+
 	return io.ReadAll(resp.Body)
 }
+
+// order returns backends to try: healthy ones first (rotated when
+// balancing), then unhealthy ones as a last resort.
+func (c *Client) order() []*backend {
+	n := len(c.backends)
+	start := 0
+	if c.balance && n > 1 {
+		start = int(c.next.Add(1)-1) % n
+	}
+
+	healthy := make([]*backend, 0, n)
+	var unhealthy []*backend
+	for i := 0; i < n; i++ {
+		b := c.backends[(start+i)%n]
+		if b.healthy.Load() {
+			healthy = append(healthy, b)
+		} else {
+			unhealthy = append(unhealthy, b)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hybridcore/internal/errs"
+)
+
+// A readiness probe's deadline must cut a hung health check short
+func TestHealthContextHonoursDeadline(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	c := NewMultiClient([]string{backend.URL}, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.HealthContext(ctx); err == nil {
+		t.Fatal("HealthContext succeeded against a hung backend")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("HealthContext took %v, want it bounded by the 50ms deadline", elapsed)
+	}
+}
+
+// A primary answering 5xx is skipped for the secondary, and once marked
+// unhealthy it is tried last
+func TestGenerateFailsOver(t *testing.T) {
+	var primaryCalls, secondaryCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls.Add(1)
+		http.Error(w, "model crashed", http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryCalls.Add(1)
+		io.WriteString(w, `{"text":"from secondary","tokens_used":3}`)
+	}))
+	defer secondary.Close()
+
+	c := NewMultiClient([]string{primary.URL, secondary.URL}, false)
+	for i := 0; i < 2; i++ {
+		resp, err := c.Generate("hello", 0, 0)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if resp.Text != "from secondary" {
+			t.Errorf("call %d: text = %q, want the secondary's answer", i, resp.Text)
+		}
+	}
+	if got := primaryCalls.Load(); got != 1 {
+		t.Errorf("primary called %d times, want once before it was marked unhealthy", got)
+	}
+	if got := secondaryCalls.Load(); got != 2 {
+		t.Errorf("secondary called %d times, want 2", got)
+	}
+}
+
+func TestGenerateAllBackendsDown(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	c := NewMultiClient([]string{closed.URL, down.URL}, false)
+	_, err := c.Generate("hello", 0, 0)
+	if err == nil {
+		t.Fatal("Generate succeeded with every backend down")
+	}
+	if errs.KindOf(err) != errs.Upstream {
+		t.Errorf("err = %v, want an Upstream error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package nlp
+
+import "sort"
+
+// Canonical entity types: the one vocabulary for the graph and API
+// clients, whichever producer found the entity
+const (
+	TypePerson        = "person"
+	TypeOrganization  = "organization"
+	TypeEmail         = "email"
+	TypePhone         = "phone"
+	TypeURL           = "url"
+	TypeDomain        = "domain"
+	TypeIPAddress     = "ip_address"
+	TypeMACAddress    = "mac_address"
+	TypeDate          = "date"
+	TypeTime          = "time"
+	TypeMoney         = "money"
+	TypeCryptoAddress = "crypto_address"
+	TypeBankAccount   = "bank_account"
+	TypePaymentCard   = "payment_card"
+	TypeHandle        = "handle"
+	TypeHashtag       = "hashtag"
+	TypeIdentifier    = "identifier"
+	TypeHash          = "hash"
+	TypeCredential    = "credential"
+	TypeFilePath      = "file_path"
+)
+
+// canonicalTypes maps regex pattern names and NLP engine labels to their
+// canonical type. Code and encoding matches (function_call, base64, ...)
+// aren't entities and have no entry.
+var canonicalTypes = map[string]string{
+	// Regex patterns
+	"person_name":    TypePerson,
+	"organization":   TypeOrganization,
+	"email":          TypeEmail,
+	"phone":          TypePhone,
+	"url":            TypeURL,
+	"domain":         TypeDomain,
+	"ip_address":     TypeIPAddress,
+	"ipv6_address":   TypeIPAddress,
+	"mac_address":    TypeMACAddress,
+	"date_iso":       TypeDate,
+	"date_eu":        TypeDate,
+	"date_text":      TypeDate,
+	"time":           TypeTime,
+	"currency":       TypeMoney,
+	"btc_address":    TypeCryptoAddress,
+	"eth_address":    TypeCryptoAddress,
+	"iban":           TypeBankAccount,
+	"credit_card":    TypePaymentCard,
+	"twitter_handle": TypeHandle,
+	"mention":        TypeHandle,
+	"hashtag":        TypeHashtag,
+	"uuid":           TypeIdentifier,
+	"md5_hash":       TypeHash,
+	"sha1_hash":      TypeHash,
+	"sha256_hash":    TypeHash,
+	"jwt":            TypeCredential,
+	"password_leak":  TypeCredential,
+	"private_key":    TypeCredential,
+	"aws_key":        TypeCredential,
+	"github_token":   TypeCredential,
+	"file_path":      TypeFilePath,
+
+	// NLP engine labels not covered above
+	"potential_name": TypePerson,
+	"date":           TypeDate,
+	"crypto_btc":     TypeCryptoAddress,
+	"crypto_eth":     TypeCryptoAddress,
+	"twitter":        TypeHandle,
+}
+
+// CanonicalType returns the canonical type for a pattern name or NLP
+// label; false when it names no entity
+func CanonicalType(typ string) (string, bool) {
+	canonical, ok := canonicalTypes[typ]
+	return canonical, ok
+}
+
+// Mention is where an entity appears in the text
+type Mention struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// CanonicalEntity is one distinct entity in the canonical taxonomy, merged
+// across its mentions and the patterns that found it
+type CanonicalEntity struct {
+	ID         string    `json:"id"` // type:normalized, stable across calls and documents
+	Type       string    `json:"type"`
+	Value      string    `json:"value"` // first spelling in the text
+	Normalized string    `json:"normalized"`
+	Confidence float64   `json:"confidence"` // highest among its mentions
+	Sensitive  bool      `json:"sensitive,omitempty"`
+	Count      int       `json:"count"`
+	Sources    []string  `json:"sources"` // patterns or NLP labels that found it
+	Mentions   []Mention `json:"mentions"`
+}
+
+// Canonicalize maps entities to the canonical taxonomy and merges those
+// with the same type and normalized value, in order of first mention.
+// Entities of no canonical type are dropped, as are mentions lying inside
+// a longer one found with at least the same confidence (the domain of an
+// email address, say).
+func Canonicalize(entities []NamedEntity) []CanonicalEntity {
+	type typedEntity struct {
+		NamedEntity
+		canonical string
+	}
+	var typed []typedEntity
+	for _, n := range entities {
+		if typ, ok := CanonicalType(n.Type); ok && n.Normalized != "" {
+			typed = append(typed, typedEntity{n, typ})
+		}
+	}
+	sort.SliceStable(typed, func(i, j int) bool {
+		a, b := typed[i], typed[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End > b.End
+		}
+		return a.Confidence > b.Confidence
+	})
+
+	out := []CanonicalEntity{}
+	index := make(map[string]int)
+	var cover *typedEntity // the kept mention reaching furthest
+	for i := range typed {
+		n := typed[i]
+		if cover != nil && n.End <= cover.End && n.Confidence <= cover.Confidence {
+			continue
+		}
+		if cover == nil || n.End > cover.End {
+			cover = &typed[i]
+		}
+
+		typ := n.canonical
+		id := typ + ":" + n.Normalized
+		mention := Mention{Start: n.Start, End: n.End}
+		at, seen := index[id]
+		if !seen {
+			index[id] = len(out)
+			out = append(out, CanonicalEntity{
+				ID:         id,
+				Type:       typ,
+				Value:      n.Value,
+				Normalized: n.Normalized,
+				Confidence: n.Confidence,
+				Sensitive:  n.Sensitive,
+				Count:      1,
+				Sources:    []string{n.Type},
+				Mentions:   []Mention{mention},
+			})
+			continue
+		}
+
+		e := &out[at]
+		e.Count++
+		e.Mentions = append(e.Mentions, mention)
+		if n.Confidence > e.Confidence {
+			e.Confidence = n.Confidence
+		}
+		e.Sensitive = e.Sensitive || n.Sensitive
+		if !containsString(e.Sources, n.Type) {
+			e.Sources = append(e.Sources, n.Type)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
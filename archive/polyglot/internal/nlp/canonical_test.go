@@ -0,0 +1,73 @@
+package nlp
+
+import (
+	"testing"
+
+	"hybridcore/internal/regex"
+)
+
+func TestCanonicalType(t *testing.T) {
+	cases := map[string]string{
+		"ipv6_address":   TypeIPAddress,
+		"mention":        TypeHandle,
+		"twitter":        TypeHandle,
+		"potential_name": TypePerson,
+		"sha256_hash":    TypeHash,
+		"iban":           TypeBankAccount,
+		"aws_key":        TypeCredential,
+	}
+	for in, want := range cases {
+		if got, ok := CanonicalType(in); !ok || got != want {
+			t.Errorf("CanonicalType(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"base64", "function_call", "no_such_label"} {
+		if got, ok := CanonicalType(in); ok {
+			t.Errorf("CanonicalType(%q) = %q, want no entity", in, got)
+		}
+	}
+}
+
+// Spellings of one value merge into one canonical entity, across patterns
+// and producers; a domain inside an email is not an entity of its own
+func TestCanonicalizeMergesByNormalizedValue(t *testing.T) {
+	text := "Alice@Example.com wrote @Bob; cc alice@example.com and bob"
+	matches := []regex.Match{
+		{Pattern: "email", Category: regex.CategoryCommunication, Value: "Alice@Example.com", Start: 0, End: 17, Confidence: 0.95},
+		{Pattern: "domain", Category: regex.CategoryNetwork, Value: "Example.com", Start: 6, End: 17, Confidence: 0.8},
+		{Pattern: "mention", Category: regex.CategorySocial, Value: "@Bob", Start: 24, End: 28, Confidence: 0.7},
+		{Pattern: "twitter_handle", Category: regex.CategorySocial, Value: "@Bob", Start: 24, End: 28, Confidence: 0.9},
+		{Pattern: "email", Category: regex.CategoryCommunication, Value: "alice@example.com", Start: 33, End: 50, Confidence: 0.95},
+		{Pattern: "base64", Category: regex.CategoryEncoding, Value: "bob", Start: 55, End: 58, Confidence: 0.3},
+	}
+	for _, m := range matches {
+		if text[m.Start:m.End] != m.Value {
+			t.Fatalf("bad fixture: %q at %d", m.Value, m.Start)
+		}
+	}
+	named := append(FromMatches(matches), FromEntity("twitter", Entity{Value: "bob", Start: 55, End: 58, Confidence: 0.5}))
+
+	got := Canonicalize(named)
+	if len(got) != 2 {
+		t.Fatalf("got %d entities, want email and handle: %+v", len(got), got)
+	}
+
+	email, handle := got[0], got[1]
+	if email.ID != "email:alice@example.com" || email.Type != TypeEmail || email.Value != "Alice@Example.com" {
+		t.Errorf("email = %+v", email)
+	}
+	if email.Count != 2 || len(email.Mentions) != 2 || email.Mentions[1] != (Mention{33, 50}) {
+		t.Errorf("email mentions = %+v", email.Mentions)
+	}
+
+	if handle.ID != "handle:bob" || handle.Normalized != "bob" || handle.Confidence != 0.9 {
+		t.Errorf("handle = %+v", handle)
+	}
+	if handle.Count != 2 {
+		t.Errorf("handle count = %d, want the @Bob mention and the NLP one", handle.Count)
+	}
+	want := []string{"twitter_handle", "twitter"}
+	if len(handle.Sources) != 2 || handle.Sources[0] != want[0] || handle.Sources[1] != want[1] {
+		t.Errorf("handle sources = %v, want %v", handle.Sources, want)
+	}
+}
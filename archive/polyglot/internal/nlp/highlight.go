@@ -0,0 +1,198 @@
+package nlp
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Highlight markers, the ones ts_headline puts around search excerpts
+const (
+	DefaultStartSel = "**"
+	DefaultStopSel  = "**"
+)
+
+// Highlighter marks query terms in plain text, for excerpts that don't come
+// out of ts_headline. A word matches when it starts with a term, ignoring
+// case, the way db.MatchTerms counts matches; the whole word is marked. A
+// term of several words is a phrase: it matches a run of consecutive words
+// each starting with the phrase's word in turn, marked as one span.
+type Highlighter struct {
+	StartSel string
+	StopSel  string
+	terms    [][]string // lowercased words of each term, longest first
+}
+
+// NewHighlighter returns a highlighter for terms using the default markers
+func NewHighlighter(terms []string) *Highlighter {
+	h := &Highlighter{StartSel: DefaultStartSel, StopSel: DefaultStopSel}
+	for _, t := range terms {
+		if words := Tokenize(t); len(words) > 0 {
+			h.terms = append(h.terms, words)
+		}
+	}
+	// Try phrases before their own first word so "new york" wins over "new"
+	sort.SliceStable(h.terms, func(i, j int) bool {
+		return len(h.terms[i]) > len(h.terms[j])
+	})
+	return h
+}
+
+// span is a byte range of text
+type span struct {
+	start, end int
+}
+
+// words splits text into its words' spans
+func words(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// matches returns the spans of text matching a term, in order
+func (h *Highlighter) matches(text string) []span {
+	if len(h.terms) == 0 {
+		return nil
+	}
+	ws := words(text)
+	var spans []span
+	for i := 0; i < len(ws); {
+		n := h.matchAt(text, ws[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		spans = append(spans, span{ws[i].start, ws[i+n-1].end})
+		i += n
+	}
+	return spans
+}
+
+// matchAt returns how many of ws, from the first, the longest matching term
+// covers; 0 when none matches there
+func (h *Highlighter) matchAt(text string, ws []span) int {
+	for _, term := range h.terms {
+		if len(term) > len(ws) {
+			continue
+		}
+		matched := true
+		for k, word := range term {
+			if !strings.HasPrefix(strings.ToLower(text[ws[k].start:ws[k].end]), word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(term)
+		}
+	}
+	return 0
+}
+
+// Highlight returns text with every matching word wrapped in the markers
+func (h *Highlighter) Highlight(text string) string {
+	spans := h.matches(text)
+	if len(spans) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.start])
+		b.WriteString(h.StartSel)
+		b.WriteString(text[s.start:s.end])
+		b.WriteString(h.StopSel)
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Snippet returns about window runes of text around the first match,
+// trimmed to whole words and highlighted, with "..." where text was cut.
+// Without a match the window starts at the beginning of text; window <= 0
+// keeps the whole text.
+func (h *Highlighter) Snippet(text string, window int) string {
+	if window <= 0 || utf8.RuneCountInString(text) <= window {
+		return h.Highlight(text)
+	}
+
+	anchor := span{}
+	if spans := h.matches(text); len(spans) > 0 {
+		anchor = spans[0]
+	}
+
+	// Center the window on the match, giving room the text can't fill on
+	// one side to the other
+	lo, hi := anchor.start, anchor.end
+	n := utf8.RuneCountInString(text[lo:hi])
+	for before := (window - n) / 2; lo > 0 && before > 0; before-- {
+		_, size := utf8.DecodeLastRuneInString(text[:lo])
+		lo -= size
+		n++
+	}
+	for ; hi < len(text) && n < window; n++ {
+		_, size := utf8.DecodeRuneInString(text[hi:])
+		hi += size
+	}
+	for ; lo > 0 && n < window; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:lo])
+		lo -= size
+	}
+
+	// Drop words cut by either edge, never the match itself
+	if lo > 0 && wordBefore(text, lo) {
+		for lo < anchor.start && wordAt(text, lo) {
+			_, size := utf8.DecodeRuneInString(text[lo:])
+			lo += size
+		}
+	}
+	if hi < len(text) && wordAt(text, hi) {
+		for hi > anchor.end && wordBefore(text, hi) {
+			_, size := utf8.DecodeLastRuneInString(text[:hi])
+			hi -= size
+		}
+	}
+
+	snippet := h.Highlight(strings.TrimSpace(text[lo:hi]))
+	if lo > 0 {
+		snippet = "..." + snippet
+	}
+	if hi < len(text) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordAt reports whether the rune starting at i is part of a word
+func wordAt(text string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return isWordRune(r)
+}
+
+// wordBefore reports whether the rune ending at i is part of a word
+func wordBefore(text string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return isWordRune(r)
+}
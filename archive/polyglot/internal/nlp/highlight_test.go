@@ -0,0 +1,63 @@
+package nlp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightMarksWholeWordsIgnoringCase(t *testing.T) {
+	h := NewHighlighter([]string{"invest", "GO"})
+	got := h.Highlight("Investments in Go, not golf; ago.")
+	want := "**Investments** in **Go**, not **golf**; ago."
+	if got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+}
+
+func TestHighlightMarksPhrasesAsOneSpan(t *testing.T) {
+	h := NewHighlighter([]string{"new", "new york"})
+	got := h.Highlight("New  York is new; york alone is not, nor New Jersey.")
+	want := "**New  York** is **new; york** alone is not, nor **New** Jersey."
+	if got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+
+	// A phrase's words must be consecutive
+	h = NewHighlighter([]string{"machine learning"})
+	if got := h.Highlight("machine and learning"); got != "machine and learning" {
+		t.Errorf("split phrase = %q, want no marks", got)
+	}
+}
+
+func TestHighlightCustomMarkers(t *testing.T) {
+	h := NewHighlighter([]string{"cat"})
+	h.StartSel, h.StopSel = "<b>", "</b>"
+	if got := h.Highlight("a Cat sat"); got != "a <b>Cat</b> sat" {
+		t.Errorf("Highlight = %q", got)
+	}
+}
+
+func TestSnippetWindowsAroundFirstMatch(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve"
+	h := NewHighlighter([]string{"seven"})
+
+	got := h.Snippet(text, 20)
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("Snippet = %q, want cut on both sides", got)
+	}
+	if !strings.Contains(got, "**seven**") {
+		t.Errorf("Snippet = %q, want the match marked", got)
+	}
+	for _, cut := range []string{"...ne", "...ix", "igh...", "ig..."} {
+		if strings.Contains(got, cut) {
+			t.Errorf("Snippet = %q splits a word", got)
+		}
+	}
+
+	if got := h.Snippet(text, 0); got != h.Highlight(text) {
+		t.Errorf("window 0 = %q, want the whole text", got)
+	}
+	if got := NewHighlighter(nil).Snippet(text, 10); !strings.HasPrefix(got, "one") {
+		t.Errorf("no match = %q, want the start of text", got)
+	}
+}
@@ -0,0 +1,96 @@
+package nlp
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Keyword is a scored term, shaped like the keywords in AnalyzeResponse
+type Keyword struct {
+	Word  string  `json:"word"`
+	Count int     `json:"count"`
+	Score float64 `json:"score"`
+}
+
+// DocFreqFunc returns, for each term, the number of corpus documents
+// containing it, plus the total number of documents in the corpus.
+type DocFreqFunc func(terms []string) (map[string]int, int, error)
+
+// Tokenize lowercases text and splits it into letter/digit runs
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ExtractKeywords ranks the meaningful terms of text. When docFreq is set
+// and the corpus is non-empty, terms are scored by TF-IDF; otherwise by raw
+// term frequency. The second return value names the scoring used.
+func ExtractKeywords(text string, limit int, docFreq DocFreqFunc) ([]Keyword, string) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, tok := range Tokenize(text) {
+		if len([]rune(tok)) < 3 || IsStopword(tok) || isNumeric(tok) {
+			continue
+		}
+		counts[tok]++
+		total++
+	}
+
+	if total == 0 {
+		return []Keyword{}, "tf"
+	}
+
+	terms := make([]string, 0, len(counts))
+	for t := range counts {
+		terms = append(terms, t)
+	}
+
+	var df map[string]int
+	var totalDocs int
+	scoring := "tf"
+	if docFreq != nil {
+		var err error
+		df, totalDocs, err = docFreq(terms)
+		if err == nil && totalDocs > 0 {
+			scoring = "tf-idf"
+		}
+	}
+
+	keywords := make([]Keyword, 0, len(terms))
+	for _, t := range terms {
+		score := float64(counts[t]) / float64(total)
+		if scoring == "tf-idf" {
+			idf := math.Log(float64(totalDocs+1)/float64(df[t]+1)) + 1
+			score *= idf
+		}
+		keywords = append(keywords, Keyword{Word: t, Count: counts[t], Score: score})
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Word < keywords[j].Word
+	})
+
+	if len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+	return keywords, scoring
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
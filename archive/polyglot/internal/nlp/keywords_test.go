@@ -0,0 +1,71 @@
+package nlp
+
+import (
+	"errors"
+	"testing"
+)
+
+const keywordText = `The wire transfer from the shell company was routed through
+Cyprus. Les fonds de la société écran sont passés par Chypre, et la société
+écran a reçu le virement. The shell company and the transfer were flagged;
+the shell company had no staff.`
+
+func TestExtractKeywordsDropsStopwords(t *testing.T) {
+	keywords, scoring := ExtractKeywords(keywordText, 50, nil)
+	if scoring != "tf" {
+		t.Errorf("scoring = %s, want tf without a corpus", scoring)
+	}
+	for _, k := range keywords {
+		if IsStopword(k.Word) || len([]rune(k.Word)) < 3 {
+			t.Errorf("kept %q", k.Word)
+		}
+	}
+
+	for _, w := range []string{"the", "from", "les", "par", "sont"} {
+		if !IsStopword(w) {
+			t.Fatalf("fixture: %q isn't a stopword", w)
+		}
+	}
+	if got, _ := ExtractKeywords("the of and le la les et", 5, nil); len(got) != 0 {
+		t.Errorf("stopwords only: %+v", got)
+	}
+}
+
+// Repeated domain terms lead on term frequency; with a corpus, a term in
+// every document drops below the rarer ones
+func TestExtractKeywordsRanksDomainTerms(t *testing.T) {
+	keywords, _ := ExtractKeywords(keywordText, 3, nil)
+	// company and shell tie on three mentions, broken alphabetically
+	if len(keywords) != 3 || keywords[0].Word != "company" || keywords[1].Word != "shell" || keywords[2].Word != "société" {
+		t.Fatalf("tf top 3 = %+v, want company, shell, société", keywords)
+	}
+	if keywords[1].Count != 3 || keywords[2].Count != 2 {
+		t.Errorf("counts = %d, %d, want 3, 2", keywords[1].Count, keywords[2].Count)
+	}
+
+	corpus := func(terms []string) (map[string]int, int, error) {
+		df := make(map[string]int)
+		for _, t := range terms {
+			df[t] = 1
+		}
+		df["company"] = 100 // in every document
+		return df, 100, nil
+	}
+	keywords, scoring := ExtractKeywords(keywordText, 5, corpus)
+	if scoring != "tf-idf" {
+		t.Fatalf("scoring = %s, want tf-idf", scoring)
+	}
+	if keywords[0].Word != "shell" {
+		t.Errorf("tf-idf top = %+v, want shell", keywords)
+	}
+	for _, k := range keywords {
+		if k.Word == "company" {
+			t.Errorf("company, in every document, still in the top 5: %+v", keywords)
+		}
+	}
+
+	failing := func([]string) (map[string]int, int, error) { return nil, 0, errors.New("db down") }
+	if _, scoring := ExtractKeywords(keywordText, 5, failing); scoring != "tf" {
+		t.Errorf("scoring = %s when the corpus lookup fails, want tf", scoring)
+	}
+}
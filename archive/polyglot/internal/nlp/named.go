@@ -0,0 +1,106 @@
+package nlp
+
+import (
+	"sort"
+
+	"hybridcore/internal/regex"
+)
+
+// Where a NamedEntity came from
+const (
+	SourceRegex = "regex"
+	SourceNLP   = "nlp"
+)
+
+// NamedEntity is the common shape for entities found by the regex matcher
+// or the Python NLP engine, so consumers don't branch on the producer.
+type NamedEntity struct {
+	Value      string         `json:"value"`
+	Type       string         `json:"type"`     // pattern name or NLP label, e.g. "email"
+	Category   regex.Category `json:"category"` // regex category; empty for NLP entities
+	Start      int            `json:"start"`
+	End        int            `json:"end"`
+	Confidence float64        `json:"confidence"`
+	Sensitive  bool           `json:"sensitive,omitempty"`
+	Source     string         `json:"source"`
+	Normalized string         `json:"normalized"`
+}
+
+// FromMatch converts a regex match
+func FromMatch(m regex.Match) NamedEntity {
+	return NamedEntity{
+		Value:      m.Value,
+		Type:       m.Pattern,
+		Category:   m.Category,
+		Start:      m.Start,
+		End:        m.End,
+		Confidence: m.Confidence,
+		Sensitive:  m.Sensitive,
+		Source:     SourceRegex,
+		Normalized: regex.Normalize(m.Pattern, m.Value),
+	}
+}
+
+// FromEntity converts an NLP entity; the NLP engine reports the type as
+// the key it groups entities under
+func FromEntity(entityType string, e Entity) NamedEntity {
+	return NamedEntity{
+		Value:      e.Value,
+		Type:       entityType,
+		Start:      e.Start,
+		End:        e.End,
+		Confidence: e.Confidence,
+		Source:     SourceNLP,
+		Normalized: regex.Normalize(entityType, e.Value),
+	}
+}
+
+// Match converts back to a regex match
+func (n NamedEntity) Match() regex.Match {
+	return regex.Match{
+		Pattern:    n.Type,
+		Category:   n.Category,
+		Value:      n.Value,
+		Start:      n.Start,
+		End:        n.End,
+		Confidence: n.Confidence,
+		Sensitive:  n.Sensitive,
+	}
+}
+
+// Entity converts back to an NLP entity (the type is dropped, as in the
+// NLP response it lives in the grouping key)
+func (n NamedEntity) Entity() Entity {
+	return Entity{
+		Value:      n.Value,
+		Start:      n.Start,
+		End:        n.End,
+		Confidence: n.Confidence,
+	}
+}
+
+// FromMatches converts a slice of regex matches
+func FromMatches(matches []regex.Match) []NamedEntity {
+	out := make([]NamedEntity, len(matches))
+	for i, m := range matches {
+		out[i] = FromMatch(m)
+	}
+	return out
+}
+
+// NamedEntities flattens the response's grouped entities, in text order
+func (r *ExtractResponse) NamedEntities() []NamedEntity {
+	var out []NamedEntity
+	for entityType, entities := range r.Entities {
+		for _, e := range entities {
+			out = append(out, FromEntity(entityType, e))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Start != out[j].Start {
+			return out[i].Start < out[j].Start
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
@@ -0,0 +1,49 @@
+package nlp
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+// SimHash fingerprints text so near-identical texts (a forwarded email, a
+// re-paste with a changed line) land a few bits apart. Features are
+// overlapping word pairs, so word order matters but a local edit only
+// disturbs the pairs around it.
+func SimHash(text string) uint64 {
+	tokens := Tokenize(text)
+	features := tokens
+	if len(tokens) > 1 {
+		features = make([]string, len(tokens)-1)
+		for i := range features {
+			features[i] = tokens[i] + " " + tokens[i+1]
+		}
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for _, f := range features {
+		h.Reset()
+		h.Write([]byte(f))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance counts the bits where two SimHash fingerprints differ
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
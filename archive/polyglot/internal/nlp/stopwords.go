@@ -0,0 +1,84 @@
+package nlp
+
+import "strings"
+
+// Stopwords for the two corpus languages (English + French)
+var englishStopwords = []string{
+	"a", "about", "above", "after", "again", "against", "all", "am", "an", "and",
+	"any", "are", "as", "at", "be", "because", "been", "before", "being", "below",
+	"between", "both", "but", "by", "can", "could", "did", "do", "does", "doing",
+	"down", "during", "each", "few", "for", "from", "further", "had", "has", "have",
+	"having", "he", "her", "here", "hers", "herself", "him", "himself", "his", "how",
+	"i", "if", "in", "into", "is", "it", "its", "itself", "just", "me", "more",
+	"most", "my", "myself", "no", "nor", "not", "now", "of", "off", "on", "once",
+	"only", "or", "other", "our", "ours", "ourselves", "out", "over", "own", "same",
+	"she", "should", "so", "some", "such", "than", "that", "the", "their", "theirs",
+	"them", "themselves", "then", "there", "these", "they", "this", "those",
+	"through", "to", "too", "under", "until", "up", "very", "was", "we", "were",
+	"what", "when", "where", "which", "while", "who", "whom", "why", "will", "with",
+	"would", "you", "your", "yours", "yourself", "yourselves",
+}
+
+var frenchStopwords = []string{
+	"a", "ai", "au", "aux", "avec", "avons", "avez", "c", "ce", "ces", "cet",
+	"cette", "d", "dans", "de", "des", "du", "elle", "elles", "en", "est", "et",
+	"été", "être", "eu", "il", "ils", "j", "je", "l", "la", "le", "les", "leur",
+	"leurs", "lui", "m", "ma", "mais", "me", "mes", "moi", "mon", "n", "ne", "nos",
+	"notre", "nous", "on", "ont", "ou", "où", "par", "pas", "pour", "qu", "que",
+	"quel", "quelle", "qui", "quoi", "s", "sa", "sans", "se", "ses", "son", "sont",
+	"sur", "t", "ta", "te", "tes", "toi", "ton", "tu", "un", "une", "vos", "votre",
+	"vous", "y",
+}
+
+var stopwords = buildStopwords(englishStopwords, frenchStopwords)
+
+func buildStopwords(lists ...[]string) map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range lists {
+		for _, w := range list {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// IsStopword reports whether word is an English or French stopword
+func IsStopword(word string) bool {
+	return stopwords[strings.ToLower(word)]
+}
+
+// Stopwords found in only one of the two lists, the cues DetectLanguage counts
+var (
+	englishOnly = exclusive(englishStopwords, frenchStopwords)
+	frenchOnly  = exclusive(frenchStopwords, englishStopwords)
+)
+
+func exclusive(list, other []string) map[string]bool {
+	set := buildStopwords(list)
+	for _, w := range other {
+		delete(set, w)
+	}
+	return set
+}
+
+// DetectLanguage guesses whether text is English ("en") or French ("fr")
+// from the stopwords only one of them uses. It returns "" when there is no
+// evidence either way, as with most short keyword queries.
+func DetectLanguage(text string) string {
+	var en, fr int
+	for _, tok := range Tokenize(text) {
+		switch {
+		case englishOnly[tok]:
+			en++
+		case frenchOnly[tok]:
+			fr++
+		}
+	}
+	switch {
+	case fr > en:
+		return "fr"
+	case en > fr:
+		return "en"
+	}
+	return ""
+}
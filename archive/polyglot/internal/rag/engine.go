@@ -1,97 +1,258 @@
 package rag
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"hybridcore/internal/clock"
 	"hybridcore/internal/db"
 	"hybridcore/internal/llm"
+	"hybridcore/internal/random"
+	"hybridcore/internal/regex"
 )
 
 type Engine struct {
 	llmClient *llm.Client
+	rng       *random.Source
+	weights   ScoreWeights
+	post      []Transformer
+	messages  Messages
+	language  string // messages for queries of no detectable language
+	matcher   *regex.Matcher
+	suggest   int // max suggested queries per answer
+	injection InjectionPolicy
+	wrapper   DocumentWrapper
 }
 
 type RAGResult struct {
 	Answer           string   `json:"answer"`
 	Sources          []Source `json:"sources"`
 	SuggestedQueries []string `json:"suggested_queries,omitempty"`
+	Fallback         string   `json:"fallback,omitempty"` // which fallback answered; empty for LLM synthesis
 }
 
+// Fallback levels, in the order they are tried once LLM synthesis fails
+const (
+	FallbackSmartAnswer = "smart_answer" // answer assembled from the top excerpts
+	FallbackSourcesOnly = "sources_only" // no answer text, only the sources
+	FallbackApology     = "apology"      // nothing to show; set by callers
+)
+
 type Source struct {
-	DocID   string  `json:"doc_id"`
-	Title   string  `json:"title"`
-	Excerpt string  `json:"excerpt"`
-	Rank    float64 `json:"rank"`
+	DocID        string         `json:"doc_id"`
+	Title        string         `json:"title"`
+	Excerpt      string         `json:"excerpt"`
+	Rank         float64        `json:"rank"`
+	MatchedTerms []string       `json:"matched_terms,omitempty"`
+	Score        ScoreBreakdown `json:"score"`
+}
+
+// Excerpt length defaults and bound, in characters
+const (
+	DefaultExcerptLength       = 200
+	DefaultAnswerExcerptLength = 300
+	MaxExcerptLength           = 2000
+)
+
+// QueryOptions carries per-request settings for Engine.Query
+type QueryOptions struct {
+	Filter db.Filter
+
+	// ExcerptLength caps source excerpts (and the excerpts quoted in a
+	// fallback answer). Zero keeps the defaults.
+	ExcerptLength int
+	// PlainExcerpts strips the ** highlight markers from source excerpts
+	// for clients that don't render Markdown.
+	PlainExcerpts bool
+	// SkipSynthesis goes straight to the fallbacks, for callers that
+	// already know the LLM is down
+	SkipSynthesis bool
+	// Verbosity sets how many sources a fallback answer summarizes, their
+	// default excerpt length and the LLM token budget. Empty is normal.
+	Verbosity Verbosity
+}
+
+func (o QueryOptions) sourceExcerptLength() int {
+	return clampExcerptLength(o.ExcerptLength, DefaultExcerptLength)
+}
+
+func (o QueryOptions) answerExcerptLength() int {
+	return clampExcerptLength(o.ExcerptLength, o.Verbosity.profile().excerptLength)
+}
+
+func clampExcerptLength(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	if n > MaxExcerptLength {
+		return MaxExcerptLength
+	}
+	return n
+}
+
+// NewEngine builds an engine over llmClient. matcher is the one configured
+// at startup (pattern flags, confidence overrides, metrics), shared with
+// the API; nil builds a default one.
+func NewEngine(llmClient *llm.Client, matcher *regex.Matcher) *Engine {
+	if matcher == nil {
+		matcher = regex.NewMatcher()
+	}
+	return &Engine{
+		llmClient: llmClient,
+		rng:       random.NewTimeSeeded(),
+		weights:   DefaultScoreWeights,
+		messages:  DefaultMessages,
+		language:  "fr",
+		matcher:   matcher,
+		suggest:   DefaultMaxSuggestions,
+		injection: InjectionDelimit,
+		wrapper:   DefaultDocumentWrapper,
+	}
 }
 
-func NewEngine(llmClient *llm.Client) *Engine {
-	return &Engine{llmClient: llmClient}
+// SetInjectionGuard sets how retrieved documents are guarded in the LLM
+// context and the markers fencing each one
+func (e *Engine) SetInjectionGuard(policy InjectionPolicy, wrapper DocumentWrapper) {
+	e.injection = policy
+	e.wrapper = wrapper
 }
 
-func (e *Engine) Query(query string, limit int) (*RAGResult, error) {
-	if limit <= 0 {
-		limit = 5
+// SetMessages replaces the canned answers; lang is used for queries whose
+// language can't be detected or has no bundle, and for keys missing in the
+// query's language. It fails when msgs has no bundle for lang.
+func (e *Engine) SetMessages(msgs Messages, lang string) error {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if len(msgs[lang]) == 0 {
+		return fmt.Errorf("no messages for language %q (have %s)", lang, strings.Join(msgs.Languages(), ", "))
 	}
+	e.messages = msgs
+	e.language = lang
+	return nil
+}
+
+// SetPostProcessors sets the transformers applied, in order, to LLM answers
+func (e *Engine) SetPostProcessors(ts ...Transformer) {
+	e.post = ts
+}
+
+// SetScoreWeights tunes how sources are ordered
+func (e *Engine) SetScoreWeights(w ScoreWeights) {
+	e.weights = w
+}
+
+// SetRand replaces the randomness behind suggestion selection; seed it
+// for reproducible output
+func (e *Engine) SetRand(r *rand.Rand) {
+	e.rng = random.New(r)
+}
+
+func (e *Engine) Query(query string, limit int, opts QueryOptions) (*RAGResult, error) {
+	limit = db.ClampLimit(limit)
 
 	// Search documents using PostgreSQL FTS
-	results, err := db.Search(query, limit)
+	results, err := db.Search(query, limit, opts.Filter)
 	if err != nil {
 		log.Printf("[RAG] Search error: %v", err)
 		return nil, fmt.Errorf("search: %w", err)
 	}
+	scores := scoreResults(results, db.QueryTerms(query), e.feedback(query, opts.Filter.Owner, results), e.weights, clock.Now())
 
-	// Build context from search results
-	var contextParts []string
 	var sources []Source
-
 	for i, r := range results {
-		contextParts = append(contextParts, fmt.Sprintf(
-			"[Document #%d: %s]\n%s\n",
-			i+1, r.Title, r.Excerpt,
-		))
+		excerpt := r.Excerpt
+		if opts.PlainExcerpts {
+			excerpt = cleanExcerpt(excerpt)
+		}
 
 		sources = append(sources, Source{
-			DocID:   r.DocID,
-			Title:   r.Title,
-			Excerpt: truncate(r.Excerpt, 200),
-			Rank:    r.Rank,
+			DocID:        r.DocID,
+			Title:        r.Title,
+			Excerpt:      truncate(excerpt, opts.sourceExcerptLength()),
+			Rank:         r.Rank,
+			MatchedTerms: r.MatchedTerms,
+			Score:        scores[i],
 		})
 	}
 
 	if len(results) == 0 {
 		return &RAGResult{
-			Answer:  "Je n'ai pas trouvé d'informations pertinentes dans les documents.",
+			Answer:  e.Message(query, MsgNoResults),
 			Sources: sources,
 		}, nil
 	}
 
-	context := strings.Join(contextParts, "\n---\n")
+	// Build context from search results
+	context, stripped := e.guardContext(results)
+	if stripped > 0 {
+		log.Printf("[RAG] Stripped %d injection phrase(s) from the context for %q", stripped, query)
+	}
 
 	// Try LLM analysis, but always have a good fallback
-	resp, err := e.llmClient.Analyze(query, context)
+	var resp *llm.AnalyzeResponse
+	if !opts.SkipSynthesis {
+		resp, err = e.llmClient.Analyze(query, context, opts.Verbosity.MaxTokens())
+	}
 	if err != nil || resp == nil || resp.Analysis == "" {
 		if err != nil {
 			log.Printf("[RAG] LLM analyze error: %v", err)
 		}
-		// Generate smart answer from sources
-		return &RAGResult{
-			Answer:           buildSmartAnswer(query, results),
+		// Generate smart answer from sources, or failing that hand back
+		// the sources alone
+		result := &RAGResult{
+			Answer:           buildSmartAnswer(query, results, opts.Verbosity.profile().sources, opts.answerExcerptLength()),
 			Sources:          sources,
-			SuggestedQueries: generateSuggestions(query, results),
-		}, nil
+			SuggestedQueries: e.suggestions(query, results, nil),
+			Fallback:         FallbackSmartAnswer,
+		}
+		if result.Answer == "" {
+			result.Answer = e.Message(query, MsgSourcesOnly)
+			result.Fallback = FallbackSourcesOnly
+		}
+		return result, nil
 	}
 
 	return &RAGResult{
-		Answer:           resp.Analysis,
+		Answer:           applyTransformers(resp.Analysis, sources, e.post),
 		Sources:          sources,
-		SuggestedQueries: resp.SuggestedQueries,
+		SuggestedQueries: e.suggestions(query, results, resp.SuggestedQueries),
 	}, nil
 }
 
-func (e *Engine) GetStats() map[string]interface{} {
-	stats := db.GetStats()
+// feedback loads relevance votes for the results when they count toward
+// the score. Ranking without them beats failing the query, so errors are
+// only logged.
+func (e *Engine) feedback(query, owner string, results []db.SearchResult) map[string]float64 {
+	if e.weights.Feedback == 0 || len(results) == 0 {
+		return nil
+	}
+	docIDs := make([]string, len(results))
+	for i, r := range results {
+		docIDs[i] = r.DocID
+	}
+	scores, err := db.FeedbackScores(query, owner, docIDs)
+	if err != nil {
+		log.Printf("[RAG] Feedback lookup error: %v", err)
+		return nil
+	}
+	return scores
+}
+
+// CheckLLM reports whether the LLM service answers its health check
+// before ctx is done
+func (e *Engine) CheckLLM(ctx context.Context) error {
+	_, err := e.llmClient.HealthContext(ctx)
+	return err
+}
+
+// GetStats reports corpus counts visible to owner ("" for all) and the
+// LLM's health
+func (e *Engine) GetStats(owner string) map[string]interface{} {
+	stats := db.GetStats(owner)
 
 	// Add LLM health
 	if health, err := e.llmClient.Health(); err == nil {
@@ -106,7 +267,9 @@ func (e *Engine) GetStats() map[string]interface{} {
 	return stats
 }
 
-func buildSmartAnswer(query string, results []db.SearchResult) string {
+// buildSmartAnswer quotes the top maxSources results, excerpts capped at
+// excerptLen, under an intro matched to the query's intent
+func buildSmartAnswer(query string, results []db.SearchResult, maxSources, excerptLen int) string {
 	if len(results) == 0 {
 		return "Aucun résultat trouvé pour cette recherche."
 	}
@@ -129,24 +292,30 @@ func buildSmartAnswer(query string, results []db.SearchResult) string {
 		answer.WriteString(fmt.Sprintf("Found %d relevant document(s). Top result: **%s**\n\n", len(results), topResult.Title))
 	}
 
-	// Extract key facts from top results
+	// Extract key facts from top results; with no excerpt to quote there
+	// is no answer to build
+	quoted := 0
 	for i, r := range results {
-		if i >= 3 {
+		if i >= maxSources {
 			break
 		}
 
 		// Extract meaningful excerpts
-		excerpt := cleanExcerpt(r.Excerpt)
-		if len(excerpt) > 300 {
-			excerpt = excerpt[:300] + "..."
+		excerpt := truncate(cleanExcerpt(r.Excerpt), excerptLen)
+		if strings.TrimSpace(excerpt) == "" {
+			continue
 		}
+		quoted++
 
 		answer.WriteString(fmt.Sprintf("**[%d] %s**\n", i+1, r.Title))
 		answer.WriteString(fmt.Sprintf("%s\n\n", excerpt))
 	}
+	if quoted == 0 {
+		return ""
+	}
 
-	if len(results) > 3 {
-		answer.WriteString(fmt.Sprintf("_...and %d more sources available._\n", len(results)-3))
+	if len(results) > maxSources {
+		answer.WriteString(fmt.Sprintf("_...and %d more sources available._\n", len(results)-maxSources))
 	}
 
 	return answer.String()
@@ -189,55 +358,33 @@ func cleanExcerpt(excerpt string) string {
 	return excerpt
 }
 
-func generateSuggestions(query string, results []db.SearchResult) []string {
-	var suggestions []string
-
-	if len(results) == 0 {
-		return suggestions
+// truncate shortens s to at most maxLen runes including the "..." suffix.
+// It never splits a multi-byte character and prefers to cut at the last
+// word boundary when one falls in the second half of the kept text.
+func truncate(s string, maxLen int) string {
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
 	}
-
-	// Extract entities from results to suggest follow-up queries
-	entities := make(map[string]bool)
-
-	for _, r := range results {
-		titleWords := strings.Fields(r.Title)
-		for _, w := range titleWords {
-			if len(w) > 3 && w[0] >= 'A' && w[0] <= 'Z' {
-				entities[w] = true
-			}
-		}
+	if maxLen <= 0 {
+		return ""
 	}
-
-	// Generate suggestions based on found entities
-	for entity := range entities {
-		if len(suggestions) >= 3 {
-			break
-		}
-		if !strings.Contains(strings.ToLower(query), strings.ToLower(entity)) {
-			suggestions = append(suggestions, fmt.Sprintf("What is %s's connection to this case?", entity))
-		}
-	}
-
-	// Add generic follow-ups if needed
-	genericFollowups := []string{
-		"What evidence exists?",
-		"Who else was involved?",
-		"What are the key dates?",
+	if maxLen <= 3 {
+		return "..."[:maxLen]
 	}
 
-	for _, g := range genericFollowups {
-		if len(suggestions) >= 3 {
+	keep := maxLen - 3
+	cut, n := len(s), 0
+	for i := range s {
+		if n == keep {
+			cut = i
 			break
 		}
-		suggestions = append(suggestions, g)
+		n++
 	}
 
-	return suggestions
-}
-
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	head := s[:cut]
+	if sp := strings.LastIndexFunc(head, unicode.IsSpace); sp > len(head)/2 {
+		head = head[:sp]
 	}
-	return s[:maxLen-3] + "..."
+	return strings.TrimRightFunc(head, unicode.IsSpace) + "..."
 }
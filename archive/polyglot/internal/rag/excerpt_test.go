@@ -0,0 +1,64 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"hybridcore/internal/db"
+)
+
+func TestExcerptLengthOptions(t *testing.T) {
+	cases := []struct {
+		opts           QueryOptions
+		source, answer int
+	}{
+		{QueryOptions{}, DefaultExcerptLength, DefaultAnswerExcerptLength},
+		{QueryOptions{ExcerptLength: -5}, DefaultExcerptLength, DefaultAnswerExcerptLength},
+		{QueryOptions{ExcerptLength: 80}, 80, 80},
+		{QueryOptions{ExcerptLength: 1 << 20}, MaxExcerptLength, MaxExcerptLength},
+		{QueryOptions{Verbosity: VerbosityBrief}, DefaultExcerptLength, VerbosityBrief.profile().excerptLength},
+		{QueryOptions{Verbosity: VerbosityDetailed, ExcerptLength: 50}, 50, 50},
+	}
+	for _, tc := range cases {
+		if got := tc.opts.sourceExcerptLength(); got != tc.source {
+			t.Errorf("%+v: source length %d, want %d", tc.opts, got, tc.source)
+		}
+		if got := tc.opts.answerExcerptLength(); got != tc.answer {
+			t.Errorf("%+v: answer length %d, want %d", tc.opts, got, tc.answer)
+		}
+	}
+}
+
+func TestCleanExcerptStripsMarkers(t *testing.T) {
+	got := cleanExcerpt("  the **lease**   was\nsigned by **Alice Martin** ")
+	if want := "the lease was signed by Alice Martin"; got != want {
+		t.Errorf("cleanExcerpt = %q, want %q", got, want)
+	}
+}
+
+// The smart answer quotes each excerpt plain and within the length asked
+func TestSmartAnswerExcerptLength(t *testing.T) {
+	long := "the **lease** " + strings.Repeat("was renewed again ", 40)
+	results := []db.SearchResult{{Document: db.Document{Title: "Lease"}, Excerpt: long}}
+
+	for _, n := range []int{40, 120} {
+		answer := buildSmartAnswer("lease terms", results, 3, n)
+		lines := strings.Split(answer, "\n")
+		var quoted string
+		for i, line := range lines {
+			if strings.HasPrefix(line, "**[1] Lease**") {
+				quoted = lines[i+1]
+			}
+		}
+		if quoted == "" {
+			t.Fatalf("no quoted excerpt in %q", answer)
+		}
+		if utf8.RuneCountInString(quoted) > n || !strings.HasSuffix(quoted, "...") {
+			t.Errorf("length %d: quoted %q", n, quoted)
+		}
+		if strings.Contains(quoted, "**") {
+			t.Errorf("length %d: markers kept in %q", n, quoted)
+		}
+	}
+}
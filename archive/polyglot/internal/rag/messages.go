@@ -0,0 +1,105 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"hybridcore/internal/nlp"
+)
+
+// Keys of the canned answers
+const (
+	MsgNoResults   = "no_results"      // search found nothing
+	MsgSourcesOnly = "sources_only"    // heads a FallbackSourcesOnly result
+	MsgError       = "error"           // search failed
+	MsgLLMDown     = "llm_unavailable" // direct LLM call failed and search did too
+)
+
+// Messages holds the canned answers by language code, then key
+type Messages map[string]map[string]string
+
+// DefaultMessages are the built-in answers, in English and French
+var DefaultMessages = Messages{
+	"en": {
+		MsgNoResults:   "I couldn't find any relevant information in the documents.",
+		MsgSourcesOnly: "I couldn't put an answer together, but here are the relevant documents.",
+		MsgError:       "Sorry, something went wrong. Please try again.",
+		MsgLLMDown:     "Sorry, the LLM is unavailable.",
+	},
+	"fr": {
+		MsgNoResults:   "Je n'ai pas trouvé d'informations pertinentes dans les documents.",
+		MsgSourcesOnly: "Je n'ai pas pu formuler de réponse, mais voici les documents pertinents.",
+		MsgError:       "Désolé, une erreur s'est produite. Réessayez.",
+		MsgLLMDown:     "Désolé, le LLM n'est pas disponible.",
+	},
+}
+
+// LoadMessages reads answers keyed by language then message key, e.g.
+// {"de": {"no_results": "..."}, "en": {"error": "..."}}, over
+// DefaultMessages: entries in the file win, the rest keep their default
+func LoadMessages(path string) (Messages, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file Messages
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	msgs := make(Messages, len(DefaultMessages)+len(file))
+	for lang, bundle := range DefaultMessages {
+		msgs[lang] = make(map[string]string, len(bundle))
+		for key, text := range bundle {
+			msgs[lang][key] = text
+		}
+	}
+	for lang, bundle := range file {
+		lang = strings.ToLower(lang)
+		if msgs[lang] == nil {
+			msgs[lang] = make(map[string]string, len(bundle))
+		}
+		for key, text := range bundle {
+			msgs[lang][key] = text
+		}
+	}
+	return msgs, nil
+}
+
+// Languages lists the languages m has a bundle for, sorted
+func (m Messages) Languages() []string {
+	langs := make([]string, 0, len(m))
+	for lang, bundle := range m {
+		if len(bundle) > 0 {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// get returns the message for key in lang, falling back to fallback's and
+// last to the built-in English text, so a known key is never empty
+func (m Messages) get(lang, fallback, key string) string {
+	if text := m[lang][key]; text != "" {
+		return text
+	}
+	if text := m[fallback][key]; text != "" {
+		return text
+	}
+	return DefaultMessages["en"][key]
+}
+
+// Message returns the canned answer for key in the language of query, or
+// in the engine's default language when that can't be told or has no
+// bundle
+func (e *Engine) Message(query, key string) string {
+	lang := nlp.DetectLanguage(query)
+	if len(e.messages[lang]) == 0 {
+		lang = e.language
+	}
+	return e.messages.get(lang, e.language, key)
+}
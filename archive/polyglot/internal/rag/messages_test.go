@@ -0,0 +1,55 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageFollowsQueryLanguage(t *testing.T) {
+	e := NewEngine(nil, nil)
+	if err := e.SetMessages(DefaultMessages, "fr"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"what is the policy for the new office", DefaultMessages["en"][MsgNoResults]},
+		{"quelle est la politique pour le nouveau bureau", DefaultMessages["fr"][MsgNoResults]},
+		{"kubernetes", DefaultMessages["fr"][MsgNoResults]}, // undetectable: the configured language
+	}
+	for _, tt := range tests {
+		if got := e.Message(tt.query, MsgNoResults); got != tt.want {
+			t.Errorf("Message(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSetMessagesRejectsUnknownLanguage(t *testing.T) {
+	e := NewEngine(nil, nil)
+	err := e.SetMessages(DefaultMessages, "de")
+	if err == nil || !strings.Contains(err.Error(), `"de"`) {
+		t.Fatalf("err = %v, want an unknown-language error", err)
+	}
+	if got := e.Message("kubernetes", MsgNoResults); got == "" {
+		t.Error("a rejected language left the engine without messages")
+	}
+}
+
+// A detected language without a bundle, or a key missing from every
+// configured bundle, still yields an answer
+func TestMessageFallsBackToDefaultBundle(t *testing.T) {
+	e := NewEngine(nil, nil)
+	msgs := Messages{"de": {MsgError: "Entschuldigung."}}
+	if err := e.SetMessages(msgs, "de"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.Message("what is the policy for the office", MsgError); got != "Entschuldigung." {
+		t.Errorf("English query = %q, want the configured German bundle", got)
+	}
+	if got := e.Message("kubernetes", MsgNoResults); got != DefaultMessages["en"][MsgNoResults] {
+		t.Errorf("missing key = %q, want the built-in English text", got)
+	}
+}
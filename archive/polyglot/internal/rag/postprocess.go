@@ -0,0 +1,90 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Transformer rewrites an LLM answer before it is returned. Transformers
+// run in the order configured, each seeing the previous one's output.
+type Transformer func(answer string, sources []Source) string
+
+// Transformers available to RAG_POSTPROCESS, by name
+var Transformers = map[string]Transformer{
+	"strip_preamble": StripPreamble,
+	"citations":      EnforceCitations,
+	"plain_text":     PlainText,
+}
+
+// ParseTransformers resolves transformer names, keeping their order
+func ParseTransformers(names []string) ([]Transformer, error) {
+	out := make([]Transformer, 0, len(names))
+	for _, name := range names {
+		t, ok := Transformers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown answer transformer %q", name)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func applyTransformers(answer string, sources []Source, ts []Transformer) string {
+	for _, t := range ts {
+		answer = t(answer, sources)
+	}
+	return answer
+}
+
+// Model boilerplate at the very start of an answer: interjections
+// ("Sure!"), AI disclaimers ("As an AI language model,") and lead-ins
+// ("Here is the analysis:"), possibly chained
+var preambleRegex = regexp.MustCompile(`(?i)^\s*(?:(?:sure|certainly|of course|absolutely|bien sûr)[!,.]\s*|as an ai(?: language model)?,?\s*|en tant qu'(?:ia|intelligence artificielle),?\s*|here(?:'s| is) (?:the|an?|my) (?:answer|summary|analysis)[^.:!\n]*[:.]\s*)+`)
+
+// StripPreamble removes a leading model preamble
+func StripPreamble(answer string, _ []Source) string {
+	stripped := preambleRegex.ReplaceAllString(answer, "")
+	if strings.TrimSpace(stripped) == "" {
+		return answer
+	}
+	return upperFirst(stripped)
+}
+
+var citationRegex = regexp.MustCompile(`\[\d+\]`)
+
+// EnforceCitations appends a numbered source list when the answer has
+// sources but cites none of them with [n] markers
+func EnforceCitations(answer string, sources []Source) string {
+	if len(sources) == 0 || citationRegex.MatchString(answer) {
+		return answer
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(answer, "\n "))
+	b.WriteString("\n\nSources:")
+	for i, s := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s", i+1, s.Title)
+	}
+	return b.String()
+}
+
+var (
+	markdownEmphasis = regexp.MustCompile(`\*\*|__|\x60`)
+	markdownHeading  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+)
+
+// PlainText strips Markdown emphasis, headings and links
+func PlainText(answer string, _ []Source) string {
+	answer = markdownLink.ReplaceAllString(answer, "$1")
+	answer = markdownHeading.ReplaceAllString(answer, "")
+	return markdownEmphasis.ReplaceAllString(answer, "")
+}
+
+func upperFirst(s string) string {
+	for i, r := range s {
+		return strings.ToUpper(string(r)) + s[i+len(string(r)):]
+	}
+	return s
+}
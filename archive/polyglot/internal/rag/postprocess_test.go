@@ -0,0 +1,73 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransformers(t *testing.T) {
+	sources := []Source{{Title: "Lease"}, {Title: "Bank statement"}}
+
+	cases := []struct {
+		name   string
+		t      Transformer
+		in     string
+		want   string
+		noSrcs bool
+	}{
+		{"preamble", StripPreamble, "Sure! As an AI language model, here is the summary: alice signed it.", "Alice signed it.", false},
+		{"preamble fr", StripPreamble, "Bien sûr, en tant qu'IA, le bail est signé.", "Le bail est signé.", false},
+		{"preamble only", StripPreamble, "Sure!", "Sure!", false},
+		{"no preamble", StripPreamble, "Alice signed it. Sure!", "Alice signed it. Sure!", false},
+		{"citations added", EnforceCitations, "Alice signed it.\n", "Alice signed it.\n\nSources:\n[1] Lease\n[2] Bank statement", false},
+		{"citations present", EnforceCitations, "Alice signed it [1].", "Alice signed it [1].", false},
+		{"citations, no sources", EnforceCitations, "Alice signed it.", "Alice signed it.", true},
+		{"plain text", PlainText, "## Summary\n**Alice** signed the `lease`, see [the file](http://x/1).", "Summary\nAlice signed the lease, see the file.", false},
+	}
+	for _, tc := range cases {
+		srcs := sources
+		if tc.noSrcs {
+			srcs = nil
+		}
+		if got := tc.t(tc.in, srcs); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Transformers run in the configured order, each on the previous output:
+// plain_text after citations also cleans the appended source titles
+func TestTransformersRunInOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) Transformer {
+		return func(answer string, _ []Source) string {
+			calls = append(calls, name)
+			return answer + "+" + name
+		}
+	}
+	got := applyTransformers("a", nil, []Transformer{record("x"), record("y"), record("z")})
+	if got != "a+x+y+z" || !reflect.DeepEqual(calls, []string{"x", "y", "z"}) {
+		t.Errorf("got %q after calls %v", got, calls)
+	}
+
+	sources := []Source{{Title: "**Lease**"}}
+	answer := "Sure! **Alice** signed."
+
+	ts, err := ParseTransformers([]string{"strip_preamble", "citations", "plain_text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := applyTransformers(answer, sources, ts), "Alice signed.\n\nSources:\n[1] Lease"; got != want {
+		t.Errorf("citations then plain_text = %q, want %q", got, want)
+	}
+
+	ts, _ = ParseTransformers([]string{"strip_preamble", "plain_text", "citations"})
+	if got, want := applyTransformers(answer, sources, ts), "Alice signed.\n\nSources:\n[1] **Lease**"; got != want {
+		t.Errorf("plain_text then citations = %q, want %q", got, want)
+	}
+
+	if _, err := ParseTransformers([]string{"citations", "shout"}); err == nil || !strings.Contains(err.Error(), `"shout"`) {
+		t.Errorf("unknown transformer: err = %v", err)
+	}
+}
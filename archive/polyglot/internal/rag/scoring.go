@@ -0,0 +1,86 @@
+package rag
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"hybridcore/internal/db"
+)
+
+// ScoreWeights blends the components of a composite relevance score
+type ScoreWeights struct {
+	Rank     float64       // FTS rank, normalized to the best hit
+	Recency  float64       // exponential decay on document age
+	Coverage float64       // share of the query's terms the document contains
+	Feedback float64       // user relevance votes on the same query
+	HalfLife time.Duration // age at which the recency component halves
+}
+
+// DefaultScoreWeights favor lexical rank but let a recent document that
+// covers more of the query overtake an older, lexically stronger one
+var DefaultScoreWeights = ScoreWeights{
+	Rank:     0.4,
+	Recency:  0.3,
+	Coverage: 0.3,
+	Feedback: 0.3,
+	HalfLife: 180 * 24 * time.Hour,
+}
+
+// ScoreBreakdown explains a composite score; components are in [0, 1]
+// except Feedback, in (-1, 1) so irrelevant votes push a document down
+type ScoreBreakdown struct {
+	Rank     float64 `json:"rank"`
+	Recency  float64 `json:"recency"`
+	Coverage float64 `json:"coverage"`
+	Feedback float64 `json:"feedback"`
+	Total    float64 `json:"total"`
+}
+
+// scoreResults computes a breakdown per result and sorts both slices by
+// descending total. terms are the query's meaningful terms and feedback
+// the aggregated votes by doc_id (see db.FeedbackScores), possibly nil.
+func scoreResults(results []db.SearchResult, terms []string, feedback map[string]float64, w ScoreWeights, now time.Time) []ScoreBreakdown {
+	maxRank := 0.0
+	for _, r := range results {
+		maxRank = math.Max(maxRank, r.Rank)
+	}
+
+	scores := make([]ScoreBreakdown, len(results))
+	for i, r := range results {
+		var s ScoreBreakdown
+		if maxRank > 0 {
+			s.Rank = r.Rank / maxRank
+		}
+		if w.HalfLife > 0 && !r.CreatedAt.IsZero() {
+			age := now.Sub(r.CreatedAt)
+			if age < 0 {
+				age = 0
+			}
+			s.Recency = math.Pow(0.5, float64(age)/float64(w.HalfLife))
+		}
+		if len(terms) > 0 {
+			s.Coverage = float64(len(r.MatchedTerms)) / float64(len(terms))
+		}
+		s.Feedback = feedback[r.DocID]
+		s.Total = w.Rank*s.Rank + w.Recency*s.Recency + w.Coverage*s.Coverage + w.Feedback*s.Feedback
+		scores[i] = s
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]].Total > scores[order[b]].Total
+	})
+
+	sortedResults := make([]db.SearchResult, len(results))
+	sortedScores := make([]ScoreBreakdown, len(results))
+	for i, idx := range order {
+		sortedResults[i] = results[idx]
+		sortedScores[i] = scores[idx]
+	}
+	copy(results, sortedResults)
+	return sortedScores
+}
@@ -0,0 +1,62 @@
+package rag
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"hybridcore/internal/db"
+)
+
+func TestScoreResultsRecentCoverageOutranksOlderRank(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	terms := []string{"alice", "lease", "paris"}
+	old := db.SearchResult{
+		Document:     db.Document{DocID: "old", CreatedAt: now.AddDate(-3, 0, 0)},
+		Rank:         0.9,
+		MatchedTerms: []string{"lease"},
+	}
+	recent := db.SearchResult{
+		Document:     db.Document{DocID: "recent", CreatedAt: now.AddDate(0, 0, -7)},
+		Rank:         0.5,
+		MatchedTerms: []string{"alice", "lease", "paris"},
+	}
+	results := []db.SearchResult{old, recent}
+
+	scores := scoreResults(results, terms, nil, DefaultScoreWeights, now)
+
+	if results[0].DocID != "recent" || results[1].DocID != "old" {
+		t.Fatalf("order = %s, %s, want recent first", results[0].DocID, results[1].DocID)
+	}
+	top, low := scores[0], scores[1]
+	if top.Rank >= low.Rank {
+		t.Errorf("rank components %v, %v: the older document should be lexically stronger", top.Rank, low.Rank)
+	}
+	if top.Coverage != 1 || math.Abs(low.Coverage-1.0/3) > 1e-9 {
+		t.Errorf("coverage = %v, %v, want 1 and 1/3", top.Coverage, low.Coverage)
+	}
+	if low.Rank != 1 || top.Recency <= low.Recency {
+		t.Errorf("breakdowns = %+v, %+v", top, low)
+	}
+
+	w := DefaultScoreWeights
+	for _, s := range scores {
+		want := w.Rank*s.Rank + w.Recency*s.Recency + w.Coverage*s.Coverage + w.Feedback*s.Feedback
+		if math.Abs(s.Total-want) > 1e-9 {
+			t.Errorf("total %v, want the weighted sum %v", s.Total, want)
+		}
+	}
+}
+
+// Irrelevant votes push an otherwise better document down
+func TestScoreResultsFeedback(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	results := []db.SearchResult{
+		{Document: db.Document{DocID: "a", CreatedAt: now}, Rank: 1},
+		{Document: db.Document{DocID: "b", CreatedAt: now}, Rank: 0.9},
+	}
+	scores := scoreResults(results, nil, map[string]float64{"a": -0.5, "b": 0.5}, DefaultScoreWeights, now)
+	if results[0].DocID != "b" || scores[0].Feedback != 0.5 {
+		t.Errorf("order = %s, %s with %+v", results[0].DocID, results[1].DocID, scores)
+	}
+}
@@ -0,0 +1,166 @@
+package rag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/nlp"
+	"hybridcore/internal/random"
+	"hybridcore/internal/regex"
+)
+
+// DefaultMaxSuggestions is how many follow-up queries an answer suggests
+// unless configured otherwise
+const DefaultMaxSuggestions = 3
+
+// Follow-up question for each entity pattern worth asking about
+var suggestionTemplates = map[string]string{
+	"person_name":  "What is %s's connection to this case?",
+	"organization": "What role did %s play in this case?",
+}
+
+// Follow-ups for when the results name too few entities
+var genericFollowups = []string{
+	"What evidence exists?",
+	"Who else was involved?",
+	"What are the key dates?",
+}
+
+// SetMaxSuggestions caps the suggested queries per answer; 0 turns them off
+func (e *Engine) SetMaxSuggestions(n int) {
+	if n < 0 {
+		n = 0
+	}
+	e.suggest = n
+}
+
+// suggestions returns up to the configured number of follow-up queries:
+// the LLM's when it offered any, then questions about the entities the
+// results name most, then generic ones. None repeats the query or another
+// suggestion.
+func (e *Engine) suggestions(query string, results []db.SearchResult, fromLLM []string) []string {
+	if e.suggest == 0 {
+		return nil
+	}
+	set := newSuggestionSet(query, e.suggest)
+	for _, q := range fromLLM {
+		set.add(q)
+	}
+	if len(results) == 0 {
+		return set.list
+	}
+	for _, m := range rankEntities(query, results, e.matcher, e.rng) {
+		set.add(fmt.Sprintf(suggestionTemplates[m.Pattern], m.Value))
+	}
+	for _, q := range genericFollowups {
+		set.add(q)
+	}
+	return set.list
+}
+
+type entityCount struct {
+	regex.Match
+	count int
+}
+
+// rankEntities finds the people and organizations named in the results'
+// titles and excerpts, most mentioned first; rng orders ties. Entities the
+// query already names, or with a stopword among their words ("The Times"),
+// are left out.
+func rankEntities(query string, results []db.SearchResult, matcher *regex.Matcher, rng *random.Source) []regex.Match {
+	queryWords := make(map[string]bool)
+	for _, tok := range nlp.Tokenize(query) {
+		queryWords[tok] = true
+	}
+
+	index := make(map[string]int) // entity key → position in entities, -1 when left out
+	var entities []entityCount
+	count := func(text string) {
+		for _, m := range matcher.FindByCategory(text, regex.CategoryEntity) {
+			if suggestionTemplates[m.Pattern] == "" {
+				continue
+			}
+			m.Value = strings.Join(strings.Fields(m.Value), " ")
+			words := nlp.Tokenize(m.Value)
+			key := strings.Join(words, " ")
+			if i, ok := index[key]; ok {
+				if i >= 0 {
+					entities[i].count++
+				}
+				continue
+			}
+			if !suggestible(words, queryWords) {
+				index[key] = -1
+				continue
+			}
+			index[key] = len(entities)
+			entities = append(entities, entityCount{Match: m, count: 1})
+		}
+	}
+	// Matched apart, so a name can't run from the title into the excerpt
+	for _, r := range results {
+		count(r.Title)
+		count(cleanExcerpt(r.Excerpt))
+	}
+
+	rng.Shuffle(len(entities), func(i, j int) {
+		entities[i], entities[j] = entities[j], entities[i]
+	})
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].count > entities[j].count
+	})
+
+	ranked := make([]regex.Match, len(entities))
+	for i, ent := range entities {
+		ranked[i] = ent.Match
+	}
+	return ranked
+}
+
+// suggestible reports whether an entity's words make a useful follow-up:
+// not all in the query and none a stopword
+func suggestible(words []string, queryWords map[string]bool) bool {
+	if len(words) == 0 {
+		return false
+	}
+	known := 0
+	for _, w := range words {
+		if nlp.IsStopword(w) {
+			return false
+		}
+		if queryWords[w] {
+			known++
+		}
+	}
+	return known < len(words)
+}
+
+// suggestionSet collects distinct suggestions up to max, comparing them
+// by their words so case and punctuation don't make a repeat look new
+type suggestionSet struct {
+	max  int
+	seen map[string]bool
+	list []string
+}
+
+func newSuggestionSet(query string, max int) *suggestionSet {
+	s := &suggestionSet{max: max, seen: make(map[string]bool)}
+	s.seen[suggestionKey(query)] = true
+	return s
+}
+
+func (s *suggestionSet) add(q string) {
+	q = strings.TrimSpace(q)
+	key := suggestionKey(q)
+	if len(s.list) >= s.max || key == "" || s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+	s.list = append(s.list, q)
+}
+
+func suggestionKey(q string) string {
+	return strings.Join(nlp.Tokenize(q), " ")
+}
@@ -0,0 +1,80 @@
+package rag
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hybridcore/internal/db"
+	"hybridcore/internal/regex"
+)
+
+// Suggestions come from the matcher the engine was given, so patterns
+// disabled at startup stay disabled here too
+func TestSuggestionsUseInjectedMatcher(t *testing.T) {
+	if err := regex.SetPatternFlags(map[string]bool{"person_name": false}); err != nil {
+		t.Fatal(err)
+	}
+	configured := regex.NewMatcher()
+	regex.SetPatternFlags(nil)
+
+	results := []db.SearchResult{{Document: db.Document{Title: "Memo"}, Excerpt: "John Smith met John Smith at the bank."}}
+
+	withDefault := NewEngine(nil, nil).suggestions("the memo", results, nil)
+	if !containsSuggestion(withDefault, "John Smith") {
+		t.Fatalf("default matcher suggested %q, want John Smith", withDefault)
+	}
+
+	withConfigured := NewEngine(nil, configured).suggestions("the memo", results, nil)
+	if containsSuggestion(withConfigured, "John Smith") {
+		t.Errorf("configured matcher suggested %q, want person_name disabled", withConfigured)
+	}
+}
+
+func containsSuggestion(list []string, s string) bool {
+	for _, q := range list {
+		if strings.Contains(q, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ties between equally mentioned entities are broken by the engine's
+// RNG: a seeded engine suggests the same order every run, and the most
+// mentioned entity still comes first whatever the seed
+func TestSuggestionsSeededAreStable(t *testing.T) {
+	results := []db.SearchResult{{
+		Document: db.Document{Title: "Board minutes"},
+		Excerpt: "Maria Lopez twice met Maria Lopez's lawyer. Present: John Smith, " +
+			"Anna Berg, Peter Novak, Claire Dubois, Omar Haddad.",
+	}}
+	run := func(seed int64) []string {
+		e := NewEngine(nil, nil)
+		e.SetRand(rand.New(rand.NewSource(seed)))
+		return e.suggestions("board minutes", results, nil)
+	}
+
+	first := run(7)
+	for i := 0; i < 5; i++ {
+		if got := run(7); !reflect.DeepEqual(got, first) {
+			t.Fatalf("seed 7 gave %q, then %q", first, got)
+		}
+	}
+	if len(first) == 0 || !strings.Contains(first[0], "Maria Lopez") {
+		t.Fatalf("suggestions = %q, want Maria Lopez first", first)
+	}
+
+	orders := make(map[string]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		got := run(seed)
+		if !strings.Contains(got[0], "Maria Lopez") {
+			t.Errorf("seed %d: %q", seed, got)
+		}
+		orders[strings.Join(got, "|")] = true
+	}
+	if len(orders) < 2 {
+		t.Error("tied entities came out in one order for every seed")
+	}
+}
@@ -0,0 +1,36 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Cuts land on rune boundaries, whatever the script
+func TestTruncateMultibyte(t *testing.T) {
+	texts := []string{
+		"Élodie a signé le contrat à Besançon après l'été",
+		"東京の会議で契約が署名されました。詳細は添付の資料をご覧ください",
+		"Контракт подписан в Москве после долгих переговоров",
+		"🚀🚀🚀 launch moved 🚀 to next quarter 🎉🎉",
+	}
+	for _, text := range texts {
+		for max := 0; max <= utf8.RuneCountInString(text)+1; max++ {
+			got := truncate(text, max)
+			if !utf8.ValidString(got) {
+				t.Fatalf("truncate(%q, %d) = %q, not valid UTF-8", text, max, got)
+			}
+			if n := utf8.RuneCountInString(got); n > max {
+				t.Errorf("truncate(%q, %d) = %q, %d runes", text, max, got, n)
+			}
+			if max >= utf8.RuneCountInString(text) && got != text {
+				t.Errorf("truncate(%q, %d) = %q, want it whole", text, max, got)
+			}
+			if max > 3 && max < utf8.RuneCountInString(text) {
+				if !strings.HasSuffix(got, "...") || !strings.HasPrefix(text, strings.TrimSuffix(got, "...")) {
+					t.Errorf("truncate(%q, %d) = %q, want a prefix then ...", text, max, got)
+				}
+			}
+		}
+	}
+}
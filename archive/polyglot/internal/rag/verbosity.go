@@ -0,0 +1,50 @@
+package rag
+
+import "strings"
+
+// Verbosity trades answer length for detail
+type Verbosity string
+
+const (
+	VerbosityBrief    Verbosity = "brief"
+	VerbosityNormal   Verbosity = "normal"
+	VerbosityDetailed Verbosity = "detailed"
+)
+
+// verbosityProfile is what a Verbosity sets: how many sources a fallback
+// answer summarizes, how long their quoted excerpts run, and the LLM's
+// token budget
+type verbosityProfile struct {
+	sources       int
+	excerptLength int
+	maxTokens     int
+}
+
+var verbosityProfiles = map[Verbosity]verbosityProfile{
+	VerbosityBrief:    {sources: 1, excerptLength: 150, maxTokens: 200},
+	VerbosityNormal:   {sources: 3, excerptLength: DefaultAnswerExcerptLength, maxTokens: 500},
+	VerbosityDetailed: {sources: 6, excerptLength: 600, maxTokens: 1000},
+}
+
+// ParseVerbosity validates a verbosity name, case-insensitively; empty
+// means VerbosityNormal
+func ParseVerbosity(s string) (Verbosity, bool) {
+	v := Verbosity(strings.ToLower(strings.TrimSpace(s)))
+	if v == "" {
+		return VerbosityNormal, true
+	}
+	_, ok := verbosityProfiles[v]
+	return v, ok
+}
+
+func (v Verbosity) profile() verbosityProfile {
+	if p, ok := verbosityProfiles[v]; ok {
+		return p
+	}
+	return verbosityProfiles[VerbosityNormal]
+}
+
+// MaxTokens is the LLM token budget for an answer at this verbosity
+func (v Verbosity) MaxTokens() int {
+	return v.profile().maxTokens
+}
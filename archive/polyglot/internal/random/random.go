@@ -0,0 +1,38 @@
+package random
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Source is a goroutine-safe wrapper around *rand.Rand. Components that
+// pick among canned responses take one so tests can seed it.
+type Source struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// New wraps r; pass rand.New(rand.NewSource(seed)) for reproducible output
+func New(r *rand.Rand) *Source {
+	return &Source{r: r}
+}
+
+// NewTimeSeeded returns a Source seeded from the clock, for production use
+func NewTimeSeeded() *Source {
+	return New(rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// Intn returns a value in [0, n)
+func (s *Source) Intn(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Intn(n)
+}
+
+// Shuffle randomizes the order of n elements via swap
+func (s *Source) Shuffle(n int, swap func(i, j int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.r.Shuffle(n, swap)
+}
@@ -0,0 +1,38 @@
+package regex
+
+import "sort"
+
+// Analysis is everything a share preview needs from one scan: what was
+// found, which of it is sensitive, and the text with that masked
+type Analysis struct {
+	MatchesByCategory map[Category][]Match `json:"matches_by_category"`
+	SensitiveMatches  []Match              `json:"sensitive_matches"`
+	RedactedText      string               `json:"redacted_text"`
+	RedactionManifest RedactionManifest    `json:"redaction_manifest"`
+}
+
+// Analyze runs every pattern over text once and derives all of Analysis
+// from those matches, so the manifest covers exactly SensitiveMatches
+// (overlapping ones merged as in Redact). Matches are in text order.
+func (m *Matcher) Analyze(text string, mode RedactionMode) Analysis {
+	matches := m.FindAll(text)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End < matches[j].End
+	})
+
+	a := Analysis{
+		MatchesByCategory: make(map[Category][]Match),
+		SensitiveMatches:  []Match{},
+	}
+	for _, match := range matches {
+		a.MatchesByCategory[match.Category] = append(a.MatchesByCategory[match.Category], match)
+		if match.Sensitive {
+			a.SensitiveMatches = append(a.SensitiveMatches, match)
+		}
+	}
+	a.RedactedText, a.RedactionManifest = Redact(text, a.SensitiveMatches, mode)
+	return a
+}
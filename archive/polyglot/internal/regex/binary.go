@@ -0,0 +1,136 @@
+package regex
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// BINARY INPUT GUARD
+// ═══════════════════════════════════════════════════════════════════
+
+// BinaryPolicy decides what the extraction endpoints do with input that
+// sniffs as binary, where base64, hash and code patterns match noise
+type BinaryPolicy string
+
+const (
+	BinaryReject  BinaryPolicy = "reject"  // refuse it
+	BinaryExtract BinaryPolicy = "extract" // match only its printable text
+	BinaryAllow   BinaryPolicy = "allow"   // match it as is
+)
+
+// ParseBinaryPolicy validates a binary policy name
+func ParseBinaryPolicy(s string) (BinaryPolicy, bool) {
+	switch p := BinaryPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case BinaryReject, BinaryExtract, BinaryAllow:
+		return p, true
+	}
+	return "", false
+}
+
+// sniffLen is how much input SniffBinary looks at, as much as
+// http.DetectContentType considers
+const sniffLen = 512
+
+// maxNonText is the share of control characters and undecodable bytes a
+// text may have; JSON decoding turns raw binary into U+FFFD, which
+// http.DetectContentType alone takes for text
+const maxNonText = 0.1
+
+// MinPrintableRun is the shortest run of printable characters
+// PrintableText keeps; shorter ones are mostly bytes that happen to decode
+const MinPrintableRun = 4
+
+// SniffBinary reports whether text looks like binary data rather than
+// text, judging by its first bytes, along with the detected content type
+func SniffBinary(text string) (bool, string) {
+	head := text
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	contentType := http.DetectContentType([]byte(head))
+	if !strings.HasPrefix(contentType, "text/") {
+		return true, contentType
+	}
+
+	var n, nonText int
+	for _, r := range head {
+		n++
+		if !isText(r) {
+			nonText++
+		}
+	}
+	if n > 0 && float64(nonText)/float64(n) > maxNonText {
+		return true, "application/octet-stream"
+	}
+	return false, contentType
+}
+
+// PrintableText blanks out everything in text but runs of at least
+// MinPrintableRun text characters, like strings(1). Byte offsets are
+// preserved, so matches still point into the original input.
+func PrintableText(text string) string {
+	b := []byte(text)
+	runStart, runLen := 0, 0
+	endRun := func(at int) {
+		if runLen > 0 && runLen < MinPrintableRun {
+			blank(b[runStart:at])
+		}
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if isText(r) {
+			if runLen == 0 {
+				runStart = i
+			}
+			runLen++
+		} else {
+			endRun(i)
+			blank(b[i : i+size])
+			runLen = 0
+		}
+		i += size
+	}
+	endRun(len(text))
+	return string(b)
+}
+
+func blank(b []byte) {
+	for i := range b {
+		b[i] = ' '
+	}
+}
+
+func isText(r rune) bool {
+	switch {
+	case r == utf8.RuneError:
+		return false
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	}
+	return unicode.IsPrint(r) || unicode.IsSpace(r)
+}
+
+// NewPrintableReader is the streaming counterpart of PrintableText for
+// large uploads: control bytes become spaces, offsets unchanged. It works
+// byte by byte, so short runs and invalid UTF-8 pass through.
+func NewPrintableReader(r io.Reader) io.Reader {
+	return printableReader{r}
+}
+
+type printableReader struct {
+	r io.Reader
+}
+
+func (p printableReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	for i, c := range b[:n] {
+		if (c < 0x20 && c != '\t' && c != '\n' && c != '\r') || c == 0x7f {
+			b[i] = ' '
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,49 @@
+package regex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniffBinary(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x01\x00"
+	if binary, ct := SniffBinary(png); !binary || ct != "image/png" {
+		t.Errorf("png: SniffBinary = %v, %q", binary, ct)
+	}
+
+	// Raw bytes after a JSON round trip are mostly U+FFFD, which
+	// DetectContentType alone calls text
+	decoded := strings.Repeat("��ab�", 40)
+	if binary, _ := SniffBinary(decoded); !binary {
+		t.Error("replacement-character soup sniffed as text")
+	}
+
+	for _, text := range []string{
+		"plain ascii mail to bob@example.com\n",
+		"Grüße aus Zürich, 東京からこんにちは — ça va?\tok\r\n",
+	} {
+		if binary, ct := SniffBinary(text); binary {
+			t.Errorf("%q sniffed as binary (%s)", text, ct)
+		}
+	}
+}
+
+// PrintableText keeps runs of text at their byte offsets and blanks the
+// rest, so matches point into the original input
+func TestPrintableTextPreservesOffsets(t *testing.T) {
+	in := "\x00\x01\x02bob@example.com\x00ab\x00\xff\xfeKEEP"
+	out := PrintableText(in)
+
+	if len(out) != len(in) {
+		t.Fatalf("length %d, want %d", len(out), len(in))
+	}
+	if i := strings.Index(in, "bob@example.com"); out[i:i+15] != "bob@example.com" {
+		t.Errorf("email moved or lost: %q", out)
+	}
+	if strings.Contains(out, "ab") {
+		t.Errorf("run shorter than MinPrintableRun kept: %q", out)
+	}
+	if !strings.HasSuffix(out, "KEEP") {
+		t.Errorf("trailing run lost: %q", out)
+	}
+}
@@ -0,0 +1,160 @@
+package regex
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// CONFIDENCE CALIBRATION
+// ═══════════════════════════════════════════════════════════════════
+
+// LabeledSample is a text with the matches a correct extraction yields
+type LabeledSample struct {
+	Text     string          `json:"text"`
+	Expected []ExpectedMatch `json:"expected"`
+}
+
+// ExpectedMatch names a value a pattern should find; Value is compared
+// after Normalize
+type ExpectedMatch struct {
+	Pattern string `json:"pattern"`
+	Value   string `json:"value"`
+}
+
+// PatternCalibration is one pattern's measured accuracy on a sample set
+type PatternCalibration struct {
+	Pattern        string  `json:"pattern"`
+	Matches        int     `json:"matches"`
+	TruePositives  int     `json:"true_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	Prior          float64 `json:"prior"`
+	Adjusted       float64 `json:"adjusted"`
+}
+
+// CalibrationReport covers every pattern that matched or was expected.
+// Overrides holds the adjusted confidences in the shape
+// LoadConfidenceOverrides reads, ready to persist.
+type CalibrationReport struct {
+	Samples   int                  `json:"samples"`
+	Patterns  []PatternCalibration `json:"patterns"`
+	Overrides map[string]float64   `json:"overrides"`
+}
+
+// calibrationPriorWeight is how many observations the hand-picked
+// confidence counts for, so a pattern seen twice doesn't jump to 0 or 1
+const calibrationPriorWeight = 5
+
+// Calibrate runs FindAll over each sample and measures, per pattern, the
+// share of its matches that were expected. The adjusted confidence blends
+// that precision with the current confidence by calibrationPriorWeight.
+func (m *Matcher) Calibrate(samples []LabeledSample) CalibrationReport {
+	stats := make(map[string]*PatternCalibration)
+	get := func(name string) *PatternCalibration {
+		if st, ok := stats[name]; ok {
+			return st
+		}
+		st := &PatternCalibration{Pattern: name}
+		stats[name] = st
+		return st
+	}
+
+	for _, sample := range samples {
+		// Expected values are a multiset: two expected hits allow two matches
+		want := make(map[string]int)
+		for _, e := range sample.Expected {
+			want[e.Pattern+"\x00"+Normalize(e.Pattern, e.Value)]++
+		}
+
+		for _, match := range m.FindAll(sample.Text) {
+			st := get(match.Pattern)
+			st.Matches++
+			key := match.Pattern + "\x00" + Normalize(match.Pattern, match.Value)
+			if want[key] > 0 {
+				want[key]--
+				st.TruePositives++
+			}
+		}
+		for _, e := range sample.Expected {
+			key := e.Pattern + "\x00" + Normalize(e.Pattern, e.Value)
+			if want[key] > 0 {
+				want[key]--
+				get(e.Pattern).FalseNegatives++
+			}
+		}
+	}
+
+	priors := make(map[string]float64, len(m.patterns))
+	for _, p := range m.patterns {
+		priors[p.Name] = p.Confidence
+	}
+
+	report := CalibrationReport{
+		Samples:   len(samples),
+		Patterns:  make([]PatternCalibration, 0, len(stats)),
+		Overrides: make(map[string]float64),
+	}
+	for name, st := range stats {
+		prior, known := priors[name]
+		st.Prior = prior
+		st.Adjusted = prior
+		if st.Matches > 0 {
+			st.Precision = float64(st.TruePositives) / float64(st.Matches)
+			st.Adjusted = (float64(st.TruePositives) + calibrationPriorWeight*prior) /
+				float64(st.Matches+calibrationPriorWeight)
+		}
+		if expected := st.TruePositives + st.FalseNegatives; expected > 0 {
+			st.Recall = float64(st.TruePositives) / float64(expected)
+		}
+		if known && st.Matches > 0 {
+			report.Overrides[name] = st.Adjusted
+		}
+		report.Patterns = append(report.Patterns, *st)
+	}
+
+	sort.Slice(report.Patterns, func(i, j int) bool {
+		return report.Patterns[i].Pattern < report.Patterns[j].Pattern
+	})
+	return report
+}
+
+// LoadConfidenceOverrides reads a JSON object of pattern name → confidence,
+// such as a saved CalibrationReport.Overrides
+func LoadConfidenceOverrides(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides map[string]float64
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// SetConfidenceOverrides replaces the confidence of the named built-in
+// patterns. Matchers share AllPatterns, so call it at startup before any
+// matching starts. Unknown names or values outside [0, 1] are rejected
+// without applying anything.
+func SetConfidenceOverrides(overrides map[string]float64) error {
+	index := make(map[string]int, len(AllPatterns))
+	for i, p := range AllPatterns {
+		index[p.Name] = i
+	}
+	for name, conf := range overrides {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("unknown pattern %q", name)
+		}
+		if conf < 0 || conf > 1 {
+			return fmt.Errorf("confidence for %q out of range: %v", name, conf)
+		}
+	}
+	for name, conf := range overrides {
+		AllPatterns[index[name]].Confidence = conf
+	}
+	return nil
+}
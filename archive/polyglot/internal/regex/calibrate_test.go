@@ -0,0 +1,61 @@
+package regex
+
+import (
+	"math"
+	"testing"
+)
+
+// Two email matches, one of them expected: precision 1/2, and the
+// adjusted confidence pulled from the prior toward it by
+// calibrationPriorWeight observations
+func TestCalibratePrecisionAndAdjustedConfidence(t *testing.T) {
+	m := NewMatcher()
+	report := m.Calibrate([]LabeledSample{{
+		Text:     "write to Alice@Example.com or noreply@example.com",
+		Expected: []ExpectedMatch{{Pattern: "email", Value: "alice@example.com"}},
+	}})
+
+	var email *PatternCalibration
+	for i := range report.Patterns {
+		if report.Patterns[i].Pattern == "email" {
+			email = &report.Patterns[i]
+		}
+	}
+	if email == nil {
+		t.Fatalf("no email calibration in %+v", report.Patterns)
+	}
+
+	if email.Matches != 2 || email.TruePositives != 1 || email.FalseNegatives != 0 {
+		t.Fatalf("email = %+v, want 2 matches, 1 true positive, 0 false negatives", *email)
+	}
+	if email.Precision != 0.5 || email.Recall != 1 {
+		t.Errorf("precision, recall = %v, %v, want 0.5, 1", email.Precision, email.Recall)
+	}
+	want := (1 + calibrationPriorWeight*email.Prior) / (2 + calibrationPriorWeight)
+	if math.Abs(email.Adjusted-want) > 1e-9 || email.Adjusted >= email.Prior {
+		t.Errorf("adjusted = %v, want %v (below prior %v)", email.Adjusted, want, email.Prior)
+	}
+	if report.Overrides["email"] != email.Adjusted {
+		t.Errorf("override = %v, want %v", report.Overrides["email"], email.Adjusted)
+	}
+}
+
+// An expected value nothing matched counts against recall only
+func TestCalibrateCountsMissedExpectations(t *testing.T) {
+	m := NewMatcher()
+	report := m.Calibrate([]LabeledSample{{
+		Text:     "no address here",
+		Expected: []ExpectedMatch{{Pattern: "email", Value: "bob@example.com"}},
+	}})
+
+	if len(report.Patterns) != 1 {
+		t.Fatalf("patterns = %+v, want only email", report.Patterns)
+	}
+	got := report.Patterns[0]
+	if got.Matches != 0 || got.FalseNegatives != 1 || got.Recall != 0 || got.Adjusted != got.Prior {
+		t.Errorf("email = %+v, want 1 false negative and the prior kept", got)
+	}
+	if _, ok := report.Overrides["email"]; ok {
+		t.Error("pattern that never matched got an override")
+	}
+}
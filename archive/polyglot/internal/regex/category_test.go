@@ -0,0 +1,26 @@
+package regex
+
+import "testing"
+
+func TestParseCategory(t *testing.T) {
+	for _, s := range []string{"crypto", "Crypto", " CRYPTO "} {
+		if c, ok := ParseCategory(s); !ok || c != CategoryCrypto {
+			t.Errorf("ParseCategory(%q) = %q, %v", s, c, ok)
+		}
+	}
+	for _, s := range []string{"", "cryptos", "email"} {
+		if c, ok := ParseCategory(s); ok {
+			t.Errorf("ParseCategory(%q) = %q, want rejected", s, c)
+		}
+	}
+}
+
+// Every pattern's category is one of the known set, so FindByCategory can
+// reach it
+func TestPatternCategoriesAreKnown(t *testing.T) {
+	for _, p := range AllPatterns {
+		if _, ok := ParseCategory(string(p.Category)); !ok {
+			t.Errorf("%s: unknown category %q", p.Name, p.Category)
+		}
+	}
+}
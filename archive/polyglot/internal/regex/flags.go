@@ -0,0 +1,96 @@
+package regex
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// disabledPatterns names built-in patterns left out of every matcher
+// built by NewMatcher; set once at startup by SetPatternFlags
+var disabledPatterns = map[string]bool{}
+
+// PatternInfo describes one pattern of a matcher's effective set
+type PatternInfo struct {
+	Name       string   `json:"name"`
+	Category   Category `json:"category"`
+	Confidence float64  `json:"confidence"`
+	Sensitive  bool     `json:"sensitive"`
+}
+
+// LoadPatternFlags reads a JSON object of pattern name → enabled, e.g.
+// {"person_name": false, "function_call": false}
+func LoadPatternFlags(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var flags map[string]bool
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return flags, nil
+}
+
+// SetPatternFlags enables or disables built-in patterns by name. Only
+// matchers built afterwards see the change, so call it at startup before
+// NewMatcher. Unknown names are rejected without applying anything.
+func SetPatternFlags(flags map[string]bool) error {
+	known := make(map[string]bool, len(AllPatterns))
+	for _, p := range AllPatterns {
+		known[p.Name] = true
+	}
+	for name := range flags {
+		if !known[name] {
+			return fmt.Errorf("unknown pattern %q", name)
+		}
+	}
+
+	disabled := make(map[string]bool)
+	for name, enabled := range flags {
+		if !enabled {
+			disabled[name] = true
+		}
+	}
+	disabledPatterns = disabled
+	return nil
+}
+
+// DisabledPatterns lists the built-in patterns turned off, sorted
+func DisabledPatterns() []string {
+	names := make([]string, 0, len(disabledPatterns))
+	for name := range disabledPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enabledPatterns is AllPatterns minus the disabled ones, in order
+func enabledPatterns() []Pattern {
+	if len(disabledPatterns) == 0 {
+		return AllPatterns
+	}
+	patterns := make([]Pattern, 0, len(AllPatterns))
+	for _, p := range AllPatterns {
+		if !disabledPatterns[p.Name] {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Patterns returns the matcher's effective pattern set, in scan order
+func (m *Matcher) Patterns() []PatternInfo {
+	infos := make([]PatternInfo, len(m.patterns))
+	for i, p := range m.patterns {
+		infos[i] = PatternInfo{
+			Name:       p.Name,
+			Category:   p.Category,
+			Confidence: p.Confidence,
+			Sensitive:  p.Sensitive,
+		}
+	}
+	return infos
+}
@@ -0,0 +1,47 @@
+package regex
+
+import "testing"
+
+func TestMetricsRecordedPerPattern(t *testing.T) {
+	m := NewMatcher()
+	text := "alice@example.com and bob@example.com via 10.1.2.3"
+
+	m.FindAll(text)
+	for _, pm := range m.Metrics() {
+		if pm.Calls != 0 {
+			t.Fatalf("metrics off: %s recorded %d calls", pm.Pattern, pm.Calls)
+		}
+	}
+
+	m.EnableMetrics(true)
+	m.FindAll(text)
+	m.FindAll(text)
+
+	byName := make(map[string]PatternMetric)
+	for _, pm := range m.Metrics() {
+		byName[pm.Pattern] = pm
+		if pm.Calls != 2 {
+			t.Errorf("%s: calls = %d, want 2", pm.Pattern, pm.Calls)
+		}
+	}
+	if len(byName) != len(m.patterns) {
+		t.Errorf("%d metrics for %d patterns", len(byName), len(m.patterns))
+	}
+	if got := byName["email"].Matches; got != 4 {
+		t.Errorf("email matches = %d, want 4", got)
+	}
+	if got := byName["ip_address"].Matches; got != 2 {
+		t.Errorf("ip_address matches = %d, want 2", got)
+	}
+	if got := byName["aws_key"].Matches; got != 0 {
+		t.Errorf("aws_key matches = %d, want 0", got)
+	}
+
+	ms := m.Metrics()
+	for i := 1; i < len(ms); i++ {
+		if ms[i].TotalMs > ms[i-1].TotalMs {
+			t.Fatalf("not ordered by cost: %s %.3f after %s %.3f",
+				ms[i].Pattern, ms[i].TotalMs, ms[i-1].Pattern, ms[i-1].TotalMs)
+		}
+	}
+}
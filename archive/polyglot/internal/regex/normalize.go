@@ -0,0 +1,113 @@
+package regex
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// VALUE NORMALIZATION
+// ═══════════════════════════════════════════════════════════════════
+
+// Normalize canonicalizes an extracted value so that spellings of the same
+// entity compare equal. kind is a pattern name ("email", "btc_address",
+// ...); unknown kinds get whitespace folding and lowercasing.
+func Normalize(kind, value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+
+	switch kind {
+	case "btc_address", "base64", "jwt", "aws_key", "github_token", "file_path":
+		// Case-sensitive encodings
+		return value
+	case "phone":
+		var b strings.Builder
+		for i, r := range value {
+			if unicode.IsDigit(r) || (r == '+' && i == 0) {
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	case "iban":
+		return strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	case "mac_address":
+		return strings.ToUpper(strings.ReplaceAll(value, "-", ":"))
+	case "twitter_handle", "mention", "hashtag":
+		return strings.ToLower(strings.TrimLeft(value, "@#"))
+	case "url", "domain":
+		return strings.TrimSuffix(strings.ToLower(value), "/")
+	}
+	return strings.ToLower(value)
+}
+
+// ParseDate turns a date_iso, date_eu or date_text match into a time.
+// date_eu is read day-first unless only month-first is valid; two-digit
+// years below 70 are 20xx. Other kinds and impossible dates report false.
+func ParseDate(kind, value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	switch kind {
+	case "date_iso":
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, true
+		}
+		if len(value) >= 10 {
+			if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+				return t, true
+			}
+		}
+	case "date_eu":
+		parts := strings.FieldsFunc(value, func(r rune) bool {
+			return r == '/' || r == '.' || r == '-'
+		})
+		if len(parts) != 3 {
+			return time.Time{}, false
+		}
+		day, _ := strconv.Atoi(parts[0])
+		month, _ := strconv.Atoi(parts[1])
+		if month > 12 && day <= 12 {
+			day, month = month, day
+		}
+		return civilDate(fullYear(parts[2]), month, day)
+	case "date_text":
+		fields := strings.Fields(value)
+		if len(fields) != 3 || len(fields[1]) < 3 {
+			return time.Time{}, false
+		}
+		day, _ := strconv.Atoi(fields[0])
+		abbr := strings.ToUpper(fields[1][:1]) + strings.ToLower(fields[1][1:3])
+		month, err := time.Parse("Jan", abbr)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return civilDate(fullYear(fields[2]), int(month.Month()), day)
+	}
+	return time.Time{}, false
+}
+
+func fullYear(s string) int {
+	year, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	if len(s) <= 2 {
+		if year < 70 {
+			return 2000 + year
+		}
+		return 1900 + year
+	}
+	return year
+}
+
+// civilDate rejects dates time.Date would silently roll over (31/02)
+func civilDate(year, month, day int) (time.Time, bool) {
+	if year <= 0 || month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,35 @@
+package regex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		kind, value string
+		want        string // YYYY-MM-DD, empty for no date
+	}{
+		{"date_iso", "2021-03-14", "2021-03-14"},
+		{"date_iso", "2021-03-14T09:30:00Z", "2021-03-14"},
+		{"date_eu", "14/03/2021", "2021-03-14"},
+		{"date_eu", "03/14/2021", "2021-03-14"}, // only month-first is valid
+		{"date_eu", "05.06.21", "2021-06-05"},   // day-first by default
+		{"date_eu", "05.06.85", "1985-06-05"},
+		{"date_text", "3 March 2021", "2021-03-03"},
+		{"date_text", "3 mar 2021", "2021-03-03"},
+		{"date_eu", "31/02/2021", ""},
+		{"date_text", "3 Mxx 2021", ""},
+		{"time", "09:30", ""},
+	}
+	for _, tt := range tests {
+		at, ok := ParseDate(tt.kind, tt.value)
+		got := ""
+		if ok {
+			got = at.Format(time.DateOnly)
+		}
+		if got != tt.want {
+			t.Errorf("ParseDate(%s, %q) = %q, want %q", tt.kind, tt.value, got, tt.want)
+		}
+	}
+}
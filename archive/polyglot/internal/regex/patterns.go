@@ -2,8 +2,11 @@ package regex
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ═══════════════════════════════════════════════════════════════════
@@ -12,49 +15,49 @@ import (
 
 var (
 	// Communication patterns
-	EmailRegex    = regexp.MustCompile(`(?i)\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`)
-	PhoneRegex    = regexp.MustCompile(`(?:\+?\d{1,3}[-.\s]?)?\(?\d{2,4}\)?[-.\s]?\d{2,4}[-.\s]?\d{2,4}`)
-	URLRegex      = regexp.MustCompile(`(?i)https?://(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&//=]*)`)
-	IPRegex       = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`)
-	IPv6Regex     = regexp.MustCompile(`(?i)(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}|(?:[0-9a-f]{1,4}:){1,7}:|(?:[0-9a-f]{1,4}:){1,6}:[0-9a-f]{1,4}`)
-	MACRegex      = regexp.MustCompile(`(?i)(?:[0-9A-F]{2}[:-]){5}[0-9A-F]{2}`)
+	EmailRegex = regexp.MustCompile(`(?i)\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`)
+	PhoneRegex = regexp.MustCompile(`(?:\+?\d{1,3}[-.\s]?)?\(?\d{2,4}\)?[-.\s]?\d{2,4}[-.\s]?\d{2,4}`)
+	URLRegex   = regexp.MustCompile(`(?i)https?://(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&//=]*)`)
+	IPRegex    = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`)
+	IPv6Regex  = regexp.MustCompile(`(?i)(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}|(?:[0-9a-f]{1,4}:){1,7}:|(?:[0-9a-f]{1,4}:){1,6}:[0-9a-f]{1,4}`)
+	MACRegex   = regexp.MustCompile(`(?i)(?:[0-9A-F]{2}[:-]){5}[0-9A-F]{2}`)
 
 	// Dates and times
-	DateISORegex   = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)?`)
-	DateEURegex    = regexp.MustCompile(`\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4}`)
-	DateTextRegex  = regexp.MustCompile(`(?i)\b\d{1,2}\s+(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\s+\d{2,4}\b`)
-	TimeRegex      = regexp.MustCompile(`\b(?:[01]?\d|2[0-3]):[0-5]\d(?::[0-5]\d)?\s*(?:AM|PM|am|pm)?\b`)
+	DateISORegex  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?)?`)
+	DateEURegex   = regexp.MustCompile(`\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4}`)
+	DateTextRegex = regexp.MustCompile(`(?i)\b\d{1,2}\s+(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\s+\d{2,4}\b`)
+	TimeRegex     = regexp.MustCompile(`\b(?:[01]?\d|2[0-3]):[0-5]\d(?::[0-5]\d)?\s*(?:AM|PM|am|pm)?\b`)
 
 	// Financial
-	CurrencyRegex  = regexp.MustCompile(`(?i)[$€£¥₿]\s*\d+(?:[.,]\d{2,3})*(?:[.,]\d{2})?|\d+(?:[.,]\d{3})*(?:[.,]\d{2})?\s*(?:USD|EUR|GBP|BTC|ETH|USDT)`)
-	BTCAddrRegex   = regexp.MustCompile(`\b[13][a-km-zA-HJ-NP-Z1-9]{25,34}\b`)
-	ETHAddrRegex   = regexp.MustCompile(`\b0x[a-fA-F0-9]{40}\b`)
-	IBANRegex      = regexp.MustCompile(`\b[A-Z]{2}\d{2}[A-Z0-9]{4}\d{7}(?:[A-Z0-9]?){0,16}\b`)
+	CurrencyRegex   = regexp.MustCompile(`(?i)[$€£¥₿]\s*\d+(?:[.,]\d{2,3})*(?:[.,]\d{2})?|\d+(?:[.,]\d{3})*(?:[.,]\d{2})?\s*(?:USD|EUR|GBP|BTC|ETH|USDT)`)
+	BTCAddrRegex    = regexp.MustCompile(`\b[13][a-km-zA-HJ-NP-Z1-9]{25,34}\b`)
+	ETHAddrRegex    = regexp.MustCompile(`\b0x[a-fA-F0-9]{40}\b`)
+	IBANRegex       = regexp.MustCompile(`\b[A-Z]{2}\d{2}[A-Z0-9]{4}\d{7}(?:[A-Z0-9]?){0,16}\b`)
 	CreditCardRegex = regexp.MustCompile(`\b(?:4[0-9]{12}(?:[0-9]{3})?|5[1-5][0-9]{14}|3[47][0-9]{13}|6(?:011|5[0-9]{2})[0-9]{12})\b`)
 
 	// Social
-	TwitterRegex   = regexp.MustCompile(`@[A-Za-z0-9_]{1,15}\b`)
-	HashtagRegex   = regexp.MustCompile(`#[A-Za-z0-9_]+\b`)
-	MentionRegex   = regexp.MustCompile(`@[A-Za-z0-9_.]+`)
+	TwitterRegex = regexp.MustCompile(`@[A-Za-z0-9_]{1,15}\b`)
+	HashtagRegex = regexp.MustCompile(`#[A-Za-z0-9_]+\b`)
+	MentionRegex = regexp.MustCompile(`@[A-Za-z0-9_.]+`)
 
 	// Technical
-	UUIDRegex      = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
-	MD5Regex       = regexp.MustCompile(`\b[a-fA-F0-9]{32}\b`)
-	SHA1Regex      = regexp.MustCompile(`\b[a-fA-F0-9]{40}\b`)
-	SHA256Regex    = regexp.MustCompile(`\b[a-fA-F0-9]{64}\b`)
-	Base64Regex    = regexp.MustCompile(`(?:[A-Za-z0-9+/]{4}){10,}(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?`)
-	JWTRegex       = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+	UUIDRegex   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	MD5Regex    = regexp.MustCompile(`\b[a-fA-F0-9]{32}\b`)
+	SHA1Regex   = regexp.MustCompile(`\b[a-fA-F0-9]{40}\b`)
+	SHA256Regex = regexp.MustCompile(`\b[a-fA-F0-9]{64}\b`)
+	Base64Regex = regexp.MustCompile(`(?:[A-Za-z0-9+/]{4}){10,}(?:[A-Za-z0-9+/]{2}==|[A-Za-z0-9+/]{3}=)?`)
+	JWTRegex    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
 
 	// Code patterns
-	FunctionCallRegex = regexp.MustCompile(`\b[a-zA-Z_][a-zA-Z0-9_]*\s*\([^)]*\)`)
-	ImportRegex       = regexp.MustCompile(`(?m)^(?:import|from|require|use|include)\s+.+$`)
-	CommentRegex      = regexp.MustCompile(`(?s)(?://.*?$|/\*.*?\*/|#.*?$)`)
+	FunctionCallRegex  = regexp.MustCompile(`\b[a-zA-Z_][a-zA-Z0-9_]*\s*\([^)]*\)`)
+	ImportRegex        = regexp.MustCompile(`(?m)^(?:import|from|require|use|include)\s+.+$`)
+	CommentRegex       = regexp.MustCompile(`(?s)(?://.*?$|/\*.*?\*/|#.*?$)`)
 	StringLiteralRegex = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`)
 
 	// Security
-	PasswordRegex  = regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api[_-]?key|token|auth)\s*[:=]\s*['"]?[^\s'"]+`)
-	PrivateKeyRegex = regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)
-	AWSKeyRegex    = regexp.MustCompile(`(?i)AKIA[0-9A-Z]{16}`)
+	PasswordRegex    = regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|api[_-]?key|token|auth)\s*[:=]\s*['"]?[^\s'"]+`)
+	PrivateKeyRegex  = regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)
+	AWSKeyRegex      = regexp.MustCompile(`(?i)AKIA[0-9A-Z]{16}`)
 	GitHubTokenRegex = regexp.MustCompile(`ghp_[a-zA-Z0-9]{36}`)
 
 	// Names and places (heuristic)
@@ -62,72 +65,113 @@ var (
 	OrgRegex        = regexp.MustCompile(`(?i)\b[A-Z][A-Za-z]*(?:\s+[A-Z][A-Za-z]*)*\s+(?:Inc|Corp|LLC|Ltd|GmbH|SA|SAS|SARL|Co|Company|Foundation|Institute|University|Association)\b`)
 
 	// File paths and URLs
-	FilePathRegex   = regexp.MustCompile(`(?:/[a-zA-Z0-9._-]+)+|(?:[A-Z]:\\(?:[a-zA-Z0-9._-]+\\?)+)`)
-	DomainRegex     = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}\b`)
+	FilePathRegex = regexp.MustCompile(`(?:/[a-zA-Z0-9._-]+)+|(?:[A-Z]:\\(?:[a-zA-Z0-9._-]+\\?)+)`)
+	DomainRegex   = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}\b`)
 )
 
 // ═══════════════════════════════════════════════════════════════════
 // PATTERN REGISTRY
 // ═══════════════════════════════════════════════════════════════════
 
+// Category groups patterns by what they find. Match and Pattern carry
+// one; /api/regex/extract/:category takes one.
+type Category string
+
+const (
+	CategoryCommunication Category = "communication"
+	CategoryNetwork       Category = "network"
+	CategoryTemporal      Category = "temporal"
+	CategoryFinancial     Category = "financial"
+	CategoryCrypto        Category = "crypto"
+	CategorySocial        Category = "social"
+	CategoryIdentifier    Category = "identifier"
+	CategoryHash          Category = "hash"
+	CategoryEncoding      Category = "encoding"
+	CategoryAuth          Category = "auth"
+	CategoryCode          Category = "code"
+	CategorySecurity      Category = "security"
+	CategoryEntity        Category = "entity"
+	CategoryFilesystem    Category = "filesystem"
+)
+
+// Categories lists every category, in AllPatterns order
+var Categories = []Category{
+	CategoryCommunication, CategoryNetwork, CategoryTemporal, CategoryFinancial,
+	CategoryCrypto, CategorySocial, CategoryIdentifier, CategoryHash,
+	CategoryEncoding, CategoryAuth, CategoryCode, CategorySecurity,
+	CategoryEntity, CategoryFilesystem,
+}
+
+// ParseCategory returns the category named s, ignoring case; false when
+// there's no such category
+func ParseCategory(s string) (Category, bool) {
+	c := Category(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Categories {
+		if c == known {
+			return c, true
+		}
+	}
+	return "", false
+}
+
 type Pattern struct {
 	Name       string
 	Regex      *regexp.Regexp
-	Category   string
+	Category   Category
 	Confidence float64
 	Sensitive  bool
 }
 
 var AllPatterns = []Pattern{
 	// Communication
-	{"email", EmailRegex, "communication", 0.95, false},
-	{"phone", PhoneRegex, "communication", 0.70, false},
-	{"url", URLRegex, "communication", 0.95, false},
-	{"ip_address", IPRegex, "network", 0.99, false},
-	{"ipv6_address", IPv6Regex, "network", 0.99, false},
-	{"mac_address", MACRegex, "network", 0.95, false},
+	{"email", EmailRegex, CategoryCommunication, 0.95, false},
+	{"phone", PhoneRegex, CategoryCommunication, 0.70, false},
+	{"url", URLRegex, CategoryCommunication, 0.95, false},
+	{"ip_address", IPRegex, CategoryNetwork, 0.99, false},
+	{"ipv6_address", IPv6Regex, CategoryNetwork, 0.99, false},
+	{"mac_address", MACRegex, CategoryNetwork, 0.95, false},
 
 	// Dates
-	{"date_iso", DateISORegex, "temporal", 0.95, false},
-	{"date_eu", DateEURegex, "temporal", 0.70, false},
-	{"date_text", DateTextRegex, "temporal", 0.80, false},
-	{"time", TimeRegex, "temporal", 0.75, false},
+	{"date_iso", DateISORegex, CategoryTemporal, 0.95, false},
+	{"date_eu", DateEURegex, CategoryTemporal, 0.70, false},
+	{"date_text", DateTextRegex, CategoryTemporal, 0.80, false},
+	{"time", TimeRegex, CategoryTemporal, 0.75, false},
 
 	// Financial
-	{"currency", CurrencyRegex, "financial", 0.85, true},
-	{"btc_address", BTCAddrRegex, "crypto", 0.90, true},
-	{"eth_address", ETHAddrRegex, "crypto", 0.95, true},
-	{"iban", IBANRegex, "financial", 0.95, true},
-	{"credit_card", CreditCardRegex, "financial", 0.90, true},
+	{"currency", CurrencyRegex, CategoryFinancial, 0.85, true},
+	{"btc_address", BTCAddrRegex, CategoryCrypto, 0.90, true},
+	{"eth_address", ETHAddrRegex, CategoryCrypto, 0.95, true},
+	{"iban", IBANRegex, CategoryFinancial, 0.95, true},
+	{"credit_card", CreditCardRegex, CategoryFinancial, 0.90, true},
 
 	// Social
-	{"twitter_handle", TwitterRegex, "social", 0.90, false},
-	{"hashtag", HashtagRegex, "social", 0.95, false},
-	{"mention", MentionRegex, "social", 0.85, false},
+	{"twitter_handle", TwitterRegex, CategorySocial, 0.90, false},
+	{"hashtag", HashtagRegex, CategorySocial, 0.95, false},
+	{"mention", MentionRegex, CategorySocial, 0.85, false},
 
 	// Technical
-	{"uuid", UUIDRegex, "identifier", 0.99, false},
-	{"md5_hash", MD5Regex, "hash", 0.80, false},
-	{"sha1_hash", SHA1Regex, "hash", 0.85, false},
-	{"sha256_hash", SHA256Regex, "hash", 0.90, false},
-	{"base64", Base64Regex, "encoding", 0.60, false},
-	{"jwt", JWTRegex, "auth", 0.95, true},
+	{"uuid", UUIDRegex, CategoryIdentifier, 0.99, false},
+	{"md5_hash", MD5Regex, CategoryHash, 0.80, false},
+	{"sha1_hash", SHA1Regex, CategoryHash, 0.85, false},
+	{"sha256_hash", SHA256Regex, CategoryHash, 0.90, false},
+	{"base64", Base64Regex, CategoryEncoding, 0.60, false},
+	{"jwt", JWTRegex, CategoryAuth, 0.95, true},
 
 	// Code
-	{"function_call", FunctionCallRegex, "code", 0.75, false},
-	{"import_statement", ImportRegex, "code", 0.90, false},
+	{"function_call", FunctionCallRegex, CategoryCode, 0.75, false},
+	{"import_statement", ImportRegex, CategoryCode, 0.90, false},
 
 	// Security
-	{"password_leak", PasswordRegex, "security", 0.80, true},
-	{"private_key", PrivateKeyRegex, "security", 0.99, true},
-	{"aws_key", AWSKeyRegex, "security", 0.95, true},
-	{"github_token", GitHubTokenRegex, "security", 0.99, true},
+	{"password_leak", PasswordRegex, CategorySecurity, 0.80, true},
+	{"private_key", PrivateKeyRegex, CategorySecurity, 0.99, true},
+	{"aws_key", AWSKeyRegex, CategorySecurity, 0.95, true},
+	{"github_token", GitHubTokenRegex, CategorySecurity, 0.99, true},
 
 	// Entities
-	{"person_name", PersonNameRegex, "entity", 0.50, false},
-	{"organization", OrgRegex, "entity", 0.60, false},
-	{"file_path", FilePathRegex, "filesystem", 0.80, false},
-	{"domain", DomainRegex, "network", 0.85, false},
+	{"person_name", PersonNameRegex, CategoryEntity, 0.50, false},
+	{"organization", OrgRegex, CategoryEntity, 0.60, false},
+	{"file_path", FilePathRegex, CategoryFilesystem, 0.80, false},
+	{"domain", DomainRegex, CategoryNetwork, 0.85, false},
 }
 
 // ═══════════════════════════════════════════════════════════════════
@@ -135,28 +179,129 @@ var AllPatterns = []Pattern{
 // ═══════════════════════════════════════════════════════════════════
 
 type Match struct {
-	Pattern    string  `json:"pattern"`
-	Category   string  `json:"category"`
-	Value      string  `json:"value"`
-	Start      int     `json:"start"`
-	End        int     `json:"end"`
-	Confidence float64 `json:"confidence"`
-	Sensitive  bool    `json:"sensitive"`
+	Pattern    string   `json:"pattern"`
+	Category   Category `json:"category"`
+	Value      string   `json:"value"`
+	Start      int      `json:"start"`
+	End        int      `json:"end"`
+	Confidence float64  `json:"confidence"`
+	Sensitive  bool     `json:"sensitive"`
+}
+
+// SensitiveScanOrder is the order HasSensitive tries sensitive patterns:
+// cheap, literal-anchored and common ones first. Sensitive patterns not
+// listed run last in AllPatterns order.
+var SensitiveScanOrder = []string{
+	"private_key", "aws_key", "github_token", "jwt", "password_leak",
+	"eth_address", "iban", "btc_address", "credit_card", "currency",
 }
 
 type Matcher struct {
 	patterns []Pattern
 	cache    sync.Map
+
+	sensitiveOrder []int // indexes into patterns, see SensitiveScanOrder
+
+	metricsOn atomic.Bool
+	metrics   map[string]*patternStats
+}
+
+type patternStats struct {
+	calls   atomic.Int64
+	matches atomic.Int64
+	nanos   atomic.Int64
+}
+
+// PatternMetric reports accumulated FindAll cost for one pattern
+type PatternMetric struct {
+	Pattern string  `json:"pattern"`
+	Calls   int64   `json:"calls"`
+	Matches int64   `json:"matches"`
+	TotalMs float64 `json:"total_ms"`
+	AvgMs   float64 `json:"avg_ms"`
 }
 
 func NewMatcher() *Matcher {
-	return &Matcher{
-		patterns: AllPatterns,
+	m := &Matcher{
+		patterns: enabledPatterns(),
+	}
+	m.metrics = make(map[string]*patternStats, len(m.patterns))
+	for _, p := range m.patterns {
+		m.metrics[p.Name] = &patternStats{}
 	}
+	m.sensitiveOrder = sensitiveOrder(m.patterns)
+	return m
+}
+
+func sensitiveOrder(patterns []Pattern) []int {
+	rank := make(map[string]int, len(SensitiveScanOrder))
+	for i, name := range SensitiveScanOrder {
+		rank[name] = i
+	}
+
+	var order []int
+	for i, p := range patterns {
+		if p.Sensitive {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, oka := rank[patterns[order[a]].Name]
+		rb, okb := rank[patterns[order[b]].Name]
+		if oka != okb {
+			return oka
+		}
+		return ra < rb
+	})
+	return order
+}
+
+// EnableMetrics turns per-pattern timing in FindAll on or off
+func (m *Matcher) EnableMetrics(on bool) {
+	m.metricsOn.Store(on)
+}
+
+func (m *Matcher) MetricsEnabled() bool {
+	return m.metricsOn.Load()
+}
+
+// Metrics returns per-pattern stats, most expensive first
+func (m *Matcher) Metrics() []PatternMetric {
+	out := make([]PatternMetric, 0, len(m.metrics))
+	for name, st := range m.metrics {
+		calls := st.calls.Load()
+		totalMs := float64(st.nanos.Load()) / float64(time.Millisecond)
+		metric := PatternMetric{
+			Pattern: name,
+			Calls:   calls,
+			Matches: st.matches.Load(),
+			TotalMs: totalMs,
+		}
+		if calls > 0 {
+			metric.AvgMs = totalMs / float64(calls)
+		}
+		out = append(out, metric)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalMs != out[j].TotalMs {
+			return out[i].TotalMs > out[j].TotalMs
+		}
+		return out[i].Pattern < out[j].Pattern
+	})
+	return out
 }
 
 func (m *Matcher) FindAll(text string) []Match {
-	var matches []Match
+	matches, _ := m.FindAllLimited(text, 0)
+	return matches
+}
+
+// FindAllLimited is FindAll keeping at most maxPerPattern matches (the
+// first ones in text order) per pattern; zero or less keeps all. totals
+// maps each matching pattern to its true match count.
+func (m *Matcher) FindAllLimited(text string, maxPerPattern int) (matches []Match, totals map[string]int) {
+	totals = make(map[string]int)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
@@ -166,12 +311,31 @@ func (m *Matcher) FindAll(text string) []Match {
 		go func(pattern Pattern) {
 			defer wg.Done()
 
+			var start time.Time
+			recording := m.metricsOn.Load()
+			if recording {
+				start = time.Now()
+			}
+
 			found := pattern.Regex.FindAllStringIndex(text, -1)
+
+			if recording {
+				if st := m.metrics[pattern.Name]; st != nil {
+					st.calls.Add(1)
+					st.matches.Add(int64(len(found)))
+					st.nanos.Add(int64(time.Since(start)))
+				}
+			}
+
 			if found == nil {
 				return
 			}
 
 			mu.Lock()
+			totals[pattern.Name] = len(found)
+			if maxPerPattern > 0 && len(found) > maxPerPattern {
+				found = found[:maxPerPattern]
+			}
 			for _, loc := range found {
 				matches = append(matches, Match{
 					Pattern:    pattern.Name,
@@ -188,10 +352,10 @@ func (m *Matcher) FindAll(text string) []Match {
 	}
 
 	wg.Wait()
-	return matches
+	return matches, totals
 }
 
-func (m *Matcher) FindByCategory(text string, category string) []Match {
+func (m *Matcher) FindByCategory(text string, category Category) []Match {
 	var matches []Match
 
 	for _, p := range m.patterns {
@@ -213,7 +377,37 @@ func (m *Matcher) FindByCategory(text string, category string) []Match {
 		}
 	}
 
-	return matches
+	return dedupeBySpan(matches)
+}
+
+// dedupeBySpan collapses matches covering the exact same span (e.g. mention
+// and twitter_handle on "@user"), keeping the highest-confidence pattern,
+// and returns them ordered by position.
+func dedupeBySpan(matches []Match) []Match {
+	type span struct{ start, end int }
+
+	best := make(map[span]int, len(matches))
+	var deduped []Match
+	for _, match := range matches {
+		key := span{match.Start, match.End}
+		if idx, ok := best[key]; ok {
+			if match.Confidence > deduped[idx].Confidence {
+				deduped[idx] = match
+			}
+			continue
+		}
+		best[key] = len(deduped)
+		deduped = append(deduped, match)
+	}
+
+	sort.SliceStable(deduped, func(i, j int) bool {
+		if deduped[i].Start != deduped[j].Start {
+			return deduped[i].Start < deduped[j].Start
+		}
+		return deduped[i].End < deduped[j].End
+	})
+
+	return deduped
 }
 
 func (m *Matcher) FindSensitive(text string) []Match {
@@ -241,6 +435,18 @@ func (m *Matcher) FindSensitive(text string) []Match {
 	return matches
 }
 
+// HasSensitive reports whether text contains any sensitive match and the
+// first pattern found, stopping at the first hit. Cheaper than
+// FindSensitive when only a yes/no is needed.
+func (m *Matcher) HasSensitive(text string) (bool, string) {
+	for _, i := range m.sensitiveOrder {
+		if p := m.patterns[i]; p.Regex.MatchString(text) {
+			return true, p.Name
+		}
+	}
+	return false, ""
+}
+
 // ═══════════════════════════════════════════════════════════════════
 // TEXT TRANSFORMATIONS
 // ═══════════════════════════════════════════════════════════════════
@@ -251,6 +457,7 @@ var (
 	NumbersRegex     = regexp.MustCompile(`\d+`)
 	HTMLTagRegex     = regexp.MustCompile(`<[^>]+>`)
 	AnsiEscapeRegex  = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	SentenceEndRegex = regexp.MustCompile(`[.!?…]+["'»)\]]*\s+`)
 )
 
 func NormalizeWhitespace(text string) string {
@@ -273,17 +480,33 @@ func StripAnsi(text string) string {
 	return AnsiEscapeRegex.ReplaceAllString(text, "")
 }
 
-func RedactSensitive(text string) string {
-	result := text
-	m := NewMatcher()
-	matches := m.FindSensitive(text)
+// Transforms maps API names to the text utilities above
+var Transforms = map[string]func(string) string{
+	"normalize_whitespace": NormalizeWhitespace,
+	"remove_punctuation":   RemovePunctuation,
+	"remove_numbers":       RemoveNumbers,
+	"strip_html":           StripHTML,
+	"strip_ansi":           StripAnsi,
+}
 
-	// Sort by position descending to preserve indices
-	for i := len(matches) - 1; i >= 0; i-- {
-		match := matches[i]
-		redacted := strings.Repeat("*", len(match.Value))
-		result = result[:match.Start] + redacted + result[match.End:]
+// SplitSentences breaks text on terminal punctuation followed by whitespace
+func SplitSentences(text string) []string {
+	var sentences []string
+	last := 0
+	for _, loc := range SentenceEndRegex.FindAllStringIndex(text, -1) {
+		if s := strings.TrimSpace(text[last:loc[1]]); s != "" {
+			sentences = append(sentences, s)
+		}
+		last = loc[1]
 	}
+	if s := strings.TrimSpace(text[last:]); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
 
+func RedactSensitive(text string) string {
+	m := NewMatcher()
+	result, _ := Redact(text, m.FindSensitive(text), RedactFull)
 	return result
 }
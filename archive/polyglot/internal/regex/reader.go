@@ -0,0 +1,70 @@
+package regex
+
+import (
+	"errors"
+	"io"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// CHUNKED MATCHING - for inputs too large to hold in memory
+// ═══════════════════════════════════════════════════════════════════
+
+const (
+	// ReaderChunkSize is how much new input each FindAllReader window reads
+	ReaderChunkSize = 256 << 10
+	// ReaderOverlap is the longest match FindAllReader guarantees to find
+	// across a chunk boundary
+	ReaderOverlap = 4 << 10
+)
+
+// FindAllReader runs FindAll over r in overlapping windows, calling fn for
+// every match with offsets relative to the start of the stream, and
+// returns the number of bytes read.
+//
+// Each window carries the last 2*ReaderOverlap bytes of the previous one.
+// A match is only accepted once it ends at least ReaderOverlap bytes
+// before the window's end (or the stream is done), and only if it ends
+// past the previous window's accept limit, so matches up to ReaderOverlap
+// long are reported exactly once even when they straddle chunks.
+func (m *Matcher) FindAllReader(r io.Reader, fn func(Match)) (int64, error) {
+	const carry = 2 * ReaderOverlap
+
+	buf := make([]byte, 0, carry+ReaderChunkSize)
+	var base, total int64 // stream offset of buf[0], bytes read
+	accepted := int64(-1) // stream offset up to which matches were reported
+
+	for {
+		n, err := io.ReadFull(r, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		total += int64(n)
+
+		final := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !final {
+			return total, err
+		}
+
+		limit := base + int64(len(buf))
+		if !final {
+			limit -= ReaderOverlap
+		}
+
+		for _, match := range m.FindAll(string(buf)) {
+			start, end := base+int64(match.Start), base+int64(match.End)
+			if end <= accepted || end > limit {
+				continue
+			}
+			match.Start, match.End = int(start), int(end)
+			fn(match)
+		}
+		accepted = limit
+
+		if final {
+			return total, nil
+		}
+
+		// Slide: keep the tail as context for the next window
+		keep := buf[len(buf)-carry:]
+		base += int64(len(buf) - carry)
+		buf = append(buf[:0], keep...)
+	}
+}
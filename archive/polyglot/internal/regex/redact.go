@@ -0,0 +1,123 @@
+package regex
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// REDACTION MODES
+// ═══════════════════════════════════════════════════════════════════
+
+type RedactionMode string
+
+const (
+	RedactFull      RedactionMode = "full"  // every character masked
+	RedactLast4     RedactionMode = "last4" // keep the last 4 characters visible
+	RedactTypeToken RedactionMode = "type"  // replace with [REDACTED:<pattern>]
+)
+
+// ParseRedactionMode maps a user-supplied mode to a RedactionMode,
+// defaulting to full masking when empty.
+func ParseRedactionMode(s string) (RedactionMode, bool) {
+	switch RedactionMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", RedactFull:
+		return RedactFull, true
+	case RedactLast4:
+		return RedactLast4, true
+	case RedactTypeToken:
+		return RedactTypeToken, true
+	}
+	return "", false
+}
+
+// RedactedItem describes one masked span (never the original value)
+type RedactedItem struct {
+	Pattern  string   `json:"pattern"`
+	Category Category `json:"category"`
+	Start    int      `json:"start"`
+	End      int      `json:"end"`
+}
+
+type RedactionManifest struct {
+	Total      int              `json:"total"`
+	ByCategory map[Category]int `json:"by_category"`
+	ByPattern  map[string]int   `json:"by_pattern"`
+	Items      []RedactedItem   `json:"items"`
+}
+
+// Redact masks the given matches in text. Overlapping spans are merged
+// first (attributed to the most confident pattern) so replacements that
+// change length can't corrupt neighbouring offsets.
+func Redact(text string, matches []Match, mode RedactionMode) (string, RedactionManifest) {
+	manifest := RedactionManifest{
+		ByCategory: make(map[Category]int),
+		ByPattern:  make(map[string]int),
+		Items:      []RedactedItem{},
+	}
+
+	spans := mergeSpans(matches)
+	for _, sp := range spans {
+		manifest.Total++
+		manifest.ByCategory[sp.Category]++
+		manifest.ByPattern[sp.Pattern]++
+		manifest.Items = append(manifest.Items, RedactedItem{
+			Pattern:  sp.Pattern,
+			Category: sp.Category,
+			Start:    sp.Start,
+			End:      sp.End,
+		})
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(text[last:sp.Start])
+		b.WriteString(mask(text[sp.Start:sp.End], sp.Pattern, mode))
+		last = sp.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), manifest
+}
+
+func mergeSpans(matches []Match) []Match {
+	sorted := make([]Match, len(matches))
+	copy(sorted, matches)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	var merged []Match
+	for _, m := range sorted {
+		if n := len(merged); n > 0 && m.Start < merged[n-1].End {
+			cur := &merged[n-1]
+			if m.End > cur.End {
+				cur.End = m.End
+			}
+			if m.Confidence > cur.Confidence {
+				cur.Pattern, cur.Category, cur.Confidence = m.Pattern, m.Category, m.Confidence
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+func mask(value, pattern string, mode RedactionMode) string {
+	switch mode {
+	case RedactTypeToken:
+		return "[REDACTED:" + pattern + "]"
+	case RedactLast4:
+		n := utf8.RuneCountInString(value)
+		if n <= 4 {
+			return strings.Repeat("*", n)
+		}
+		runes := []rune(value)
+		return strings.Repeat("*", n-4) + string(runes[n-4:])
+	default:
+		return strings.Repeat("*", len(value))
+	}
+}
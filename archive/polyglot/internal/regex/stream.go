@@ -0,0 +1,82 @@
+package regex
+
+import "unicode/utf8"
+
+// DefaultRedactWindow is how many trailing bytes a StreamRedactor holds
+// back by default: longer than any sensitive value the patterns match in
+// practice, so one split across pieces is still seen whole
+const DefaultRedactWindow = 256
+
+// StreamRedactor masks sensitive values in text that arrives piece by
+// piece. It keeps the last window bytes unsent, plus any match reaching
+// into them, so a value split across pieces is matched whole before any
+// of it goes out. Values longer than the window can leak their start.
+type StreamRedactor struct {
+	matcher *Matcher
+	mode    RedactionMode
+	window  int
+	buf     string
+
+	// Redacted counts the values masked so far
+	Redacted int
+}
+
+// NewStreamRedactor returns a redactor for one stream; window <= 0 uses
+// DefaultRedactWindow
+func (m *Matcher) NewStreamRedactor(mode RedactionMode, window int) *StreamRedactor {
+	if window <= 0 {
+		window = DefaultRedactWindow
+	}
+	return &StreamRedactor{matcher: m, mode: mode, window: window}
+}
+
+// Push feeds the next piece and returns the redacted text now safe to
+// emit, possibly empty
+func (r *StreamRedactor) Push(piece string) string {
+	r.buf += piece
+	cut := len(r.buf) - r.window
+	if cut <= 0 {
+		return ""
+	}
+
+	matches := r.matcher.FindSensitive(r.buf)
+	// A match crossing the cut, or ending where the text does, may still
+	// be incomplete: hold it back whole
+	for _, m := range matches {
+		if m.Start < cut && (m.End > cut || m.End == len(r.buf)) {
+			cut = m.Start
+		}
+	}
+	for cut > 0 && !utf8.RuneStart(r.buf[cut]) {
+		cut--
+	}
+	if cut <= 0 {
+		return ""
+	}
+
+	var done []Match
+	for _, m := range matches {
+		if m.End <= cut {
+			done = append(done, m)
+		}
+	}
+	out := r.redact(r.buf[:cut], done)
+	r.buf = r.buf[cut:]
+	return out
+}
+
+// Flush redacts and returns whatever is still held at the end of the stream
+func (r *StreamRedactor) Flush() string {
+	out := r.redact(r.buf, r.matcher.FindSensitive(r.buf))
+	r.buf = ""
+	return out
+}
+
+func (r *StreamRedactor) redact(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	out, manifest := Redact(text, matches, r.mode)
+	r.Redacted += manifest.Total
+	return out
+}
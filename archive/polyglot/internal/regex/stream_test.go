@@ -0,0 +1,50 @@
+package regex
+
+import (
+	"strings"
+	"testing"
+)
+
+// An AWS key split across two pieces is held back until it is whole, then
+// masked; nothing before the flush leaks either half
+func TestStreamRedactorMasksSecretSplitAcrossPieces(t *testing.T) {
+	m := NewMatcher()
+	r := m.NewStreamRedactor(RedactTypeToken, 16)
+
+	var out strings.Builder
+	for _, piece := range []string{
+		"the deploy script still uses AKIAIOSF",
+		"ODNN7EXAMPLE as its key, rotate it before the release",
+	} {
+		got := r.Push(piece)
+		if strings.Contains(got, "AKIA") || strings.Contains(got, "ODNN7") {
+			t.Fatalf("Push(%q) leaked part of the key: %q", piece, got)
+		}
+		out.WriteString(got)
+	}
+	out.WriteString(r.Flush())
+
+	got := out.String()
+	if strings.Contains(got, "AKIAIOSF") || strings.Contains(got, "ODNN7EXAMPLE") {
+		t.Fatalf("key not masked: %q", got)
+	}
+	if !strings.HasPrefix(got, "the deploy script still uses ") || !strings.HasSuffix(got, " as its key, rotate it before the release") {
+		t.Errorf("surrounding text changed: %q", got)
+	}
+	if r.Redacted != 1 {
+		t.Errorf("Redacted = %d, want 1", r.Redacted)
+	}
+}
+
+// Text well clear of the window goes out before the stream ends
+func TestStreamRedactorFlushesSafePrefix(t *testing.T) {
+	r := NewMatcher().NewStreamRedactor(RedactTypeToken, 8)
+
+	got := r.Push("nothing sensitive in this sentence")
+	if got == "" || !strings.HasPrefix("nothing sensitive in this sentence", got) {
+		t.Fatalf("Push = %q, want a prefix of the input", got)
+	}
+	if rest := r.Flush(); got+rest != "nothing sensitive in this sentence" {
+		t.Errorf("Push+Flush = %q", got+rest)
+	}
+}
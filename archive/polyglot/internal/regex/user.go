@@ -0,0 +1,127 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ═══════════════════════════════════════════════════════════════════
+// USER-SUPPLIED PATTERNS
+// ═══════════════════════════════════════════════════════════════════
+
+const (
+	// MaxUserPatternLength bounds the size of a user pattern's program
+	MaxUserPatternLength = 1000
+	// MaxUserMatches caps how many matches a user pattern may return
+	MaxUserMatches = 1000
+)
+
+// Flags accepted for user patterns, as in Go's (?flags) syntax
+const userFlags = "imsU"
+
+// ErrPatternTimeout is returned when a user pattern overruns its deadline
+var ErrPatternTimeout = errors.New("pattern matching timed out")
+
+// A quantified group that itself contains a quantifier, e.g. (a+)+ or
+// (\w*x)*. Harmless to RE2 but the classic ReDoS shape, and needlessly
+// expensive on large inputs, so user patterns may not use it.
+var nestedQuantifierRegex = regexp.MustCompile(`\([^()]*[+*}][^()]*\)(?:[+*]|\{\d+,\d*\})`)
+
+// CompileUserPattern compiles a pattern supplied through the API with
+// optional flags ("i", "m", "s", "U"). Go's RE2 engine matches in linear
+// time, so the guard here is on pattern size rather than backtracking.
+func CompileUserPattern(pattern, flags string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern required")
+	}
+	if len(pattern) > MaxUserPatternLength {
+		return nil, fmt.Errorf("pattern too long (max %d characters)", MaxUserPatternLength)
+	}
+
+	if nestedQuantifierRegex.MatchString(pattern) {
+		return nil, errors.New("pattern rejected: nested quantifiers like (a+)+ are not allowed")
+	}
+
+	var used strings.Builder
+	for _, f := range flags {
+		if !strings.ContainsRune(userFlags, f) {
+			return nil, fmt.Errorf("unknown flag %q (allowed: %s)", f, userFlags)
+		}
+		if !strings.ContainsRune(used.String(), f) {
+			used.WriteRune(f)
+		}
+	}
+
+	expr := pattern
+	if used.Len() > 0 {
+		expr = "(?" + used.String() + ")" + pattern
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+type TestMatch struct {
+	Value  string   `json:"value"`
+	Start  int      `json:"start"`
+	End    int      `json:"end"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// TestPattern runs re over text, returning at most MaxUserMatches matches
+// and whether the result was cut short.
+func TestPattern(re *regexp.Regexp, text string) ([]TestMatch, bool) {
+	found := re.FindAllStringSubmatchIndex(text, MaxUserMatches+1)
+
+	truncated := len(found) > MaxUserMatches
+	if truncated {
+		found = found[:MaxUserMatches]
+	}
+
+	matches := make([]TestMatch, 0, len(found))
+	for _, loc := range found {
+		m := TestMatch{
+			Value: text[loc[0]:loc[1]],
+			Start: loc[0],
+			End:   loc[1],
+		}
+		for g := 2; g < len(loc); g += 2 {
+			if loc[g] < 0 {
+				m.Groups = append(m.Groups, "")
+				continue
+			}
+			m.Groups = append(m.Groups, text[loc[g]:loc[g+1]])
+		}
+		matches = append(matches, m)
+	}
+	return matches, truncated
+}
+
+// TestPatternContext runs TestPattern but gives up when ctx expires. The
+// matching goroutine can't be interrupted, so it is abandoned and its
+// result discarded; the caller is freed immediately.
+func TestPatternContext(ctx context.Context, re *regexp.Regexp, text string) ([]TestMatch, bool, error) {
+	type result struct {
+		matches   []TestMatch
+		truncated bool
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		matches, truncated := TestPattern(re, text)
+		done <- result{matches, truncated}
+	}()
+
+	select {
+	case r := <-done:
+		return r.matches, r.truncated, nil
+	case <-ctx.Done():
+		return nil, false, ErrPatternTimeout
+	}
+}
@@ -0,0 +1,60 @@
+package regex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileUserPatternRejectsNestedQuantifiers(t *testing.T) {
+	for _, pattern := range []string{`(a+)+`, `(\w*x)*`, `(a|b+){2,}`, `^(\d+)*$`} {
+		if _, err := CompileUserPattern(pattern, ""); err == nil || !strings.Contains(err.Error(), "nested quantifiers") {
+			t.Errorf("%s: err = %v, want a nested quantifier rejection", pattern, err)
+		}
+	}
+	for _, pattern := range []string{`(ab)+`, `a+b*`, `(?:foo|bar)\d+`} {
+		if _, err := CompileUserPattern(pattern, ""); err != nil {
+			t.Errorf("%s: %v", pattern, err)
+		}
+	}
+}
+
+// A pattern that is slow over a large input is abandoned at the deadline
+// instead of holding the caller until it finishes
+func TestPatternContextTimesOut(t *testing.T) {
+	re, err := CompileUserPattern(`[a-z]{1,60}[0-9]{1,60}[A-Z]{1,60}!`, "i")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("abcdefghij0123456789", 1<<18) // 5 MiB, no match
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	matches, _, err := TestPatternContext(ctx, re, text)
+	if !errors.Is(err, ErrPatternTimeout) {
+		t.Fatalf("err = %v, want ErrPatternTimeout", err)
+	}
+	if matches != nil {
+		t.Errorf("timed out run returned %d matches", len(matches))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v, want soon after the 5ms deadline", elapsed)
+	}
+}
+
+func TestPatternContextWithinDeadline(t *testing.T) {
+	re, err := CompileUserPattern(`(\w+)@example\.com`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, truncated, err := TestPatternContext(context.Background(), re, "mail bob@example.com")
+	if err != nil || truncated {
+		t.Fatalf("err, truncated = %v, %v", err, truncated)
+	}
+	if len(matches) != 1 || matches[0].Start != 5 || len(matches[0].Groups) != 1 || matches[0].Groups[0] != "bob" {
+		t.Errorf("matches = %+v", matches)
+	}
+}
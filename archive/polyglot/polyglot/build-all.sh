@@ -71,7 +71,11 @@ build_brain() {
 
     if command -v go &> /dev/null; then
         go mod tidy
-        go build -o brain -ldflags="-s -w" .
+        local version commit build_time
+        version=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+        commit=$(git rev-parse --short HEAD 2>/dev/null || echo dev)
+        build_time=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+        go build -o brain -ldflags="-s -w -X main.version=$version -X main.commit=$commit -X main.buildTime=$build_time" .
         impulse "Brain formed: go-brain built"
     else
         pain "go not found - brain cannot form"
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	b := NewCircuitBreaker(2, 20*time.Millisecond)
+
+	b.Failure()
+	if !b.Allow() || b.State() != "closed" {
+		t.Fatalf("one failure below threshold: state %s", b.State())
+	}
+	b.Failure()
+	if b.Allow() || b.State() != "open" {
+		t.Fatalf("at threshold: allowed while %s", b.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !b.Allow() || b.State() != "half_open" {
+		t.Fatalf("after cooldown: state %s, want one probe half-open", b.State())
+	}
+	if b.Allow() {
+		t.Error("second call allowed while the probe is in flight")
+	}
+	b.Failure()
+	if b.Allow() || b.State() != "open" {
+		t.Fatalf("failed probe: state %s, want open again", b.State())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	b.Allow()
+	b.Success()
+	if !b.Allow() || b.State() != "closed" {
+		t.Errorf("successful probe: state %s, want closed", b.State())
+	}
+}
+
+func TestCallOrganFailsFastWhenOpen(t *testing.T) {
+	const slow = 50 * time.Millisecond
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	stubOrgans(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			time.Sleep(slow)
+			http.Error(w, "overloaded", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"results":[]}`)
+	}), "blood")
+	breakers["blood"] = NewCircuitBreaker(3, 100*time.Millisecond)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		callOrgan(ctx, "blood", "/search", nil)
+	}
+	if state := breakers["blood"].State(); state != "open" {
+		t.Fatalf("after 3 failures the breaker is %s, want open", state)
+	}
+
+	began := time.Now()
+	_, err := callOrgan(ctx, "blood", "/search", nil)
+	if !errors.Is(err, errCircuitOpen) {
+		t.Errorf("open breaker: err = %v, want %v", err, errCircuitOpen)
+	}
+	if elapsed := time.Since(began); elapsed >= slow {
+		t.Errorf("open breaker took %v, want a fast failure", elapsed)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("organ called %d times, want 3: the open breaker must not reach it", n)
+	}
+
+	// After the cooldown one probe goes through and closes the breaker
+	healthy.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	if _, err := callOrgan(ctx, "blood", "/search", nil); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := breakers["blood"].State(); state != "closed" {
+		t.Errorf("after a good probe the breaker is %s, want closed", state)
+	}
+}
@@ -6,3 +6,5 @@ require (
 	github.com/gorilla/mux v1.8.1
 	golang.org/x/time v0.5.0
 )
+
+require golang.org/x/sync v0.5.0
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Build info sits at the top level, like the gateway's and go-search's
+func TestHealthReportsBuildInfoFlat(t *testing.T) {
+	rec := httptest.NewRecorder()
+	healthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	for _, field := range []string{"version", "commit", "build_time", "go_version"} {
+		if _, ok := body[field].(string); !ok {
+			t.Errorf("%s missing from the top level: %s", field, rec.Body.String())
+		}
+	}
+	if _, nested := body["build"]; nested {
+		t.Error("build info still nested under \"build\"")
+	}
+}
+
+// healthOn answers 200 on path only, 404 elsewhere
+func healthOn(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"status":"ok"}`)
+	}
+}
+
+func TestWithHealthPaths(t *testing.T) {
+	t.Setenv("VEINS_HEALTH_PATH", "/api/health")
+	m := withHealthPaths(map[string]*Organ{"veins": {Name: "veins"}, "cells": {Name: "cells"}})
+	if m["veins"].HealthPath != "/api/health" || m["cells"].HealthPath != "/health" {
+		t.Errorf("health paths: veins %q, cells %q", m["veins"].HealthPath, m["cells"].HealthPath)
+	}
+}
+
+func TestProbeOrgansUseHealthPaths(t *testing.T) {
+	stubOrgans(t, healthOn("/health"), "cells")
+	stubOrgans(t, healthOn("/api/health"), "veins")
+
+	organMu.Lock()
+	oldPath := organs["veins"].HealthPath
+	organs["veins"].HealthPath = "/api/health"
+	organMu.Unlock()
+	t.Cleanup(func() {
+		organMu.Lock()
+		organs["veins"].HealthPath = oldPath
+		organMu.Unlock()
+	})
+
+	details, up := probeOrgans(context.Background())
+	for _, name := range []string{"cells", "veins"} {
+		if !up[name] {
+			t.Errorf("%s probed as %v, want healthy on its own path", name, details[name]["status"])
+		}
+	}
+}
+
+func TestHealthRollup(t *testing.T) {
+	tests := []struct {
+		name      string
+		down      []string
+		status    string
+		code      int
+		readyCode int
+	}{
+		{"all up", nil, healthHealthy, 200, 200},
+		{"optional down", []string{"lungs", "blood"}, healthDegraded, 200, 200},
+		{"critical down", []string{"veins"}, healthCritical, 503, 503},
+		{"critical and optional down", []string{"cells", "lungs"}, healthCritical, 503, 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubOrgans(t, healthOn("/health"), "lungs", "cells", "veins", "blood")
+			if len(tt.down) > 0 {
+				organsDown(t, tt.down...)
+			}
+
+			rec := httptest.NewRecorder()
+			healthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+			var body struct {
+				Status string                            `json:"status"`
+				Organs map[string]map[string]interface{} `json:"organs"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+			}
+			if rec.Code != tt.code || body.Status != tt.status {
+				t.Errorf("/health = %d %q, want %d %q", rec.Code, body.Status, tt.code, tt.status)
+			}
+			for _, name := range tt.down {
+				if body.Organs[name]["status"] != "offline" {
+					t.Errorf("%s reported %v, want offline", name, body.Organs[name]["status"])
+				}
+			}
+
+			rec = httptest.NewRecorder()
+			readyzHandler(rec, httptest.NewRequest("GET", "/readyz", nil))
+			if rec.Code != tt.readyCode {
+				t.Errorf("/readyz = %d, want %d", rec.Code, tt.readyCode)
+			}
+		})
+	}
+}
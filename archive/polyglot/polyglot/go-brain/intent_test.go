@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestClassifyIntentMatchesWholeWords(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"email of John Doe", routeLookup},
+		{"what is the phone number listed for the Geneva office", routeLookup},
+		{"summarize the fraud case", routeAnalysis},
+		{"show every transfer made by the holding in March", routeAnalysis},
+		{"accountability failures across the three subsidiaries last year", routeAnalysis},
+		{"who is behind the shell company registered in Panama", routeLookup},
+	}
+	for _, tt := range tests {
+		if got := classifyIntent(tt.query); got != tt.want {
+			t.Errorf("classifyIntent(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// investigation is the decoded body of an investigateHandler response
+type investigation struct {
+	Route     string                 `json:"route"`
+	Synthesis map[string]interface{} `json:"synthesis"`
+	Complete  bool                   `json:"complete"`
+	Degraded  bool                   `json:"degraded"`
+	Missing   []string               `json:"missing"`
+	Errors    map[string]string      `json:"errors"`
+	Timeline  []PhaseTiming          `json:"timeline"`
+}
+
+func investigate(t *testing.T, query string) investigation {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	rec := httptest.NewRecorder()
+	investigateHandler(rec, httptest.NewRequest("POST", "/investigate", strings.NewReader(string(body))))
+	if rec.Code != 200 {
+		t.Fatalf("investigate %q: %d %s", query, rec.Code, rec.Body)
+	}
+	var out investigation
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return out
+}
+
+// organsUp answers every organ call successfully
+var organsUp = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/synthesize" {
+		io.WriteString(w, `{"synthesis":"Alice moved the funds."}`)
+		return
+	}
+	io.WriteString(w, `{"results":[]}`)
+})
+
+// organsDown points the named organs at a server that is already gone
+func organsDown(t *testing.T, names ...string) {
+	t.Helper()
+	stubOrgans(t, organsUp, names...).Close()
+}
+
+func TestInvestigateDegradation(t *testing.T) {
+	const analysis = "explain how alice moved the funds"
+	const lookup = "who is alice"
+
+	tests := []struct {
+		name    string
+		query   string
+		down    []string
+		missing string
+	}{
+		{"all succeed", analysis, nil, ""},
+		{"search down", lookup, []string{"blood"}, "search"},
+		{"synthesis down", analysis, []string{"veins"}, "synthesize"},
+		{"all down", analysis, []string{"cells", "blood", "veins"}, "extract,search,synthesize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubOrgans(t, organsUp, "cells", "blood", "veins")
+			if len(tt.down) > 0 {
+				organsDown(t, tt.down...)
+			}
+
+			out := investigate(t, tt.query)
+			if got := strings.Join(out.Missing, ","); got != tt.missing {
+				t.Errorf("missing = %q, want %q", got, tt.missing)
+			}
+			if out.Complete != (tt.missing == "") || out.Degraded != (tt.missing != "") {
+				t.Errorf("complete %v, degraded %v with missing %q", out.Complete, out.Degraded, tt.missing)
+			}
+			if out.Missing == nil {
+				t.Error("missing encoded as null, want []")
+			}
+		})
+	}
+}
+
+func TestInvestigateTimeline(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	stubOrgans(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		organsUp(w, r)
+	}), "cells", "blood", "veins")
+
+	tests := []struct {
+		query   string
+		skipped string
+	}{
+		{"explain how alice moved the funds", ""},
+		{"who is alice", "synthesize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			out := investigate(t, tt.query)
+
+			seen := map[string]int{}
+			var last float64
+			for _, p := range out.Timeline {
+				seen[p.Phase]++
+				if p.StartMs < last {
+					t.Errorf("%s starts at %vms, before the previous phase at %vms", p.Phase, p.StartMs, last)
+				}
+				last = p.StartMs
+
+				switch {
+				case p.Phase == tt.skipped:
+					if p.Status != "skipped" || p.DurationMs != 0 {
+						t.Errorf("%s = %+v, want skipped with no duration", p.Phase, p)
+					}
+				case p.Phase == "analyze":
+					if p.Status != "ok" || p.DurationMs < 0 || p.DurationMs >= float64(delay.Milliseconds()) {
+						t.Errorf("analyze = %+v, want ok and well under %v", p, delay)
+					}
+				default:
+					if p.Status != "ok" || p.DurationMs < float64(delay.Milliseconds()) || p.DurationMs > 2000 {
+						t.Errorf("%s = %+v, want ok and at least the organ's %v", p.Phase, p, delay)
+					}
+				}
+			}
+			for _, phase := range []string{"analyze", "extract", "search", "synthesize"} {
+				if seen[phase] != 1 {
+					t.Errorf("%d %q entries in %+v, want 1", seen[phase], phase, out.Timeline)
+				}
+			}
+			if len(out.Timeline) != 4 {
+				t.Errorf("timeline has %d entries, want 4", len(out.Timeline))
+			}
+		})
+	}
+}
+
+func TestSynthesisRequestCarriesPrompt(t *testing.T) {
+	defer func(instructions, language string) {
+		synthesisInstructions, synthesisLanguage = instructions, language
+	}(synthesisInstructions, synthesisLanguage)
+	synthesisInstructions, synthesisLanguage = "Cite every document.", "en"
+
+	var sent map[string]interface{}
+	stubOrgans(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/synthesize" {
+			json.NewDecoder(r.Body).Decode(&sent)
+		}
+		organsUp(w, r)
+	}), "cells", "blood", "veins")
+
+	for _, tt := range []struct{ language, want string }{{"", "en"}, {"fr", "fr"}} {
+		sent = nil
+		body, _ := json.Marshal(map[string]string{"query": "explain how alice moved the funds", "language": tt.language})
+		rec := httptest.NewRecorder()
+		investigateHandler(rec, httptest.NewRequest("POST", "/investigate", strings.NewReader(string(body))))
+		if rec.Code != 200 {
+			t.Fatalf("investigate: %d %s", rec.Code, rec.Body)
+		}
+
+		if sent == nil {
+			t.Fatal("no /synthesize request sent")
+		}
+		if sent["instructions"] != "Cite every document." {
+			t.Errorf("instructions = %v, want the configured ones", sent["instructions"])
+		}
+		if sent["language"] != tt.want {
+			t.Errorf("language %q: sent %v, want %s", tt.language, sent["language"], tt.want)
+		}
+		if sent["query"] != "explain how alice moved the funds" {
+			t.Errorf("query = %v", sent["query"])
+		}
+	}
+}
+
+func TestValidateSynthesis(t *testing.T) {
+	tests := []struct {
+		result map[string]interface{}
+		ok     bool
+	}{
+		{nil, false},
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{"error": "model overloaded"}, false},
+		{map[string]interface{}{"synthesis": "  "}, false},
+		{map[string]interface{}{"synthesis": "Alice moved the funds."}, true},
+		{map[string]interface{}{"answer": "Alice."}, true},
+		{map[string]interface{}{"summary": "Alice."}, true},
+	}
+	for _, tt := range tests {
+		if err := validateSynthesis(tt.result); (err == nil) != tt.ok {
+			t.Errorf("%v: err = %v, want ok %v", tt.result, err, tt.ok)
+		}
+	}
+}
@@ -12,39 +12,209 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"golang.org/x/time/rate"
 )
 
+// =============================================================================
+// BUILD INFO
+// =============================================================================
+
+// Injected at build time:
+//
+//	go build -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=..."
+var (
+	version   = "dev"
+	commit    = "dev"
+	buildTime = "dev"
+)
+
 // =============================================================================
 // ORGAN REGISTRY
 // =============================================================================
 
 type Organ struct {
-	Name    string
-	URL     string
-	Healthy bool
-	Latency time.Duration
+	Name       string
+	URL        string
+	HealthPath string // overridable with <NAME>_HEALTH_PATH, e.g. VEINS_HEALTH_PATH=/api/health
+	Critical   bool   // listed in BRAIN_CRITICAL_ORGANS; down means the brain is down
+	Healthy    bool
+	Latency    time.Duration
 }
 
-var organs = map[string]*Organ{
+var organs = withHealthPaths(map[string]*Organ{
 	"lungs": {Name: "lungs", URL: "http://127.0.0.1:3000"},  // Node.js
 	"cells": {Name: "cells", URL: "http://127.0.0.1:9001"},  // Rust
 	"veins": {Name: "veins", URL: "http://127.0.0.1:8000"},  // Python
 	"blood": {Name: "blood", URL: "http://127.0.0.1:9003"},  // C++
+})
+
+func withHealthPaths(m map[string]*Organ) map[string]*Organ {
+	critical := make(map[string]bool)
+	for _, name := range strings.Split(getEnv("BRAIN_CRITICAL_ORGANS", "cells,veins"), ",") {
+		critical[strings.TrimSpace(name)] = true
+	}
+
+	for name, o := range m {
+		o.HealthPath = os.Getenv(strings.ToUpper(name) + "_HEALTH_PATH")
+		if o.HealthPath == "" {
+			o.HealthPath = "/health"
+		}
+		o.Critical = critical[name]
+	}
+	return m
+}
+
+// Overall health verdicts
+const (
+	healthHealthy  = "healthy"
+	healthDegraded = "degraded"
+	healthCritical = "critical"
+)
+
+// rollupHealth is critical if any critical organ is down, degraded if an
+// optional one is, healthy otherwise
+func rollupHealth(up map[string]bool) string {
+	verdict := healthHealthy
+	for name, healthy := range up {
+		if healthy {
+			continue
+		}
+		if o := organs[name]; o != nil && o.Critical {
+			return healthCritical
+		}
+		verdict = healthDegraded
+	}
+	return verdict
 }
 
 var organMu sync.RWMutex
 
+// =============================================================================
+// CIRCUIT BREAKERS (reflexes)
+// =============================================================================
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+var errCircuitOpen = errors.New("circuit_open")
+
+// errThrottled is an organ still answering 429 after its retries: alive,
+// so neither unhealthy nor a breaker failure, but without a result
+var errThrottled = errors.New("throttled")
+
+// CircuitBreaker trips after `threshold` consecutive failures and rejects
+// calls until `cooldown` has passed, then lets a single probe through.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may proceed
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
+
+var breakers = newBreakers()
+
+func newBreakers() map[string]*CircuitBreaker {
+	threshold := getEnvInt("BRAIN_BREAKER_THRESHOLD", 5)
+	cooldown := getEnvDuration("BRAIN_BREAKER_COOLDOWN", 30*time.Second)
+
+	m := make(map[string]*CircuitBreaker, len(organs))
+	for name := range organs {
+		m[name] = NewCircuitBreaker(threshold, cooldown)
+	}
+	return m
+}
+
 // =============================================================================
 // BRAIN METRICS (neural activity)
 // =============================================================================
@@ -53,7 +223,9 @@ type BrainMetrics struct {
 	Thoughts    atomic.Int64 // requests processed
 	Decisions   atomic.Int64 // successful decisions
 	Errors      atomic.Int64 // errors
-	NeuralPaths atomic.Int64 // concurrent goroutines
+	NeuralPaths atomic.Int64 // concurrent goroutines, at most maxNeuralPaths
+	Rejected    atomic.Int64 // investigations turned away for lack of paths
+	Throttled   atomic.Int64 // organ calls answered 429
 	StartTime   time.Time
 }
 
@@ -76,11 +248,109 @@ type AnalysisRequest struct {
 
 type Strategy struct {
 	Priority    string   `json:"priority"`
+	Route       string   `json:"route"`
 	SearchTerms []string `json:"search_terms"`
 	EntityTypes []string `json:"entity_types"`
 	Confidence  float64  `json:"confidence"`
 }
 
+// Query routes: lookups go straight to search, analysis gets full synthesis
+const (
+	routeLookup   = "lookup"
+	routeAnalysis = "analysis"
+)
+
+var analysisKeywords = []string{
+	"summarize", "summary", "explain", "analyze", "analyse", "why", "how",
+	"compare", "relationship", "connection", "timeline", "overview", "pattern",
+	"résumer", "résumé", "expliquer", "analyser", "pourquoi", "comment", "lien",
+}
+
+var lookupKeywords = []string{
+	"email", "e-mail", "phone", "address", "number", "account", "iban", "wallet",
+	"who is", "where is", "when did", "date of", "adresse", "téléphone", "qui est",
+}
+
+// classifyIntent labels a query as a lookup (a specific fact or identifier)
+// or an analysis (synthesis across documents). Keywords match whole words,
+// so "how" doesn't fire on "show" nor "account" on "accountability".
+func classifyIntent(query string) string {
+	words := queryWords(query)
+	for _, kw := range analysisKeywords {
+		if hasPhrase(words, kw) {
+			return routeAnalysis
+		}
+	}
+	for _, kw := range lookupKeywords {
+		if hasPhrase(words, kw) {
+			return routeLookup
+		}
+	}
+	// Short queries are almost always name/term lookups
+	if len(tokenize(query)) <= 4 {
+		return routeLookup
+	}
+	return routeAnalysis
+}
+
+// queryWords splits s into lowercase words of letters and digits
+func queryWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// hasPhrase reports whether the words of phrase occur in words, in a row
+func hasPhrase(words []string, phrase string) bool {
+	want := queryWords(phrase)
+	if len(want) == 0 {
+		return false
+	}
+	for i := 0; i+len(want) <= len(words); i++ {
+		match := true
+		for j, w := range want {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Strategies are pure functions of the query, so memoize them
+const maxCachedStrategies = 1024
+
+var strategyCache = struct {
+	sync.RWMutex
+	m map[string]Strategy
+}{m: make(map[string]Strategy)}
+
+func cachedStrategy(query string) Strategy {
+	key := strings.ToLower(strings.TrimSpace(query))
+
+	strategyCache.RLock()
+	strategy, ok := strategyCache.m[key]
+	strategyCache.RUnlock()
+	if ok {
+		return strategy
+	}
+
+	strategy = analyzeQuery(query)
+
+	strategyCache.Lock()
+	if len(strategyCache.m) >= maxCachedStrategies {
+		strategyCache.m = make(map[string]Strategy)
+	}
+	strategyCache.m[key] = strategy
+	strategyCache.Unlock()
+
+	return strategy
+}
+
 func analyzeQuery(query string) Strategy {
 	// Brain decides strategy based on query content
 	strategy := Strategy{
@@ -102,6 +372,7 @@ func analyzeQuery(query string) Strategy {
 
 	// Extract search terms (simple tokenization)
 	strategy.SearchTerms = tokenize(query)
+	strategy.Route = classifyIntent(query)
 
 	return strategy
 }
@@ -142,38 +413,218 @@ func callOrgan(ctx context.Context, organName, path string, data interface{}) (m
 		return nil, fmt.Errorf("unknown organ: %s", organName)
 	}
 
-	body, _ := json.Marshal(data)
-	req, err := http.NewRequestWithContext(ctx, "POST", organ.URL+path, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
+	breaker := breakers[organName]
+	if !breaker.Allow() {
+		return nil, errCircuitOpen
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	start := time.Now()
-	resp, err := http.DefaultClient.Do(req)
-	latency := time.Since(start)
+	body, _ := json.Marshal(data)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", organ.URL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
 
-	organMu.Lock()
-	organ.Latency = latency
-	organMu.Unlock()
+		start := time.Now()
+		resp, err := organClient.Do(req)
+		latency := time.Since(start)
+		if organLog.Sample() {
+			logOrganCall(req, resp, err, latency)
+		}
 
-	if err != nil {
 		organMu.Lock()
-		organ.Healthy = false
+		organ.Latency = latency
 		organMu.Unlock()
-		return nil, err
+
+		if err != nil {
+			organMu.Lock()
+			organ.Healthy = false
+			organMu.Unlock()
+			breaker.Failure()
+			return nil, err
+		}
+
+		// Throttling means the organ is up: wait as told and try again
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			organMu.Lock()
+			organ.Healthy = true
+			organMu.Unlock()
+			breaker.Success()
+			metrics.Throttled.Add(1)
+
+			wait := throttleWait(resp.Header.Get("Retry-After"), attempt)
+			if attempt >= throttleRetries || wait > throttleMaxWait {
+				return nil, errThrottled
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		defer resp.Body.Close()
+
+		organMu.Lock()
+		organ.Healthy = resp.StatusCode == 200
+		organMu.Unlock()
+
+		if resp.StatusCode >= 500 {
+			breaker.Failure()
+		} else {
+			breaker.Success()
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		var result map[string]interface{}
+		json.Unmarshal(respBody, &result)
+
+		return result, nil
+	}
+}
+
+// How callOrgan treats an organ answering 429: up to throttleRetries more
+// attempts, each after its Retry-After (or throttleBackoff doubling per
+// attempt when absent). A wait longer than throttleMaxWait gives up
+// straight away with errThrottled.
+var (
+	throttleRetries = getEnvInt("BRAIN_THROTTLE_RETRIES", 2)
+	throttleBackoff = getEnvDuration("BRAIN_THROTTLE_BACKOFF", 250*time.Millisecond)
+	throttleMaxWait = getEnvDuration("BRAIN_THROTTLE_MAX_WAIT", 2*time.Second)
+)
+
+// throttleWait reads a Retry-After header, in seconds or as an HTTP date
+func throttleWait(retryAfter string, attempt int) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return throttleBackoff << attempt
+}
+
+// =============================================================================
+// ORGAN CLIENT
+// =============================================================================
+
+// organClient is shared by every organ call so connections to each organ
+// are pooled and reused. HTTP/2 is negotiated with TLS organs.
+var organClient = &http.Client{Transport: newOrganTransport()}
+
+func newOrganTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   getEnvDuration("BRAIN_DIAL_TIMEOUT", 5*time.Second),
+		KeepAlive: getEnvDuration("BRAIN_KEEPALIVE", 30*time.Second),
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          getEnvInt("BRAIN_MAX_IDLE_CONNS", 100),
+		MaxIdleConnsPerHost:   getEnvInt("BRAIN_MAX_IDLE_PER_HOST", 32),
+		MaxConnsPerHost:       getEnvInt("BRAIN_MAX_CONNS_PER_HOST", 64),
+		IdleConnTimeout:       getEnvDuration("BRAIN_IDLE_CONN_TIMEOUT", 90*time.Second),
+		TLSHandshakeTimeout:   getEnvDuration("BRAIN_TLS_TIMEOUT", 10*time.Second),
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// =============================================================================
+// ORGAN CALL LOGGING
+// =============================================================================
+
+// Headers whose values never reach the log
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// logSampler picks an evenly spread share of calls: with rate r, exactly
+// floor(n*r) of the first n calls are sampled
+type logSampler struct {
+	rate float64
+	n    atomic.Uint64
+}
+
+func (s *logSampler) Sample() bool {
+	if s.rate <= 0 {
+		return false
 	}
-	defer resp.Body.Close()
+	n := s.n.Add(1)
+	return uint64(float64(n)*s.rate) != uint64(float64(n-1)*s.rate)
+}
+
+// BRAIN_UPSTREAM_LOG_SAMPLE is the share of organ calls logged, 0 to 1
+var organLog = &logSampler{rate: getEnvFloat("BRAIN_UPSTREAM_LOG_SAMPLE", 0)}
 
-	organMu.Lock()
-	organ.Healthy = resp.StatusCode == 200
-	organMu.Unlock()
+func logOrganCall(req *http.Request, resp *http.Response, err error, latency time.Duration) {
+	status := "error: "
+	if err != nil {
+		status += err.Error()
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	log.Printf("[organ] %s %s -> %s (%v) headers=%s",
+		req.Method, req.URL, status, latency.Round(time.Millisecond), redactHeaders(req.Header))
+}
 
-	respBody, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	json.Unmarshal(respBody, &result)
+// redactHeaders renders h for logging, sorted, with sensitive values masked
+func redactHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	return result, nil
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := strings.Join(h[k], ",")
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, k+"="+value)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// =============================================================================
+// SYNTHESIS (veins)
+// =============================================================================
+
+const defaultSynthesisInstructions = "Answer the query using only the supplied entities and search results. " +
+	"Be concise and factual, cite the documents you rely on, and say so when the evidence is insufficient."
+
+// Steering for the veins /synthesize call. The language can be overridden
+// per request.
+var (
+	synthesisInstructions = getEnv("BRAIN_SYNTHESIS_INSTRUCTIONS", defaultSynthesisInstructions)
+	synthesisLanguage     = getEnv("BRAIN_SYNTHESIS_LANGUAGE", "en")
+)
+
+// Fields veins may put its answer in
+var synthesisAnswerKeys = []string{"synthesis", "answer", "summary"}
+
+// validateSynthesis rejects veins responses that carry an error or no
+// answer text, so they are reported as a failed phase rather than passed
+// through as a result
+func validateSynthesis(result map[string]interface{}) error {
+	if result == nil {
+		return errors.New("empty synthesis response")
+	}
+	if msg, _ := result["error"].(string); msg != "" {
+		return fmt.Errorf("synthesis failed: %s", msg)
+	}
+	for _, key := range synthesisAnswerKeys {
+		if text, _ := result[key].(string); strings.TrimSpace(text) != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("synthesis response has none of %s", strings.Join(synthesisAnswerKeys, ", "))
 }
 
 // =============================================================================
@@ -195,7 +646,7 @@ func analyzeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	strategy := analyzeQuery(req.Query)
+	strategy := cachedStrategy(req.Query)
 	metrics.Decisions.Add(1)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -211,10 +662,20 @@ func investigateHandler(w http.ResponseWriter, r *http.Request) {
 	metrics.Thoughts.Add(1)
 	ctx := r.Context()
 
+	release, ok := acquireNeuralPaths(ctx, investigationPaths)
+	if !ok {
+		metrics.Rejected.Add(1)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many investigations in flight", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
 	var req struct {
 		Query     string `json:"query"`
 		Domain    string `json:"domain,omitempty"`
 		SessionID string `json:"sessionId,omitempty"`
+		Language  string `json:"language,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		metrics.Errors.Add(1)
@@ -222,63 +683,109 @@ func investigateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Phase 1: Analyze and create strategy
-	strategy := analyzeQuery(req.Query)
+	tl := newTimeline()
 
-	// Phase 2: Parallel organ calls with goroutines (neural pathways)
-	var wg sync.WaitGroup
+	// Phase 1: Analyze and create strategy
+	began := time.Now()
+	strategy := cachedStrategy(req.Query)
+	tl.record("analyze", began, nil)
+
+	// Phase 2: Parallel organ calls with goroutines (neural pathways),
+	// at most fanoutLimit at once
+	group, groupCtx := errgroup.WithContext(ctx)
+	if fanoutLimit > 0 {
+		group.SetLimit(fanoutLimit)
+	}
 	var extractResult, searchResult map[string]interface{}
 	var extractErr, searchErr error
 
 	// Cells (Rust) - entity extraction
-	wg.Add(1)
-	metrics.NeuralPaths.Add(1)
-	go func() {
-		defer wg.Done()
+	group.Go(func() error {
+		metrics.NeuralPaths.Add(1)
 		defer metrics.NeuralPaths.Add(-1)
-		extractResult, extractErr = callOrgan(ctx, "cells", "/extract", map[string]string{"text": req.Query})
-	}()
+		began := time.Now()
+		extractResult, extractErr = callOrgan(groupCtx, "cells", "/extract", map[string]string{"text": req.Query})
+		tl.record("extract", began, extractErr)
+		return fanoutErr(extractErr)
+	})
 
 	// Blood (C++) - search
-	wg.Add(1)
-	metrics.NeuralPaths.Add(1)
-	go func() {
-		defer wg.Done()
+	group.Go(func() error {
+		metrics.NeuralPaths.Add(1)
 		defer metrics.NeuralPaths.Add(-1)
-		searchResult, searchErr = callOrgan(ctx, "blood", "/search", map[string]interface{}{
+		began := time.Now()
+		searchResult, searchErr = callOrgan(groupCtx, "blood", "/search", map[string]interface{}{
 			"query": req.Query,
 			"limit": 20,
 		})
-	}()
+		tl.record("search", began, searchErr)
+		return fanoutErr(searchErr)
+	})
 
-	wg.Wait()
+	group.Wait()
 
 	// Phase 3: Synthesize with veins (Python/LLM)
+	language := req.Language
+	if language == "" {
+		language = synthesisLanguage
+	}
 	synthesisInput := map[string]interface{}{
-		"query":    req.Query,
-		"strategy": strategy,
-		"entities": extractResult,
-		"search":   searchResult,
+		"query":        req.Query,
+		"strategy":     strategy,
+		"entities":     extractResult,
+		"search":       searchResult,
+		"instructions": synthesisInstructions,
+		"language":     language,
 	}
 
+	// Lookups are answered by search alone; only analysis pays for synthesis
 	var synthesisResult map[string]interface{}
-	if extractErr == nil && searchErr == nil {
-		synthesisResult, _ = callOrgan(ctx, "veins", "/synthesize", synthesisInput)
+	var synthesisErr error
+	if strategy.Route == routeAnalysis && extractErr == nil && searchErr == nil {
+		began := time.Now()
+		synthesisResult, synthesisErr = callOrgan(ctx, "veins", "/synthesize", synthesisInput)
+		if synthesisErr == nil {
+			if synthesisErr = validateSynthesis(synthesisResult); synthesisErr != nil {
+				synthesisResult = nil
+			}
+		}
+		tl.record("synthesize", began, synthesisErr)
+	} else {
+		tl.skip("synthesize")
 	}
 
 	metrics.Decisions.Add(1)
 
+	// Partial results still return 200; complete/degraded/missing tell the
+	// client which parts are absent
+	missing := []string{}
+	if extractErr != nil {
+		missing = append(missing, "extract")
+	}
+	if searchErr != nil {
+		missing = append(missing, "search")
+	}
+	if strategy.Route == routeAnalysis && synthesisResult == nil {
+		missing = append(missing, "synthesize")
+	}
+
 	// Return combined result
 	response := map[string]interface{}{
 		"success":   true,
 		"sessionId": req.SessionID,
+		"route":     strategy.Route,
 		"strategy":  strategy,
 		"entities":  extractResult,
 		"search":    searchResult,
 		"synthesis": synthesisResult,
+		"timeline":  tl.entries(),
+		"complete":  len(missing) == 0,
+		"degraded":  len(missing) > 0,
+		"missing":   missing,
 		"errors": map[string]string{
-			"extract": errStr(extractErr),
-			"search":  errStr(searchErr),
+			"extract":    errStr(extractErr),
+			"search":     errStr(searchErr),
+			"synthesize": errStr(synthesisErr),
 		},
 	}
 
@@ -286,6 +793,114 @@ func investigateHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// Per-investigation fan-out: how many organ calls run at once, and whether
+// the first failure cancels the others
+var (
+	fanoutLimit    = getEnvInt("BRAIN_FANOUT_LIMIT", 4)
+	fanoutFailFast = getEnvBool("BRAIN_FANOUT_FAIL_FAST", false)
+)
+
+// Neural path budget across all investigations: each reserves a path per
+// organ goroutine up front, so NeuralPaths never exceeds maxNeuralPaths.
+// A request that can't get its paths within neuralPathWait is answered
+// 503. Zero or less lifts the bound.
+var (
+	maxNeuralPaths = getEnvInt("BRAIN_MAX_NEURAL_PATHS", 64)
+	neuralPathWait = getEnvDuration("BRAIN_NEURAL_PATH_WAIT", time.Second)
+	neuralPathSem  = semaphore.NewWeighted(int64(max(maxNeuralPaths, 1)))
+)
+
+// investigationPaths is the goroutines one investigation fans out
+const investigationPaths = 2
+
+// checkNeuralPaths refuses a budget too small for even one investigation,
+// which would answer every request 503
+func checkNeuralPaths(max int) error {
+	if max > 0 && max < investigationPaths {
+		return fmt.Errorf("BRAIN_MAX_NEURAL_PATHS=%d is below the %d paths one investigation takes", max, investigationPaths)
+	}
+	return nil
+}
+
+// acquireNeuralPaths reserves n paths, waiting up to neuralPathWait. The
+// release func must be called once the goroutines are done.
+func acquireNeuralPaths(ctx context.Context, n int64) (release func(), ok bool) {
+	if maxNeuralPaths <= 0 {
+		return func() {}, true
+	}
+	ctx, cancel := context.WithTimeout(ctx, neuralPathWait)
+	defer cancel()
+	if err := neuralPathSem.Acquire(ctx, n); err != nil {
+		return nil, false
+	}
+	return func() { neuralPathSem.Release(n) }, true
+}
+
+// fanoutErr is what a failed organ call returns to its group: the error
+// when failing fast (cancelling the siblings), nil to let them finish
+func fanoutErr(err error) error {
+	if fanoutFailFast {
+		return err
+	}
+	return nil
+}
+
+// PhaseTiming is one step of an investigation's timeline. StartMs is the
+// offset from the start of the request.
+type PhaseTiming struct {
+	Phase      string  `json:"phase"`
+	StartMs    float64 `json:"start_ms"`
+	DurationMs float64 `json:"duration_ms"`
+	Status     string  `json:"status"` // ok, error or skipped
+}
+
+// timeline collects phase timings from concurrent goroutines
+type timeline struct {
+	start  time.Time
+	mu     sync.Mutex
+	phases []PhaseTiming
+}
+
+func newTimeline() *timeline {
+	return &timeline{start: time.Now()}
+}
+
+func (t *timeline) record(phase string, began time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	t.add(PhaseTiming{
+		Phase:      phase,
+		StartMs:    msSince(t.start, began),
+		DurationMs: msSince(began, time.Now()),
+		Status:     status,
+	})
+}
+
+func (t *timeline) skip(phase string) {
+	t.add(PhaseTiming{Phase: phase, StartMs: msSince(t.start, time.Now()), Status: "skipped"})
+}
+
+func (t *timeline) add(p PhaseTiming) {
+	t.mu.Lock()
+	t.phases = append(t.phases, p)
+	t.mu.Unlock()
+}
+
+// entries returns the phases ordered by start
+func (t *timeline) entries() []PhaseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := append([]PhaseTiming(nil), t.phases...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartMs < out[j].StartMs })
+	return out
+}
+
+func msSince(from, to time.Time) float64 {
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
 func errStr(err error) string {
 	if err != nil {
 		return err.Error()
@@ -293,11 +908,11 @@ func errStr(err error) string {
 	return ""
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	metrics.Thoughts.Add(1)
-
-	// Check all organs in parallel
+// probeOrgans checks all organs in parallel, returning per-organ details
+// and whether each is up
+func probeOrgans(ctx context.Context) (map[string]map[string]interface{}, map[string]bool) {
 	organHealth := make(map[string]map[string]interface{})
+	up := make(map[string]bool)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
@@ -306,67 +921,162 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		go func(n string, o *Organ) {
 			defer wg.Done()
 
-			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+			ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 			defer cancel()
 
 			start := time.Now()
-			req, _ := http.NewRequestWithContext(ctx, "GET", o.URL+"/health", nil)
-			resp, err := http.DefaultClient.Do(req)
+			req, _ := http.NewRequestWithContext(ctx, "GET", o.URL+o.HealthPath, nil)
+			resp, err := organClient.Do(req)
 			latency := time.Since(start)
 
 			status := "offline"
-			if err == nil && resp.StatusCode == 200 {
-				status = "healthy"
+			if err == nil {
+				if resp.StatusCode == 200 {
+					status = "healthy"
+				}
 				resp.Body.Close()
 			}
 
 			mu.Lock()
 			organHealth[n] = map[string]interface{}{
-				"status":  status,
-				"latency": latency.Milliseconds(),
+				"status":   status,
+				"latency":  latency.Milliseconds(),
+				"circuit":  breakers[n].State(),
+				"critical": o.Critical,
 			}
+			up[n] = status == "healthy"
 			mu.Unlock()
 		}(name, organ)
 	}
 	wg.Wait()
+	return organHealth, up
+}
+
+// Liveness: the process is up
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// Readiness: no critical organ is down
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	organHealth, up := probeOrgans(r.Context())
+	verdict := rollupHealth(up)
+
+	w.Header().Set("Content-Type", "application/json")
+	status := "ready"
+	if verdict == healthCritical {
+		status = "not_ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"health": verdict,
+		"organs": organHealth,
+	})
+}
 
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	metrics.Thoughts.Add(1)
+
+	organHealth, up := probeOrgans(r.Context())
+	verdict := rollupHealth(up)
 	response := map[string]interface{}{
-		"status": "thinking",
-		"uptime": time.Since(metrics.StartTime).Seconds(),
+		"status":     verdict,
+		"uptime":     time.Since(metrics.StartTime).Seconds(),
+		"version":    version,
+		"commit":     commit,
+		"build_time": buildTime,
+		"go_version": runtime.Version(),
 		"metrics": map[string]int64{
 			"thoughts":     metrics.Thoughts.Load(),
 			"decisions":    metrics.Decisions.Load(),
 			"errors":       metrics.Errors.Load(),
 			"neural_paths": metrics.NeuralPaths.Load(),
+			"max_paths":    int64(maxNeuralPaths),
+			"rejected":     metrics.Rejected.Load(),
+			"throttled":    metrics.Throttled.Load(),
 		},
 		"organs": organHealth,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if verdict == healthCritical {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
 // =============================================================================
 // MAIN
 // =============================================================================
 
 func main() {
+	if err := checkNeuralPaths(maxNeuralPaths); err != nil {
+		log.Fatal(err)
+	}
+
 	r := mux.NewRouter()
 
 	// API routes
 	r.HandleFunc("/analyze", analyzeHandler).Methods("POST")
 	r.HandleFunc("/investigate", investigateHandler).Methods("POST")
 	r.HandleFunc("/health", healthHandler).Methods("GET")
+	r.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", readyzHandler).Methods("GET")
 
 	// Middleware
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("X-Brain-Version", "1.0.0")
+			w.Header().Set("X-Brain-Version", version)
 			next.ServeHTTP(w, r)
 		})
 	})
 
-	fmt.Println(`
+	fmt.Print(`
 ╔═══════════════════════════════════════════════════════════╗
 ║       L Investigation - Go BRAIN                          ║
 ║       Decision-making & coordination                      ║
@@ -388,5 +1098,14 @@ func main() {
 		port = "8085"
 	}
 	fmt.Printf("Starting brain on :%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	// Bounded connections: a slow or stalled client can't hold one open
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: getEnvDuration("BRAIN_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("BRAIN_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      getEnvDuration("BRAIN_WRITE_TIMEOUT", 2*time.Minute),
+		IdleTimeout:       getEnvDuration("BRAIN_IDLE_TIMEOUT", 2*time.Minute),
+	}
+	log.Fatal(srv.ListenAndServe())
 }
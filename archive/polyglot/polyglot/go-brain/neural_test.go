@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// stubOrgans points the named organs at h, each with a fresh breaker,
+// until the test ends
+func stubOrgans(t *testing.T, h http.Handler, names ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	organMu.Lock()
+	defer organMu.Unlock()
+	for _, name := range names {
+		organ, breaker := organs[name], breakers[name]
+		oldURL := organ.URL
+		organ.URL = srv.URL
+		breakers[name] = NewCircuitBreaker(breaker.threshold, breaker.cooldown)
+		t.Cleanup(func() {
+			organMu.Lock()
+			organ.URL = oldURL
+			organMu.Unlock()
+			breakers[name] = breaker
+		})
+	}
+	return srv
+}
+
+func TestCheckNeuralPaths(t *testing.T) {
+	for _, max := range []int{0, -1, investigationPaths, 64} {
+		if err := checkNeuralPaths(max); err != nil {
+			t.Errorf("max %d: %v", max, err)
+		}
+	}
+	if err := checkNeuralPaths(investigationPaths - 1); err == nil {
+		t.Errorf("max %d accepted, but an investigation could never start", investigationPaths-1)
+	}
+}
+
+// Two investigations hold the whole budget while their organ calls hang:
+// a third is turned away with 503, and NeuralPaths never goes past it
+func TestNeuralPathBackpressure(t *testing.T) {
+	defer func(max int, wait time.Duration, sem *semaphore.Weighted) {
+		maxNeuralPaths, neuralPathWait, neuralPathSem = max, wait, sem
+	}(maxNeuralPaths, neuralPathWait, neuralPathSem)
+	maxNeuralPaths, neuralPathWait = 2*investigationPaths, 20*time.Millisecond
+	neuralPathSem = semaphore.NewWeighted(int64(maxNeuralPaths))
+
+	unblock := make(chan struct{})
+	var inFlight atomic.Int32
+	stubOrgans(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/synthesize" {
+			inFlight.Add(1)
+			<-unblock
+		}
+		io.WriteString(w, `{}`)
+	}), "cells", "blood", "veins")
+
+	// Sample NeuralPaths until the investigations are done
+	var peak atomic.Int64
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			if n := metrics.NeuralPaths.Load(); n > peak.Load() {
+				peak.Store(n)
+			}
+			select {
+			case <-stop:
+				return
+			default:
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	investigate := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		investigateHandler(rec, httptest.NewRequest("POST", "/investigate", strings.NewReader(`{"query":"who is alice"}`)))
+		return rec
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = investigate().Code
+		}(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for inFlight.Load() < int32(maxNeuralPaths) {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d organ calls started, want %d", inFlight.Load(), maxNeuralPaths)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rejected := metrics.Rejected.Load()
+	rec := investigate()
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("third investigation = %d (Retry-After %q), want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if got := metrics.Rejected.Load() - rejected; got != 1 {
+		t.Errorf("Rejected grew by %d, want 1", got)
+	}
+
+	close(unblock)
+	wg.Wait()
+	close(stop)
+	<-sampled
+
+	for i, code := range codes {
+		if code != 200 {
+			t.Errorf("investigation %d = %d, want 200", i, code)
+		}
+	}
+	if got := peak.Load(); got > int64(maxNeuralPaths) || got == 0 {
+		t.Errorf("NeuralPaths peaked at %d, want at most %d", got, maxNeuralPaths)
+	}
+	if got := metrics.NeuralPaths.Load(); got != 0 {
+		t.Errorf("NeuralPaths = %d after the investigations, want 0", got)
+	}
+}
+
+func TestOrganLogSampledAndRedacted(t *testing.T) {
+	stubOrgans(t, organsUp, "blood")
+	defer func(l *logSampler) { organLog = l }(organLog)
+	organLog = &logSampler{rate: 0.25}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for i := 0; i < 8; i++ {
+		if _, err := callOrgan(context.Background(), "blood", "/search", map[string]string{"query": "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := strings.Count(logs.String(), "[organ] POST "); n != 2 {
+		t.Errorf("logged %d of 8 calls at rate 0.25, want 2:\n%s", n, logs.String())
+	}
+
+	h := http.Header{"Authorization": {"Bearer hunter2"}, "X-Api-Key": {"hunter3"}, "Content-Type": {"application/json"}}
+	got := redactHeaders(h)
+	if want := "{Authorization=[REDACTED] Content-Type=application/json X-Api-Key=[REDACTED]}"; got != want {
+		t.Errorf("redactHeaders = %s, want %s", got, want)
+	}
+}
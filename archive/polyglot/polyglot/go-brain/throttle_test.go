@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottleWait(t *testing.T) {
+	defer func(d time.Duration) { throttleBackoff = d }(throttleBackoff)
+	throttleBackoff = 100 * time.Millisecond
+
+	tests := []struct {
+		retryAfter string
+		attempt    int
+		want       time.Duration
+	}{
+		{"3", 0, 3 * time.Second},
+		{" 0 ", 2, 0},
+		{"", 0, 100 * time.Millisecond},
+		{"", 2, 400 * time.Millisecond},
+		{"soon", 1, 200 * time.Millisecond},
+		{"-1", 0, 100 * time.Millisecond},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := throttleWait(tt.retryAfter, tt.attempt); got != tt.want {
+			t.Errorf("throttleWait(%q, %d) = %v, want %v", tt.retryAfter, tt.attempt, got, tt.want)
+		}
+	}
+
+	future := time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat)
+	if got := throttleWait(future, 0); got <= 3*time.Second || got > 5*time.Second {
+		t.Errorf("throttleWait(%q) = %v, want about 5s", future, got)
+	}
+}
+
+// throttled answers 429 to the first n calls, with retryAfter if set, then 200
+func throttled(n int32, retryAfter string, calls *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, `{"results":[]}`)
+	}
+}
+
+func TestCallOrganThrottled(t *testing.T) {
+	defer func(retries int, backoff, maxWait time.Duration) {
+		throttleRetries, throttleBackoff, throttleMaxWait = retries, backoff, maxWait
+	}(throttleRetries, throttleBackoff, throttleMaxWait)
+	throttleRetries, throttleBackoff, throttleMaxWait = 2, 10*time.Millisecond, time.Second
+
+	tests := []struct {
+		name       string
+		throttles  int32
+		retryAfter string
+		wantErr    error
+		wantCalls  int32
+		minWait    time.Duration
+	}{
+		{"retried until it answers", 2, "", nil, 3, 30 * time.Millisecond},
+		{"gives up after the retries", 10, "", errThrottled, 3, 30 * time.Millisecond},
+		{"Retry-After over the cap", 10, "5", errThrottled, 1, 0},
+		{"Retry-After within the cap", 1, "0", nil, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			stubOrgans(t, throttled(tt.throttles, tt.retryAfter, &calls), "blood")
+			// A single counted failure would open this breaker
+			breakers["blood"] = NewCircuitBreaker(1, time.Minute)
+			throttledBefore := metrics.Throttled.Load()
+
+			began := time.Now()
+			_, err := callOrgan(context.Background(), "blood", "/search", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("organ called %d times, want %d", n, tt.wantCalls)
+			}
+			if elapsed := time.Since(began); elapsed < tt.minWait {
+				t.Errorf("returned after %v, before backing off %v", elapsed, tt.minWait)
+			}
+
+			organMu.RLock()
+			healthy := organs["blood"].Healthy
+			organMu.RUnlock()
+			if !healthy {
+				t.Error("throttled organ marked unhealthy")
+			}
+			if state := breakers["blood"].State(); state != "closed" {
+				t.Errorf("breaker %s after throttling, want closed", state)
+			}
+			want := int64(min(tt.throttles, tt.wantCalls))
+			if got := metrics.Throttled.Load() - throttledBefore; got != want {
+				t.Errorf("Throttled grew by %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestCallOrganThrottledCancelled(t *testing.T) {
+	defer func(retries int, maxWait time.Duration) {
+		throttleRetries, throttleMaxWait = retries, maxWait
+	}(throttleRetries, throttleMaxWait)
+	throttleRetries, throttleMaxWait = 2, 10*time.Second
+
+	var calls atomic.Int32
+	stubOrgans(t, throttled(10, "5", &calls), "blood")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	began := time.Now()
+	if _, err := callOrgan(ctx, "blood", "/search", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's deadline", err)
+	}
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("waited %v for Retry-After despite the context ending", elapsed)
+	}
+}